@@ -0,0 +1,100 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rook/rook/pkg/agent/csi"
+	"github.com/rook/rook/pkg/agent/flexvolume/crd"
+	"github.com/rook/rook/pkg/agent/flexvolume/manager/ceph"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/kit"
+	"github.com/rook/rook/pkg/util/flags"
+	"github.com/spf13/cobra"
+)
+
+var csiCmd = &cobra.Command{
+	Use:    "csi",
+	Short:  "Runs the rook CSI driver",
+	Hidden: true,
+}
+
+var (
+	csiEndpoint string
+	csiNodeID   string
+)
+
+func init() {
+	csiCmd.Flags().StringVar(&csiEndpoint, "endpoint", csi.DefaultEndpoint, "the endpoint where the CSI driver listens")
+	csiCmd.Flags().StringVar(&csiNodeID, "node-id", "", "the id of the node of the CSI driver. Default is the name of the node")
+	flags.SetFlagsFromEnv(csiCmd.Flags(), "ROOK")
+	csiCmd.RunE = startCSI
+}
+
+func startCSI(cmd *cobra.Command, args []string) error {
+
+	setLogLevel()
+
+	logStartupInfo(csiCmd.Flags())
+
+	if csiNodeID == "" {
+		csiNodeID = os.Getenv(k8sutil.NodeNameEnvVar)
+	}
+	if csiNodeID == "" {
+		return fmt.Errorf("the node id is required. set the --node-id flag or the %s env var", k8sutil.NodeNameEnvVar)
+	}
+
+	clientset, apiExtClientset, err := getClientset()
+	if err != nil {
+		fmt.Printf("failed to get k8s client. %+v", err)
+		os.Exit(1)
+	}
+
+	logger.Info("starting rook CSI driver")
+	context := createContext()
+	context.NetworkInfo = clusterd.NetworkInfo{}
+	context.ConfigDir = k8sutil.DataDir
+	context.Clientset = clientset
+	context.APIExtensionClientset = apiExtClientset
+
+	volumeAttachmentClient, _, err := kit.NewHTTPClient(k8sutil.CustomResourceGroup, k8sutil.V1Alpha1, crd.SchemeBuilder)
+	if err != nil {
+		fmt.Printf("failed to create Volumeattach CRD client. %+v\n", err)
+		os.Exit(1)
+	}
+
+	driver := csi.NewDriver(context, volumeAttachmentClient, ceph.NewVolumeManager(context), csiNodeID, csiEndpoint)
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGTERM)
+	go func() {
+		<-sigc
+		logger.Infof("shutdown signal received, exiting...")
+		driver.Stop()
+	}()
+
+	if err := driver.Run(); err != nil {
+		fmt.Printf("failed to run rook CSI driver. %+v\n", err)
+		os.Exit(1)
+	}
+
+	return nil
+}
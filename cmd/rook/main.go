@@ -94,10 +94,12 @@ func addCommands() {
 	rootCmd.AddCommand(monCmd)
 	rootCmd.AddCommand(osdCmd)
 	rootCmd.AddCommand(mgrCmd)
+	rootCmd.AddCommand(rbdMirrorCmd)
 	rootCmd.AddCommand(rgwCmd)
 	rootCmd.AddCommand(mdsCmd)
 	rootCmd.AddCommand(apiCmd)
 	rootCmd.AddCommand(agentCmd)
+	rootCmd.AddCommand(csiCmd)
 	rootCmd.AddCommand(operatorCmd)
 }
 
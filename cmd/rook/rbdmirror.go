@@ -0,0 +1,78 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rook/rook/pkg/ceph/mon"
+	"github.com/rook/rook/pkg/ceph/rbdmirror"
+	"github.com/rook/rook/pkg/util/flags"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rbdMirrorName     string
+	rbdMirrorKeyring  string
+	rbdMirrorPeersDir string
+)
+
+var rbdMirrorCmd = &cobra.Command{
+	Use:    "rbd-mirror",
+	Short:  "Generates rbd-mirror config and runs the rbd-mirror daemon",
+	Hidden: true,
+}
+
+func init() {
+	rbdMirrorCmd.Flags().StringVar(&rbdMirrorName, "rbd-mirror-name", "", "the rbd-mirror name")
+	rbdMirrorCmd.Flags().StringVar(&rbdMirrorKeyring, "rbd-mirror-keyring", "", "the rbd-mirror keyring")
+	rbdMirrorCmd.Flags().StringVar(&rbdMirrorPeersDir, "rbd-mirror-peers-dir", "", "the dir with the connection settings of the peer clusters")
+	addCephFlags(rbdMirrorCmd)
+
+	flags.SetFlagsFromEnv(rbdMirrorCmd.Flags(), RookEnvVarPrefix)
+
+	rbdMirrorCmd.RunE = startRBDMirror
+}
+
+func startRBDMirror(cmd *cobra.Command, args []string) error {
+	required := []string{"mon-endpoints", "cluster-name", "mon-secret", "admin-secret", "rbd-mirror-name", "rbd-mirror-keyring"}
+	if err := flags.VerifyRequiredFlags(rbdMirrorCmd, required); err != nil {
+		return err
+	}
+
+	setLogLevel()
+
+	logStartupInfo(rbdMirrorCmd.Flags())
+
+	clusterInfo.Monitors = mon.ParseMonEndpoints(cfg.monEndpoints)
+	config := &rbdmirror.Config{
+		Name:          rbdMirrorName,
+		Keyring:       rbdMirrorKeyring,
+		ClusterInfo:   &clusterInfo,
+		PeersDir:      rbdMirrorPeersDir,
+		PeerConfigDir: rbdmirror.DefaultPeerConfigDir,
+		InProc:        true,
+	}
+
+	err := rbdmirror.Run(createContext(), config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	return nil
+}
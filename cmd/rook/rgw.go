@@ -33,20 +33,58 @@ var rgwCmd = &cobra.Command{
 
 var (
 	rgwName       string
+	rgwRealm      string
+	rgwZoneGroup  string
 	rgwKeyring    string
 	rgwHost       string
+	rgwDNSName    string
 	rgwCert       string
+	rgwKey        string
 	rgwPort       int
 	rgwSecurePort int
+	rgwFrontend   string
+	rgwThreads    int
+	rgwTimeoutMs  int
+	rgwRedirect   bool
+	rgwLDAP       rgw.LDAPConfig
+	rgwKeystone   rgw.KeystoneConfig
 )
 
 func init() {
 	rgwCmd.Flags().StringVar(&rgwName, "rgw-name", "", "name of the object store")
+	rgwCmd.Flags().StringVar(&rgwRealm, "rgw-realm", "", "name of the realm of a secondary zone")
+	rgwCmd.Flags().StringVar(&rgwZoneGroup, "rgw-zonegroup", "", "name of the zone group of a secondary zone")
 	rgwCmd.Flags().StringVar(&rgwKeyring, "rgw-keyring", "", "the rgw keyring")
 	rgwCmd.Flags().StringVar(&rgwHost, "rgw-host", "", "dns host name")
+	rgwCmd.Flags().StringVar(&rgwDNSName, "rgw-dns-name", "", "domain of the virtual-host-style requests to the buckets (default is the host)")
 	rgwCmd.Flags().StringVar(&rgwCert, "rgw-cert", "", "path to the ssl certificate in pem format")
 	rgwCmd.Flags().IntVar(&rgwPort, "rgw-port", 0, "rgw port (http)")
 	rgwCmd.Flags().IntVar(&rgwSecurePort, "rgw-secure-port", 0, "rgw secure port number (https)")
+	rgwCmd.Flags().StringVar(&rgwKey, "rgw-key", "", "path to the ssl private key in pem format if it is not in the certificate")
+	rgwCmd.Flags().StringVar(&rgwFrontend, "rgw-frontend", rgw.FrontendCivetweb, "the http frontend (civetweb or beast)")
+	rgwCmd.Flags().IntVar(&rgwThreads, "rgw-thread-pool-size", 0, "number of threads that serve the requests")
+	rgwCmd.Flags().IntVar(&rgwTimeoutMs, "rgw-request-timeout-ms", 0, "timeout of the requests in milliseconds")
+	rgwCmd.Flags().BoolVar(&rgwRedirect, "rgw-redirect-http", false, "redirect the http port to the https port")
+
+	// ldap authentication
+	rgwCmd.Flags().StringVar(&rgwLDAP.URI, "rgw-ldap-uri", "", "uri of the ldap server that authenticates the s3 users")
+	rgwCmd.Flags().StringVar(&rgwLDAP.BindDN, "rgw-ldap-binddn", "", "dn of the ldap service account")
+	rgwCmd.Flags().StringVar(&rgwLDAP.SecretPath, "rgw-ldap-secret-path", "", "path to the password of the ldap service account")
+	rgwCmd.Flags().StringVar(&rgwLDAP.SearchDN, "rgw-ldap-searchdn", "", "base dn of the ldap users")
+	rgwCmd.Flags().StringVar(&rgwLDAP.DNAttribute, "rgw-ldap-dnattr", "", "attribute of the user dn with the user name")
+	rgwCmd.Flags().StringVar(&rgwLDAP.SearchFilter, "rgw-ldap-searchfilter", "", "filter of the ldap users")
+
+	// keystone authentication
+	rgwCmd.Flags().StringVar(&rgwKeystone.URL, "rgw-keystone-url", "", "url of the keystone service that authenticates the users")
+	rgwCmd.Flags().IntVar(&rgwKeystone.APIVersion, "rgw-keystone-api-version", 0, "keystone api version (2 or 3)")
+	rgwCmd.Flags().StringVar(&rgwKeystone.AdminUser, "rgw-keystone-admin-user", "", "keystone admin user")
+	rgwCmd.Flags().StringVar(&rgwKeystone.AdminPassword, "rgw-keystone-admin-secret", "", "password of the keystone admin user")
+	rgwCmd.Flags().StringVar(&rgwKeystone.AdminProject, "rgw-keystone-admin-project", "", "project of the keystone admin user")
+	rgwCmd.Flags().StringVar(&rgwKeystone.AdminDomain, "rgw-keystone-admin-domain", "", "domain of the keystone admin user")
+	rgwCmd.Flags().StringSliceVar(&rgwKeystone.AcceptedRoles, "rgw-keystone-accepted-roles", nil, "keystone roles of the users allowed to access the object store")
+	rgwCmd.Flags().BoolVar(&rgwKeystone.ImplicitTenants, "rgw-keystone-implicit-tenants", false, "create an rgw tenant for each keystone project")
+	rgwCmd.Flags().BoolVar(&rgwKeystone.S3, "rgw-keystone-s3", false, "authenticate the s3 requests with keystone")
+	rgwCmd.Flags().BoolVar(&rgwKeystone.InsecureSkipVerify, "rgw-keystone-insecure-skip-verify", false, "do not verify the certificate of the keystone service")
 	addCephFlags(rgwCmd)
 
 	flags.SetFlagsFromEnv(rgwCmd.Flags(), RookEnvVarPrefix)
@@ -70,14 +108,28 @@ func startRGW(cmd *cobra.Command, args []string) error {
 
 	clusterInfo.Monitors = mon.ParseMonEndpoints(cfg.monEndpoints)
 	config := &rgw.Config{
-		ClusterInfo:     &clusterInfo,
-		Name:            rgwName,
-		Keyring:         rgwKeyring,
-		Host:            rgwHost,
-		Port:            rgwPort,
-		SecurePort:      rgwSecurePort,
-		CertificatePath: rgwCert,
-		InProc:          true,
+		ClusterInfo:      &clusterInfo,
+		Name:             rgwName,
+		Realm:            rgwRealm,
+		ZoneGroup:        rgwZoneGroup,
+		Keyring:          rgwKeyring,
+		Host:             rgwHost,
+		DNSName:          rgwDNSName,
+		Port:             rgwPort,
+		SecurePort:       rgwSecurePort,
+		CertificatePath:  rgwCert,
+		KeyPath:          rgwKey,
+		Frontend:         rgwFrontend,
+		ThreadPoolSize:   rgwThreads,
+		RequestTimeoutMs: rgwTimeoutMs,
+		RedirectHTTP:     rgwRedirect,
+		InProc:           true,
+	}
+	if rgwLDAP.URI != "" {
+		config.LDAP = &rgwLDAP
+	}
+	if rgwKeystone.URL != "" {
+		config.Keystone = &rgwKeystone
 	}
 
 	err := rgw.Run(createContext(), config)
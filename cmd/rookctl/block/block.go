@@ -30,6 +30,9 @@ func init() {
 	Cmd.AddCommand(listCmd)
 	Cmd.AddCommand(createCmd)
 	Cmd.AddCommand(deleteCmd)
+	Cmd.AddCommand(resizeCmd)
+	Cmd.AddCommand(flattenCmd)
+	Cmd.AddCommand(snapshotCmd)
 
 	if runtime.GOOS == "linux" {
 		Cmd.AddCommand(mapCmd)
@@ -0,0 +1,80 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package block
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rook/rook/cmd/rookctl/rook"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/rook/client"
+	"github.com/rook/rook/pkg/util/flags"
+	"github.com/spf13/cobra"
+)
+
+var (
+	resizeImageName     string
+	resizeImagePoolName string
+	resizeImageSize     uint64
+)
+
+var resizeCmd = &cobra.Command{
+	Use:   "resize",
+	Short: "Grows a block image in the cluster to a new size",
+}
+
+func init() {
+	resizeCmd.Flags().StringVar(&resizeImageName, "name", "", "Name of block image to resize (required)")
+	resizeCmd.Flags().StringVar(&resizeImagePoolName, "pool-name", "rbd", "Name of storage pool of the block image")
+	resizeCmd.Flags().Uint64Var(&resizeImageSize, "size", 0, "New size in bytes of the block image (required)")
+
+	resizeCmd.MarkFlagRequired("name")
+	resizeCmd.MarkFlagRequired("size")
+	resizeCmd.RunE = resizeBlockImageEntry
+}
+
+func resizeBlockImageEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := flags.VerifyRequiredFlags(cmd, []string{"name"}); err != nil {
+		return err
+	}
+
+	if err := flags.VerifyRequiredUint64Flags(cmd, []string{"size"}); err != nil {
+		return err
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	out, err := resizeBlockImage(resizeImageName, resizeImagePoolName, resizeImageSize, c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Println(out)
+	return nil
+}
+
+func resizeBlockImage(imageName, poolName string, size uint64, c client.RookRestClient) (string, error) {
+	image := model.BlockImage{Name: imageName, PoolName: poolName, Size: size}
+	resp, err := c.ResizeBlockImage(image)
+	if err != nil {
+		return "", fmt.Errorf("failed to resize block image '%+v': %+v", image, err)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package block
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/rook/rook/cmd/rookctl/rook"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/rook/client"
+	"github.com/rook/rook/pkg/util/display"
+	"github.com/rook/rook/pkg/util/flags"
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapImageName     string
+	snapImagePoolName string
+	snapName          string
+	cloneName         string
+	clonePoolName     string
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Performs commands and operations on snapshots of block images",
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "Gets a listing of the snapshots of a block image",
+	Aliases: []string{"ls"},
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Creates a snapshot of a block image",
+}
+
+var snapshotDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Deletes a snapshot of a block image",
+}
+
+var snapshotRollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Reverts a block image to the content of a snapshot",
+}
+
+var snapshotProtectCmd = &cobra.Command{
+	Use:   "protect",
+	Short: "Protects a snapshot from deletion so it can be cloned",
+}
+
+var snapshotUnprotectCmd = &cobra.Command{
+	Use:   "unprotect",
+	Short: "Removes the protection of a snapshot",
+}
+
+var snapshotCloneCmd = &cobra.Command{
+	Use:   "clone",
+	Short: "Creates a new block image from a protected snapshot",
+}
+
+func init() {
+	snapshotCmd.PersistentFlags().StringVar(&snapImageName, "name", "", "Name of the block image (required)")
+	snapshotCmd.PersistentFlags().StringVar(&snapImagePoolName, "pool-name", "rbd", "Name of the storage pool of the block image")
+
+	for _, cmd := range []*cobra.Command{snapshotCreateCmd, snapshotDeleteCmd, snapshotRollbackCmd,
+		snapshotProtectCmd, snapshotUnprotectCmd, snapshotCloneCmd} {
+		cmd.Flags().StringVar(&snapName, "snapshot", "", "Name of the snapshot (required)")
+		cmd.MarkFlagRequired("snapshot")
+	}
+	snapshotCloneCmd.Flags().StringVar(&cloneName, "clone-name", "", "Name of the new block image (required)")
+	snapshotCloneCmd.Flags().StringVar(&clonePoolName, "clone-pool-name", "", "Name of the storage pool of the new block image. Defaults to the pool of the snapshot.")
+	snapshotCloneCmd.MarkFlagRequired("clone-name")
+
+	snapshotListCmd.RunE = listSnapshotsEntry
+	snapshotCreateCmd.RunE = snapshotActionEntry(createSnapshot)
+	snapshotDeleteCmd.RunE = snapshotActionEntry(deleteSnapshot)
+	snapshotRollbackCmd.RunE = snapshotActionEntry(rollbackSnapshot)
+	snapshotProtectCmd.RunE = snapshotActionEntry(protectSnapshot)
+	snapshotUnprotectCmd.RunE = snapshotActionEntry(unprotectSnapshot)
+	snapshotCloneCmd.RunE = cloneSnapshotEntry
+
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+	snapshotCmd.AddCommand(snapshotDeleteCmd)
+	snapshotCmd.AddCommand(snapshotRollbackCmd)
+	snapshotCmd.AddCommand(snapshotProtectCmd)
+	snapshotCmd.AddCommand(snapshotUnprotectCmd)
+	snapshotCmd.AddCommand(snapshotCloneCmd)
+}
+
+func listSnapshotsEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := flags.VerifyRequiredFlags(cmd, []string{"name"}); err != nil {
+		return err
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	out, err := listSnapshots(snapImageName, snapImagePoolName, c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Print(out)
+	return nil
+}
+
+func listSnapshots(imageName, poolName string, c client.RookRestClient) (string, error) {
+	snapshots, err := c.GetBlockImageSnapshots(model.BlockImage{Name: imageName, PoolName: poolName})
+	if err != nil {
+		return "", fmt.Errorf("failed to get snapshots of block image %s: %+v", imageName, err)
+	}
+
+	if len(snapshots) == 0 {
+		return "", nil
+	}
+
+	var buffer bytes.Buffer
+	w := rook.NewTableWriter(&buffer)
+
+	fmt.Fprintln(w, "NAME\tIMAGE\tPOOL\tSIZE")
+
+	for _, s := range snapshots {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Name, s.ImageName, s.PoolName, display.BytesToString(s.Size))
+	}
+
+	w.Flush()
+	return buffer.String(), nil
+}
+
+// returns the entry point of a command that acts on a single snapshot
+func snapshotActionEntry(action func(snapshot model.BlockSnapshot, c client.RookRestClient) (string, error)) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		rook.SetupLogging()
+
+		if err := flags.VerifyRequiredFlags(cmd, []string{"name", "snapshot"}); err != nil {
+			return err
+		}
+
+		c := rook.NewRookNetworkRestClient()
+		snapshot := model.BlockSnapshot{Name: snapName, ImageName: snapImageName, PoolName: snapImagePoolName}
+		out, err := action(snapshot, c)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		fmt.Println(out)
+		return nil
+	}
+}
+
+func createSnapshot(snapshot model.BlockSnapshot, c client.RookRestClient) (string, error) {
+	resp, err := c.CreateBlockImageSnapshot(snapshot)
+	if err != nil {
+		return "", fmt.Errorf("failed to create snapshot '%+v': %+v", snapshot, err)
+	}
+
+	return resp, nil
+}
+
+func deleteSnapshot(snapshot model.BlockSnapshot, c client.RookRestClient) (string, error) {
+	resp, err := c.DeleteBlockImageSnapshot(snapshot)
+	if err != nil {
+		return "", fmt.Errorf("failed to delete snapshot '%+v': %+v", snapshot, err)
+	}
+
+	return resp, nil
+}
+
+func rollbackSnapshot(snapshot model.BlockSnapshot, c client.RookRestClient) (string, error) {
+	resp, err := c.RollbackBlockImageSnapshot(snapshot)
+	if err != nil {
+		return "", fmt.Errorf("failed to rollback snapshot '%+v': %+v", snapshot, err)
+	}
+
+	return resp, nil
+}
+
+func protectSnapshot(snapshot model.BlockSnapshot, c client.RookRestClient) (string, error) {
+	resp, err := c.ProtectBlockImageSnapshot(snapshot)
+	if err != nil {
+		return "", fmt.Errorf("failed to protect snapshot '%+v': %+v", snapshot, err)
+	}
+
+	return resp, nil
+}
+
+func unprotectSnapshot(snapshot model.BlockSnapshot, c client.RookRestClient) (string, error) {
+	resp, err := c.UnprotectBlockImageSnapshot(snapshot)
+	if err != nil {
+		return "", fmt.Errorf("failed to unprotect snapshot '%+v': %+v", snapshot, err)
+	}
+
+	return resp, nil
+}
+
+func cloneSnapshotEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := flags.VerifyRequiredFlags(cmd, []string{"name", "snapshot", "clone-name"}); err != nil {
+		return err
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	snapshot := model.BlockSnapshot{Name: snapName, ImageName: snapImageName, PoolName: snapImagePoolName}
+	out, err := cloneSnapshot(snapshot, cloneName, clonePoolName, c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Println(out)
+	return nil
+}
+
+func cloneSnapshot(snapshot model.BlockSnapshot, cloneName, clonePoolName string, c client.RookRestClient) (string, error) {
+	clone := model.BlockImage{Name: cloneName, PoolName: clonePoolName}
+	resp, err := c.CloneBlockImageSnapshot(snapshot, clone)
+	if err != nil {
+		return "", fmt.Errorf("failed to clone snapshot '%+v' to '%+v': %+v", snapshot, clone, err)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package block
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/rook/client"
+	"github.com/rook/rook/pkg/rook/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListSnapshots(t *testing.T) {
+	c := &test.MockRookRestClient{
+		MockGetBlockImageSnapshots: func(image model.BlockImage) ([]model.BlockSnapshot, error) {
+			return []model.BlockSnapshot{
+				{Name: "snap1", ImageName: image.Name, PoolName: image.PoolName, Size: 1048576},
+			}, nil
+		},
+	}
+
+	out, err := listSnapshots("myimage1", "mypool1", c)
+	assert.Nil(t, err)
+	assert.Equal(t, "NAME      IMAGE      POOL      SIZE\nsnap1     myimage1   mypool1   1.00 MiB\n", out)
+}
+
+func TestListSnapshotsFailure(t *testing.T) {
+	c := &test.MockRookRestClient{
+		MockGetBlockImageSnapshots: func(image model.BlockImage) ([]model.BlockSnapshot, error) {
+			return nil, fmt.Errorf("mock failure")
+		},
+	}
+
+	out, err := listSnapshots("myimage1", "mypool1", c)
+	assert.NotNil(t, err)
+	assert.Equal(t, "", out)
+}
+
+func TestSnapshotActions(t *testing.T) {
+	var calls []string
+	record := func(action string) func(snapshot model.BlockSnapshot) (string, error) {
+		return func(snapshot model.BlockSnapshot) (string, error) {
+			calls = append(calls, fmt.Sprintf("%s %s/%s@%s", action, snapshot.PoolName, snapshot.ImageName, snapshot.Name))
+			return fmt.Sprintf("succeeded %s", action), nil
+		}
+	}
+	c := &test.MockRookRestClient{
+		MockCreateBlockImageSnapshot:    record("create"),
+		MockDeleteBlockImageSnapshot:    record("delete"),
+		MockRollbackBlockImageSnapshot:  record("rollback"),
+		MockProtectBlockImageSnapshot:   record("protect"),
+		MockUnprotectBlockImageSnapshot: record("unprotect"),
+	}
+
+	snapshot := model.BlockSnapshot{Name: "snap1", ImageName: "myimage1", PoolName: "mypool1"}
+	for _, action := range []func(model.BlockSnapshot, client.RookRestClient) (string, error){
+		createSnapshot, protectSnapshot, unprotectSnapshot, rollbackSnapshot, deleteSnapshot} {
+		_, err := action(snapshot, c)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, []string{
+		"create mypool1/myimage1@snap1",
+		"protect mypool1/myimage1@snap1",
+		"unprotect mypool1/myimage1@snap1",
+		"rollback mypool1/myimage1@snap1",
+		"delete mypool1/myimage1@snap1",
+	}, calls)
+}
+
+func TestCloneSnapshot(t *testing.T) {
+	c := &test.MockRookRestClient{
+		MockCloneBlockImageSnapshot: func(snapshot model.BlockSnapshot, clone model.BlockImage) (string, error) {
+			return fmt.Sprintf("cloned %s to %s/%s", snapshot.Name, clone.PoolName, clone.Name), nil
+		},
+	}
+
+	snapshot := model.BlockSnapshot{Name: "snap1", ImageName: "myimage1", PoolName: "mypool1"}
+	out, err := cloneSnapshot(snapshot, "myclone", "mypool2", c)
+	assert.Nil(t, err)
+	assert.Equal(t, "cloned snap1 to mypool2/myclone", out)
+
+	c.MockCloneBlockImageSnapshot = func(snapshot model.BlockSnapshot, clone model.BlockImage) (string, error) {
+		return "", fmt.Errorf("snapshot is not protected")
+	}
+	out, err = cloneSnapshot(snapshot, "myclone", "", c)
+	assert.NotNil(t, err)
+	assert.Equal(t, "", out)
+}
@@ -0,0 +1,351 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package object
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/rook/rook/cmd/rookctl/rook"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/rook/client"
+	"github.com/spf13/cobra"
+)
+
+var (
+	maxSizeFlag      int64
+	maxObjectsFlag   int64
+	disableQuotaFlag bool
+	fixIndexFlag     bool
+	fileFlag         string
+)
+
+var bucketPolicyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Gets, sets or deletes the policy of a bucket",
+}
+
+var bucketLifecycleCmd = &cobra.Command{
+	Use:   "lifecycle",
+	Short: "Gets, sets or deletes the lifecycle rules of a bucket",
+}
+
+func init() {
+	bucketCmd.AddCommand(bucketQuotaCmd)
+	bucketQuotaCmd.RunE = setBucketQuotaEntry
+	bucketCmd.AddCommand(bucketLinkCmd)
+	bucketLinkCmd.RunE = linkBucketEntry
+	bucketCmd.AddCommand(bucketReshardCmd)
+	bucketReshardCmd.RunE = reshardBucketEntry
+	bucketCmd.AddCommand(bucketCheckCmd)
+	bucketCheckCmd.RunE = checkBucketEntry
+
+	bucketCmd.AddCommand(bucketPolicyCmd)
+	bucketPolicyCmd.AddCommand(policyGetCmd)
+	policyGetCmd.RunE = getBucketPolicyEntry
+	bucketPolicyCmd.AddCommand(policySetCmd)
+	policySetCmd.RunE = setBucketPolicyEntry
+	bucketPolicyCmd.AddCommand(policyDeleteCmd)
+	policyDeleteCmd.RunE = deleteBucketPolicyEntry
+
+	bucketCmd.AddCommand(bucketLifecycleCmd)
+	bucketLifecycleCmd.AddCommand(lifecycleGetCmd)
+	lifecycleGetCmd.RunE = getBucketLifecycleEntry
+	bucketLifecycleCmd.AddCommand(lifecycleSetCmd)
+	lifecycleSetCmd.RunE = setBucketLifecycleEntry
+	bucketLifecycleCmd.AddCommand(lifecycleDeleteCmd)
+	lifecycleDeleteCmd.RunE = deleteBucketLifecycleEntry
+
+	bucketQuotaCmd.Flags().Int64Var(&maxSizeFlag, "max-size", 0, "The maximum size of the objects in the bucket in bytes (0 is unlimited)")
+	bucketQuotaCmd.Flags().Int64Var(&maxObjectsFlag, "max-objects", 0, "The maximum number of objects in the bucket (0 is unlimited)")
+	bucketQuotaCmd.Flags().BoolVar(&disableQuotaFlag, "disable", false, "Disables the quota of the bucket")
+
+	bucketCheckCmd.Flags().BoolVar(&fixIndexFlag, "fix", false, "Repairs the index of the bucket")
+
+	policySetCmd.Flags().StringVarP(&fileFlag, "file", "f", "", "The file with the policy document in json, or - for stdin")
+	lifecycleSetCmd.Flags().StringVarP(&fileFlag, "file", "f", "", "The file with the lifecycle rules in json, or - for stdin")
+}
+
+var bucketQuotaCmd = &cobra.Command{
+	Use:   "quota [ObjectStore] [BucketName]",
+	Short: "Sets or disables the quota of a bucket",
+}
+
+func setBucketQuotaEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := checkObjectArgs(args, []string{"[BucketName]"}); err != nil {
+		return err
+	}
+
+	var quota *model.ObjectBucketQuota
+	if !disableQuotaFlag {
+		quota = &model.ObjectBucketQuota{MaxSize: maxSizeFlag, MaxObjects: maxObjectsFlag}
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	out, err := setBucketQuota(c, args[0], args[1], quota)
+	return printBucketResult(out, err)
+}
+
+func setBucketQuota(c client.RookRestClient, storeName, bucketName string, quota *model.ObjectBucketQuota) (string, error) {
+	if err := bucketError(c.SetBucketQuota(storeName, bucketName, quota), bucketName, "set quota of"); err != nil {
+		return "", err
+	}
+	if quota == nil {
+		return "Bucket quota disabled\n", nil
+	}
+	return "Bucket quota set\n", nil
+}
+
+var bucketLinkCmd = &cobra.Command{
+	Use:   "link [ObjectStore] [BucketName] [UserID]",
+	Short: "Transfers the bucket to another user",
+}
+
+func linkBucketEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := checkObjectArgs(args, []string{"[BucketName]", "[UserID]"}); err != nil {
+		return err
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	err := bucketError(c.SetBucketOwner(args[0], args[1], args[2]), args[1], "link")
+	return printBucketResult(fmt.Sprintf("Bucket linked to %s\n", args[2]), err)
+}
+
+var bucketReshardCmd = &cobra.Command{
+	Use:   "reshard [ObjectStore] [BucketName] [NumShards]",
+	Short: "Reshards the index of the bucket",
+}
+
+func reshardBucketEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := checkObjectArgs(args, []string{"[BucketName]", "[NumShards]"}); err != nil {
+		return err
+	}
+	numShards, err := strconv.Atoi(args[2])
+	if err != nil {
+		return fmt.Errorf("invalid number of shards %s", args[2])
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	err = bucketError(c.ReshardBucket(args[0], args[1], numShards), args[1], "reshard")
+	return printBucketResult("Bucket resharded\n", err)
+}
+
+var bucketCheckCmd = &cobra.Command{
+	Use:   "check [ObjectStore] [BucketName]",
+	Short: "Checks the index of the bucket against its objects, and repairs the index with --fix",
+}
+
+func checkBucketEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := checkObjectArgs(args, []string{"[BucketName]"}); err != nil {
+		return err
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	check, err := c.CheckBucketIndex(args[0], args[1], fixIndexFlag)
+	if err = bucketError(err, args[1], "check"); err != nil {
+		return printBucketResult("", err)
+	}
+	return printBucketResult(fmt.Sprintln(check.Result), nil)
+}
+
+var policyGetCmd = &cobra.Command{
+	Use:   "get [ObjectStore] [BucketName]",
+	Short: "Gets the policy document of the bucket",
+}
+
+func getBucketPolicyEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := checkObjectArgs(args, []string{"[BucketName]"}); err != nil {
+		return err
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	policy, err := c.GetBucketPolicy(args[0], args[1])
+	if client.IsHttpNotFound(err) {
+		return printBucketResult("", fmt.Errorf("Unable to find bucket %s or its policy", args[1]))
+	}
+	return printBucketResult(fmt.Sprintln(policy), bucketError(err, args[1], "get policy of"))
+}
+
+var policySetCmd = &cobra.Command{
+	Use:   "set [ObjectStore] [BucketName] --file [PolicyFile]",
+	Short: "Replaces the policy of the bucket with the policy document",
+}
+
+func setBucketPolicyEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := checkObjectArgs(args, []string{"[BucketName]"}); err != nil {
+		return err
+	}
+	policy, err := readFileFlag()
+	if err != nil {
+		return err
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	err = bucketError(c.SetBucketPolicy(args[0], args[1], string(policy)), args[1], "set policy of")
+	return printBucketResult("Bucket policy set\n", err)
+}
+
+var policyDeleteCmd = &cobra.Command{
+	Use:   "delete [ObjectStore] [BucketName]",
+	Short: "Deletes the policy of the bucket",
+}
+
+func deleteBucketPolicyEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := checkObjectArgs(args, []string{"[BucketName]"}); err != nil {
+		return err
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	err := bucketError(c.DeleteBucketPolicy(args[0], args[1]), args[1], "delete policy of")
+	return printBucketResult("Bucket policy deleted\n", err)
+}
+
+var lifecycleGetCmd = &cobra.Command{
+	Use:   "get [ObjectStore] [BucketName]",
+	Short: "Gets the lifecycle rules of the bucket",
+}
+
+func getBucketLifecycleEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := checkObjectArgs(args, []string{"[BucketName]"}); err != nil {
+		return err
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	out, err := getBucketLifecycle(c, args[0], args[1])
+	return printBucketResult(out, err)
+}
+
+func getBucketLifecycle(c client.RookRestClient, storeName, bucketName string) (string, error) {
+	rules, err := c.GetBucketLifecycle(storeName, bucketName)
+	if client.IsHttpNotFound(err) {
+		return "", fmt.Errorf("Unable to find bucket %s or its lifecycle", bucketName)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get lifecycle of bucket: %+v", err)
+	}
+
+	var buffer bytes.Buffer
+	w := rook.NewTableWriter(&buffer)
+
+	fmt.Fprintln(w, "ID\tPREFIX\tENABLED\tEXPIRATION DAYS\tNONCURRENT EXPIRATION DAYS\tABORT UPLOAD DAYS")
+
+	for _, r := range rules {
+		fmt.Fprintf(w, "%s\t%s\t%t\t%d\t%d\t%d\n", r.ID, r.Prefix, !r.Disabled, r.ExpirationDays,
+			r.NoncurrentVersionExpirationDays, r.AbortIncompleteMultipartUploadDays)
+	}
+
+	w.Flush()
+	return buffer.String(), nil
+}
+
+var lifecycleSetCmd = &cobra.Command{
+	Use:   "set [ObjectStore] [BucketName] --file [RulesFile]",
+	Short: "Replaces the lifecycle rules of the bucket with the rules in json",
+}
+
+func setBucketLifecycleEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := checkObjectArgs(args, []string{"[BucketName]"}); err != nil {
+		return err
+	}
+	data, err := readFileFlag()
+	if err != nil {
+		return err
+	}
+	var rules []model.ObjectBucketLifecycleRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("invalid lifecycle rules. %+v", err)
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	err = bucketError(c.SetBucketLifecycle(args[0], args[1], rules), args[1], "set lifecycle of")
+	return printBucketResult("Bucket lifecycle set\n", err)
+}
+
+var lifecycleDeleteCmd = &cobra.Command{
+	Use:   "delete [ObjectStore] [BucketName]",
+	Short: "Deletes the lifecycle rules of the bucket",
+}
+
+func deleteBucketLifecycleEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := checkObjectArgs(args, []string{"[BucketName]"}); err != nil {
+		return err
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	err := bucketError(c.DeleteBucketLifecycle(args[0], args[1]), args[1], "delete lifecycle of")
+	return printBucketResult("Bucket lifecycle deleted\n", err)
+}
+
+// bucketError converts the error of a bucket operation to the message for the user
+func bucketError(err error, bucketName, action string) error {
+	if err == nil {
+		return nil
+	}
+	if client.IsHttpNotFound(err) {
+		return fmt.Errorf("Unable to find bucket %s", bucketName)
+	}
+	if client.IsHttpStatusCode(err, http.StatusUnprocessableEntity) {
+		restErr := err.(client.RookRestError)
+		return fmt.Errorf(string(restErr.Body))
+	}
+	return fmt.Errorf("failed to %s bucket: %+v", action, err)
+}
+
+func printBucketResult(out string, err error) error {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Print(out)
+	return nil
+}
+
+// readFileFlag reads the file of the --file flag, or stdin if the file is -
+func readFileFlag() ([]byte, error) {
+	if fileFlag == "" {
+		return nil, fmt.Errorf("--file is required")
+	}
+	if fileFlag == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(fileFlag)
+}
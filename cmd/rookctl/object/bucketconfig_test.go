@@ -0,0 +1,66 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package object
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/rook/client"
+	"github.com/rook/rook/pkg/rook/test"
+)
+
+func TestSetBucketQuota(t *testing.T) {
+	var quotas []*model.ObjectBucketQuota
+	c := &test.MockRookRestClient{
+		MockSetBucketQuota: func(storeName, bucketName string, quota *model.ObjectBucketQuota) error {
+			quotas = append(quotas, quota)
+			return nil
+		},
+	}
+
+	out, err := setBucketQuota(c, "my-store", "photos", &model.ObjectBucketQuota{MaxObjects: 100})
+	assert.Nil(t, err)
+	assert.Equal(t, "Bucket quota set\n", out)
+
+	out, err = setBucketQuota(c, "my-store", "photos", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "Bucket quota disabled\n", out)
+	assert.Equal(t, []*model.ObjectBucketQuota{{MaxObjects: 100}, nil}, quotas)
+
+	// the bucket is not found
+	c.MockSetBucketQuota = func(storeName, bucketName string, quota *model.ObjectBucketQuota) error {
+		return client.RookRestError{Status: http.StatusNotFound}
+	}
+	_, err = setBucketQuota(c, "my-store", "photos", nil)
+	assert.Equal(t, "Unable to find bucket photos", err.Error())
+}
+
+func TestGetBucketLifecycle(t *testing.T) {
+	c := &test.MockRookRestClient{
+		MockGetBucketLifecycle: func(storeName, bucketName string) ([]model.ObjectBucketLifecycleRule, error) {
+			return []model.ObjectBucketLifecycleRule{{ID: "logs", Prefix: "logs/", ExpirationDays: 30}}, nil
+		},
+	}
+
+	out, err := getBucketLifecycle(c, "my-store", "photos")
+	assert.Nil(t, err)
+	assert.Contains(t, out, "logs/")
+	assert.Contains(t, out, "30")
+}
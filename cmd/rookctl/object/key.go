@@ -0,0 +1,342 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rook/rook/cmd/rookctl/rook"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/rook/client"
+	"github.com/spf13/cobra"
+)
+
+var (
+	accessKeyFlag string
+	secretKeyFlag string
+	swiftUserFlag string
+	accessFlag    string
+	overlapFlag   time.Duration
+)
+
+var userKeyCmd = &cobra.Command{
+	Use:   "key",
+	Short: "Performs commands and operations on the S3 and swift keys of object store users",
+}
+
+var userSubuserCmd = &cobra.Command{
+	Use:   "subuser",
+	Short: "Performs commands and operations on the swift subusers of object store users",
+}
+
+func init() {
+	userCmd.AddCommand(userKeyCmd)
+	userKeyCmd.AddCommand(keyListCmd)
+	keyListCmd.RunE = listKeysEntry
+	userKeyCmd.AddCommand(keyCreateCmd)
+	keyCreateCmd.RunE = createKeyEntry
+	userKeyCmd.AddCommand(keyDeleteCmd)
+	keyDeleteCmd.RunE = deleteKeyEntry
+	userKeyCmd.AddCommand(keyRotateCmd)
+	keyRotateCmd.RunE = rotateKeysEntry
+
+	userCmd.AddCommand(userSubuserCmd)
+	userSubuserCmd.AddCommand(subuserCreateCmd)
+	subuserCreateCmd.RunE = createSubuserEntry
+	userSubuserCmd.AddCommand(subuserDeleteCmd)
+	subuserDeleteCmd.RunE = deleteSubuserEntry
+
+	keyCreateCmd.Flags().StringVar(&accessKeyFlag, "access-key", "", "The access key of the S3 key. Generated if not set.")
+	keyCreateCmd.Flags().StringVar(&secretKeyFlag, "secret-key", "", "The secret key. Generated if not set.")
+	keyCreateCmd.Flags().StringVar(&swiftUserFlag, "subuser", "", "Replaces the swift key of the subuser instead of adding an S3 key")
+
+	keyRotateCmd.Flags().DurationVar(&overlapFlag, "overlap", 0,
+		"How long the old keys remain valid after the new key is created, e.g. 10m. The old keys are revoked immediately if 0.")
+
+	subuserCreateCmd.Flags().StringVar(&accessFlag, "access", "full", "The access of the subuser: read, write, readwrite or full")
+}
+
+var keyListCmd = &cobra.Command{
+	Use:     "list [ObjectStore] [UserID]",
+	Short:   "Gets a listing of the keys of the user",
+	Aliases: []string{"ls"},
+}
+
+func listKeysEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := checkObjectArgs(args, []string{"[UserID]"}); err != nil {
+		return err
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	out, err := listKeys(c, args[0], args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Print(out)
+	return nil
+}
+
+func listKeys(c client.RookRestClient, storeName, id string) (string, error) {
+	keys, err := c.ListObjectUserKeys(storeName, id)
+	if client.IsHttpNotFound(err) {
+		return "", fmt.Errorf("Unable to find user %s", id)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get keys: %+v", err)
+	}
+
+	if len(keys) == 0 {
+		return "", nil
+	}
+
+	var buffer bytes.Buffer
+	w := rook.NewTableWriter(&buffer)
+
+	fmt.Fprintln(w, "USER\tTYPE\tACCESS KEY\tSECRET KEY")
+
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", k.User, k.Type, k.AccessKey, k.SecretKey)
+	}
+
+	w.Flush()
+	return buffer.String(), nil
+}
+
+var keyCreateCmd = &cobra.Command{
+	Use:   "create [ObjectStore] [UserID]",
+	Short: "Adds an S3 key to the user, or replaces the swift key of a subuser",
+}
+
+func createKeyEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := checkObjectArgs(args, []string{"[UserID]"}); err != nil {
+		return err
+	}
+
+	key := model.ObjectUserKey{Type: "s3", AccessKey: accessKeyFlag, SecretKey: secretKeyFlag}
+	if swiftUserFlag != "" {
+		key = model.ObjectUserKey{Type: "swift", User: fmt.Sprintf("%s:%s", args[1], swiftUserFlag), SecretKey: secretKeyFlag}
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	out, err := createKey(c, args[0], args[1], key)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Print(out)
+	return nil
+}
+
+func createKey(c client.RookRestClient, storeName, id string, key model.ObjectUserKey) (string, error) {
+	createdKey, err := c.CreateObjectUserKey(storeName, id, key)
+
+	if client.IsHttpStatusCode(err, http.StatusUnprocessableEntity) {
+		restErr := err.(client.RookRestError)
+		return "", fmt.Errorf(string(restErr.Body))
+	}
+	if client.IsHttpNotFound(err) {
+		return "", fmt.Errorf("Unable to find user %s", id)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to create key: %+v", err)
+	}
+
+	return fmt.Sprintf("Key Created\n\n%s", outputKey(*createdKey)), nil
+}
+
+var keyDeleteCmd = &cobra.Command{
+	Use:   "delete [ObjectStore] [UserID] [AccessKey]",
+	Short: "Revokes the S3 key with the access key, or the swift key of a subuser, e.g. alice:swift",
+}
+
+func deleteKeyEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := checkObjectArgs(args, []string{"[UserID]", "[AccessKey]"}); err != nil {
+		return err
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	out, err := deleteKey(c, args[0], args[1], args[2])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Print(out)
+	return nil
+}
+
+func deleteKey(c client.RookRestClient, storeName, id, accessKey string) (string, error) {
+	err := c.DeleteObjectUserKey(storeName, id, accessKey)
+
+	if client.IsHttpNotFound(err) {
+		return "", fmt.Errorf("Unable to find key %s of user %s", accessKey, id)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to delete key: %+v", err)
+	}
+	return "Key deleted\n", nil
+}
+
+var keyRotateCmd = &cobra.Command{
+	Use:   "rotate [ObjectStore] [UserID]",
+	Short: "Creates a new S3 key for the user. The API server revokes the old S3 keys after the overlap",
+}
+
+func rotateKeysEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := checkObjectArgs(args, []string{"[UserID]"}); err != nil {
+		return err
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	err := rotateKeys(c, args[0], args[1], overlapFlag, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+// rotateKeys creates a new S3 key for the user. The API server revokes the old S3 keys of the user when the overlap has
+// passed, so the command returns right away and the apps can switch to the new key while the old keys are still valid.
+func rotateKeys(c client.RookRestClient, storeName, id string, overlap time.Duration, out io.Writer) error {
+	if overlap < 0 {
+		return fmt.Errorf("the overlap cannot be negative")
+	}
+
+	result, err := c.RotateObjectUserKeys(storeName, id, model.ObjectUserKeyRotation{OverlapSeconds: int(overlap.Seconds())})
+	if client.IsHttpNotFound(err) {
+		return fmt.Errorf("Unable to find user %s", id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to rotate keys: %+v", err)
+	}
+	fmt.Fprintf(out, "Key Created\n\n%s\n", outputKey(result.Key))
+
+	if len(result.OldAccessKeys) == 0 {
+		return nil
+	}
+	if overlap == 0 {
+		fmt.Fprintf(out, "Keys %s revoked\n", strings.Join(result.OldAccessKeys, ", "))
+	} else {
+		fmt.Fprintf(out, "Keys %s will be revoked after %s\n", strings.Join(result.OldAccessKeys, ", "), result.RevokeAfter.Format(time.RFC3339))
+	}
+	return nil
+}
+
+var subuserCreateCmd = &cobra.Command{
+	Use:   "create [ObjectStore] [UserID] [Subuser]",
+	Short: "Creates a swift subuser of the user with a generated swift key",
+}
+
+func createSubuserEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := checkObjectArgs(args, []string{"[UserID]", "[Subuser]"}); err != nil {
+		return err
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	out, err := createSubuser(c, args[0], args[1], model.ObjectSubuser{Name: args[2], Access: accessFlag})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Print(out)
+	return nil
+}
+
+func createSubuser(c client.RookRestClient, storeName, id string, subuser model.ObjectSubuser) (string, error) {
+	key, err := c.CreateObjectSubuser(storeName, id, subuser)
+
+	if client.IsHttpStatusCode(err, http.StatusUnprocessableEntity) {
+		restErr := err.(client.RookRestError)
+		return "", fmt.Errorf(string(restErr.Body))
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to create subuser: %+v", err)
+	}
+
+	return fmt.Sprintf("Subuser Created\n\n%s", outputKey(*key)), nil
+}
+
+var subuserDeleteCmd = &cobra.Command{
+	Use:   "delete [ObjectStore] [UserID] [Subuser]",
+	Short: "Deletes the swift subuser and its keys",
+}
+
+func deleteSubuserEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := checkObjectArgs(args, []string{"[UserID]", "[Subuser]"}); err != nil {
+		return err
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	out, err := deleteSubuser(c, args[0], args[1], args[2])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Print(out)
+	return nil
+}
+
+func deleteSubuser(c client.RookRestClient, storeName, id, name string) (string, error) {
+	err := c.DeleteObjectSubuser(storeName, id, name)
+
+	if client.IsHttpNotFound(err) {
+		return "", fmt.Errorf("Unable to find subuser %s of user %s", name, id)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to delete subuser: %+v", err)
+	}
+	return "Subuser deleted\n", nil
+}
+
+func outputKey(key model.ObjectUserKey) string {
+	var buffer bytes.Buffer
+	w := rook.NewTableWriter(&buffer)
+
+	fmt.Fprintf(w, "User:\t%s\n", key.User)
+	fmt.Fprintf(w, "Type:\t%s\n", key.Type)
+	if key.AccessKey != "" {
+		fmt.Fprintf(w, "Access Key:\t%s\n", key.AccessKey)
+	}
+	fmt.Fprintf(w, "Secret Key:\t%s\n", key.SecretKey)
+
+	w.Flush()
+	return buffer.String()
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/rook/test"
+)
+
+func TestRotateKeys(t *testing.T) {
+	var rotation model.ObjectUserKeyRotation
+	revokeAfter := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := &test.MockRookRestClient{
+		MockRotateObjectUserKeys: func(storeName, id string, r model.ObjectUserKeyRotation) (*model.ObjectUserKeyRotationResult, error) {
+			rotation = r
+			return &model.ObjectUserKeyRotationResult{
+				Key:           model.ObjectUserKey{User: "alice", Type: "s3", AccessKey: "new", SecretKey: "secret"},
+				OldAccessKeys: []string{"old1", "old2"},
+				RevokeAfter:   revokeAfter,
+			}, nil
+		},
+	}
+
+	// the command returns right away and the API server revokes the old keys after the overlap
+	var out bytes.Buffer
+	err := rotateKeys(c, "my-store", "alice", 10*time.Minute, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, 600, rotation.OverlapSeconds)
+	assert.Contains(t, out.String(), "new")
+	assert.Contains(t, out.String(), "Keys old1, old2 will be revoked after 2018-06-01T12:00:00Z")
+
+	// the old keys are revoked immediately without overlap
+	out.Reset()
+	err = rotateKeys(c, "my-store", "alice", 0, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, rotation.OverlapSeconds)
+	assert.Contains(t, out.String(), "Keys old1, old2 revoked")
+
+	// the overlap cannot be negative
+	err = rotateKeys(c, "my-store", "alice", -time.Minute, &out)
+	assert.NotNil(t, err)
+
+	c.MockRotateObjectUserKeys = func(storeName, id string, r model.ObjectUserKeyRotation) (*model.ObjectUserKeyRotationResult, error) {
+		return nil, fmt.Errorf("mock rotate keys failed")
+	}
+	err = rotateKeys(c, "my-store", "alice", 0, &out)
+	assert.NotNil(t, err)
+}
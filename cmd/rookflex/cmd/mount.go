@@ -19,11 +19,14 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/rpc"
 	"os"
+	"strings"
 
 	"github.com/rook/rook/pkg/agent/flexvolume"
 	"github.com/spf13/cobra"
+	"k8s.io/kubernetes/pkg/util/exec"
 	k8smount "k8s.io/kubernetes/pkg/util/mount"
 )
 
@@ -73,8 +76,13 @@ func handleMount(cmd *cobra.Command, args []string) error {
 	}
 
 	mounter := getMounter()
-	// Mount the volume to a global volume path
-	err = mountDevice(client, mounter, devicePath, globalVolumeMountPath, opts)
+	if opts.Filesystem != "" {
+		// Mount the directory of the shared file system volume to a global volume path
+		err = mountFilesystem(client, mounter, devicePath, globalVolumeMountPath, opts)
+	} else {
+		// Mount the volume to a global volume path
+		err = mountDevice(client, mounter, devicePath, globalVolumeMountPath, opts)
+	}
 	if err != nil {
 		return err
 	}
@@ -112,11 +120,28 @@ func mountDevice(client *rpc.Client, mounter *k8smount.SafeFormatAndMount, devic
 			return fmt.Errorf("Rook: Mount volume failed. Error checking if %s is a mount point: %v", globalVolumeMountPath, err)
 		}
 	}
-	options := []string{opts.RW}
+	// the mount and mkfs options from the storage class must be in the allow-list
+	mountOptions := flexvolume.ParseMountOptions(opts.MountOptions)
+	if err := flexvolume.ValidateMountOptions(mountOptions); err != nil {
+		log(client, fmt.Sprintf("mount volume %s/%s failed: %v", opts.Pool, opts.Image, err), true)
+		return fmt.Errorf("Rook: Mount volume failed: %v", err)
+	}
+	mkfsOptions := flexvolume.ParseMkfsOptions(opts.MkfsOptions)
+	if err := flexvolume.ValidateMkfsOptions(opts.FsType, mkfsOptions); err != nil {
+		log(client, fmt.Sprintf("mount volume %s/%s failed: %v", opts.Pool, opts.Image, err), true)
+		return fmt.Errorf("Rook: Mount volume failed: %v", err)
+	}
+
+	options := append([]string{opts.RW}, mountOptions...)
 	if notMnt {
 		err = redirectStdout(
 			client,
 			func() error {
+				if len(mkfsOptions) > 0 {
+					if err := formatDevice(mounter, devicePath, opts.FsType, mkfsOptions); err != nil {
+						return err
+					}
+				}
 				if err = mounter.FormatAndMount(devicePath, globalVolumeMountPath, opts.FsType, options); err != nil {
 					return fmt.Errorf("failed to mount volume %s [%s] to %s, error %v", devicePath, opts.FsType, globalVolumeMountPath, err)
 				}
@@ -136,6 +161,96 @@ func mountDevice(client *rpc.Client, mounter *k8smount.SafeFormatAndMount, devic
 	return nil
 }
 
+// formatDevice creates the file system with the mkfs options if the device is not formatted yet. FormatAndMount only
+// formats with the default options, and it leaves the file system created here as is.
+func formatDevice(mounter *k8smount.SafeFormatAndMount, devicePath, fsType string, mkfsOptions []string) error {
+	if fsType == "" {
+		fsType = flexvolume.DefaultFsType
+	}
+	formatted, err := isFormatted(mounter.Runner, devicePath)
+	if err != nil {
+		return err
+	}
+	if formatted {
+		return nil
+	}
+
+	args := mkfsOptions
+	if fsType == "ext4" || fsType == "ext3" {
+		// do not ask for confirmation when formatting the whole device
+		args = append([]string{"-F"}, args...)
+	}
+	args = append(args, devicePath)
+	output, err := mounter.Runner.Command("mkfs."+fsType, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to format volume %s [%s] with options %v, error %v. output: %s", devicePath, fsType, mkfsOptions, err, string(output))
+	}
+	return nil
+}
+
+// isFormatted checks if the device has a file system or a partition table
+func isFormatted(runner exec.Interface, devicePath string) (bool, error) {
+	output, err := runner.Command("blkid", "-p", "-s", "TYPE", "-s", "PTTYPE", "-o", "export", devicePath).CombinedOutput()
+	if err != nil {
+		// blkid exits with 2 when the device has no file system or partition table
+		if exitErr, ok := err.(exec.ExitError); ok && exitErr.ExitStatus() == 2 {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check the format of %s, error %v. output: %s", devicePath, err, string(output))
+	}
+	return strings.TrimSpace(string(output)) != "", nil
+}
+
+func mountFilesystem(client *rpc.Client, mounter *k8smount.SafeFormatAndMount, source, globalVolumeMountPath string, opts *flexvolume.AttachOptions) error {
+	notMnt, err := mounter.Interface.IsLikelyNotMountPoint(globalVolumeMountPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			if err := os.MkdirAll(globalVolumeMountPath, 0750); err != nil {
+				return fmt.Errorf("Rook: Mount volume failed. Cannot create global volume mount path dir: %v", err)
+			}
+			notMnt = true
+		} else {
+			return fmt.Errorf("Rook: Mount volume failed. Error checking if %s is a mount point: %v", globalVolumeMountPath, err)
+		}
+	}
+	if !notMnt {
+		return nil
+	}
+
+	// the volume is mounted as the ceph user that can only access its directory. the key is passed in a secret file
+	// that only root can read, so it does not show up in the mount options.
+	var credentials flexvolume.FilesystemCredentials
+	err = client.Call("FlexvolumeController.GetFilesystemCredentials", opts, &credentials)
+	if err != nil {
+		log(client, fmt.Sprintf("mount volume %s of file system %s failed: %v", opts.Path, opts.Filesystem, err), true)
+		return fmt.Errorf("Rook: Mount volume failed: %v", err)
+	}
+	secretFile, err := writeSecretFile(credentials.Key)
+	if err != nil {
+		log(client, fmt.Sprintf("mount volume %s of file system %s failed: %v", opts.Path, opts.Filesystem, err), true)
+		return fmt.Errorf("Rook: Mount volume failed: %v", err)
+	}
+	defer os.Remove(secretFile)
+
+	log(client, fmt.Sprintf("mounting volume %s of file system %s on %s", opts.Path, opts.Filesystem, globalVolumeMountPath), false)
+	mountOptions := fmt.Sprintf("name=%s,secretfile=%s,mds_namespace=%s", credentials.User, secretFile, opts.Filesystem)
+	options := []string{opts.RW, mountOptions}
+	err = redirectStdout(
+		client,
+		func() error {
+			if err := mounter.Interface.Mount(source, globalVolumeMountPath, "ceph", options); err != nil {
+				return fmt.Errorf("failed to mount volume %s of file system %s to %s, error %v", opts.Path, opts.Filesystem, globalVolumeMountPath, err)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		log(client, fmt.Sprintf("mount volume %s of file system %s failed: %v", opts.Path, opts.Filesystem, err), true)
+		os.Remove(globalVolumeMountPath)
+	}
+	return err
+}
+
 func mount(client *rpc.Client, mounter *k8smount.SafeFormatAndMount, globalVolumeMountPath string, opts *flexvolume.AttachOptions) error {
 
 	log(client, fmt.Sprintf("mounting global mount path %s on %s", globalVolumeMountPath, opts.MountDir), false)
@@ -174,3 +289,19 @@ func mount(client *rpc.Client, mounter *k8smount.SafeFormatAndMount, globalVolum
 	}
 	return err
 }
+
+// writeSecretFile writes the key to a temporary file that only root can read. The ceph mount helper reads the key
+// from the file and adds it to the kernel keyring.
+func writeSecretFile(key string) (string, error) {
+	file, err := ioutil.TempFile("", "rook-secret")
+	if err != nil {
+		return "", fmt.Errorf("failed to create secret file. %+v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(key); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write secret file. %+v", err)
+	}
+	return file.Name(), nil
+}
@@ -68,12 +68,21 @@ func (a *Agent) Run() error {
 
 	flexvolumeServer.Start()
 
+	// grow the file systems of the attached volumes when they are expanded and remove the directories of the deleted
+	// shared file system volumes
+	stopChan := make(chan struct{})
+	go flexvolumeServer.StartVolumeWatch(stopChan)
+
+	// unmap the images and remove the attachments that were left behind while the agent was down
+	go flexvolumeServer.StartReconcile(flexvolume.ReconcileInterval, stopChan)
+
 	sigc := make(chan os.Signal, 1)
 	signal.Notify(sigc, syscall.SIGTERM)
 	for {
 		select {
 		case <-sigc:
 			logger.Infof("shutdown signal received, exiting...")
+			close(stopChan)
 			flexvolumeServer.Stop()
 			return nil
 		}
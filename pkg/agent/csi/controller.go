@@ -0,0 +1,437 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package csi
+
+import (
+	"strconv"
+	"time"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi/v0"
+	"github.com/rook/rook/pkg/agent/flexvolume"
+	ceph "github.com/rook/rook/pkg/ceph/client"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/provisioner"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// the size of the volumes created without a capacity range
+	defaultVolumeSize = int64(1024 * 1024 * 1024)
+)
+
+// controllerServer creates and deletes the images of the volumes and their snapshots
+type controllerServer struct {
+	context *clusterd.Context
+}
+
+func (s *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume name is required")
+	}
+	opts, err := provisioner.ParseBlockVolumeOptions(req.GetParameters())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if opts.QoS != (ceph.ImageQoS{}) {
+		// the limits are applied in the cgroup of the pod, which is only known to the flexvolume driver
+		return nil, status.Error(codes.InvalidArgument, "QoS limits are only supported by the rook.io/block provisioner")
+	}
+	if len(opts.MountOptions) > 0 || len(opts.MkfsOptions) > 0 {
+		// the mount flags of the volume capability are set from the mountOptions of the storage class instead
+		return nil, status.Error(codes.InvalidArgument, "mountOptions and mkfsOptions parameters are only supported by the rook.io/block provisioner. use the mountOptions of the storage class")
+	}
+	if err := validateVolumeCapabilities(req.GetVolumeCapabilities(), opts.MultiWriter); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	size := req.GetCapacityRange().GetRequiredBytes()
+	if size == 0 {
+		size = defaultVolumeSize
+	}
+	volume := blockVolume{clusterName: opts.ClusterName, pool: opts.Pool, image: req.GetName()}
+
+	// the volume may have been created by a previous call that timed out
+	image, err := s.getImage(volume)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if image != nil {
+		if int64(image.Size) < size {
+			return nil, status.Errorf(codes.AlreadyExists, "volume %s already exists with the smaller size %d", volume.id(), image.Size)
+		}
+		logger.Infof("volume %s already exists", volume.id())
+		return createVolumeResponse(volume, int64(image.Size), opts), nil
+	}
+
+	if opts.MultiWriter {
+		logger.Warningf("volume %s can be attached read-write by multiple pods. the pods must coordinate their writes, for example with a cluster file system, or the data will be corrupted", volume.id())
+	}
+
+	if snapshot := req.GetVolumeContentSource().GetSnapshot(); snapshot != nil {
+		if err := s.createVolumeFromSnapshot(volume, snapshot.GetId(), size); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := ceph.CreateImageWithOptions(s.context, volume.clusterName, volume.image, volume.pool, uint64(size), opts.ImageOptions); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to create volume %s. %+v", volume.id(), err)
+		}
+	}
+
+	image, err = s.getImage(volume)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if image == nil {
+		return nil, status.Errorf(codes.Internal, "volume %s not found after it was created", volume.id())
+	}
+	logger.Infof("created volume %s", volume.id())
+	return createVolumeResponse(volume, int64(image.Size), opts), nil
+}
+
+// createVolumeFromSnapshot clones the snapshot to the image of the volume. The volume must be in the same cluster as
+// the snapshot and cannot be smaller.
+func (s *controllerServer) createVolumeFromSnapshot(volume blockVolume, snapshotID string, size int64) error {
+	source, snapName, err := parseSnapshotID(snapshotID)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if source.clusterName != volume.clusterName {
+		return status.Errorf(codes.InvalidArgument, "snapshot %s is not in the cluster %s of the volume", snapshotID, volume.clusterName)
+	}
+	snapshot, err := s.getSnapshot(source, snapName)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if snapshot == nil {
+		return status.Errorf(codes.NotFound, "snapshot %s not found", snapshotID)
+	}
+
+	if err := ceph.CloneImage(s.context, volume.clusterName, source.image, source.pool, snapName, volume.image, volume.pool); err != nil {
+		return status.Errorf(codes.Internal, "failed to create volume %s from snapshot %s. %+v", volume.id(), snapshotID, err)
+	}
+	if uint64(size) > snapshot.Size {
+		if err := ceph.ResizeImage(s.context, volume.clusterName, volume.image, volume.pool, uint64(size)); err != nil {
+			return status.Errorf(codes.Internal, "failed to grow volume %s created from snapshot %s. %+v", volume.id(), snapshotID, err)
+		}
+	}
+	return nil
+}
+
+func (s *controllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
+	if req.GetVolumeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume id is required")
+	}
+	volume, err := parseVolumeID(req.GetVolumeId())
+	if err != nil {
+		// the volume was not created by this driver so there is nothing to delete
+		logger.Warningf("not deleting volume. %+v", err)
+		return &csi.DeleteVolumeResponse{}, nil
+	}
+
+	image, err := s.getImage(volume)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if image == nil {
+		logger.Infof("volume %s already deleted", volume.id())
+		return &csi.DeleteVolumeResponse{}, nil
+	}
+
+	if err := ceph.DeleteImage(s.context, volume.clusterName, volume.image, volume.pool); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to delete volume %s. %+v", volume.id(), err)
+	}
+	logger.Infof("deleted volume %s", volume.id())
+	return &csi.DeleteVolumeResponse{}, nil
+}
+
+// ControllerPublishVolume checks that the volume exists and can be used by the node. The image is mapped on the node
+// when the volume is staged.
+func (s *controllerServer) ControllerPublishVolume(ctx context.Context, req *csi.ControllerPublishVolumeRequest) (*csi.ControllerPublishVolumeResponse, error) {
+	if req.GetVolumeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume id is required")
+	}
+	if req.GetNodeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "node id is required")
+	}
+	if req.GetVolumeCapability() == nil {
+		return nil, status.Error(codes.InvalidArgument, "volume capability is required")
+	}
+	volume, err := s.getVolume(req.GetVolumeId())
+	if err != nil {
+		return nil, err
+	}
+	multiWriter, _ := strconv.ParseBool(req.GetVolumeAttributes()[flexvolume.MultiWriterKey])
+	if err := validateVolumeCapabilities([]*csi.VolumeCapability{req.GetVolumeCapability()}, multiWriter); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	logger.Infof("publishing volume %s to node %s", volume.id(), req.GetNodeId())
+	return &csi.ControllerPublishVolumeResponse{}, nil
+}
+
+// ControllerUnpublishVolume has nothing to do. The image is unmapped on the node when the volume is unstaged.
+func (s *controllerServer) ControllerUnpublishVolume(ctx context.Context, req *csi.ControllerUnpublishVolumeRequest) (*csi.ControllerUnpublishVolumeResponse, error) {
+	if req.GetVolumeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume id is required")
+	}
+	logger.Infof("unpublishing volume %s from node %s", req.GetVolumeId(), req.GetNodeId())
+	return &csi.ControllerUnpublishVolumeResponse{}, nil
+}
+
+func (s *controllerServer) ValidateVolumeCapabilities(ctx context.Context, req *csi.ValidateVolumeCapabilitiesRequest) (*csi.ValidateVolumeCapabilitiesResponse, error) {
+	if req.GetVolumeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume id is required")
+	}
+	if _, err := s.getVolume(req.GetVolumeId()); err != nil {
+		return nil, err
+	}
+
+	multiWriter, _ := strconv.ParseBool(req.GetVolumeAttributes()[flexvolume.MultiWriterKey])
+	if err := validateVolumeCapabilities(req.GetVolumeCapabilities(), multiWriter); err != nil {
+		return &csi.ValidateVolumeCapabilitiesResponse{Supported: false, Message: err.Error()}, nil
+	}
+	return &csi.ValidateVolumeCapabilitiesResponse{Supported: true}, nil
+}
+
+func (s *controllerServer) ListVolumes(ctx context.Context, req *csi.ListVolumesRequest) (*csi.ListVolumesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
+
+func (s *controllerServer) GetCapacity(ctx context.Context, req *csi.GetCapacityRequest) (*csi.GetCapacityResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
+
+func (s *controllerServer) ControllerGetCapabilities(ctx context.Context, req *csi.ControllerGetCapabilitiesRequest) (*csi.ControllerGetCapabilitiesResponse, error) {
+	capabilities := []*csi.ControllerServiceCapability{}
+	for _, c := range []csi.ControllerServiceCapability_RPC_Type{
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
+		csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME,
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
+		csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS,
+	} {
+		capabilities = append(capabilities, &csi.ControllerServiceCapability{
+			Type: &csi.ControllerServiceCapability_Rpc{
+				Rpc: &csi.ControllerServiceCapability_RPC{Type: c},
+			},
+		})
+	}
+	return &csi.ControllerGetCapabilitiesResponse{Capabilities: capabilities}, nil
+}
+
+// CreateSnapshot creates a protected snapshot of the image so volumes can be cloned from it
+func (s *controllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshotRequest) (*csi.CreateSnapshotResponse, error) {
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "snapshot name is required")
+	}
+	if req.GetSourceVolumeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "source volume id is required")
+	}
+	volume, err := s.getVolume(req.GetSourceVolumeId())
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, err := s.getSnapshot(volume, req.GetName())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if snapshot == nil {
+		if err := ceph.CreateSnapshot(s.context, volume.clusterName, volume.image, volume.pool, req.GetName()); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to create snapshot %s. %+v", volume.snapshotID(req.GetName()), err)
+		}
+		if err := ceph.ProtectSnapshot(s.context, volume.clusterName, volume.image, volume.pool, req.GetName()); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to protect snapshot %s. %+v", volume.snapshotID(req.GetName()), err)
+		}
+		if snapshot, err = s.getSnapshot(volume, req.GetName()); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if snapshot == nil {
+			return nil, status.Errorf(codes.Internal, "snapshot %s not found after it was created", volume.snapshotID(req.GetName()))
+		}
+		logger.Infof("created snapshot %s", volume.snapshotID(req.GetName()))
+	}
+
+	return &csi.CreateSnapshotResponse{Snapshot: newSnapshot(volume, *snapshot)}, nil
+}
+
+func (s *controllerServer) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshotRequest) (*csi.DeleteSnapshotResponse, error) {
+	if req.GetSnapshotId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "snapshot id is required")
+	}
+	volume, snapName, err := parseSnapshotID(req.GetSnapshotId())
+	if err != nil {
+		// the snapshot was not created by this driver so there is nothing to delete
+		logger.Warningf("not deleting snapshot. %+v", err)
+		return &csi.DeleteSnapshotResponse{}, nil
+	}
+
+	image, err := s.getImage(volume)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if image != nil {
+		snapshot, err := s.getSnapshot(volume, snapName)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if snapshot != nil {
+			// the snapshot cannot be unprotected while volumes are cloned from it
+			if err := ceph.UnprotectSnapshot(s.context, volume.clusterName, volume.image, volume.pool, snapName); err != nil {
+				return nil, status.Errorf(codes.FailedPrecondition, "failed to unprotect snapshot %s. volumes created from the snapshot must be deleted or flattened first. %+v", req.GetSnapshotId(), err)
+			}
+			if err := ceph.DeleteSnapshot(s.context, volume.clusterName, volume.image, volume.pool, snapName); err != nil {
+				return nil, status.Errorf(codes.Internal, "failed to delete snapshot %s. %+v", req.GetSnapshotId(), err)
+			}
+			logger.Infof("deleted snapshot %s", req.GetSnapshotId())
+			return &csi.DeleteSnapshotResponse{}, nil
+		}
+	}
+
+	logger.Infof("snapshot %s already deleted", req.GetSnapshotId())
+	return &csi.DeleteSnapshotResponse{}, nil
+}
+
+// ListSnapshots lists the snapshot with the given id or the snapshots of the given volume. The snapshots of all the
+// volumes are not listed since the driver does not know all the clusters and pools.
+func (s *controllerServer) ListSnapshots(ctx context.Context, req *csi.ListSnapshotsRequest) (*csi.ListSnapshotsResponse, error) {
+	var volume blockVolume
+	var snapName string
+	var err error
+	switch {
+	case req.GetSnapshotId() != "":
+		if volume, snapName, err = parseSnapshotID(req.GetSnapshotId()); err != nil {
+			return &csi.ListSnapshotsResponse{}, nil
+		}
+	case req.GetSourceVolumeId() != "":
+		if volume, err = parseVolumeID(req.GetSourceVolumeId()); err != nil {
+			return &csi.ListSnapshotsResponse{}, nil
+		}
+	default:
+		return &csi.ListSnapshotsResponse{}, nil
+	}
+
+	image, err := s.getImage(volume)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if image == nil {
+		return &csi.ListSnapshotsResponse{}, nil
+	}
+	snapshots, err := ceph.ListSnapshots(s.context, volume.clusterName, volume.image, volume.pool)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	entries := []*csi.ListSnapshotsResponse_Entry{}
+	for _, snapshot := range snapshots {
+		if snapName == "" || snapshot.Name == snapName {
+			entries = append(entries, &csi.ListSnapshotsResponse_Entry{Snapshot: newSnapshot(volume, snapshot)})
+		}
+	}
+
+	// the starting token is the index of the first entry to return
+	start := 0
+	if req.GetStartingToken() != "" {
+		if start, err = strconv.Atoi(req.GetStartingToken()); err != nil || start < 0 || start > len(entries) {
+			return nil, status.Errorf(codes.Aborted, "invalid starting token %q", req.GetStartingToken())
+		}
+	}
+	entries = entries[start:]
+	nextToken := ""
+	if max := int(req.GetMaxEntries()); max > 0 && len(entries) > max {
+		entries = entries[:max]
+		nextToken = strconv.Itoa(start + max)
+	}
+	return &csi.ListSnapshotsResponse{Entries: entries, NextToken: nextToken}, nil
+}
+
+// getVolume parses the volume id and checks that the image of the volume exists
+func (s *controllerServer) getVolume(id string) (blockVolume, error) {
+	volume, err := parseVolumeID(id)
+	if err != nil {
+		return blockVolume{}, status.Error(codes.NotFound, err.Error())
+	}
+	image, err := s.getImage(volume)
+	if err != nil {
+		return blockVolume{}, status.Error(codes.Internal, err.Error())
+	}
+	if image == nil {
+		return blockVolume{}, status.Errorf(codes.NotFound, "volume %s not found", id)
+	}
+	return volume, nil
+}
+
+// getImage returns the image of the volume, or nil if the image does not exist
+func (s *controllerServer) getImage(volume blockVolume) (*ceph.CephBlockImage, error) {
+	images, err := ceph.ListImages(s.context, volume.clusterName, volume.pool)
+	if err != nil {
+		return nil, err
+	}
+	for i := range images {
+		if images[i].Name == volume.image {
+			return &images[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// getSnapshot returns the snapshot of the image, or nil if the snapshot does not exist
+func (s *controllerServer) getSnapshot(volume blockVolume, name string) (*ceph.CephBlockImageSnapshot, error) {
+	snapshots, err := ceph.ListSnapshots(s.context, volume.clusterName, volume.image, volume.pool)
+	if err != nil {
+		return nil, err
+	}
+	for i := range snapshots {
+		if snapshots[i].Name == name {
+			return &snapshots[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func createVolumeResponse(volume blockVolume, size int64, opts *provisioner.BlockVolumeOptions) *csi.CreateVolumeResponse {
+	attributes := map[string]string{
+		flexvolume.ClusterNameKey: volume.clusterName,
+		flexvolume.PoolKey:        volume.pool,
+		flexvolume.ImageKey:       volume.image,
+	}
+	if opts.FsType != "" {
+		attributes[fsTypeKey] = opts.FsType
+	}
+	if opts.MultiWriter {
+		attributes[flexvolume.MultiWriterKey] = "true"
+	}
+	return &csi.CreateVolumeResponse{
+		Volume: &csi.Volume{
+			Id:            volume.id(),
+			CapacityBytes: size,
+			Attributes:    attributes,
+		},
+	}
+}
+
+func newSnapshot(volume blockVolume, snapshot ceph.CephBlockImageSnapshot) *csi.Snapshot {
+	return &csi.Snapshot{
+		Id:             volume.snapshotID(snapshot.Name),
+		SourceVolumeId: volume.id(),
+		SizeBytes:      int64(snapshot.Size),
+		// rbd does not report when the snapshot was taken
+		CreatedAt: time.Now().UnixNano(),
+		Status:    &csi.SnapshotStatus{Type: csi.SnapshotStatus_READY},
+	}
+}
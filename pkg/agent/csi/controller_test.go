@@ -0,0 +1,217 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package csi
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi/v0"
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeRBD keeps the images and snapshots created with the rbd tool
+type fakeRBD struct {
+	images    map[string]int
+	snapshots map[string]int
+	commands  []string
+}
+
+func newFakeRBD() *fakeRBD {
+	return &fakeRBD{images: map[string]int{}, snapshots: map[string]int{}}
+}
+
+func (f *fakeRBD) executor() *exectest.MockExecutor {
+	return &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if command != "rbd" {
+				return "", nil
+			}
+			f.commands = append(f.commands, strings.Join(args[:2], " "))
+			switch args[0] {
+			case "ls":
+				images := []string{}
+				for name, size := range f.images {
+					if strings.HasPrefix(name, args[2]+"/") {
+						images = append(images, fmt.Sprintf(`{"image":"%s","size":%d,"format":2}`, strings.TrimPrefix(name, args[2]+"/"), size))
+					}
+				}
+				return "[" + strings.Join(images, ",") + "]", nil
+			case "create":
+				f.images[args[1]] = mustAtoi(args[3]) * 1024 * 1024
+			case "rm":
+				delete(f.images, args[1])
+			case "clone":
+				f.images[args[2]] = f.snapshots[args[1]]
+			case "resize":
+				f.images[args[1]] = mustAtoi(args[3]) * 1024 * 1024
+			case "snap":
+				switch args[1] {
+				case "ls":
+					snapshots := []string{}
+					for name, size := range f.snapshots {
+						if strings.HasPrefix(name, args[2]+"@") {
+							snapshots = append(snapshots, fmt.Sprintf(`{"id":1,"name":"%s","size":%d}`, strings.TrimPrefix(name, args[2]+"@"), size))
+						}
+					}
+					return "[" + strings.Join(snapshots, ",") + "]", nil
+				case "create":
+					image := strings.Split(args[2], "@")[0]
+					f.snapshots[args[2]] = f.images[image]
+				case "rm":
+					delete(f.snapshots, args[2])
+				}
+			}
+			return "", nil
+		},
+	}
+}
+
+func mustAtoi(s string) int {
+	var i int
+	fmt.Sscanf(s, "%d", &i)
+	return i
+}
+
+func mountCapability(mode csi.VolumeCapability_AccessMode_Mode) *csi.VolumeCapability {
+	return &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+		AccessMode: &csi.VolumeCapability_AccessMode{Mode: mode},
+	}
+}
+
+func TestCreateDeleteVolume(t *testing.T) {
+	rbd := newFakeRBD()
+	s := &controllerServer{context: &clusterd.Context{Executor: rbd.executor()}}
+
+	req := &csi.CreateVolumeRequest{
+		Name:               "pvc-1",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: 10 * 1024 * 1024},
+		VolumeCapabilities: []*csi.VolumeCapability{mountCapability(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER)},
+		Parameters:         map[string]string{"pool": "replicapool", "clusterName": "rook", "fsType": "xfs"},
+	}
+	resp, err := s.CreateVolume(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, "rook/replicapool/pvc-1", resp.Volume.Id)
+	assert.Equal(t, int64(10*1024*1024), resp.Volume.CapacityBytes)
+	assert.Equal(t, "xfs", resp.Volume.Attributes["fsType"])
+	assert.Equal(t, 10*1024*1024, rbd.images["replicapool/pvc-1"])
+
+	// the existing volume is returned when the request is retried
+	resp, err = s.CreateVolume(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, "rook/replicapool/pvc-1", resp.Volume.Id)
+
+	// the volume cannot be requested again with a bigger size
+	req.CapacityRange.RequiredBytes = 20 * 1024 * 1024
+	_, err = s.CreateVolume(context.TODO(), req)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	// multiple writers are only allowed for volumes created with multiWriter
+	req.VolumeCapabilities = []*csi.VolumeCapability{mountCapability(csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER)}
+	_, err = s.CreateVolume(context.TODO(), req)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// invalid storage class parameters are rejected
+	req.Parameters = map[string]string{"clusterName": "rook"}
+	_, err = s.CreateVolume(context.TODO(), req)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// QoS limits are not supported by the CSI driver
+	req.Parameters = map[string]string{"pool": "replicapool", "readIOPSLimit": "100"}
+	_, err = s.CreateVolume(context.TODO(), req)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// the mount and mkfs options are not supported by the CSI driver
+	req.Parameters = map[string]string{"pool": "replicapool", "mountOptions": "noatime"}
+	_, err = s.CreateVolume(context.TODO(), req)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = s.DeleteVolume(context.TODO(), &csi.DeleteVolumeRequest{VolumeId: "rook/replicapool/pvc-1"})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(rbd.images))
+
+	// deleting a volume that does not exist succeeds
+	_, err = s.DeleteVolume(context.TODO(), &csi.DeleteVolumeRequest{VolumeId: "rook/replicapool/pvc-1"})
+	assert.Nil(t, err)
+}
+
+func TestControllerPublishVolume(t *testing.T) {
+	rbd := newFakeRBD()
+	rbd.images["replicapool/pvc-1"] = 1024 * 1024
+	s := &controllerServer{context: &clusterd.Context{Executor: rbd.executor()}}
+
+	req := &csi.ControllerPublishVolumeRequest{
+		VolumeId:         "rook/replicapool/pvc-1",
+		NodeId:           "node1",
+		VolumeCapability: mountCapability(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER),
+	}
+	_, err := s.ControllerPublishVolume(context.TODO(), req)
+	assert.Nil(t, err)
+
+	req.VolumeId = "rook/replicapool/pvc-2"
+	_, err = s.ControllerPublishVolume(context.TODO(), req)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestSnapshots(t *testing.T) {
+	rbd := newFakeRBD()
+	rbd.images["replicapool/pvc-1"] = 10 * 1024 * 1024
+	s := &controllerServer{context: &clusterd.Context{Executor: rbd.executor()}}
+
+	resp, err := s.CreateSnapshot(context.TODO(), &csi.CreateSnapshotRequest{Name: "snap1", SourceVolumeId: "rook/replicapool/pvc-1"})
+	assert.Nil(t, err)
+	assert.Equal(t, "rook/replicapool/pvc-1@snap1", resp.Snapshot.Id)
+	assert.Equal(t, "rook/replicapool/pvc-1", resp.Snapshot.SourceVolumeId)
+	assert.Equal(t, int64(10*1024*1024), resp.Snapshot.SizeBytes)
+	assert.Contains(t, rbd.commands, "snap protect")
+
+	list, err := s.ListSnapshots(context.TODO(), &csi.ListSnapshotsRequest{SourceVolumeId: "rook/replicapool/pvc-1"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(list.Entries))
+	assert.Equal(t, "rook/replicapool/pvc-1@snap1", list.Entries[0].Snapshot.Id)
+
+	// a bigger volume is cloned from the snapshot
+	_, err = s.CreateVolume(context.TODO(), &csi.CreateVolumeRequest{
+		Name:               "pvc-2",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: 20 * 1024 * 1024},
+		VolumeCapabilities: []*csi.VolumeCapability{mountCapability(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER)},
+		Parameters:         map[string]string{"pool": "replicapool"},
+		VolumeContentSource: &csi.VolumeContentSource{
+			Type: &csi.VolumeContentSource_Snapshot{
+				Snapshot: &csi.VolumeContentSource_SnapshotSource{Id: "rook/replicapool/pvc-1@snap1"},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 20*1024*1024, rbd.images["replicapool/pvc-2"])
+	assert.Contains(t, rbd.commands, "clone replicapool/pvc-1@snap1")
+
+	_, err = s.DeleteSnapshot(context.TODO(), &csi.DeleteSnapshotRequest{SnapshotId: "rook/replicapool/pvc-1@snap1"})
+	assert.Nil(t, err)
+	assert.Contains(t, rbd.commands, "snap unprotect")
+	assert.Equal(t, 0, len(rbd.snapshots))
+
+	list, err = s.ListSnapshots(context.TODO(), &csi.ListSnapshotsRequest{SnapshotId: "rook/replicapool/pvc-1@snap1"})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(list.Entries))
+}
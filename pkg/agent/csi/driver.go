@@ -0,0 +1,141 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package csi implements the Container Storage Interface plugin for Rook block volumes.
+package csi
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi/v0"
+	"github.com/coreos/pkg/capnslog"
+	"github.com/rook/rook/pkg/agent/flexvolume"
+	"github.com/rook/rook/pkg/clusterd"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"k8s.io/client-go/rest"
+	"k8s.io/kubernetes/pkg/util/exec"
+	"k8s.io/kubernetes/pkg/util/mount"
+)
+
+const (
+	// DriverName is the name of the Rook CSI driver for block volumes
+	DriverName = flexvolume.CSIBlockDriver
+
+	// DefaultEndpoint is the unix socket where the driver listens for the CSI sidecars and the kubelet
+	DefaultEndpoint = "unix:///csi/csi.sock"
+)
+
+var logger = capnslog.NewPackageLogger("github.com/rook/rook", "rook-csi")
+
+// Driver serves the CSI identity, controller and node services of Rook block volumes. The controller service creates
+// and deletes the images the same way as the rook.io/block provisioner and the node service maps them with the same
+// volume manager as the flexvolume driver. The staged volumes are recorded in the VolumeAttachment resources of the
+// flexvolume driver.
+type Driver struct {
+	endpoint   string
+	server     *grpc.Server
+	identity   *identityServer
+	controller *controllerServer
+	node       *nodeServer
+}
+
+// NewDriver creates the CSI driver for the given node
+func NewDriver(context *clusterd.Context, volumeAttachmentClient rest.Interface, volumeManager flexvolume.VolumeManager, nodeID, endpoint string) *Driver {
+	return &Driver{
+		endpoint:   endpoint,
+		identity:   &identityServer{},
+		controller: &controllerServer{context: context},
+		node: &nodeServer{
+			nodeID:        nodeID,
+			volumeManager: volumeManager,
+			attacher:      flexvolume.NewCSIController(context, volumeAttachmentClient, volumeManager),
+			mounter: &mount.SafeFormatAndMount{
+				Interface: mount.New("" /* default mount path */),
+				Runner:    exec.New(),
+			},
+		},
+	}
+}
+
+// Run serves the CSI services on the endpoint until the driver is stopped
+func (d *Driver) Run() error {
+	scheme, address, err := parseEndpoint(d.endpoint)
+	if err != nil {
+		return err
+	}
+	if scheme == "unix" {
+		// remove the socket left by a previous instance of the driver
+		if err := os.Remove(address); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove socket %s. %+v", address, err)
+		}
+	}
+
+	listener, err := net.Listen(scheme, address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s. %+v", d.endpoint, err)
+	}
+
+	d.server = grpc.NewServer(grpc.UnaryInterceptor(logRequest))
+	csi.RegisterIdentityServer(d.server, d.identity)
+	csi.RegisterControllerServer(d.server, d.controller)
+	csi.RegisterNodeServer(d.server, d.node)
+
+	logger.Infof("serving CSI driver %s on %s", DriverName, d.endpoint)
+	return d.server.Serve(listener)
+}
+
+// Stop the driver after the pending requests are completed
+func (d *Driver) Stop() {
+	if d.server != nil {
+		d.server.GracefulStop()
+	}
+}
+
+// parseEndpoint splits the endpoint, such as unix:///csi/csi.sock or tcp://127.0.0.1:10000, into the network and the address
+func parseEndpoint(endpoint string) (string, string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid endpoint %s. %+v", endpoint, err)
+	}
+
+	switch u.Scheme {
+	case "unix":
+		if u.Path == "" {
+			return "", "", fmt.Errorf("invalid endpoint %s. the path of the socket is required", endpoint)
+		}
+		return u.Scheme, u.Path, nil
+	case "tcp":
+		if u.Host == "" {
+			return "", "", fmt.Errorf("invalid endpoint %s. the address is required", endpoint)
+		}
+		return u.Scheme, u.Host, nil
+	}
+	return "", "", fmt.Errorf("invalid endpoint %s. only unix and tcp endpoints are supported", endpoint)
+}
+
+// logRequest logs the failed CSI requests
+func logRequest(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	logger.Debugf("%s: %+v", info.FullMethod, req)
+	resp, err := handler(ctx, req)
+	if err != nil {
+		logger.Errorf("%s failed. %+v", info.FullMethod, err)
+	}
+	return resp, err
+}
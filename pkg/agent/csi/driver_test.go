@@ -0,0 +1,39 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package csi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	scheme, address, err := parseEndpoint("unix:///csi/csi.sock")
+	assert.Nil(t, err)
+	assert.Equal(t, "unix", scheme)
+	assert.Equal(t, "/csi/csi.sock", address)
+
+	scheme, address, err = parseEndpoint("tcp://127.0.0.1:10000")
+	assert.Nil(t, err)
+	assert.Equal(t, "tcp", scheme)
+	assert.Equal(t, "127.0.0.1:10000", address)
+
+	_, _, err = parseEndpoint("http://127.0.0.1")
+	assert.NotNil(t, err)
+	_, _, err = parseEndpoint("unix://")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,296 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package csi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi/v0"
+	"github.com/rook/rook/pkg/agent/flexvolume"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/kubernetes/pkg/util/mount"
+)
+
+const (
+	fsTypeKey     = "fsType"
+	defaultFsType = "ext4"
+)
+
+// volumeAttacher maps the images of the staged volumes and records them in the VolumeAttachment resources of the
+// flexvolume driver, so the volumes are fenced on node failure and reconciled by the agent
+type volumeAttacher interface {
+	StageVolume(attachOpts flexvolume.AttachOptions) (string, error)
+	UnstageVolume(detachOpts flexvolume.AttachOptions) error
+}
+
+// nodeServer maps the images on the node and mounts them for the pods
+type nodeServer struct {
+	nodeID        string
+	volumeManager flexvolume.VolumeManager
+	attacher      volumeAttacher
+	mounter       *mount.SafeFormatAndMount
+}
+
+// NodeStageVolume maps the image, records the node in the VolumeAttachment resource of the volume and mounts its file
+// system at the staging path shared by the pods of the node. The file system is created on the first mount.
+func (s *nodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStageVolumeRequest) (*csi.NodeStageVolumeResponse, error) {
+	if req.GetVolumeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume id is required")
+	}
+	if req.GetStagingTargetPath() == "" {
+		return nil, status.Error(codes.InvalidArgument, "staging target path is required")
+	}
+	if req.GetVolumeCapability() == nil {
+		return nil, status.Error(codes.InvalidArgument, "volume capability is required")
+	}
+	volume, err := parseVolumeID(req.GetVolumeId())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	attachOpts := stageOptions(volume, req.GetStagingTargetPath(), req.GetVolumeCapability())
+	if req.GetVolumeCapability().GetBlock() != nil {
+		// raw block volumes are only mapped. the device is bind mounted to the pods when the volume is published.
+		devicePath, err := s.attacher.StageVolume(attachOpts)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to map volume %s. %+v", volume.id(), err)
+		}
+		logger.Infof("staged raw block volume %s at device %s", volume.id(), devicePath)
+		return &csi.NodeStageVolumeResponse{}, nil
+	}
+
+	stagingPath := req.GetStagingTargetPath()
+	notMnt, err := s.isNotMountPoint(stagingPath)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if !notMnt {
+		logger.Infof("volume %s already staged at %s", volume.id(), stagingPath)
+		return &csi.NodeStageVolumeResponse{}, nil
+	}
+
+	devicePath, err := s.attacher.StageVolume(attachOpts)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to map volume %s. %+v", volume.id(), err)
+	}
+
+	mountCapability := req.GetVolumeCapability().GetMount()
+	fsType := mountCapability.GetFsType()
+	if fsType == "" {
+		fsType = req.GetVolumeAttributes()[fsTypeKey]
+	}
+	if fsType == "" {
+		fsType = defaultFsType
+	}
+	options := mountCapability.GetMountFlags()
+	if err := s.mounter.FormatAndMount(devicePath, stagingPath, fsType, options); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to mount volume %s [%s] to %s. %+v", devicePath, fsType, stagingPath, err)
+	}
+
+	logger.Infof("staged volume %s from device %s at %s", volume.id(), devicePath, stagingPath)
+	return &csi.NodeStageVolumeResponse{}, nil
+}
+
+// NodeUnstageVolume unmounts the file system from the staging path, if the volume is not a raw block volume, removes
+// the node from the VolumeAttachment resource of the volume and unmaps the image
+func (s *nodeServer) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstageVolumeRequest) (*csi.NodeUnstageVolumeResponse, error) {
+	if req.GetVolumeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume id is required")
+	}
+	if req.GetStagingTargetPath() == "" {
+		return nil, status.Error(codes.InvalidArgument, "staging target path is required")
+	}
+	volume, err := parseVolumeID(req.GetVolumeId())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	if err := s.unmount(req.GetStagingTargetPath()); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if err := s.attacher.UnstageVolume(stageOptions(volume, req.GetStagingTargetPath(), nil)); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to unmap volume %s. %+v", volume.id(), err)
+	}
+
+	logger.Infof("unstaged volume %s from %s", volume.id(), req.GetStagingTargetPath())
+	return &csi.NodeUnstageVolumeResponse{}, nil
+}
+
+// NodePublishVolume bind mounts the staging path of the volume, or the device of raw block volumes, to the target path of the pod
+func (s *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
+	if req.GetVolumeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume id is required")
+	}
+	if req.GetStagingTargetPath() == "" {
+		return nil, status.Error(codes.InvalidArgument, "staging target path is required")
+	}
+	if req.GetTargetPath() == "" {
+		return nil, status.Error(codes.InvalidArgument, "target path is required")
+	}
+	if req.GetVolumeCapability() == nil {
+		return nil, status.Error(codes.InvalidArgument, "volume capability is required")
+	}
+
+	targetPath := req.GetTargetPath()
+	source := req.GetStagingTargetPath()
+	if req.GetVolumeCapability().GetBlock() != nil {
+		volume, err := parseVolumeID(req.GetVolumeId())
+		if err != nil {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		// the image was mapped when the volume was staged. attaching a mapped image returns its device.
+		if source, err = s.volumeManager.Attach(volume.image, volume.pool, volume.clusterName); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to get the device of volume %s. %+v", volume.id(), err)
+		}
+		// the device is bind mounted to a file at the target path
+		if err := createFile(targetPath); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	notMnt, err := s.isNotMountPoint(targetPath)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if !notMnt {
+		logger.Infof("volume %s already published at %s", req.GetVolumeId(), targetPath)
+		return &csi.NodePublishVolumeResponse{}, nil
+	}
+
+	options := []string{"bind"}
+	if req.GetReadonly() {
+		options = append(options, flexvolume.ReadOnly)
+	}
+	options = append(options, req.GetVolumeCapability().GetMount().GetMountFlags()...)
+	if err := s.mounter.Interface.Mount(source, targetPath, "", options); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to mount %s to %s. %+v", source, targetPath, err)
+	}
+
+	logger.Infof("published volume %s at %s", req.GetVolumeId(), targetPath)
+	return &csi.NodePublishVolumeResponse{}, nil
+}
+
+// NodeUnpublishVolume unmounts the volume from the target path of the pod
+func (s *nodeServer) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
+	if req.GetVolumeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume id is required")
+	}
+	if req.GetTargetPath() == "" {
+		return nil, status.Error(codes.InvalidArgument, "target path is required")
+	}
+
+	if err := s.unmount(req.GetTargetPath()); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	logger.Infof("unpublished volume %s from %s", req.GetVolumeId(), req.GetTargetPath())
+	return &csi.NodeUnpublishVolumeResponse{}, nil
+}
+
+func (s *nodeServer) NodeGetId(ctx context.Context, req *csi.NodeGetIdRequest) (*csi.NodeGetIdResponse, error) {
+	return &csi.NodeGetIdResponse{NodeId: s.nodeID}, nil
+}
+
+func (s *nodeServer) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoRequest) (*csi.NodeGetInfoResponse, error) {
+	return &csi.NodeGetInfoResponse{NodeId: s.nodeID}, nil
+}
+
+func (s *nodeServer) NodeGetCapabilities(ctx context.Context, req *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
+	return &csi.NodeGetCapabilitiesResponse{
+		Capabilities: []*csi.NodeServiceCapability{
+			{
+				Type: &csi.NodeServiceCapability_Rpc{
+					Rpc: &csi.NodeServiceCapability_RPC{
+						Type: csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// stageOptions returns the options of the attachment of the volume at the staging path. The VolumeAttachment resource
+// of the volume is named after its image, which is the name of the persistent volume.
+func stageOptions(volume blockVolume, stagingPath string, capability *csi.VolumeCapability) flexvolume.AttachOptions {
+	opts := flexvolume.AttachOptions{
+		Image:       volume.image,
+		Pool:        volume.pool,
+		ClusterName: volume.clusterName,
+		VolumeName:  volume.image,
+		MountDir:    stagingPath,
+		RW:          flexvolume.ReadWrite,
+	}
+	switch capability.GetAccessMode().GetMode() {
+	case csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY, csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY:
+		opts.RW = flexvolume.ReadOnly
+	case csi.VolumeCapability_AccessMode_MULTI_NODE_SINGLE_WRITER, csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER:
+		// the volume is staged on several nodes at the same time, so the other nodes are not fenced
+		opts.MultiWriter = "true"
+	}
+	return opts
+}
+
+// isNotMountPoint checks if the path is not mounted yet, creating the directory of the path if needed
+func (s *nodeServer) isNotMountPoint(path string) (bool, error) {
+	notMnt, err := s.mounter.Interface.IsLikelyNotMountPoint(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return false, fmt.Errorf("failed to check if %s is a mount point. %+v", path, err)
+		}
+		if err := os.MkdirAll(path, 0750); err != nil {
+			return false, fmt.Errorf("failed to create dir %s. %+v", path, err)
+		}
+		return true, nil
+	}
+	return notMnt, nil
+}
+
+// createFile creates the file at the path, and its directory, if it does not exist
+func createFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create dir of %s. %+v", path, err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0640)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s. %+v", path, err)
+	}
+	return f.Close()
+}
+
+// unmount unmounts the path if it is mounted and removes its directory or file
+func (s *nodeServer) unmount(path string) error {
+	notMnt, err := s.mounter.Interface.IsLikelyNotMountPoint(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to check if %s is a mount point. %+v", path, err)
+	}
+	if !notMnt {
+		if err := s.mounter.Interface.Unmount(path); err != nil {
+			return fmt.Errorf("failed to unmount %s. %+v", path, err)
+		}
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove dir %s. %+v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package csi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi/v0"
+	"github.com/rook/rook/pkg/agent/flexvolume"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/kubernetes/pkg/util/mount"
+)
+
+type fakeVolumeManager struct {
+	attached []string
+	detached []string
+}
+
+func (f *fakeVolumeManager) Init() error {
+	return nil
+}
+
+func (f *fakeVolumeManager) Attach(image, pool, clusterName string) (string, error) {
+	f.attached = append(f.attached, fmt.Sprintf("%s/%s/%s", clusterName, pool, image))
+	return "/dev/rbd0", nil
+}
+
+func (f *fakeVolumeManager) Detach(image, pool, clusterName string) error {
+	f.detached = append(f.detached, fmt.Sprintf("%s/%s/%s", clusterName, pool, image))
+	return nil
+}
+
+func (f *fakeVolumeManager) Expand(image, pool, clusterName string) error {
+	return nil
+}
+
+func (f *fakeVolumeManager) AttachFilesystem(fsName, path, clusterName string, quota int64) (string, error) {
+	return "", nil
+}
+
+func (f *fakeVolumeManager) RenewFence(clusterName string, addresses []string) error {
+	return nil
+}
+
+func (f *fakeVolumeManager) PurgeFilesystem(fsName, path, clusterName string) error {
+	return nil
+}
+
+func (f *fakeVolumeManager) Fence(image, pool, clusterName string, nodeAddresses []string) ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeVolumeManager) Unfence(clusterName string, addresses []string) error {
+	return nil
+}
+
+// fakeVolumeAttacher maps the images with the fake volume manager and records the options of the staged volumes
+type fakeVolumeAttacher struct {
+	volumeManager *fakeVolumeManager
+	staged        []flexvolume.AttachOptions
+	unstaged      []flexvolume.AttachOptions
+}
+
+func (f *fakeVolumeAttacher) StageVolume(attachOpts flexvolume.AttachOptions) (string, error) {
+	f.staged = append(f.staged, attachOpts)
+	return f.volumeManager.Attach(attachOpts.Image, attachOpts.Pool, attachOpts.ClusterName)
+}
+
+func (f *fakeVolumeAttacher) UnstageVolume(detachOpts flexvolume.AttachOptions) error {
+	f.unstaged = append(f.unstaged, detachOpts)
+	return f.volumeManager.Detach(detachOpts.Image, detachOpts.Pool, detachOpts.ClusterName)
+}
+
+func TestNodePublishUnpublishVolume(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+	stagingPath := path.Join(dir, "staging")
+	targetPath := path.Join(dir, "target")
+
+	mounter := &mount.FakeMounter{}
+	s := &nodeServer{
+		nodeID:        "node1",
+		volumeManager: &fakeVolumeManager{},
+		mounter:       &mount.SafeFormatAndMount{Interface: mounter},
+	}
+
+	req := &csi.NodePublishVolumeRequest{
+		VolumeId:          "rook/replicapool/pvc-1",
+		StagingTargetPath: stagingPath,
+		TargetPath:        targetPath,
+		VolumeCapability:  mountCapability(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER),
+		Readonly:          true,
+	}
+	_, err := s.NodePublishVolume(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(mounter.MountPoints))
+	assert.Equal(t, stagingPath, mounter.MountPoints[0].Device)
+	assert.Equal(t, targetPath, mounter.MountPoints[0].Path)
+
+	// the volume is only mounted once
+	_, err = s.NodePublishVolume(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(mounter.MountPoints))
+
+	_, err = s.NodeUnpublishVolume(context.TODO(), &csi.NodeUnpublishVolumeRequest{VolumeId: req.VolumeId, TargetPath: targetPath})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(mounter.MountPoints))
+	_, err = os.Stat(targetPath)
+	assert.True(t, os.IsNotExist(err))
+
+	req.TargetPath = ""
+	_, err = s.NodePublishVolume(context.TODO(), req)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestNodeUnstageVolume(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+	stagingPath := path.Join(dir, "staging")
+	os.MkdirAll(stagingPath, 0750)
+
+	volumeManager := &fakeVolumeManager{}
+	attacher := &fakeVolumeAttacher{volumeManager: volumeManager}
+	mounter := &mount.FakeMounter{MountPoints: []mount.MountPoint{{Device: "/dev/rbd0", Path: stagingPath}}}
+	s := &nodeServer{
+		nodeID:        "node1",
+		volumeManager: volumeManager,
+		attacher:      attacher,
+		mounter:       &mount.SafeFormatAndMount{Interface: mounter},
+	}
+
+	// the file system is unmounted, the image unmapped and the attachment of the staging path removed
+	_, err := s.NodeUnstageVolume(context.TODO(), &csi.NodeUnstageVolumeRequest{VolumeId: "rook/replicapool/pvc-1", StagingTargetPath: stagingPath})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(mounter.MountPoints))
+	assert.Equal(t, []string{"rook/replicapool/pvc-1"}, volumeManager.detached)
+	assert.Equal(t, 1, len(attacher.unstaged))
+	assert.Equal(t, "pvc-1", attacher.unstaged[0].VolumeName)
+	assert.Equal(t, stagingPath, attacher.unstaged[0].MountDir)
+}
+
+func TestNodeStagePublishBlockVolume(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+	stagingPath := path.Join(dir, "staging")
+	targetPath := path.Join(dir, "pods", "pod1", "pvc-1")
+
+	volumeManager := &fakeVolumeManager{}
+	attacher := &fakeVolumeAttacher{volumeManager: volumeManager}
+	mounter := &mount.FakeMounter{}
+	s := &nodeServer{
+		nodeID:        "node1",
+		volumeManager: volumeManager,
+		attacher:      attacher,
+		mounter:       &mount.SafeFormatAndMount{Interface: mounter},
+	}
+	capability := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Block{Block: &csi.VolumeCapability_BlockVolume{}},
+		AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+	}
+
+	// the image of a raw block volume is mapped without a file system and its attachment is recorded
+	_, err := s.NodeStageVolume(context.TODO(), &csi.NodeStageVolumeRequest{
+		VolumeId:          "rook/replicapool/pvc-1",
+		StagingTargetPath: stagingPath,
+		VolumeCapability:  capability,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"rook/replicapool/pvc-1"}, volumeManager.attached)
+	assert.Equal(t, 0, len(mounter.MountPoints))
+	assert.Equal(t, 1, len(attacher.staged))
+	assert.Equal(t, "pvc-1", attacher.staged[0].VolumeName)
+	assert.Equal(t, stagingPath, attacher.staged[0].MountDir)
+	assert.Equal(t, flexvolume.ReadWrite, attacher.staged[0].RW)
+	assert.Equal(t, "", attacher.staged[0].MultiWriter)
+
+	// the device is bind mounted to a file at the target path
+	_, err = s.NodePublishVolume(context.TODO(), &csi.NodePublishVolumeRequest{
+		VolumeId:          "rook/replicapool/pvc-1",
+		StagingTargetPath: stagingPath,
+		TargetPath:        targetPath,
+		VolumeCapability:  capability,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(mounter.MountPoints))
+	assert.Equal(t, "/dev/rbd0", mounter.MountPoints[0].Device)
+	assert.Equal(t, targetPath, mounter.MountPoints[0].Path)
+	info, err := os.Stat(targetPath)
+	assert.Nil(t, err)
+	assert.False(t, info.IsDir())
+
+	_, err = s.NodeUnpublishVolume(context.TODO(), &csi.NodeUnpublishVolumeRequest{VolumeId: "rook/replicapool/pvc-1", TargetPath: targetPath})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(mounter.MountPoints))
+	_, err = os.Stat(targetPath)
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = s.NodeUnstageVolume(context.TODO(), &csi.NodeUnstageVolumeRequest{VolumeId: "rook/replicapool/pvc-1", StagingTargetPath: stagingPath})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"rook/replicapool/pvc-1"}, volumeManager.detached)
+	assert.Equal(t, 1, len(attacher.unstaged))
+}
+
+func TestStageOptions(t *testing.T) {
+	volume := blockVolume{clusterName: "rook", pool: "replicapool", image: "pvc-1"}
+
+	opts := stageOptions(volume, "/staging", mountCapability(csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY))
+	assert.Equal(t, flexvolume.ReadOnly, opts.RW)
+	assert.Equal(t, "", opts.MultiWriter)
+
+	// the other nodes of a volume written by multiple nodes are not fenced
+	opts = stageOptions(volume, "/staging", mountCapability(csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER))
+	assert.Equal(t, flexvolume.ReadWrite, opts.RW)
+	assert.Equal(t, "true", opts.MultiWriter)
+
+	// the volume is unstaged without a capability
+	opts = stageOptions(volume, "/staging", nil)
+	assert.Equal(t, "pvc-1", opts.VolumeName)
+	assert.Equal(t, "/staging", opts.MountDir)
+}
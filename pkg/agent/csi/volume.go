@@ -0,0 +1,84 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package csi
+
+import (
+	"fmt"
+	"strings"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi/v0"
+)
+
+// blockVolume identifies an image. The CSI calls only pass the volume id, so the id of the volume contains everything
+// needed to find the image: <clusterName>/<pool>/<image>. Snapshot ids are the id of the volume followed by
+// @<snapshot>.
+type blockVolume struct {
+	clusterName string
+	pool        string
+	image       string
+}
+
+func (v blockVolume) id() string {
+	return fmt.Sprintf("%s/%s/%s", v.clusterName, v.pool, v.image)
+}
+
+func (v blockVolume) snapshotID(snapshot string) string {
+	return fmt.Sprintf("%s@%s", v.id(), snapshot)
+}
+
+func parseVolumeID(id string) (blockVolume, error) {
+	parts := strings.Split(id, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" || strings.Contains(parts[2], "@") {
+		return blockVolume{}, fmt.Errorf("invalid volume id %q", id)
+	}
+	return blockVolume{clusterName: parts[0], pool: parts[1], image: parts[2]}, nil
+}
+
+func parseSnapshotID(id string) (blockVolume, string, error) {
+	i := strings.LastIndex(id, "@")
+	if i < 0 || i == len(id)-1 {
+		return blockVolume{}, "", fmt.Errorf("invalid snapshot id %q", id)
+	}
+	volume, err := parseVolumeID(id[:i])
+	if err != nil {
+		return blockVolume{}, "", fmt.Errorf("invalid snapshot id %q. %+v", id, err)
+	}
+	return volume, id[i+1:], nil
+}
+
+// validateVolumeCapabilities checks that the volume can be used with the capabilities. Volumes can be mounted with a
+// file system or used as raw block devices. They can be written by a single node unless they were created with multiWriter.
+func validateVolumeCapabilities(capabilities []*csi.VolumeCapability, multiWriter bool) error {
+	if len(capabilities) == 0 {
+		return fmt.Errorf("volume capabilities are required")
+	}
+
+	for _, c := range capabilities {
+		switch c.GetAccessMode().GetMode() {
+		case csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+			csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY,
+			csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY:
+		case csi.VolumeCapability_AccessMode_MULTI_NODE_SINGLE_WRITER,
+			csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER:
+			if !multiWriter {
+				return fmt.Errorf("access mode %s requires a volume created with multiWriter", c.GetAccessMode().GetMode())
+			}
+		default:
+			return fmt.Errorf("unsupported access mode %s", c.GetAccessMode().GetMode())
+		}
+	}
+	return nil
+}
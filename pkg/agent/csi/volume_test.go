@@ -0,0 +1,66 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package csi
+
+import (
+	"testing"
+
+	csi "github.com/container-storage-interface/spec/lib/go/csi/v0"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVolumeID(t *testing.T) {
+	volume, err := parseVolumeID("rook/replicapool/pvc-1")
+	assert.Nil(t, err)
+	assert.Equal(t, blockVolume{clusterName: "rook", pool: "replicapool", image: "pvc-1"}, volume)
+	assert.Equal(t, "rook/replicapool/pvc-1", volume.id())
+
+	for _, id := range []string{"", "pvc-1", "replicapool/pvc-1", "rook//pvc-1", "rook/replicapool/pvc-1/x", "rook/replicapool/pvc-1@snap"} {
+		_, err = parseVolumeID(id)
+		assert.NotNil(t, err, id)
+	}
+
+	volume, snapshot, err := parseSnapshotID("rook/replicapool/pvc-1@snap1")
+	assert.Nil(t, err)
+	assert.Equal(t, "pvc-1", volume.image)
+	assert.Equal(t, "snap1", snapshot)
+	assert.Equal(t, "rook/replicapool/pvc-1@snap1", volume.snapshotID(snapshot))
+
+	for _, id := range []string{"rook/replicapool/pvc-1", "rook/replicapool/pvc-1@", "pvc-1@snap1"} {
+		_, _, err = parseSnapshotID(id)
+		assert.NotNil(t, err, id)
+	}
+}
+
+func TestValidateVolumeCapabilities(t *testing.T) {
+	capabilities := []*csi.VolumeCapability{
+		mountCapability(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER),
+		mountCapability(csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY),
+	}
+	assert.Nil(t, validateVolumeCapabilities(capabilities, false))
+	assert.NotNil(t, validateVolumeCapabilities(nil, false))
+
+	// raw block volumes are supported
+	capabilities = append(capabilities, &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Block{Block: &csi.VolumeCapability_BlockVolume{}},
+		AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+	})
+	assert.Nil(t, validateVolumeCapabilities(capabilities, false))
+
+	capabilities = append(capabilities, mountCapability(csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER))
+	assert.NotNil(t, validateVolumeCapabilities(capabilities, false))
+	assert.Nil(t, validateVolumeCapabilities(capabilities, true))
+}
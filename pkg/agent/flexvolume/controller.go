@@ -27,6 +27,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/coreos/pkg/capnslog"
 	"github.com/rook/rook/pkg/agent/flexvolume/crd"
@@ -41,6 +42,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/kubernetes/pkg/util/mount"
 	"k8s.io/kubernetes/pkg/util/version"
 )
 
@@ -48,6 +50,18 @@ const (
 	StorageClassKey       = "storageClass"
 	PoolKey               = "pool"
 	ImageKey              = "image"
+	ClusterNameKey        = "clusterName"
+	FilesystemKey         = "filesystem"
+	PathKey               = "path"
+	FilesystemUserKey     = "user"
+	FilesystemSecretKey   = "key"
+	MultiWriterKey        = "multiWriter"
+	ReadIOPSLimitKey      = "readIOPSLimit"
+	WriteIOPSLimitKey     = "writeIOPSLimit"
+	ReadBPSLimitKey       = "readBPSLimit"
+	WriteBPSLimitKey      = "writeBPSLimit"
+	MountOptionsKey       = "mountOptions"
+	MkfsOptionsKey        = "mkfsOptions"
 	kubeletDefaultRootDir = "/var/lib/kubelet"
 	serverVersionV170     = "v1.7.0"
 )
@@ -59,6 +73,11 @@ type FlexvolumeController struct {
 	clientset                  kubernetes.Interface
 	volumeManager              VolumeManager
 	volumeAttachmentController crd.VolumeAttachmentController
+	mounter                    mount.Interface
+	blkioCgroupDir             string
+	sysBlockDir                string
+	// attachLock serializes the attaches with the reconciliation so an image is not unmapped while it is attached
+	attachLock sync.Mutex
 }
 
 func newFlexvolumeController(context *clusterd.Context, volumeAttachmentCRDClient rest.Interface, manager VolumeManager) (*FlexvolumeController, error) {
@@ -80,11 +99,16 @@ func newFlexvolumeController(context *clusterd.Context, volumeAttachmentCRDClien
 		clientset:                  context.Clientset,
 		volumeManager:              manager,
 		volumeAttachmentController: controller,
+		mounter:                    mount.New("" /* default mount path */),
+		blkioCgroupDir:             blkioCgroupDirDefault,
+		sysBlockDir:                sysBlockDirDefault,
 	}, nil
 }
 
 // Attach attaches rook volume to the node
 func (c *FlexvolumeController) Attach(attachOpts AttachOptions, devicePath *string) error {
+	c.attachLock.Lock()
+	defer c.attachLock.Unlock()
 
 	namespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
 	node := os.Getenv(k8sutil.NodeNameEnvVar)
@@ -92,6 +116,19 @@ func (c *FlexvolumeController) Attach(attachOpts AttachOptions, devicePath *stri
 	// Name of CRD is the PV name. This is done so that the CRD can be use for fencing
 	crdName := attachOpts.VolumeName
 
+	// Shared file system volumes and multi-writer volumes can be attached by any number of RW pods
+	multiWriter := strings.ToLower(attachOpts.MultiWriter) == "true"
+	shared := attachOpts.Filesystem != "" || multiWriter
+
+	// The I/O limits of block volumes are applied to the pod once the image is mapped
+	var qos *crd.QoSLimits
+	if attachOpts.Filesystem == "" {
+		var err error
+		if qos, err = getQoSLimits(attachOpts); err != nil {
+			return fmt.Errorf("failed to attach volume %s. %+v", crdName, err)
+		}
+	}
+
 	// Check if this volume has been attached
 	volumeattachObj, err := c.volumeAttachmentController.Get(namespace, crdName)
 	if err != nil {
@@ -101,6 +138,7 @@ func (c *FlexvolumeController) Attach(attachOpts AttachOptions, devicePath *stri
 		// No volumeattach CRD for this volume found. Create one
 		volumeattachObj = crd.NewVolumeAttachment(crdName, namespace, node, attachOpts.PodNamespace, attachOpts.Pod,
 			attachOpts.MountDir, strings.ToLower(attachOpts.RW) == ReadOnly)
+		volumeattachObj.Attachments[0].QoS = qos
 		logger.Infof("Creating Volume attach Resource %s/%s: %+v", volumeattachObj.Namespace, volumeattachObj.Name, attachOpts)
 		err = c.volumeAttachmentController.Create(volumeattachObj)
 		if err != nil {
@@ -126,7 +164,10 @@ func (c *FlexvolumeController) Attach(attachOpts AttachOptions, devicePath *stri
 
 		if !found {
 			// Check if there is already an attachment with RW.
-			index := getPodRWAttachmentObject(volumeattachObj)
+			index := -1
+			if !shared {
+				index = getPodRWAttachmentObject(volumeattachObj)
+			}
 			if index != -1 {
 				// check if the RW attachment is orphaned.
 				attachment := &volumeattachObj.Attachments[index]
@@ -136,12 +177,21 @@ func (c *FlexvolumeController) Attach(attachOpts AttachOptions, devicePath *stri
 						return fmt.Errorf("failed to get pod CRD %s/%s. %+v", attachment.PodNamespace, attachment.PodName, err)
 					}
 
-					// Attachment is orphaned. Update attachment record and proceed with attaching
+					// Attachment is orphaned. The node of the attachment may have failed with the image still mapped.
+					// Fence its clients before the image is mapped on this node.
+					if attachment.Node != node {
+						if err := c.fenceNode(&volumeattachObj, attachment.Node, attachOpts); err != nil {
+							return fmt.Errorf("failed to fence volume %s on node %s. %+v", crdName, attachment.Node, err)
+						}
+					}
+
+					// Update attachment record and proceed with attaching
 					attachment.Node = node
 					attachment.MountDir = attachOpts.MountDir
 					attachment.PodNamespace = attachOpts.PodNamespace
 					attachment.PodName = attachOpts.Pod
 					attachment.ReadOnly = attachOpts.RW == ReadOnly
+					attachment.QoS = qos
 					err = c.volumeAttachmentController.Update(volumeattachObj)
 					if err != nil {
 						return fmt.Errorf("failed to update volume CRD %s. %+v", crdName, err)
@@ -154,7 +204,7 @@ func (c *FlexvolumeController) Attach(attachOpts AttachOptions, devicePath *stri
 			} else {
 				// No RW attachment found. Check if this is a RW attachment request.
 				// We only support RW once attachment. No mixing either with RO
-				if attachOpts.RW == "rw" && len(volumeattachObj.Attachments) > 0 {
+				if !shared && attachOpts.RW == "rw" && len(volumeattachObj.Attachments) > 0 {
 					return fmt.Errorf("failed to attach volume %s for pod %s/%s. Volume is already attached by one or more pods",
 						crdName, attachOpts.PodNamespace, attachOpts.Pod)
 				}
@@ -166,8 +216,15 @@ func (c *FlexvolumeController) Attach(attachOpts AttachOptions, devicePath *stri
 					PodName:      attachOpts.Pod,
 					MountDir:     attachOpts.MountDir,
 					ReadOnly:     attachOpts.RW == ReadOnly,
+					QoS:          qos,
 				}
 				volumeattachObj.Attachments = append(volumeattachObj.Attachments, newAttach)
+				if multiWriter {
+					volumeattachObj.Status.Warning = getMultiWriterWarning(volumeattachObj)
+					if volumeattachObj.Status.Warning != "" {
+						logger.Warningf("volume %s: %s", crdName, volumeattachObj.Status.Warning)
+					}
+				}
 				err = c.volumeAttachmentController.Update(volumeattachObj)
 				if err != nil {
 					return fmt.Errorf("failed to update volume CRD %s. %+v", crdName, err)
@@ -175,24 +232,61 @@ func (c *FlexvolumeController) Attach(attachOpts AttachOptions, devicePath *stri
 			}
 		}
 	}
+	if attachOpts.Filesystem != "" {
+		*devicePath, err = c.volumeManager.AttachFilesystem(attachOpts.Filesystem, attachOpts.Path, attachOpts.ClusterName, attachOpts.Quota)
+		if err != nil {
+			return fmt.Errorf("failed to attach volume %s of file system %s: %+v", attachOpts.Path, attachOpts.Filesystem, err)
+		}
+		return nil
+	}
+
 	*devicePath, err = c.volumeManager.Attach(attachOpts.Image, attachOpts.Pool, attachOpts.ClusterName)
 	if err != nil {
 		return fmt.Errorf("failed to attach volume %s/%s: %+v", attachOpts.Pool, attachOpts.Image, err)
 	}
+	if qos != nil {
+		if err := c.applyQoSLimits(attachOpts.PodID, *devicePath, *qos); err != nil {
+			return fmt.Errorf("failed to apply the QoS limits of volume %s/%s: %+v", attachOpts.Pool, attachOpts.Image, err)
+		}
+	}
 	return nil
 }
 
 // Detach detaches a rook volume to the node
 func (c *FlexvolumeController) Detach(detachOpts AttachOptions, _ *struct{} /* void reply */) error {
 
-	err := c.volumeManager.Detach(detachOpts.Image, detachOpts.Pool, detachOpts.ClusterName)
-	if err != nil {
-		return fmt.Errorf("Failed to detach volume %s/%s: %+v", detachOpts.Pool, detachOpts.Image, err)
+	// there is nothing to detach from the node for shared file system volumes once they are unmounted
+	if detachOpts.Filesystem == "" {
+		err := c.volumeManager.Detach(detachOpts.Image, detachOpts.Pool, detachOpts.ClusterName)
+		if err != nil {
+			return fmt.Errorf("Failed to detach volume %s/%s: %+v", detachOpts.Pool, detachOpts.Image, err)
+		}
 	}
 
 	namespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
 	crdName := detachOpts.VolumeName
 	volumeAttach, err := c.volumeAttachmentController.Get(namespace, crdName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get volume CRD %s. %+v", crdName, err)
+	}
+
+	// remove the blacklist entries of the clients fenced on this node now that the volume is detached
+	node := os.Getenv(k8sutil.NodeNameEnvVar)
+	if i := getFencedClientsIndex(volumeAttach, node); i != -1 {
+		if err := c.volumeManager.Unfence(detachOpts.ClusterName, volumeAttach.Status.Fenced[i].Addresses); err != nil {
+			return fmt.Errorf("failed to unfence volume %s on node %s: %+v", crdName, node, err)
+		}
+		volumeAttach.Status.Fenced = append(volumeAttach.Status.Fenced[:i], volumeAttach.Status.Fenced[i+1:]...)
+		if len(volumeAttach.Attachments) > 0 {
+			if err := c.volumeAttachmentController.Update(volumeAttach); err != nil {
+				return fmt.Errorf("failed to update volume CRD %s. %+v", crdName, err)
+			}
+		}
+	}
+
 	if len(volumeAttach.Attachments) == 0 {
 		logger.Infof("Deleting VolumeAttachment CRD %s/%s", namespace, crdName)
 		return c.volumeAttachmentController.Delete(namespace, crdName)
@@ -228,11 +322,37 @@ func (c *FlexvolumeController) RemoveAttachmentObject(detachOpts AttachOptions,
 		if nodeAttachmentCount == 1 {
 			*safeToDetach = true
 		}
+		if volumeAttach.Status.Warning != "" {
+			volumeAttach.Status.Warning = getMultiWriterWarning(volumeAttach)
+		}
 		return c.volumeAttachmentController.Update(volumeAttach)
 	}
+
+	// The attachment was taken over by another node while this node was down. The volume must be detached from this node.
+	if nodeAttachmentCount == 0 && getFencedClientsIndex(volumeAttach, node) != -1 {
+		logger.Infof("volume %s was taken over by another node. detaching it from this node", crdName)
+		*safeToDetach = true
+		return nil
+	}
 	return fmt.Errorf("VolumeAttachment CRD %s found but attachment to the mountDir %s was not found", crdName, detachOpts.MountDir)
 }
 
+// GetFilesystemCredentials returns the ceph user and key that were created for a shared file system volume by the
+// provisioner. The driver passes the key to the mount in a secret file so it does not show up in the mount options.
+func (c *FlexvolumeController) GetFilesystemCredentials(attachOpts AttachOptions, credentials *FilesystemCredentials) error {
+	secret, err := c.clientset.CoreV1().Secrets(attachOpts.ClusterName).Get(attachOpts.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get key of volume %s: %+v", attachOpts.VolumeName, err)
+	}
+	user := string(secret.Data[FilesystemUserKey])
+	key := string(secret.Data[FilesystemSecretKey])
+	if user == "" || key == "" {
+		return fmt.Errorf("key of volume %s not found in secret %s/%s", attachOpts.VolumeName, secret.Namespace, secret.Name)
+	}
+	*credentials = FilesystemCredentials{User: user, Key: key}
+	return nil
+}
+
 // Log logs messages from the driver
 func (c *FlexvolumeController) Log(message LogMessage, _ *struct{} /* void reply */) error {
 	if message.IsError {
@@ -243,6 +363,21 @@ func (c *FlexvolumeController) Log(message LogMessage, _ *struct{} /* void reply
 	return nil
 }
 
+// getClusterName gets the cluster of the volume from the PV. Volumes that were provisioned before the cluster was
+// stored in the PV get it from their storage class.
+func (c *FlexvolumeController) getClusterName(flexVolume *v1.FlexVolumeSource) (string, error) {
+	if clusterName := flexVolume.Options[ClusterNameKey]; clusterName != "" {
+		return clusterName, nil
+	}
+
+	storageClass := flexVolume.Options[StorageClassKey]
+	clusterName, err := c.parseClusterName(storageClass)
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse clusterName from storageClass %s: %+v", storageClass, err)
+	}
+	return clusterName, nil
+}
+
 func (c *FlexvolumeController) parseClusterName(storageClassName string) (string, error) {
 	sc, err := c.clientset.Storage().StorageClasses().Get(storageClassName, metav1.GetOptions{})
 	if err != nil {
@@ -307,9 +442,42 @@ func (c *FlexvolumeController) GetAttachInfoFromMountDir(mountDir string, attach
 	if attachOptions.StorageClass == "" {
 		attachOptions.StorageClass = pv.Spec.PersistentVolumeSource.FlexVolume.Options[StorageClassKey]
 	}
-	attachOptions.ClusterName, err = c.parseClusterName(attachOptions.StorageClass)
-	if err != nil {
-		return fmt.Errorf("Failed to parse clusterName from storageClass %s: %+v", attachOptions.StorageClass, err)
+	if attachOptions.Filesystem == "" {
+		attachOptions.Filesystem = pv.Spec.PersistentVolumeSource.FlexVolume.Options[FilesystemKey]
+	}
+	if attachOptions.Path == "" {
+		attachOptions.Path = pv.Spec.PersistentVolumeSource.FlexVolume.Options[PathKey]
+	}
+	if attachOptions.MultiWriter == "" {
+		attachOptions.MultiWriter = pv.Spec.PersistentVolumeSource.FlexVolume.Options[MultiWriterKey]
+	}
+	if attachOptions.ReadIOPS == "" {
+		attachOptions.ReadIOPS = pv.Spec.PersistentVolumeSource.FlexVolume.Options[ReadIOPSLimitKey]
+	}
+	if attachOptions.WriteIOPS == "" {
+		attachOptions.WriteIOPS = pv.Spec.PersistentVolumeSource.FlexVolume.Options[WriteIOPSLimitKey]
+	}
+	if attachOptions.ReadBPS == "" {
+		attachOptions.ReadBPS = pv.Spec.PersistentVolumeSource.FlexVolume.Options[ReadBPSLimitKey]
+	}
+	if attachOptions.WriteBPS == "" {
+		attachOptions.WriteBPS = pv.Spec.PersistentVolumeSource.FlexVolume.Options[WriteBPSLimitKey]
+	}
+	if attachOptions.MountOptions == "" {
+		attachOptions.MountOptions = pv.Spec.PersistentVolumeSource.FlexVolume.Options[MountOptionsKey]
+	}
+	if attachOptions.MkfsOptions == "" {
+		attachOptions.MkfsOptions = pv.Spec.PersistentVolumeSource.FlexVolume.Options[MkfsOptionsKey]
+	}
+	if attachOptions.Filesystem != "" && attachOptions.Quota == 0 {
+		capacity := pv.Spec.Capacity[v1.ResourceStorage]
+		attachOptions.Quota = capacity.Value()
+	}
+	if attachOptions.ClusterName == "" {
+		attachOptions.ClusterName, err = c.getClusterName(pv.Spec.PersistentVolumeSource.FlexVolume)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -369,6 +537,70 @@ func findPodByID(pods *v1.PodList, podUID types.UID) *v1.Pod {
 	return nil
 }
 
+// fenceNode fences the clients of the volume on the given node and records them in the VolumeAttachment resource
+func (c *FlexvolumeController) fenceNode(volumeAttach *crd.VolumeAttachment, nodeName string, attachOpts AttachOptions) error {
+	n, err := c.clientset.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get node %s. %+v", nodeName, err)
+		}
+		logger.Warningf("node %s not found. the clients of volume %s on the node cannot be fenced", nodeName, volumeAttach.Name)
+		return nil
+	}
+
+	addresses := []string{}
+	for _, address := range n.Status.Addresses {
+		if address.Type != v1.NodeHostName {
+			addresses = append(addresses, address.Address)
+		}
+	}
+
+	logger.Infof("fencing clients of volume %s on node %s", volumeAttach.Name, nodeName)
+	blacklisted, fenceErr := c.volumeManager.Fence(attachOpts.Image, attachOpts.Pool, attachOpts.ClusterName, addresses)
+	if len(blacklisted) == 0 {
+		return fenceErr
+	}
+
+	if i := getFencedClientsIndex(*volumeAttach, nodeName); i != -1 {
+		volumeAttach.Status.Fenced[i].Addresses = append(volumeAttach.Status.Fenced[i].Addresses, blacklisted...)
+		volumeAttach.Status.Fenced[i].ClusterName = attachOpts.ClusterName
+	} else {
+		volumeAttach.Status.Fenced = append(volumeAttach.Status.Fenced,
+			crd.FencedClients{Node: nodeName, Addresses: blacklisted, ClusterName: attachOpts.ClusterName})
+	}
+	if fenceErr != nil {
+		// save the clients that were blacklisted so they are removed from the blacklist when the node detaches the volume
+		if err := c.volumeAttachmentController.Update(*volumeAttach); err != nil {
+			logger.Errorf("failed to save the fenced clients of volume %s. %+v", volumeAttach.Name, err)
+		}
+	}
+	return fenceErr
+}
+
+// getFencedClientsIndex returns the index of the fenced clients of the node in the VolumeAttachment resource
+func getFencedClientsIndex(volumeAttachmentObject crd.VolumeAttachment, node string) int {
+	for i, f := range volumeAttachmentObject.Status.Fenced {
+		if f.Node == node {
+			return i
+		}
+	}
+	return -1
+}
+
+// getMultiWriterWarning returns a warning if a multi-writer volume is attached read-write by more than one pod
+func getMultiWriterWarning(volumeAttachmentObject crd.VolumeAttachment) string {
+	count := 0
+	for _, a := range volumeAttachmentObject.Attachments {
+		if !a.ReadOnly {
+			count++
+		}
+	}
+	if count <= 1 {
+		return ""
+	}
+	return fmt.Sprintf("volume is attached read-write by %d pods. the pods must coordinate their writes, for example with a cluster file system, or the data will be corrupted", count)
+}
+
 // getPodRWAttachmentObject loops through the list of attachments of the VolumeAttachment
 // resource and returns the index of the first RW attachment object
 func getPodRWAttachmentObject(volumeAttachmentObject crd.VolumeAttachment) int {
@@ -37,6 +37,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
@@ -379,6 +380,223 @@ func TestMultipleAttachReadOnly(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestMultipleAttachReadWriteFilesystem(t *testing.T) {
+	clientset := test.New(3)
+
+	os.Setenv(k8sutil.PodNamespaceEnvVar, "rook-system")
+	defer os.Unsetenv(k8sutil.PodNamespaceEnvVar)
+
+	os.Setenv(k8sutil.NodeNameEnvVar, "node1")
+	defer os.Unsetenv(k8sutil.NodeNameEnvVar)
+
+	context := &clusterd.Context{
+		Clientset: clientset,
+	}
+
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "otherpod",
+			Namespace: "Default",
+		},
+		Status: v1.PodStatus{
+			Phase: "running",
+		},
+	}
+	clientset.CoreV1().Pods("Default").Create(&pod)
+
+	existingCRD := &crd.VolumeAttachment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pvc-123",
+			Namespace: "rook-system",
+		},
+		Attachments: []crd.Attachment{
+			{
+				Node:         "otherNode",
+				PodNamespace: "Default",
+				PodName:      "otherpod",
+				MountDir:     "/tmt/test",
+				ReadOnly:     false,
+			},
+		},
+	}
+
+	opts := AttachOptions{
+		Filesystem:   "myfs",
+		Path:         "/volumes/pvc-123",
+		Quota:        1024,
+		ClusterName:  "testCluster",
+		MountDir:     "/test/pods/pod123/volumes/rook.io~rook/pvc-123",
+		VolumeName:   "pvc-123",
+		Pod:          "myPod",
+		PodNamespace: "Default",
+		RW:           "rw",
+	}
+
+	scheme := crd.RegisterFakeAPI()
+	fakeClient := &fakerestclient.RESTClient{
+		NegotiatedSerializer: serializer.DirectCodecFactory{CodecFactory: serializer.NewCodecFactory(scheme)},
+		APIRegistry:          api.Registry,
+		Client: fakerestclient.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			switch p, m := req.URL.Path, req.Method; {
+			case p == "/namespaces/rook-system/volumeattachments/pvc-123" && m == "GET":
+				return &http.Response{StatusCode: 200, Header: defaultHeader(), Body: objBody(existingCRD)}, nil
+			case p == "/namespaces/rook-system/volumeattachments/pvc-123" && m == "PUT":
+				o, _ := ioutil.ReadAll(req.Body)
+				var volAtt crd.VolumeAttachment
+				json.Unmarshal(o, &volAtt)
+
+				// shared file system volumes can be attached read-write by multiple pods
+				assert.Equal(t, 2, len(volAtt.Attachments))
+				assert.True(t, containsAttachment(
+					crd.Attachment{
+						PodNamespace: opts.PodNamespace,
+						PodName:      opts.Pod,
+						MountDir:     opts.MountDir,
+						ReadOnly:     false,
+						Node:         "node1",
+					}, volAtt.Attachments,
+				), "VolumeAttachment crd does not contain expected attachment")
+
+				return &http.Response{StatusCode: 200, Header: defaultHeader(), Body: objBody(req.Body)}, nil
+			default:
+				t.Fatalf("unexpected request: %#v\n%#v", req.URL, req)
+				return nil, nil
+			}
+		}),
+	}
+
+	devicePath := ""
+	controller := &FlexvolumeController{
+		clientset:                  context.Clientset,
+		volumeAttachmentController: crd.New(fakeClient),
+		volumeManager:              &FakeVolumeManager{},
+	}
+
+	err := controller.Attach(opts, &devicePath)
+	assert.Nil(t, err)
+	assert.Equal(t, "testCluster:myfs//volumes/pvc-123/1024", devicePath)
+}
+
+func TestMultipleAttachReadWriteMultiWriter(t *testing.T) {
+	clientset := test.New(3)
+
+	os.Setenv(k8sutil.PodNamespaceEnvVar, "rook-system")
+	defer os.Unsetenv(k8sutil.PodNamespaceEnvVar)
+
+	os.Setenv(k8sutil.NodeNameEnvVar, "node1")
+	defer os.Unsetenv(k8sutil.NodeNameEnvVar)
+
+	existingCRD := &crd.VolumeAttachment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pvc-123",
+			Namespace: "rook-system",
+		},
+		Attachments: []crd.Attachment{
+			{
+				Node:         "otherNode",
+				PodNamespace: "Default",
+				PodName:      "otherpod",
+				MountDir:     "/tmt/test",
+				ReadOnly:     false,
+			},
+		},
+	}
+
+	opts := AttachOptions{
+		Image:        "image123",
+		Pool:         "testpool",
+		ClusterName:  "testCluster",
+		MultiWriter:  "true",
+		MountDir:     "/test/pods/pod123/volumes/rook.io~rook/pvc-123",
+		VolumeName:   "pvc-123",
+		Pod:          "myPod",
+		PodNamespace: "Default",
+		RW:           "rw",
+	}
+
+	updated := false
+	scheme := crd.RegisterFakeAPI()
+	fakeClient := &fakerestclient.RESTClient{
+		NegotiatedSerializer: serializer.DirectCodecFactory{CodecFactory: serializer.NewCodecFactory(scheme)},
+		APIRegistry:          api.Registry,
+		Client: fakerestclient.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			switch p, m := req.URL.Path, req.Method; {
+			case p == "/namespaces/rook-system/volumeattachments/pvc-123" && m == "GET":
+				return &http.Response{StatusCode: 200, Header: defaultHeader(), Body: objBody(existingCRD)}, nil
+			case p == "/namespaces/rook-system/volumeattachments/pvc-123" && m == "PUT":
+				o, _ := ioutil.ReadAll(req.Body)
+				var volAtt crd.VolumeAttachment
+				json.Unmarshal(o, &volAtt)
+
+				// both RW attachments are recorded and the multiple writers are reported
+				assert.Equal(t, 2, len(volAtt.Attachments))
+				assert.Contains(t, volAtt.Status.Warning, "attached read-write by 2 pods")
+				updated = true
+
+				return &http.Response{StatusCode: 200, Header: defaultHeader(), Body: objBody(req.Body)}, nil
+			default:
+				t.Fatalf("unexpected request: %#v\n%#v", req.URL, req)
+				return nil, nil
+			}
+		}),
+	}
+
+	devicePath := ""
+	controller := &FlexvolumeController{
+		clientset:                  clientset,
+		volumeAttachmentController: crd.New(fakeClient),
+		volumeManager:              &FakeVolumeManager{},
+	}
+
+	err := controller.Attach(opts, &devicePath)
+	assert.Nil(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, "/image123/testpool/testCluster", devicePath)
+}
+
+func TestGetMultiWriterWarning(t *testing.T) {
+	va := crd.VolumeAttachment{
+		Attachments: []crd.Attachment{
+			{PodName: "pod1", ReadOnly: false},
+			{PodName: "pod2", ReadOnly: true},
+		},
+	}
+	assert.Equal(t, "", getMultiWriterWarning(va))
+
+	va.Attachments = append(va.Attachments, crd.Attachment{PodName: "pod3", ReadOnly: false})
+	assert.Contains(t, getMultiWriterWarning(va), "attached read-write by 2 pods")
+}
+
+func TestGetFilesystemCredentials(t *testing.T) {
+	clientset := test.New(3)
+	controller := &FlexvolumeController{
+		clientset: clientset,
+	}
+	opts := AttachOptions{
+		Filesystem:  "myfs",
+		ClusterName: "testCluster",
+		VolumeName:  "pvc-123",
+	}
+
+	// the key of the volume is not found
+	var credentials FilesystemCredentials
+	err := controller.GetFilesystemCredentials(opts, &credentials)
+	assert.NotNil(t, err)
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "pvc-123", Namespace: "testCluster"},
+		Data: map[string][]byte{
+			FilesystemUserKey:   []byte("rook-pvc-123"),
+			FilesystemSecretKey: []byte("mykey"),
+		},
+	}
+	clientset.CoreV1().Secrets("testCluster").Create(secret)
+	err = controller.GetFilesystemCredentials(opts, &credentials)
+	assert.Nil(t, err)
+	assert.Equal(t, "rook-pvc-123", credentials.User)
+	assert.Equal(t, "mykey", credentials.Key)
+}
+
 func TestOrphanAttach(t *testing.T) {
 	clientset := test.New(3)
 
@@ -463,6 +681,164 @@ func TestOrphanAttach(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestOrphanAttachFenceNode(t *testing.T) {
+	clientset := test.New(3)
+
+	os.Setenv(k8sutil.PodNamespaceEnvVar, "rook-system")
+	defer os.Unsetenv(k8sutil.PodNamespaceEnvVar)
+
+	os.Setenv(k8sutil.NodeNameEnvVar, "node1")
+	defer os.Unsetenv(k8sutil.NodeNameEnvVar)
+
+	existingCRD := &crd.VolumeAttachment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pvc-123",
+			Namespace: "rook-system",
+		},
+		Attachments: []crd.Attachment{
+			{
+				Node:         "node2",
+				PodNamespace: "Default",
+				PodName:      "myPod",
+				MountDir:     "/tmt/test",
+				ReadOnly:     false,
+			},
+		},
+	}
+
+	opts := AttachOptions{
+		Image:        "image123",
+		Pool:         "testpool",
+		ClusterName:  "testCluster",
+		MountDir:     "/test/pods/pod123/volumes/rook.io~rook/pvc-123",
+		VolumeName:   "pvc-123",
+		Pod:          "myPod",
+		PodNamespace: "Default",
+		RW:           "rw",
+	}
+
+	updated := false
+	scheme := crd.RegisterFakeAPI()
+	fakeClient := &fakerestclient.RESTClient{
+		NegotiatedSerializer: serializer.DirectCodecFactory{CodecFactory: serializer.NewCodecFactory(scheme)},
+		APIRegistry:          api.Registry,
+		Client: fakerestclient.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			switch p, m := req.URL.Path, req.Method; {
+			case p == "/namespaces/rook-system/volumeattachments/pvc-123" && m == "GET":
+				return &http.Response{StatusCode: 200, Header: defaultHeader(), Body: objBody(existingCRD)}, nil
+			case p == "/namespaces/rook-system/volumeattachments/pvc-123" && m == "PUT":
+				o, _ := ioutil.ReadAll(req.Body)
+				var volAtt crd.VolumeAttachment
+				json.Unmarshal(o, &volAtt)
+
+				// the attachment is taken over and the clients of the failed node are recorded
+				assert.Equal(t, 1, len(volAtt.Attachments))
+				assert.Equal(t, "node1", volAtt.Attachments[0].Node)
+				assert.Equal(t, []crd.FencedClients{{Node: "node2", Addresses: []string{"2.2.2.2:0/1234"}}}, volAtt.Status.Fenced)
+				updated = true
+
+				return &http.Response{StatusCode: 200, Header: defaultHeader(), Body: objBody(req.Body)}, nil
+			default:
+				t.Fatalf("unexpected request: %#v\n%#v", req.URL, req)
+				return nil, nil
+			}
+		}),
+	}
+
+	devicePath := ""
+	volumeManager := &FakeVolumeManager{}
+	controller := &FlexvolumeController{
+		clientset:                  clientset,
+		volumeAttachmentController: crd.New(fakeClient),
+		volumeManager:              volumeManager,
+	}
+
+	err := controller.Attach(opts, &devicePath)
+	assert.Nil(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, []string{"2.2.2.2:0/1234"}, volumeManager.fenced)
+}
+
+func TestDetachFencedNode(t *testing.T) {
+	clientset := test.New(3)
+
+	os.Setenv(k8sutil.PodNamespaceEnvVar, "rook-system")
+	defer os.Unsetenv(k8sutil.PodNamespaceEnvVar)
+
+	os.Setenv(k8sutil.NodeNameEnvVar, "node2")
+	defer os.Unsetenv(k8sutil.NodeNameEnvVar)
+
+	// the volume was taken over by node1 while node2 was down
+	existingCRD := &crd.VolumeAttachment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pvc-123",
+			Namespace: "rook-system",
+		},
+		Attachments: []crd.Attachment{
+			{
+				Node:         "node1",
+				PodNamespace: "Default",
+				PodName:      "myPod",
+				MountDir:     "/test/pods/pod456/volumes/rook.io~rook/pvc-123",
+			},
+		},
+		Status: crd.VolumeAttachmentStatus{
+			Fenced: []crd.FencedClients{{Node: "node2", Addresses: []string{"2.2.2.2:0/1234"}}},
+		},
+	}
+
+	updated := false
+	scheme := crd.RegisterFakeAPI()
+	fakeClient := &fakerestclient.RESTClient{
+		NegotiatedSerializer: serializer.DirectCodecFactory{CodecFactory: serializer.NewCodecFactory(scheme)},
+		APIRegistry:          api.Registry,
+		Client: fakerestclient.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			switch p, m := req.URL.Path, req.Method; {
+			case p == "/namespaces/rook-system/volumeattachments/pvc-123" && m == "GET":
+				return &http.Response{StatusCode: 200, Header: defaultHeader(), Body: objBody(existingCRD)}, nil
+			case p == "/namespaces/rook-system/volumeattachments/pvc-123" && m == "PUT":
+				o, _ := ioutil.ReadAll(req.Body)
+				var volAtt crd.VolumeAttachment
+				json.Unmarshal(o, &volAtt)
+
+				// the attachment of node1 is kept and the fenced clients are cleared
+				assert.Equal(t, 1, len(volAtt.Attachments))
+				assert.Equal(t, 0, len(volAtt.Status.Fenced))
+				updated = true
+
+				return &http.Response{StatusCode: 200, Header: defaultHeader(), Body: objBody(req.Body)}, nil
+			default:
+				t.Fatalf("unexpected request: %#v\n%#v", req.URL, req)
+				return nil, nil
+			}
+		}),
+	}
+
+	opts := AttachOptions{
+		VolumeName:  "pvc-123",
+		ClusterName: "testCluster",
+		MountDir:    "/test/pods/pod123/volumes/rook.io~rook/pvc-123",
+	}
+
+	volumeManager := &FakeVolumeManager{}
+	controller := &FlexvolumeController{
+		clientset:                  clientset,
+		volumeAttachmentController: crd.New(fakeClient),
+		volumeManager:              volumeManager,
+	}
+
+	// the volume must be detached from the node that was fenced
+	safeToDetach := false
+	err := controller.RemoveAttachmentObject(opts, &safeToDetach)
+	assert.Nil(t, err)
+	assert.True(t, safeToDetach)
+
+	err = controller.Detach(opts, nil)
+	assert.Nil(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, []string{"2.2.2.2:0/1234"}, volumeManager.unfenced)
+}
+
 // This tests the idempotency of the VolumeAttachment record.
 // If the VolumeAttachment record was previously created for this pod
 // and the attach flow should continue.
@@ -716,6 +1092,41 @@ func TestGetAttachInfoFromMountDir(t *testing.T) {
 	assert.Equal(t, "testCluster", opts.ClusterName)
 }
 
+func TestGetAttachInfoClusterNameFromPV(t *testing.T) {
+	clientset := test.New(3)
+
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pvc-123"},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				FlexVolume: &v1.FlexVolumeSource{
+					Driver: "rook.io/rook",
+					Options: map[string]string{
+						StorageClassKey: "deletedClass",
+						PoolKey:         "pool123",
+						ImageKey:        "pvc-123",
+						ClusterNameKey:  "otherCluster",
+					},
+				},
+			},
+			ClaimRef: &v1.ObjectReference{Namespace: "testnamespace"},
+		},
+	}
+	clientset.CoreV1().PersistentVolumes().Create(pv)
+
+	controller := &FlexvolumeController{
+		clientset:     clientset,
+		volumeManager: &FakeVolumeManager{},
+	}
+
+	// the cluster is found in the PV even when the storage class does not exist anymore
+	opts := AttachOptions{VolumeName: "pvc-123", PodID: "pod123", Pod: "myPod"}
+	err := controller.GetAttachInfoFromMountDir("/test/pods/pod123/volumes/rook.io~rook/pvc-123", &opts)
+	assert.Nil(t, err)
+	assert.Equal(t, "otherCluster", opts.ClusterName)
+	assert.Equal(t, "pool123", opts.Pool)
+}
+
 func TestParseClusterName(t *testing.T) {
 	clientset := test.New(3)
 
@@ -752,7 +1163,14 @@ func TestGetCRDNameFromMountDirInvalid(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
-type FakeVolumeManager struct{}
+type FakeVolumeManager struct {
+	detached []string
+	expanded []string
+	fenced   []string
+	unfenced []string
+	purged   []string
+	renewed  []string
+}
 
 func (f *FakeVolumeManager) Init() error {
 	return nil
@@ -763,6 +1181,40 @@ func (f *FakeVolumeManager) Attach(image, pool, clusterName string) (string, err
 }
 
 func (f *FakeVolumeManager) Detach(image, pool, clusterName string) error {
+	f.detached = append(f.detached, fmt.Sprintf("%s/%s/%s", image, pool, clusterName))
+	return nil
+}
+
+func (f *FakeVolumeManager) Expand(image, pool, clusterName string) error {
+	f.expanded = append(f.expanded, fmt.Sprintf("%s/%s/%s", image, pool, clusterName))
+	return nil
+}
+
+func (f *FakeVolumeManager) AttachFilesystem(fsName, path, clusterName string, quota int64) (string, error) {
+	return fmt.Sprintf("%s:%s/%s/%d", clusterName, fsName, path, quota), nil
+}
+
+func (f *FakeVolumeManager) PurgeFilesystem(fsName, path, clusterName string) error {
+	f.purged = append(f.purged, fmt.Sprintf("%s/%s%s", clusterName, fsName, path))
+	return nil
+}
+
+func (f *FakeVolumeManager) Fence(image, pool, clusterName string, nodeAddresses []string) ([]string, error) {
+	blacklisted := []string{}
+	for _, address := range nodeAddresses {
+		blacklisted = append(blacklisted, address+":0/1234")
+	}
+	f.fenced = append(f.fenced, blacklisted...)
+	return blacklisted, nil
+}
+
+func (f *FakeVolumeManager) Unfence(clusterName string, addresses []string) error {
+	f.unfenced = append(f.unfenced, addresses...)
+	return nil
+}
+
+func (f *FakeVolumeManager) RenewFence(clusterName string, addresses []string) error {
+	f.renewed = append(f.renewed, addresses...)
 	return nil
 }
 
@@ -788,3 +1240,105 @@ func containsAttachment(attachment crd.Attachment, attachments []crd.Attachment)
 	}
 	return false
 }
+
+func TestVolumeExpansion(t *testing.T) {
+	clientset := test.New(3)
+	sc := storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "storageClass1"},
+		Provisioner: "rook.io/block",
+		Parameters:  map[string]string{"pool": "testpool", "clusterName": "testCluster"},
+	}
+	clientset.StorageV1().StorageClasses().Create(&sc)
+
+	manager := &FakeVolumeManager{}
+	controller := &FlexvolumeController{
+		clientset:     clientset,
+		volumeManager: manager,
+	}
+
+	newPV := func(size string) *v1.PersistentVolume {
+		return &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pvc-123"},
+			Spec: v1.PersistentVolumeSpec{
+				Capacity: v1.ResourceList{v1.ResourceStorage: resource.MustParse(size)},
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					FlexVolume: &v1.FlexVolumeSource{
+						Driver: "rook.io/rook",
+						Options: map[string]string{
+							StorageClassKey: "storageClass1",
+							PoolKey:         "pool123",
+							ImageKey:        "pvc-123",
+						},
+					},
+				},
+			},
+		}
+	}
+
+	// the volumes are checked when the agent starts
+	controller.onPersistentVolumeAdd(newPV("1Gi"))
+	assert.Equal(t, []string{"pvc-123/pool123/testCluster"}, manager.expanded)
+
+	// the volume manager compares the file system with the device on every update
+	controller.onPersistentVolumeUpdate(newPV("1Gi"), newPV("2Gi"))
+	controller.onPersistentVolumeUpdate(newPV("2Gi"), newPV("2Gi"))
+	assert.Equal(t, 3, len(manager.expanded))
+
+	// volumes of other drivers are ignored
+	otherPV := newPV("3Gi")
+	otherPV.Spec.PersistentVolumeSource.FlexVolume.Driver = "other/driver"
+	controller.onPersistentVolumeUpdate(newPV("2Gi"), otherPV)
+	assert.Equal(t, 3, len(manager.expanded))
+}
+
+func TestPurgeVolume(t *testing.T) {
+	os.Setenv(k8sutil.NodeNameEnvVar, "node1")
+	defer os.Unsetenv(k8sutil.NodeNameEnvVar)
+
+	clientset := test.New(3)
+	manager := &FakeVolumeManager{}
+	controller := &FlexvolumeController{
+		clientset:     clientset,
+		volumeManager: manager,
+	}
+
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pvc-123"},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				FlexVolume: &v1.FlexVolumeSource{
+					Driver: "rook.io/rook",
+					Options: map[string]string{
+						FilesystemKey:  "myfs",
+						PathKey:        "/volumes/pvc-123",
+						ClusterNameKey: "testCluster",
+					},
+				},
+			},
+		},
+	}
+	clientset.CoreV1().PersistentVolumes().Create(pv)
+
+	// nothing is removed until the operator requests it
+	controller.onPersistentVolumeAdd(pv)
+	assert.Equal(t, 0, len(manager.purged))
+
+	// the agent claims the removal first
+	pv.Annotations = map[string]string{PurgeAnnotation: PurgeRequested}
+	controller.onPersistentVolumeUpdate(pv, pv)
+	assert.Equal(t, 0, len(manager.purged))
+	pv, _ = clientset.CoreV1().PersistentVolumes().Get("pvc-123", metav1.GetOptions{})
+	assert.Equal(t, "node1", pv.Annotations[PurgeNodeAnnotation])
+
+	// the removal claimed by another agent is skipped
+	other := pv.DeepCopy()
+	other.Annotations[PurgeNodeAnnotation] = "node2"
+	controller.onPersistentVolumeUpdate(other, other)
+	assert.Equal(t, 0, len(manager.purged))
+
+	// the agent that claimed the removal removes the directory
+	controller.onPersistentVolumeUpdate(pv, pv)
+	assert.Equal(t, []string{"testCluster/myfs/volumes/pvc-123"}, manager.purged)
+	pv, _ = clientset.CoreV1().PersistentVolumes().Get("pvc-123", metav1.GetOptions{})
+	assert.Equal(t, PurgeCompleted, pv.Annotations[PurgeAnnotation])
+}
@@ -70,6 +70,15 @@ func (c *VolumeAttachmentCRDController) Get(namespace, name string) (VolumeAttac
 		Do().Into(&result)
 }
 
+// List lists all the VolumeAttachment CRDs in the namespace
+func (c *VolumeAttachmentCRDController) List(namespace string) (VolumeAttachmentList, error) {
+	var result VolumeAttachmentList
+	return result, c.client.Get().
+		Resource(CustomResourceNamePlural).
+		Namespace(namespace).
+		Do().Into(&result)
+}
+
 // Create creates the volume attach CRD resource in Kubernetes
 func (c *VolumeAttachmentCRDController) Create(volumeAttachment VolumeAttachment) error {
 	return c.client.Post().
@@ -31,7 +31,26 @@ var schemeGroupVersion = schema.GroupVersion{Group: k8sutil.CustomResourceGroup,
 type VolumeAttachment struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
-	Attachments       []Attachment `json:"attachments"`
+	Attachments       []Attachment           `json:"attachments"`
+	Status            VolumeAttachmentStatus `json:"status,omitempty"`
+}
+
+// VolumeAttachmentStatus reports the state of the attachments of a volume
+type VolumeAttachmentStatus struct {
+	// A warning about the attachments, such as a volume attached read-write by multiple pods
+	Warning string `json:"warning,omitempty"`
+
+	// The clients that were blacklisted when the volume was taken over from a failed node. The blacklist entries are
+	// removed when the volume is detached from that node.
+	Fenced []FencedClients `json:"fenced,omitempty"`
+}
+
+// FencedClients are the blacklisted client addresses on a node
+type FencedClients struct {
+	Node      string   `json:"node"`
+	Addresses []string `json:"addresses"`
+	// The cluster of the blacklist, where the entries are renewed until they are removed
+	ClusterName string `json:"clusterName,omitempty"`
 }
 
 type Attachment struct {
@@ -40,6 +59,21 @@ type Attachment struct {
 	PodName      string `json:"podName"`
 	MountDir     string `json:"mountDir"`
 	ReadOnly     bool   `json:"readOnly"`
+
+	// The I/O limits applied to the device of the volume in the blkio cgroup of the pod
+	QoS *QoSLimits `json:"qos,omitempty"`
+
+	// Whether the volume was staged on the node by the CSI driver. The mount dir is the staging path of the node and
+	// the attachment has no pod.
+	CSI bool `json:"csi,omitempty"`
+}
+
+// QoSLimits are the I/O limits of a volume in operations or bytes per second. Zero is unlimited.
+type QoSLimits struct {
+	ReadIOPS  uint64 `json:"readIOPS,omitempty"`
+	WriteIOPS uint64 `json:"writeIOPS,omitempty"`
+	ReadBPS   uint64 `json:"readBPS,omitempty"`
+	WriteBPS  uint64 `json:"writeBPS,omitempty"`
 }
 
 type VolumeAttachmentList struct {
@@ -52,6 +86,7 @@ type VolumeAttachmentList struct {
 type VolumeAttachmentController interface {
 	Create(volumeAttachment VolumeAttachment) error
 	Get(namespace, name string) (VolumeAttachment, error)
+	List(namespace string) (VolumeAttachmentList, error)
 	Update(volumeAttachment VolumeAttachment) error
 	Delete(namespace, name string) error
 }
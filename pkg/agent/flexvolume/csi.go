@@ -0,0 +1,163 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package flexvolume
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rook/rook/pkg/agent/flexvolume/crd"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+	"k8s.io/kubernetes/pkg/util/mount"
+)
+
+// NewCSIController creates the controller that maps the images of the volumes staged by the CSI driver and records
+// them in the VolumeAttachment resources, so they are fenced and reconciled like the flexvolumes. The CSI driver
+// requires Kubernetes 1.11, so the VolumeAttachment resources are always CRDs.
+func NewCSIController(context *clusterd.Context, volumeAttachmentCRDClient rest.Interface, manager VolumeManager) *FlexvolumeController {
+	return &FlexvolumeController{
+		clientset:                  context.Clientset,
+		volumeManager:              manager,
+		volumeAttachmentController: crd.New(volumeAttachmentCRDClient),
+		mounter:                    mount.New("" /* default mount path */),
+	}
+}
+
+// StageVolume maps the image of a CSI volume on the node and records the attachment of the node in the
+// VolumeAttachment resource of the volume. The kubelet only stages a volume that is written by a single node once it
+// was detached from the previous node, or once the previous node failed, so the clients of the nodes that still have
+// a read-write attachment are fenced before the image is mapped. The attachment is recorded once the image is mapped,
+// so the reconciliation removes the attachments whose image is no longer mapped.
+func (c *FlexvolumeController) StageVolume(attachOpts AttachOptions) (string, error) {
+	c.attachLock.Lock()
+	defer c.attachLock.Unlock()
+
+	namespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
+	node := os.Getenv(k8sutil.NodeNameEnvVar)
+	crdName := attachOpts.VolumeName
+	multiWriter := strings.ToLower(attachOpts.MultiWriter) == "true"
+	readOnly := attachOpts.RW == ReadOnly
+
+	exists := true
+	volumeAttach, err := c.volumeAttachmentController.Get(namespace, crdName)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return "", fmt.Errorf("failed to get volume CRD %s. %+v", crdName, err)
+		}
+		exists = false
+		volumeAttach = crd.VolumeAttachment{}
+		volumeAttach.Name = crdName
+		volumeAttach.Namespace = namespace
+	}
+
+	if exists && !multiWriter && !readOnly {
+		attachments := []crd.Attachment{}
+		for _, a := range volumeAttach.Attachments {
+			if a.Node != node && !a.ReadOnly {
+				if err := c.fenceNode(&volumeAttach, a.Node, attachOpts); err != nil {
+					return "", fmt.Errorf("failed to fence volume %s on node %s. %+v", crdName, a.Node, err)
+				}
+				continue
+			}
+			attachments = append(attachments, a)
+		}
+		if len(attachments) != len(volumeAttach.Attachments) {
+			// save the fenced clients before the image is mapped, so they are removed from the blacklist when the
+			// volume is detached from this node even if the image cannot be mapped
+			volumeAttach.Attachments = attachments
+			if err := c.volumeAttachmentController.Update(volumeAttach); err != nil {
+				return "", fmt.Errorf("failed to update volume CRD %s. %+v", crdName, err)
+			}
+			if volumeAttach, err = c.volumeAttachmentController.Get(namespace, crdName); err != nil {
+				return "", fmt.Errorf("failed to get volume CRD %s. %+v", crdName, err)
+			}
+		}
+	}
+
+	devicePath, err := c.volumeManager.Attach(attachOpts.Image, attachOpts.Pool, attachOpts.ClusterName)
+	if err != nil {
+		return "", fmt.Errorf("failed to attach volume %s/%s: %+v", attachOpts.Pool, attachOpts.Image, err)
+	}
+
+	for _, a := range volumeAttach.Attachments {
+		if a.Node == node && a.MountDir == attachOpts.MountDir {
+			return devicePath, nil
+		}
+	}
+	volumeAttach.Attachments = append(volumeAttach.Attachments, crd.Attachment{
+		Node:     node,
+		MountDir: attachOpts.MountDir,
+		ReadOnly: readOnly,
+		CSI:      true,
+	})
+	if multiWriter {
+		volumeAttach.Status.Warning = getMultiWriterWarning(volumeAttach)
+		if volumeAttach.Status.Warning != "" {
+			logger.Warningf("volume %s: %s", crdName, volumeAttach.Status.Warning)
+		}
+	}
+
+	if !exists {
+		logger.Infof("Creating Volume attach Resource %s/%s: %+v", namespace, crdName, attachOpts)
+		if err := c.volumeAttachmentController.Create(volumeAttach); err != nil {
+			return "", fmt.Errorf("failed to create volume CRD %s. %+v", crdName, err)
+		}
+		return devicePath, nil
+	}
+	if err := c.volumeAttachmentController.Update(volumeAttach); err != nil {
+		return "", fmt.Errorf("failed to update volume CRD %s. %+v", crdName, err)
+	}
+	return devicePath, nil
+}
+
+// UnstageVolume removes the attachment of the node from the VolumeAttachment resource of a CSI volume and unmaps its
+// image. The clients of the node that were fenced are removed from the blacklist, and the VolumeAttachment resource
+// is deleted once no attachment is left.
+func (c *FlexvolumeController) UnstageVolume(detachOpts AttachOptions) error {
+	namespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
+	node := os.Getenv(k8sutil.NodeNameEnvVar)
+	crdName := detachOpts.VolumeName
+
+	volumeAttach, err := c.volumeAttachmentController.Get(namespace, crdName)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get volume CRD %s. %+v", crdName, err)
+		}
+	} else {
+		attachments := []crd.Attachment{}
+		for _, a := range volumeAttach.Attachments {
+			if a.Node != node || a.MountDir != detachOpts.MountDir {
+				attachments = append(attachments, a)
+			}
+		}
+		if len(attachments) != len(volumeAttach.Attachments) {
+			logger.Infof("Deleting attachment for mountDir %s from Volume attach CRD %s/%s", detachOpts.MountDir, namespace, crdName)
+			volumeAttach.Attachments = attachments
+			if volumeAttach.Status.Warning != "" {
+				volumeAttach.Status.Warning = getMultiWriterWarning(volumeAttach)
+			}
+			if err := c.volumeAttachmentController.Update(volumeAttach); err != nil {
+				return fmt.Errorf("failed to update volume CRD %s. %+v", crdName, err)
+			}
+		}
+	}
+
+	return c.Detach(detachOpts, nil)
+}
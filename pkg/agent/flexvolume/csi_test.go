@@ -0,0 +1,120 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package flexvolume
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/rook/rook/pkg/agent/flexvolume/crd"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	fakerestclient "k8s.io/client-go/rest/fake"
+	"k8s.io/kubernetes/pkg/api"
+)
+
+func TestStageUnstageVolume(t *testing.T) {
+	clientset := test.New(3)
+
+	os.Setenv(k8sutil.PodNamespaceEnvVar, "rook-system")
+	defer os.Unsetenv(k8sutil.PodNamespaceEnvVar)
+
+	os.Setenv(k8sutil.NodeNameEnvVar, "node1")
+	defer os.Unsetenv(k8sutil.NodeNameEnvVar)
+
+	// node2 failed while the volume was staged
+	var current *crd.VolumeAttachment
+	current = &crd.VolumeAttachment{
+		ObjectMeta: metav1.ObjectMeta{Name: "pvc-123", Namespace: "rook-system"},
+		Attachments: []crd.Attachment{
+			{Node: "node2", MountDir: "/var/lib/kubelet/plugins/kubernetes.io/csi/pv/pvc-123/globalmount", CSI: true},
+		},
+	}
+	deleted := false
+	scheme := crd.RegisterFakeAPI()
+	fakeClient := &fakerestclient.RESTClient{
+		NegotiatedSerializer: serializer.DirectCodecFactory{CodecFactory: serializer.NewCodecFactory(scheme)},
+		APIRegistry:          api.Registry,
+		Client: fakerestclient.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			switch p, m := req.URL.Path, req.Method; {
+			case p == "/namespaces/rook-system/volumeattachments/pvc-123" && m == "GET":
+				if current == nil {
+					return &http.Response{StatusCode: 404, Header: defaultHeader(), Body: objBody("")}, nil
+				}
+				return &http.Response{StatusCode: 200, Header: defaultHeader(), Body: objBody(current)}, nil
+			case p == "/namespaces/rook-system/volumeattachments/pvc-123" && m == "PUT":
+				o, _ := ioutil.ReadAll(req.Body)
+				var volAtt crd.VolumeAttachment
+				json.Unmarshal(o, &volAtt)
+				current = &volAtt
+				return &http.Response{StatusCode: 200, Header: defaultHeader(), Body: objBody(volAtt)}, nil
+			case p == "/namespaces/rook-system/volumeattachments/pvc-123" && m == "DELETE":
+				current = nil
+				deleted = true
+				return &http.Response{StatusCode: 200, Header: defaultHeader(), Body: objBody("")}, nil
+			default:
+				t.Fatalf("unexpected request: %#v\n%#v", req.URL, req)
+				return nil, nil
+			}
+		}),
+	}
+
+	volumeManager := &FakeVolumeManager{}
+	controller := &FlexvolumeController{
+		clientset:                  clientset,
+		volumeAttachmentController: crd.New(fakeClient),
+		volumeManager:              volumeManager,
+	}
+
+	opts := AttachOptions{
+		Image:       "pvc-123",
+		Pool:        "replicapool",
+		ClusterName: "rook",
+		VolumeName:  "pvc-123",
+		MountDir:    "/var/lib/kubelet/plugins/kubernetes.io/csi/pv/pvc-123/globalmount",
+		RW:          ReadWrite,
+	}
+
+	// the clients of the failed node are fenced and the attachment of this node is recorded
+	devicePath, err := controller.StageVolume(opts)
+	assert.Nil(t, err)
+	assert.Equal(t, "/pvc-123/replicapool/rook", devicePath)
+	assert.Equal(t, []string{"2.2.2.2:0/1234"}, volumeManager.fenced)
+	assert.Equal(t, []crd.Attachment{{Node: "node1", MountDir: opts.MountDir, CSI: true}}, current.Attachments)
+	assert.Equal(t, []crd.FencedClients{{Node: "node2", Addresses: []string{"2.2.2.2:0/1234"}, ClusterName: "rook"}}, current.Status.Fenced)
+
+	// staging the volume again does not add another attachment
+	_, err = controller.StageVolume(opts)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(current.Attachments))
+
+	// the attachment is removed and the image unmapped. the resource is deleted once no attachment is left.
+	err = controller.UnstageVolume(opts)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"pvc-123/replicapool/rook"}, volumeManager.detached)
+	assert.True(t, deleted)
+
+	// unstaging the volume again only unmaps the image
+	err = controller.UnstageVolume(opts)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(volumeManager.detached))
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package flexvolume
+
+import (
+	"fmt"
+
+	"github.com/rook/rook/pkg/operator/kit"
+	"k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+var persistentVolumeResource = kit.CustomResource{
+	Name:    "persistentvolume",
+	Plural:  "persistentvolumes",
+	Version: "v1",
+}
+
+// StartVolumeWatch watches the persistent volumes to grow the file system of the rook volumes attached to this node
+// when the operator has increased their capacity, and to remove the directory of the deleted shared file system
+// volumes. The volumes are checked when the watch starts, so a file system that was not grown while the agent was
+// down is grown when the agent restarts. The call blocks until the done channel is closed.
+func (s *FlexvolumeServer) StartVolumeWatch(done chan struct{}) error {
+	return s.controller.watchVolumes(done)
+}
+
+func (c *FlexvolumeController) watchVolumes(done chan struct{}) error {
+	handlers := cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.onPersistentVolumeAdd,
+		UpdateFunc: c.onPersistentVolumeUpdate,
+	}
+
+	watcher := kit.NewWatcher(persistentVolumeResource, v1.NamespaceAll, handlers, c.clientset.CoreV1().RESTClient())
+	return watcher.Watch(&v1.PersistentVolume{}, done)
+}
+
+func (c *FlexvolumeController) onPersistentVolumeAdd(obj interface{}) {
+	pv := obj.(*v1.PersistentVolume)
+	c.expandVolume(pv)
+	c.purgeVolume(pv)
+}
+
+func (c *FlexvolumeController) onPersistentVolumeUpdate(oldObj, newObj interface{}) {
+	pv := newObj.(*v1.PersistentVolume)
+	c.expandVolume(pv)
+	c.purgeVolume(pv)
+}
+
+// expandVolume grows the file system of the volume if it is attached to this node. The volume manager compares the
+// file system with the size of the device, so an expansion that failed or was missed is retried on the next event.
+func (c *FlexvolumeController) expandVolume(pv *v1.PersistentVolume) {
+	flexVolume := pv.Spec.PersistentVolumeSource.FlexVolume
+	if flexVolume == nil || flexVolume.Driver != fmt.Sprintf("%s/%s", FlexvolumeVendor, FlexvolumeDriver) {
+		// not a rook volume
+		return
+	}
+	if flexVolume.Options[FilesystemKey] != "" {
+		// shared file system volumes are not expanded
+		return
+	}
+
+	image := flexVolume.Options[ImageKey]
+	pool := flexVolume.Options[PoolKey]
+	clusterName, err := c.getClusterName(flexVolume)
+	if err != nil {
+		logger.Errorf("failed to expand volume %s. %+v", pv.Name, err)
+		return
+	}
+
+	if err := c.volumeManager.Expand(image, pool, clusterName); err != nil {
+		logger.Errorf("failed to expand volume %s. %+v", pv.Name, err)
+	}
+}
@@ -22,6 +22,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -38,6 +40,15 @@ import (
 const (
 	findDevicePathMaxRetries = 10
 	rbdKernelModuleName      = "rbd"
+	cephQuotaMaxBytesAttr    = "ceph.quota.max_bytes"
+
+	// the file system is not grown if it is smaller than the device by less than the tolerance, which is left over
+	// when the device size is not a multiple of the block or allocation group size
+	growTolerance = 1024 * 1024
+
+	// the blacklist entries of fenced clients are removed when the failed node detaches the volume. they expire in
+	// case the node never comes back, and are renewed by the agent of the node the volume is attached to until then.
+	fenceExpireSeconds = 60 * 60
 )
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "ceph-volumeattacher")
@@ -159,6 +170,231 @@ func (vm *VolumeManager) Detach(image, pool, clusterName string) error {
 	return nil
 }
 
+// Expand grows the file system of the volume to the size of the image if the volume is attached to this node and the
+// file system is smaller than the image. The image must already be resized.
+func (vm *VolumeManager) Expand(image, pool, clusterName string) error {
+	devicePath, err := vm.isAttached(image, pool, clusterName)
+	if err != nil {
+		return fmt.Errorf("failed to check if volume %s/%s is attached cluster %s. %+v", pool, image, clusterName, err)
+	}
+	if devicePath == "" {
+		logger.Debugf("volume %s/%s is not attached to this node", pool, image)
+		return nil
+	}
+
+	device := strings.TrimPrefix(devicePath, "/dev/")
+	mountPath, err := sys.GetDeviceMountPoint(device, vm.context.Executor)
+	if err != nil {
+		return fmt.Errorf("failed to get mount point of volume %s/%s. %+v", pool, image, err)
+	}
+	if mountPath == "" {
+		logger.Infof("volume %s/%s is attached but not mounted. the file system will not be grown", pool, image)
+		return nil
+	}
+
+	filesystems, err := sys.GetDeviceFilesystems(device, vm.context.Executor)
+	if err != nil {
+		return fmt.Errorf("failed to get file system of volume %s/%s. %+v", pool, image, err)
+	}
+	// the device is listed once for each of its mounts
+	fstype := strings.Split(filesystems, ",")[0]
+
+	deviceSize, err := sys.GetDeviceSize(devicePath, vm.context.Executor)
+	if err != nil {
+		return fmt.Errorf("failed to get size of volume %s/%s. %+v", pool, image, err)
+	}
+	filesystemSize, err := sys.GetFilesystemSize(devicePath, mountPath, fstype, vm.context.Executor)
+	if err != nil {
+		return fmt.Errorf("failed to get file system size of volume %s/%s. %+v", pool, image, err)
+	}
+	if filesystemSize+growTolerance >= deviceSize {
+		logger.Debugf("file system of volume %s/%s already fills the device", pool, image)
+		return nil
+	}
+
+	logger.Infof("growing %s file system of volume %s/%s on %s", fstype, pool, image, devicePath)
+	if err := sys.GrowFilesystem(devicePath, mountPath, fstype, vm.context.Executor); err != nil {
+		return fmt.Errorf("failed to grow file system of volume %s/%s. %+v", pool, image, err)
+	}
+	logger.Infof("expanded volume %s/%s", pool, image)
+	return nil
+}
+
+// AttachFilesystem prepares the directory of a shared file system volume and returns the source to mount it from.
+// The directory is created if it does not exist yet and its quota is set to the given number of bytes.
+func (vm *VolumeManager) AttachFilesystem(fsName, volumePath, clusterName string, quota int64) (string, error) {
+	var source string
+	err := vm.withFilesystemRoot(fsName, clusterName, func(rootDir, monitors string) error {
+		volumeDir := path.Join(rootDir, volumePath)
+		if err := os.MkdirAll(volumeDir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s in file system %s. %+v", volumePath, fsName, err)
+		}
+
+		if quota > 0 {
+			logger.Infof("setting quota of directory %s in file system %s to %d bytes", volumePath, fsName, quota)
+			err := vm.context.Executor.ExecuteCommand(false, "set quota "+volumePath, "setfattr",
+				"-n", cephQuotaMaxBytesAttr, "-v", strconv.FormatInt(quota, 10), volumeDir)
+			if err != nil {
+				return fmt.Errorf("failed to set quota of directory %s in file system %s. %+v", volumePath, fsName, err)
+			}
+		}
+		source = fmt.Sprintf("%s:%s", monitors, volumePath)
+		return nil
+	})
+	return source, err
+}
+
+// PurgeFilesystem removes the directory of a shared file system volume with all of its data
+func (vm *VolumeManager) PurgeFilesystem(fsName, volumePath, clusterName string) error {
+	return vm.withFilesystemRoot(fsName, clusterName, func(rootDir, monitors string) error {
+		logger.Infof("removing directory %s from file system %s", volumePath, fsName)
+		if err := os.RemoveAll(path.Join(rootDir, volumePath)); err != nil {
+			return fmt.Errorf("failed to remove directory %s from file system %s. %+v", volumePath, fsName, err)
+		}
+		return nil
+	})
+}
+
+// withFilesystemRoot mounts the root of the file system as the admin while the given function manages the directories
+// of the volumes. The admin key is passed to the mount in a secret file so it does not show up in the mount options,
+// and the pods never see the admin mount since they mount the directory of their volume as its own ceph user.
+func (vm *VolumeManager) withFilesystemRoot(fsName, clusterName string, f func(rootDir, monitors string) error) error {
+	clusterInfo, _, err := mon.LoadClusterInfo(vm.context, clusterName)
+	if err != nil {
+		return fmt.Errorf("failed to load cluster information from cluster %s: %+v", clusterName, err)
+	}
+	monEndpoints := make([]string, 0, len(clusterInfo.Monitors))
+	for _, monitor := range clusterInfo.Monitors {
+		monEndpoints = append(monEndpoints, monitor.Endpoint)
+	}
+	monitors := strings.Join(monEndpoints, ",")
+
+	rootDir, err := ioutil.TempDir("", "rook-"+fsName)
+	if err != nil {
+		return fmt.Errorf("failed to create mount point for file system %s: %+v", fsName, err)
+	}
+	defer os.Remove(rootDir)
+
+	// the temp file is only readable by root
+	secretFile, err := ioutil.TempFile("", "rook-admin")
+	if err != nil {
+		return fmt.Errorf("failed to create secret file for file system %s: %+v", fsName, err)
+	}
+	defer os.Remove(secretFile.Name())
+	_, err = secretFile.WriteString(clusterInfo.AdminSecret)
+	secretFile.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write secret file for file system %s: %+v", fsName, err)
+	}
+
+	options := fmt.Sprintf("name=admin,secretfile=%s,mds_namespace=%s", secretFile.Name(), fsName)
+	if err := sys.MountDeviceWithOptions(monitors+":/", rootDir, "ceph", options, vm.context.Executor); err != nil {
+		return fmt.Errorf("failed to mount file system %s cluster %s. %+v", fsName, clusterName, err)
+	}
+	defer sys.UnmountDevice(rootDir, vm.context.Executor)
+
+	return f(rootDir, monitors)
+}
+
+// Fence blacklists the clients of the image on the node with the given addresses and breaks their locks on the image, so
+// a stale client on a failed node cannot write to the image after it is attached to another node. The blacklisted client
+// addresses are returned.
+func (vm *VolumeManager) Fence(image, pool, clusterName string, nodeAddresses []string) ([]string, error) {
+	// load the cluster info to generate the admin connection config
+	if _, _, err := mon.LoadClusterInfo(vm.context, clusterName); err != nil {
+		return nil, fmt.Errorf("failed to load cluster information from cluster %s: %+v", clusterName, err)
+	}
+
+	watchers, err := cephclient.GetImageWatchers(vm.context, clusterName, image, pool)
+	if err != nil {
+		return nil, err
+	}
+	locks, err := cephclient.ListImageLocks(vm.context, clusterName, image, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	blacklisted := []string{}
+	blacklist := func(address string) error {
+		for _, a := range blacklisted {
+			if a == address {
+				return nil
+			}
+		}
+		logger.Infof("blacklisting client %s of volume %s/%s", address, pool, image)
+		if err := cephclient.BlacklistAdd(vm.context, clusterName, address, fenceExpireSeconds); err != nil {
+			return err
+		}
+		blacklisted = append(blacklisted, address)
+		return nil
+	}
+
+	for _, watcher := range watchers {
+		if isNodeAddress(watcher.Address, nodeAddresses) {
+			if err := blacklist(watcher.Address); err != nil {
+				return blacklisted, err
+			}
+		}
+	}
+	for _, lock := range locks {
+		if isNodeAddress(lock.Address, nodeAddresses) {
+			if err := blacklist(lock.Address); err != nil {
+				return blacklisted, err
+			}
+			logger.Infof("breaking lock %s of client %s on volume %s/%s", lock.ID, lock.Locker, pool, image)
+			if err := cephclient.RemoveImageLock(vm.context, clusterName, image, pool, lock.ID, lock.Locker); err != nil {
+				return blacklisted, err
+			}
+		}
+	}
+
+	return blacklisted, nil
+}
+
+// Unfence removes the client addresses from the blacklist
+func (vm *VolumeManager) Unfence(clusterName string, addresses []string) error {
+	if _, _, err := mon.LoadClusterInfo(vm.context, clusterName); err != nil {
+		return fmt.Errorf("failed to load cluster information from cluster %s: %+v", clusterName, err)
+	}
+
+	for _, address := range addresses {
+		logger.Infof("removing client %s from the blacklist", address)
+		if err := cephclient.BlacklistRemove(vm.context, clusterName, address); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenewFence adds the fenced clients to the blacklist again so their entries do not expire while they are fenced
+func (vm *VolumeManager) RenewFence(clusterName string, addresses []string) error {
+	if _, _, err := mon.LoadClusterInfo(vm.context, clusterName); err != nil {
+		return fmt.Errorf("failed to load cluster information from cluster %s: %+v", clusterName, err)
+	}
+
+	for _, address := range addresses {
+		if err := cephclient.BlacklistAdd(vm.context, clusterName, address, fenceExpireSeconds); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isNodeAddress checks if the client address, in the form <ip>:<port>/<nonce>, is one of the node addresses
+func isNodeAddress(clientAddress string, nodeAddresses []string) bool {
+	i := strings.LastIndex(clientAddress, ":")
+	if i < 0 {
+		return false
+	}
+	ip := strings.Trim(clientAddress[:i], "[]")
+	for _, address := range nodeAddresses {
+		if address == ip {
+			return true
+		}
+	}
+	return false
+}
+
 // Check if the volume is attached
 func (vm *VolumeManager) isAttached(image, pool, clusterName string) (string, error) {
 	devicePath, err := vm.devicePathFinder.FindDevicePath(image, pool, clusterName)
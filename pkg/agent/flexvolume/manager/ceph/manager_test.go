@@ -230,3 +230,225 @@ func TestAlreadyDetached(t *testing.T) {
 	err := vm.Detach("image1", "testpool", "testCluster")
 	assert.Nil(t, err)
 }
+
+func TestExpand(t *testing.T) {
+	var growArgs []string
+	blockCount := "262144"
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			switch command {
+			case "mount":
+				return "/dev/rbd3 on /var/lib/kubelet/plugins/rook.io/rook/mounts/pvc-1 type ext4 (rw,relatime)", nil
+			case "df":
+				return "Filesystem     Type\n/dev/rbd3      ext4", nil
+			case "lsblk":
+				// a 2GiB device
+				return `SIZE="2147483648" ROTA="1" RO="0" TYPE="disk" PKNAME=""`, nil
+			case "dumpe2fs":
+				return "Block count:              " + blockCount + "\nBlock size:               4096\n", nil
+			}
+			return "", nil
+		},
+		MockExecuteCommand: func(debug bool, actionName string, command string, args ...string) error {
+			growArgs = append([]string{command}, args...)
+			return nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	// the file system is grown on the node where the volume is attached
+	vm := &VolumeManager{
+		context:          context,
+		devicePathFinder: &fakeDevicePathFinder{response: []string{"/dev/rbd3"}},
+	}
+	err := vm.Expand("image1", "testpool", "testCluster")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"resize2fs", "/dev/rbd3"}, growArgs)
+
+	// nothing to grow if the file system already fills the device
+	growArgs = nil
+	blockCount = "524288"
+	err = vm.Expand("image1", "testpool", "testCluster")
+	assert.Nil(t, err)
+	assert.Nil(t, growArgs)
+
+	// nothing to grow if the volume is not attached to this node
+	growArgs = nil
+	vm.devicePathFinder = &fakeDevicePathFinder{response: []string{""}}
+	err = vm.Expand("image1", "testpool", "testCluster")
+	assert.Nil(t, err)
+	assert.Nil(t, growArgs)
+}
+
+func TestAttachFilesystem(t *testing.T) {
+	clientset := test.New(3)
+	clusterName := "testCluster"
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	cm := &v1.ConfigMap{
+		Data: map[string]string{
+			"data": "rook-ceph-mon0=10.0.0.1:6790",
+		},
+	}
+	cm.Name = "rook-ceph-mon-endpoints"
+	clientset.CoreV1().ConfigMaps(clusterName).Create(cm)
+
+	var commands []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if strings.Contains(command, "ceph-authtool") {
+				cephtest.CreateConfigDir(path.Join(configDir, clusterName))
+			}
+			return "", nil
+		},
+		MockExecuteCommand: func(debug bool, actionName string, command string, args ...string) error {
+			commands = append(commands, command)
+			switch command {
+			case "mount":
+				assert.Equal(t, "ceph", args[1])
+				assert.Contains(t, args[3], "name=admin,secretfile=")
+				assert.NotContains(t, args[3], "secret=")
+				assert.Contains(t, args[3], "mds_namespace=myfs")
+				assert.Equal(t, "10.0.0.1:6790:/", args[4])
+			case "setfattr":
+				assert.Equal(t, "ceph.quota.max_bytes", args[1])
+				assert.Equal(t, "1073741824", args[3])
+				assert.True(t, strings.HasSuffix(args[4], "/volumes/pvc-1"))
+			}
+			return nil
+		},
+	}
+
+	context := &clusterd.Context{
+		Clientset: clientset,
+		Executor:  executor,
+		ConfigDir: configDir,
+	}
+	vm := &VolumeManager{context: context}
+	source, err := vm.AttachFilesystem("myfs", "/volumes/pvc-1", clusterName, 1073741824)
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.1:6790:/volumes/pvc-1", source)
+	assert.Equal(t, []string{"mount", "setfattr", "umount"}, commands)
+}
+
+func TestPurgeFilesystem(t *testing.T) {
+	clientset := test.New(3)
+	clusterName := "testCluster"
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	cm := &v1.ConfigMap{
+		Data: map[string]string{
+			"data": "rook-ceph-mon0=10.0.0.1:6790",
+		},
+	}
+	cm.Name = "rook-ceph-mon-endpoints"
+	clientset.CoreV1().ConfigMaps(clusterName).Create(cm)
+
+	var rootDir string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if strings.Contains(command, "ceph-authtool") {
+				cephtest.CreateConfigDir(path.Join(configDir, clusterName))
+			}
+			return "", nil
+		},
+		MockExecuteCommand: func(debug bool, actionName string, command string, args ...string) error {
+			if command == "mount" {
+				// the volumes in the mounted file system
+				rootDir = args[5]
+				os.MkdirAll(path.Join(rootDir, "volumes/pvc-1/data"), 0755)
+				os.MkdirAll(path.Join(rootDir, "volumes/pvc-2"), 0755)
+			}
+			return nil
+		},
+	}
+
+	context := &clusterd.Context{
+		Clientset: clientset,
+		Executor:  executor,
+		ConfigDir: configDir,
+	}
+	vm := &VolumeManager{context: context}
+	err := vm.PurgeFilesystem("myfs", "/volumes/pvc-1", clusterName)
+	assert.Nil(t, err)
+	defer os.RemoveAll(rootDir)
+
+	// only the directory of the volume is removed
+	_, err = os.Stat(path.Join(rootDir, "volumes/pvc-1"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(path.Join(rootDir, "volumes/pvc-2"))
+	assert.Nil(t, err)
+}
+
+func TestFence(t *testing.T) {
+	clientset := test.New(3)
+	clusterName := "testCluster"
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	cm := &v1.ConfigMap{
+		Data: map[string]string{
+			"data": "rook-ceph-mon0=10.0.0.1:6790",
+		},
+	}
+	cm.Name = "rook-ceph-mon-endpoints"
+	clientset.CoreV1().ConfigMaps(clusterName).Create(cm)
+
+	var blacklisted, unblacklisted, removedLocks []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if strings.Contains(command, "ceph-authtool") {
+				cephtest.CreateConfigDir(path.Join(configDir, clusterName))
+			}
+			if command != "rbd" {
+				return "", nil
+			}
+			switch args[0] {
+			case "status":
+				return `{"watchers":[{"address":"2.2.2.2:0/1234","client":4157,"cookie":18446462598732840961},` +
+					`{"address":"1.1.1.1:0/5678","client":4160,"cookie":18446462598732840962}]}`, nil
+			case "lock":
+				if args[1] == "list" {
+					return `{"auto 18446462598732840961":{"locker":"client.4157","address":"2.2.2.2:0/1234"}}`, nil
+				}
+				removedLocks = append(removedLocks, strings.Join(args[2:5], " "))
+			}
+			return "", nil
+		},
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command, outfileArg string, args ...string) (string, error) {
+			if args[0] == "osd" && args[1] == "blacklist" {
+				if args[2] == "add" {
+					assert.Equal(t, "3600", args[4])
+					blacklisted = append(blacklisted, args[3])
+				} else {
+					unblacklisted = append(unblacklisted, args[3])
+				}
+			}
+			return "", nil
+		},
+	}
+
+	context := &clusterd.Context{
+		Clientset: clientset,
+		Executor:  executor,
+		ConfigDir: configDir,
+	}
+	vm := &VolumeManager{context: context}
+
+	// only the clients on the failed node are blacklisted and their locks broken
+	addresses, err := vm.Fence("image1", "testpool", clusterName, []string{"2.2.2.2"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2.2.2.2:0/1234"}, addresses)
+	assert.Equal(t, []string{"2.2.2.2:0/1234"}, blacklisted)
+	assert.Equal(t, []string{"testpool/image1 auto 18446462598732840961 client.4157"}, removedLocks)
+
+	err = vm.Unfence(clusterName, addresses)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2.2.2.2:0/1234"}, unblacklisted)
+}
+
+func TestIsNodeAddress(t *testing.T) {
+	assert.True(t, isNodeAddress("2.2.2.2:0/1234", []string{"1.1.1.1", "2.2.2.2"}))
+	assert.False(t, isNodeAddress("2.2.2.20:0/1234", []string{"2.2.2.2"}))
+	assert.True(t, isNodeAddress("[fe80::1]:0/1234", []string{"fe80::1"}))
+	assert.False(t, isNodeAddress("bogus", []string{"2.2.2.2"}))
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package flexvolume
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DefaultFsType is the file system created on block volumes when no fsType is given
+	DefaultFsType = "ext4"
+)
+
+// allowedMountOptions are the mount options of block volumes that take no value
+var allowedMountOptions = map[string]bool{
+	"noatime":     true,
+	"nodiratime":  true,
+	"relatime":    true,
+	"strictatime": true,
+	"lazytime":    true,
+	"discard":     true,
+	"nodiscard":   true,
+	"barrier":     true,
+	"nobarrier":   true,
+	"sync":        true,
+	"async":       true,
+	"dirsync":     true,
+	"nosuid":      true,
+	"nodev":       true,
+	"noexec":      true,
+	"inode64":     true,
+	"largeio":     true,
+	"nouuid":      true,
+}
+
+// allowedMountOptionsWithValue are the mount options of block volumes in the form option=value
+var allowedMountOptionsWithValue = map[string]bool{
+	"data":                 true,
+	"commit":               true,
+	"inode_readahead_blks": true,
+	"stripe":               true,
+	"logbufs":              true,
+	"logbsize":             true,
+	"allocsize":            true,
+}
+
+// allowedMkfsOptions are the mkfs flags allowed for each file system and whether the flag takes a value
+var allowedMkfsOptions = map[string]map[string]bool{
+	"ext4": extMkfsOptions,
+	"ext3": extMkfsOptions,
+	"xfs": {
+		"-b": true, // block size
+		"-d": true, // data section, e.g. su=64k,sw=4
+		"-i": true, // inode size
+		"-l": true, // log section
+		"-m": true, // metadata, e.g. crc=1
+		"-n": true, // naming, e.g. ftype=1
+		"-K": false,
+	},
+}
+
+var extMkfsOptions = map[string]bool{
+	"-b": true, // block size
+	"-E": true, // extended options, e.g. lazy_itable_init=0,stride=16
+	"-i": true, // bytes per inode
+	"-I": true, // inode size
+	"-J": true, // journal options
+	"-m": true, // reserved blocks percentage
+	"-N": true, // number of inodes
+	"-O": true, // features
+	"-T": true, // usage type
+	"-j": false,
+}
+
+// optionValuePattern matches the values of the mount and mkfs options. Values cannot start with a dash so they are
+// not taken for another flag.
+var optionValuePattern = regexp.MustCompile(`^[A-Za-z0-9_.^+][A-Za-z0-9_.,:=^+-]*$`)
+
+// ParseMountOptions splits the comma separated mount options
+func ParseMountOptions(options string) []string {
+	var result []string
+	for _, o := range strings.Split(options, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			result = append(result, o)
+		}
+	}
+	return result
+}
+
+// ParseMkfsOptions splits the space separated mkfs arguments
+func ParseMkfsOptions(options string) []string {
+	return strings.Fields(options)
+}
+
+// ValidateMountOptions checks that the mount options of a block volume are in the allow-list
+func ValidateMountOptions(options []string) error {
+	for _, o := range options {
+		if allowedMountOptions[o] {
+			continue
+		}
+		parts := strings.SplitN(o, "=", 2)
+		if len(parts) == 2 && allowedMountOptionsWithValue[parts[0]] && optionValuePattern.MatchString(parts[1]) {
+			continue
+		}
+		return fmt.Errorf("mount option %q is not allowed", o)
+	}
+	return nil
+}
+
+// ValidateMkfsOptions checks that the mkfs arguments are in the allow-list of the file system
+func ValidateMkfsOptions(fsType string, args []string) error {
+	if fsType == "" {
+		fsType = DefaultFsType
+	}
+	allowed, ok := allowedMkfsOptions[fsType]
+	if !ok {
+		if len(args) == 0 {
+			return nil
+		}
+		return fmt.Errorf("mkfs options are not supported for file system %s", fsType)
+	}
+
+	for i := 0; i < len(args); i++ {
+		takesValue, ok := allowed[args[i]]
+		if !ok {
+			return fmt.Errorf("mkfs option %q is not allowed for file system %s", args[i], fsType)
+		}
+		if !takesValue {
+			continue
+		}
+		if i+1 == len(args) || !optionValuePattern.MatchString(args[i+1]) {
+			return fmt.Errorf("mkfs option %s requires a valid value", args[i])
+		}
+		i++
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package flexvolume
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateMountOptions(t *testing.T) {
+	options := ParseMountOptions("noatime, discard,,commit=30")
+	assert.Equal(t, []string{"noatime", "discard", "commit=30"}, options)
+	assert.Nil(t, ValidateMountOptions(options))
+	assert.Nil(t, ValidateMountOptions(nil))
+
+	// options that change the mount itself are not allowed
+	assert.NotNil(t, ValidateMountOptions([]string{"remount"}))
+	assert.NotNil(t, ValidateMountOptions([]string{"noatime=1"}))
+	assert.NotNil(t, ValidateMountOptions([]string{"commit=30 /etc"}))
+	assert.NotNil(t, ValidateMountOptions([]string{"commit="}))
+}
+
+func TestValidateMkfsOptions(t *testing.T) {
+	args := ParseMkfsOptions(" -m 1  -E lazy_itable_init=0,stride=16 -j")
+	assert.Equal(t, []string{"-m", "1", "-E", "lazy_itable_init=0,stride=16", "-j"}, args)
+	assert.Nil(t, ValidateMkfsOptions("", args))
+	assert.Nil(t, ValidateMkfsOptions("ext3", args))
+	assert.Nil(t, ValidateMkfsOptions("xfs", []string{"-n", "ftype=1", "-K"}))
+
+	// the flags are checked for the file system
+	assert.NotNil(t, ValidateMkfsOptions("xfs", args))
+	assert.NotNil(t, ValidateMkfsOptions("ext4", []string{"-n", "ftype=1"}))
+	assert.NotNil(t, ValidateMkfsOptions("btrfs", []string{"-K"}))
+	assert.Nil(t, ValidateMkfsOptions("btrfs", nil))
+
+	// the flags with a value require a valid value
+	assert.NotNil(t, ValidateMkfsOptions("ext4", []string{"-m"}))
+	assert.NotNil(t, ValidateMkfsOptions("ext4", []string{"-m", "-j"}))
+	assert.NotNil(t, ValidateMkfsOptions("ext4", []string{"-O", "^has_journal;reboot"}))
+}
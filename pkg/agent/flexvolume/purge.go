@@ -0,0 +1,114 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package flexvolume
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// PurgeAnnotation is set by the operator on the PV of a deleted shared file system volume to request the removal
+	// of the directory of the volume. The operator cannot mount the file system, so one of the agents removes it.
+	PurgeAnnotation = "rook.io/purge"
+	// PurgeNodeAnnotation is the node of the agent that claimed the removal of the directory
+	PurgeNodeAnnotation = "rook.io/purge-node"
+
+	// PurgeRequested is the state of a directory that is waiting to be removed by an agent
+	PurgeRequested = "requested"
+	// PurgeFailed is the state of a directory that the agent failed to remove. The operator requests it again.
+	PurgeFailed = "failed"
+	// PurgeCompleted is the state of a directory that was removed
+	PurgeCompleted = "completed"
+)
+
+// purgeVolume removes the directory of a shared file system volume when the operator requested it. The agents race
+// to claim the removal with an update of the PV at the version they have seen, so only one of them succeeds. The
+// agent that claimed the removal also completes it when it restarts, since the watch starts with all the PVs.
+func (c *FlexvolumeController) purgeVolume(pv *v1.PersistentVolume) {
+	flexVolume := pv.Spec.PersistentVolumeSource.FlexVolume
+	if flexVolume == nil || flexVolume.Driver != fmt.Sprintf("%s/%s", FlexvolumeVendor, FlexvolumeDriver) {
+		// not a rook volume
+		return
+	}
+	if flexVolume.Options[FilesystemKey] == "" || pv.Annotations[PurgeAnnotation] != PurgeRequested {
+		return
+	}
+
+	node := os.Getenv(k8sutil.NodeNameEnvVar)
+	switch pv.Annotations[PurgeNodeAnnotation] {
+	case "":
+		// the update triggers the event on which the directory is removed by the agent that claimed it
+		if err := SetPurgeState(c.clientset, pv, PurgeRequested, node); err != nil && !errors.IsConflict(err) {
+			logger.Errorf("failed to claim removal of volume %s. %+v", pv.Name, err)
+		}
+		return
+	case node:
+	default:
+		// claimed by another agent
+		return
+	}
+
+	fsName := flexVolume.Options[FilesystemKey]
+	volumePath := flexVolume.Options[PathKey]
+	clusterName, err := c.getClusterName(flexVolume)
+	if err == nil {
+		err = c.volumeManager.PurgeFilesystem(fsName, volumePath, clusterName)
+	}
+
+	state := PurgeCompleted
+	if err != nil {
+		logger.Errorf("failed to remove directory %s of volume %s from file system %s. %+v", volumePath, pv.Name, fsName, err)
+		state = PurgeFailed
+		node = ""
+	} else {
+		logger.Infof("removed directory %s of volume %s from file system %s", volumePath, pv.Name, fsName)
+	}
+	if err := SetPurgeState(c.clientset, pv, state, node); err != nil {
+		logger.Errorf("failed to update removal state of volume %s. %+v", pv.Name, err)
+	}
+}
+
+// SetPurgeState updates the purge annotations of the PV. The update fails with a conflict if the PV changed since
+// the given version. An empty node removes the claim.
+func SetPurgeState(clientset kubernetes.Interface, pv *v1.PersistentVolume, state, node string) error {
+	var nodeValue interface{}
+	if node != "" {
+		nodeValue = node
+	}
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": pv.ResourceVersion,
+			"annotations": map[string]interface{}{
+				PurgeAnnotation:     state,
+				PurgeNodeAnnotation: nodeValue,
+			},
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	_, err = clientset.CoreV1().PersistentVolumes().Patch(pv.Name, types.MergePatchType, data)
+	return err
+}
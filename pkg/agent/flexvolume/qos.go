@@ -0,0 +1,140 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package flexvolume
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rook/rook/pkg/agent/flexvolume/crd"
+)
+
+// errPodCgroupFound stops the search of the cgroup of a pod
+var errPodCgroupFound = errors.New("pod cgroup found")
+
+const (
+	blkioCgroupDirDefault = "/sys/fs/cgroup/blkio"
+	sysBlockDirDefault    = "/sys/block"
+)
+
+// getQoSLimits parses the I/O limits of the volume from the attach options. Returns nil if the volume has no limits.
+func getQoSLimits(attachOpts AttachOptions) (*crd.QoSLimits, error) {
+	limits := &crd.QoSLimits{}
+	for _, l := range []struct {
+		name  string
+		value string
+		limit *uint64
+	}{
+		{ReadIOPSLimitKey, attachOpts.ReadIOPS, &limits.ReadIOPS},
+		{WriteIOPSLimitKey, attachOpts.WriteIOPS, &limits.WriteIOPS},
+		{ReadBPSLimitKey, attachOpts.ReadBPS, &limits.ReadBPS},
+		{WriteBPSLimitKey, attachOpts.WriteBPS, &limits.WriteBPS},
+	} {
+		if l.value == "" {
+			continue
+		}
+		v, err := strconv.ParseUint(l.value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q. %+v", l.name, l.value, err)
+		}
+		*l.limit = v
+	}
+
+	if *limits == (crd.QoSLimits{}) {
+		return nil, nil
+	}
+	return limits, nil
+}
+
+// applyQoSLimits throttles the I/O of the pod to the device in the blkio cgroup of the pod. The kubelet creates the
+// cgroup of the pod before its volumes are mounted, and the throttles go away with the cgroup when the pod is deleted.
+func (c *FlexvolumeController) applyQoSLimits(podID, devicePath string, limits crd.QoSLimits) error {
+	device, err := getDeviceNumber(c.sysBlockDir, devicePath)
+	if err != nil {
+		return err
+	}
+	podCgroup, err := findPodCgroup(c.blkioCgroupDir, podID)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range []struct {
+		file  string
+		limit uint64
+	}{
+		{"blkio.throttle.read_iops_device", limits.ReadIOPS},
+		{"blkio.throttle.write_iops_device", limits.WriteIOPS},
+		{"blkio.throttle.read_bps_device", limits.ReadBPS},
+		{"blkio.throttle.write_bps_device", limits.WriteBPS},
+	} {
+		if t.limit == 0 {
+			continue
+		}
+		rule := fmt.Sprintf("%s %d", device, t.limit)
+		if err := ioutil.WriteFile(filepath.Join(podCgroup, t.file), []byte(rule), 0644); err != nil {
+			return fmt.Errorf("failed to set %s to %q in cgroup %s. %+v", t.file, rule, podCgroup, err)
+		}
+	}
+
+	logger.Infof("applied QoS limits %+v to device %s of pod %s", limits, devicePath, podID)
+	return nil
+}
+
+// getDeviceNumber returns the major:minor number of the block device
+func getDeviceNumber(sysBlockDir, devicePath string) (string, error) {
+	devFile := filepath.Join(sysBlockDir, filepath.Base(devicePath), "dev")
+	content, err := ioutil.ReadFile(devFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the device number of %s. %+v", devicePath, err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// findPodCgroup finds the blkio cgroup of the pod. The dir of the cgroup is pod<uid> with the cgroupfs driver of the
+// kubelet and kubepods-<qos>-pod<uid with underscores>.slice with the systemd driver.
+func findPodCgroup(blkioCgroupDir, podID string) (string, error) {
+	systemdID := strings.Replace(podID, "-", "_", -1)
+	names := map[string]bool{
+		"pod" + podID:                                    true,
+		"kubepods-pod" + systemdID + ".slice":            true,
+		"kubepods-burstable-pod" + systemdID + ".slice":  true,
+		"kubepods-besteffort-pod" + systemdID + ".slice": true,
+	}
+
+	podCgroup := ""
+	err := filepath.Walk(blkioCgroupDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && names[info.Name()] {
+			podCgroup = path
+			return errPodCgroupFound
+		}
+		return nil
+	})
+	if err != nil && err != errPodCgroupFound {
+		return "", fmt.Errorf("failed to search the blkio cgroup of pod %s. %+v", podID, err)
+	}
+	if podCgroup == "" {
+		return "", fmt.Errorf("blkio cgroup of pod %s not found in %s", podID, blkioCgroupDir)
+	}
+	return podCgroup, nil
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package flexvolume
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rook/rook/pkg/agent/flexvolume/crd"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	fakerestclient "k8s.io/client-go/rest/fake"
+	"k8s.io/kubernetes/pkg/api"
+)
+
+func TestGetQoSLimits(t *testing.T) {
+	limits, err := getQoSLimits(AttachOptions{})
+	assert.Nil(t, err)
+	assert.Nil(t, limits)
+
+	limits, err = getQoSLimits(AttachOptions{ReadIOPS: "100", WriteBPS: "1048576"})
+	assert.Nil(t, err)
+	assert.Equal(t, crd.QoSLimits{ReadIOPS: 100, WriteBPS: 1048576}, *limits)
+
+	_, err = getQoSLimits(AttachOptions{WriteIOPS: "-1"})
+	assert.NotNil(t, err)
+}
+
+func TestFindPodCgroup(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "TestFindPodCgroup")
+	defer os.RemoveAll(dir)
+
+	// cgroupfs driver
+	cgroupfs := filepath.Join(dir, "cgroupfs")
+	os.MkdirAll(filepath.Join(cgroupfs, "kubepods", "burstable", "pod1234-abcd"), 0755)
+	ioutil.WriteFile(filepath.Join(cgroupfs, "blkio.throttle.read_iops_device"), []byte(""), 0644)
+	podCgroup, err := findPodCgroup(cgroupfs, "1234-abcd")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(cgroupfs, "kubepods", "burstable", "pod1234-abcd"), podCgroup)
+
+	// systemd driver
+	systemd := filepath.Join(dir, "systemd")
+	os.MkdirAll(filepath.Join(systemd, "kubepods.slice", "kubepods-besteffort.slice", "kubepods-besteffort-pod1234_abcd.slice"), 0755)
+	podCgroup, err = findPodCgroup(systemd, "1234-abcd")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(systemd, "kubepods.slice", "kubepods-besteffort.slice", "kubepods-besteffort-pod1234_abcd.slice"), podCgroup)
+
+	_, err = findPodCgroup(systemd, "5678")
+	assert.NotNil(t, err)
+}
+
+func TestAttachWithQoSLimits(t *testing.T) {
+	clientset := test.New(3)
+
+	os.Setenv(k8sutil.PodNamespaceEnvVar, "rook-system")
+	defer os.Unsetenv(k8sutil.PodNamespaceEnvVar)
+
+	os.Setenv(k8sutil.NodeNameEnvVar, "node1")
+	defer os.Unsetenv(k8sutil.NodeNameEnvVar)
+
+	dir, _ := ioutil.TempDir("", "TestAttachWithQoSLimits")
+	defer os.RemoveAll(dir)
+	blkioDir := filepath.Join(dir, "blkio")
+	podCgroup := filepath.Join(blkioDir, "kubepods", "pod123")
+	os.MkdirAll(podCgroup, 0755)
+	// the fake volume manager maps the image to /<image>/<pool>/<cluster>
+	sysBlockDir := filepath.Join(dir, "block")
+	os.MkdirAll(filepath.Join(sysBlockDir, "testCluster"), 0755)
+	ioutil.WriteFile(filepath.Join(sysBlockDir, "testCluster", "dev"), []byte("251:0\n"), 0644)
+
+	var created crd.VolumeAttachment
+	scheme := crd.RegisterFakeAPI()
+	fakeClient := &fakerestclient.RESTClient{
+		NegotiatedSerializer: serializer.DirectCodecFactory{CodecFactory: serializer.NewCodecFactory(scheme)},
+		APIRegistry:          api.Registry,
+		Client: fakerestclient.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			switch p, m := req.URL.Path, req.Method; {
+			case p == "/namespaces/rook-system/volumeattachments/pvc-123" && m == "GET":
+				return &http.Response{StatusCode: 404, Header: defaultHeader(), Body: objBody("")}, nil
+			case p == "/namespaces/rook-system/volumeattachments" && m == "POST":
+				o, _ := ioutil.ReadAll(req.Body)
+				json.Unmarshal(o, &created)
+				return &http.Response{StatusCode: 200, Header: defaultHeader(), Body: objBody(created)}, nil
+			default:
+				t.Fatalf("unexpected request: %#v\n%#v", req.URL, req)
+				return nil, nil
+			}
+		}),
+	}
+
+	controller := &FlexvolumeController{
+		clientset:                  clientset,
+		volumeAttachmentController: crd.New(fakeClient),
+		volumeManager:              &FakeVolumeManager{},
+		blkioCgroupDir:             blkioDir,
+		sysBlockDir:                sysBlockDir,
+	}
+
+	opts := AttachOptions{
+		Image:        "image123",
+		Pool:         "testpool",
+		ClusterName:  "testCluster",
+		MountDir:     "/test/pods/pod123/volumes/rook.io~rook/pvc-123",
+		VolumeName:   "pvc-123",
+		Pod:          "myPod",
+		PodID:        "123",
+		PodNamespace: "Default",
+		RW:           "rw",
+		ReadIOPS:     "100",
+		WriteBPS:     "1048576",
+	}
+	devicePath := ""
+	err := controller.Attach(opts, &devicePath)
+	assert.Nil(t, err)
+
+	// the throttles are set in the cgroup of the pod
+	rule, _ := ioutil.ReadFile(filepath.Join(podCgroup, "blkio.throttle.read_iops_device"))
+	assert.Equal(t, "251:0 100", string(rule))
+	rule, _ = ioutil.ReadFile(filepath.Join(podCgroup, "blkio.throttle.write_bps_device"))
+	assert.Equal(t, "251:0 1048576", string(rule))
+	_, err = os.Stat(filepath.Join(podCgroup, "blkio.throttle.write_iops_device"))
+	assert.True(t, os.IsNotExist(err))
+
+	// the limits are reported in the attachment
+	assert.Equal(t, 1, len(created.Attachments))
+	assert.Equal(t, &crd.QoSLimits{ReadIOPS: 100, WriteBPS: 1048576}, created.Attachments[0].QoS)
+
+	// the cgroup of the pod must exist
+	opts.PodID = "456"
+	err = controller.Attach(opts, &devicePath)
+	assert.NotNil(t, err)
+}
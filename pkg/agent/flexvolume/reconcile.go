@@ -0,0 +1,293 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package flexvolume
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/rook/rook/pkg/agent/flexvolume/crd"
+	"github.com/rook/rook/pkg/ceph/util"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ReconcileInterval is the interval between the reconciliations of the volumes of the node
+	ReconcileInterval = 10 * time.Minute
+)
+
+// StartReconcile reconciles the rook volumes of this node when the agent starts and then periodically until the done
+// channel is closed. Images that remained mapped while the agent was down, such as after a crash during a detach,
+// are unmapped and the attachments of the pods that are gone, and of the CSI volumes whose image is no longer mapped,
+// are removed from the VolumeAttachment resources.
+func (s *FlexvolumeServer) StartReconcile(interval time.Duration, done chan struct{}) {
+	podsDir := path.Join(s.controller.getKubeletRootDir(), "pods")
+	for {
+		if err := s.controller.reconcileVolumes(podsDir, util.RBDSysBusPathDefault); err != nil {
+			logger.Errorf("failed to reconcile the volumes of the node. %+v", err)
+		}
+
+		select {
+		case <-done:
+			logger.Infof("stopping the reconciliation of the volumes of the node")
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// reconcileVolumes removes the stale attachments of this node and unmaps the images of the rook volumes that are
+// no longer attached to any pod on this node
+func (c *FlexvolumeController) reconcileVolumes(podsDir, sysBusDir string) error {
+	namespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
+	node := os.Getenv(k8sutil.NodeNameEnvVar)
+
+	volumeAttachments, err := c.volumeAttachmentController.List(namespace)
+	if err != nil {
+		return fmt.Errorf("failed to list the VolumeAttachment CRDs in namespace %s. %+v", namespace, err)
+	}
+
+	mappedImages, err := util.ListRBDMappedImages(sysBusDir)
+	if err != nil {
+		return fmt.Errorf("failed to list the mapped images. %+v", err)
+	}
+	// the VolumeAttachment resources of the CSI volumes are named after their image
+	mappedImageNames := map[string]bool{}
+	for _, image := range mappedImages {
+		mappedImageNames[image.Image] = true
+	}
+
+	// the attachments of the pods can only be checked against the pod dirs if the kubelet pods dir is mounted in the agent
+	checkPods := true
+	if _, err := os.Stat(podsDir); err != nil {
+		logger.Warningf("kubelet pods dir %s is not available. skipping the cleanup of stale pod attachments. %+v", podsDir, err)
+		checkPods = false
+	}
+
+	// the volumes that are still attached to pods on this node
+	attached := map[string]*crd.VolumeAttachment{}
+	for i := range volumeAttachments.Items {
+		volumeAttach := &volumeAttachments.Items[i]
+		if err := c.removeStaleAttachments(volumeAttach, node, podsDir, checkPods, mappedImageNames[volumeAttach.Name]); err != nil {
+			logger.Errorf("failed to remove the stale attachments of volume %s. %+v", volumeAttach.Name, err)
+			continue
+		}
+		attached[volumeAttach.Name] = volumeAttach
+		if getNodeAttachmentCount(*volumeAttach, node) > 0 {
+			c.renewFences(volumeAttach, node)
+		}
+	}
+
+	if len(mappedImages) == 0 {
+		return nil
+	}
+
+	// images are matched to their volume with the persistent volumes. images that are not rook flex volumes are left
+	// alone. the images of the CSI volumes are unmapped when the kubelet unstages the volumes.
+	pvs, err := c.clientset.CoreV1().PersistentVolumes().List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list persistent volumes. %+v", err)
+	}
+	rookVolumes := map[string]*v1.PersistentVolume{}
+	for i, pv := range pvs.Items {
+		flexVolume := pv.Spec.PersistentVolumeSource.FlexVolume
+		if flexVolume == nil || flexVolume.Driver != fmt.Sprintf("%s/%s", FlexvolumeVendor, FlexvolumeDriver) {
+			continue
+		}
+		rookVolumes[path.Join(flexVolume.Options[PoolKey], flexVolume.Options[ImageKey])] = &pvs.Items[i]
+	}
+
+	for _, image := range mappedImages {
+		pv, ok := rookVolumes[path.Join(image.Pool, image.Image)]
+		if !ok {
+			continue
+		}
+		if volumeAttach, ok := attached[pv.Name]; ok && getNodeAttachmentCount(*volumeAttach, node) > 0 {
+			continue
+		}
+
+		devicePath := util.RBDDevicePathPrefix + image.ID
+		mounted, err := c.isDeviceMounted(devicePath)
+		if err != nil {
+			logger.Errorf("failed to check if device %s of volume %s is mounted. %+v", devicePath, pv.Name, err)
+			continue
+		}
+		if mounted {
+			logger.Warningf("volume %s is not attached to any pod on this node but device %s is still mounted", pv.Name, devicePath)
+			continue
+		}
+
+		if err := c.detachOrphanedImage(pv, namespace, node); err != nil {
+			logger.Errorf("failed to detach orphaned volume %s. %+v", pv.Name, err)
+		}
+	}
+	return nil
+}
+
+// removeStaleAttachments removes the attachments of this node whose pod is gone and whose pod dir was removed by the
+// kubelet, and the attachments of the CSI volumes whose image is no longer mapped on this node, such as after the node
+// rebooted. The CSI driver records the attachment once the image is mapped. The VolumeAttachment resource is deleted if
+// no attachment is left.
+func (c *FlexvolumeController) removeStaleAttachments(volumeAttach *crd.VolumeAttachment, node, podsDir string, checkPods, mapped bool) error {
+	attachments := []crd.Attachment{}
+	for _, a := range volumeAttach.Attachments {
+		if a.Node == node && a.CSI && !mapped {
+			logger.Infof("removing stale attachment of volume %s staged at %s. the image is not mapped", volumeAttach.Name, a.MountDir)
+			continue
+		}
+		if a.Node == node && !a.CSI && checkPods {
+			stale, err := c.isStaleAttachment(a, podsDir)
+			if err != nil {
+				return err
+			}
+			if stale {
+				logger.Infof("removing stale attachment of volume %s for pod %s/%s", volumeAttach.Name, a.PodNamespace, a.PodName)
+				continue
+			}
+		}
+		attachments = append(attachments, a)
+	}
+	if len(attachments) == len(volumeAttach.Attachments) {
+		return nil
+	}
+
+	volumeAttach.Attachments = attachments
+	if len(volumeAttach.Attachments) == 0 && len(volumeAttach.Status.Fenced) == 0 {
+		// the resource is kept while it has fenced clients so they are removed from the blacklist when the image is unmapped
+		logger.Infof("Deleting VolumeAttachment CRD %s/%s", volumeAttach.Namespace, volumeAttach.Name)
+		return c.volumeAttachmentController.Delete(volumeAttach.Namespace, volumeAttach.Name)
+	}
+	if volumeAttach.Status.Warning != "" {
+		volumeAttach.Status.Warning = getMultiWriterWarning(*volumeAttach)
+	}
+	return c.volumeAttachmentController.Update(*volumeAttach)
+}
+
+// isStaleAttachment checks if the pod of the attachment is gone. The kubelet removes the pod dir once the volumes of the
+// pod are unmounted, so the attachment can be removed without failing a pending unmount.
+func (c *FlexvolumeController) isStaleAttachment(attachment crd.Attachment, podsDir string) (bool, error) {
+	podID, _, err := getPodAndPVNameFromMountDir(attachment.MountDir)
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(path.Join(podsDir, podID)); !os.IsNotExist(err) {
+		return false, nil
+	}
+
+	pod, err := c.clientset.CoreV1().Pods(attachment.PodNamespace).Get(attachment.PodName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to get pod %s/%s. %+v", attachment.PodNamespace, attachment.PodName, err)
+	}
+	// a pod with the same name, such as a pod of a stateful set, may have replaced the pod of the attachment
+	return string(pod.UID) != podID, nil
+}
+
+// detachOrphanedImage unmaps the image of a volume that is not attached to any pod on this node. The clients of this
+// node that were fenced when the volume was taken over by another node are removed from the blacklist. The attaches
+// are blocked while the VolumeAttachment resource is checked again and the image is unmapped, so an image that was
+// attached since the resources were listed is left alone.
+func (c *FlexvolumeController) detachOrphanedImage(pv *v1.PersistentVolume, namespace, node string) error {
+	flexVolume := pv.Spec.PersistentVolumeSource.FlexVolume
+	clusterName, err := c.getClusterName(flexVolume)
+	if err != nil {
+		return err
+	}
+
+	c.attachLock.Lock()
+	defer c.attachLock.Unlock()
+
+	var volumeAttach *crd.VolumeAttachment
+	current, err := c.volumeAttachmentController.Get(namespace, pv.Name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get volume CRD %s. %+v", pv.Name, err)
+		}
+	} else {
+		if getNodeAttachmentCount(current, node) > 0 {
+			logger.Infof("volume %s was attached to a pod on this node during the reconciliation. not detaching it", pv.Name)
+			return nil
+		}
+		volumeAttach = &current
+	}
+
+	logger.Infof("detaching orphaned volume %s", pv.Name)
+	if err := c.volumeManager.Detach(flexVolume.Options[ImageKey], flexVolume.Options[PoolKey], clusterName); err != nil {
+		return err
+	}
+
+	if volumeAttach == nil {
+		return nil
+	}
+	i := getFencedClientsIndex(*volumeAttach, node)
+	if i == -1 {
+		return nil
+	}
+	if err := c.volumeManager.Unfence(clusterName, volumeAttach.Status.Fenced[i].Addresses); err != nil {
+		return fmt.Errorf("failed to unfence volume %s on node %s. %+v", volumeAttach.Name, node, err)
+	}
+	volumeAttach.Status.Fenced = append(volumeAttach.Status.Fenced[:i], volumeAttach.Status.Fenced[i+1:]...)
+	if len(volumeAttach.Attachments) == 0 && len(volumeAttach.Status.Fenced) == 0 {
+		logger.Infof("Deleting VolumeAttachment CRD %s/%s", volumeAttach.Namespace, volumeAttach.Name)
+		return c.volumeAttachmentController.Delete(volumeAttach.Namespace, volumeAttach.Name)
+	}
+	return c.volumeAttachmentController.Update(*volumeAttach)
+}
+
+// renewFences adds the clients that were fenced when the volume was attached to this node to the blacklist again, so
+// their entries do not expire while the failed nodes may still have the image mapped
+func (c *FlexvolumeController) renewFences(volumeAttach *crd.VolumeAttachment, node string) {
+	for _, fenced := range volumeAttach.Status.Fenced {
+		if fenced.Node == node || fenced.ClusterName == "" {
+			continue
+		}
+		if err := c.volumeManager.RenewFence(fenced.ClusterName, fenced.Addresses); err != nil {
+			logger.Errorf("failed to renew the fenced clients of volume %s on node %s. %+v", volumeAttach.Name, fenced.Node, err)
+		}
+	}
+}
+
+// isDeviceMounted checks if the device is mounted on the node
+func (c *FlexvolumeController) isDeviceMounted(devicePath string) (bool, error) {
+	mountPoints, err := c.mounter.List()
+	if err != nil {
+		return false, err
+	}
+	for _, m := range mountPoints {
+		if m.Device == devicePath {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getNodeAttachmentCount returns the number of attachments of the volume on the node
+func getNodeAttachmentCount(volumeAttachmentObject crd.VolumeAttachment, node string) int {
+	count := 0
+	for _, a := range volumeAttachmentObject.Attachments {
+		if a.Node == node {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package flexvolume
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/rook/rook/pkg/agent/flexvolume/crd"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/kubernetes"
+	fakerestclient "k8s.io/client-go/rest/fake"
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/util/mount"
+)
+
+func TestReconcileVolumes(t *testing.T) {
+	clientset := test.New(3)
+
+	os.Setenv(k8sutil.PodNamespaceEnvVar, "rook-system")
+	defer os.Unsetenv(k8sutil.PodNamespaceEnvVar)
+
+	os.Setenv(k8sutil.NodeNameEnvVar, "node1")
+	defer os.Unsetenv(k8sutil.NodeNameEnvVar)
+
+	dir, _ := ioutil.TempDir("", "TestReconcileVolumes")
+	defer os.RemoveAll(dir)
+	podsDir := filepath.Join(dir, "pods")
+	os.MkdirAll(filepath.Join(podsDir, "pod2"), 0755)
+	sysBusDir := filepath.Join(dir, "rbd")
+	mapImages(sysBusDir, "pvc-1", "pvc-2", "pvc-3", "pvc-4", "pvc-5", "other")
+	for _, name := range []string{"pvc-1", "pvc-2", "pvc-3", "pvc-4", "pvc-5"} {
+		createRookPV(clientset, name)
+	}
+
+	volumeAttachments := crd.VolumeAttachmentList{
+		Items: []crd.VolumeAttachment{
+			// the pod of the attachment and its pod dir are gone
+			newTestVolumeAttachment("pvc-1", "node1", "pod1"),
+			// the pod dir of the attachment still exists
+			newTestVolumeAttachment("pvc-2", "node1", "pod2"),
+			// the volume was taken over by another node that fenced this node
+			newTestVolumeAttachment("pvc-3", "node2", "pod3"),
+		},
+	}
+	volumeAttachments.Items[2].Status.Fenced = []crd.FencedClients{{Node: "node1", Addresses: []string{"1.1.1.1:0/1234"}}}
+	// the clients of another node were fenced when the volume was attached to this node
+	volumeAttachments.Items[1].Status.Fenced = []crd.FencedClients{{Node: "node3", Addresses: []string{"3.3.3.3:0/1234"}, ClusterName: "rook"}}
+
+	// the image of pvc-5 was not attached when the resources were listed, but it is attached to a pod on this node
+	// by the time it would be unmapped
+	current := map[string]crd.VolumeAttachment{
+		"pvc-3": volumeAttachments.Items[2],
+		"pvc-5": newTestVolumeAttachment("pvc-5", "node1", "pod5"),
+	}
+
+	deleted := []string{}
+	updated := []crd.VolumeAttachment{}
+	scheme := crd.RegisterFakeAPI()
+	fakeClient := &fakerestclient.RESTClient{
+		NegotiatedSerializer: serializer.DirectCodecFactory{CodecFactory: serializer.NewCodecFactory(scheme)},
+		APIRegistry:          api.Registry,
+		Client: fakerestclient.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			switch p, m := req.URL.Path, req.Method; {
+			case p == "/namespaces/rook-system/volumeattachments" && m == "GET":
+				return &http.Response{StatusCode: 200, Header: defaultHeader(), Body: objBody(volumeAttachments)}, nil
+			case m == "GET":
+				if volumeAttach, ok := current[filepath.Base(p)]; ok {
+					return &http.Response{StatusCode: 200, Header: defaultHeader(), Body: objBody(volumeAttach)}, nil
+				}
+				return &http.Response{StatusCode: 404, Header: defaultHeader(), Body: objBody("")}, nil
+			case m == "DELETE":
+				deleted = append(deleted, filepath.Base(p))
+				return &http.Response{StatusCode: 200, Header: defaultHeader(), Body: objBody(req.Body)}, nil
+			case m == "PUT":
+				o, _ := ioutil.ReadAll(req.Body)
+				var volAtt crd.VolumeAttachment
+				json.Unmarshal(o, &volAtt)
+				updated = append(updated, volAtt)
+				return &http.Response{StatusCode: 200, Header: defaultHeader(), Body: objBody(volAtt)}, nil
+			default:
+				t.Fatalf("unexpected request: %#v\n%#v", req.URL, req)
+				return nil, nil
+			}
+		}),
+	}
+
+	manager := &FakeVolumeManager{}
+	controller := &FlexvolumeController{
+		clientset:                  clientset,
+		volumeAttachmentController: crd.New(fakeClient),
+		volumeManager:              manager,
+		// the image of pvc-4 is not attached to any pod but its device is still mounted
+		mounter: &mount.FakeMounter{MountPoints: []mount.MountPoint{{Device: "/dev/rbd3", Path: "/mnt/pvc-4"}}},
+	}
+
+	err := controller.reconcileVolumes(podsDir, sysBusDir)
+	assert.Nil(t, err)
+
+	// the stale attachment is removed
+	assert.Equal(t, []string{"pvc-1"}, deleted)
+
+	// the orphaned images are unmapped and the fenced clients of this node removed from the blacklist. the image of
+	// pvc-5 that was attached during the reconciliation is left alone.
+	assert.Equal(t, []string{"pvc-1/replicapool/rook", "pvc-3/replicapool/rook"}, manager.detached)
+	assert.Equal(t, []string{"1.1.1.1:0/1234"}, manager.unfenced)
+	assert.Equal(t, 1, len(updated))
+
+	// the blacklist entries of the clients fenced by this node are renewed
+	assert.Equal(t, []string{"3.3.3.3:0/1234"}, manager.renewed)
+	assert.Equal(t, "pvc-3", updated[0].Name)
+	assert.Equal(t, 1, len(updated[0].Attachments))
+	assert.Equal(t, 0, len(updated[0].Status.Fenced))
+}
+
+func TestReconcileVolumesWithoutPodsDir(t *testing.T) {
+	clientset := test.New(3)
+
+	os.Setenv(k8sutil.PodNamespaceEnvVar, "rook-system")
+	defer os.Unsetenv(k8sutil.PodNamespaceEnvVar)
+
+	os.Setenv(k8sutil.NodeNameEnvVar, "node1")
+	defer os.Unsetenv(k8sutil.NodeNameEnvVar)
+
+	dir, _ := ioutil.TempDir("", "TestReconcileVolumesWithoutPodsDir")
+	defer os.RemoveAll(dir)
+	sysBusDir := filepath.Join(dir, "rbd")
+	mapImages(sysBusDir, "pvc-1")
+	createRookPV(clientset, "pvc-1")
+
+	volumeAttachments := crd.VolumeAttachmentList{
+		Items: []crd.VolumeAttachment{newTestVolumeAttachment("pvc-1", "node1", "pod1")},
+	}
+	scheme := crd.RegisterFakeAPI()
+	fakeClient := &fakerestclient.RESTClient{
+		NegotiatedSerializer: serializer.DirectCodecFactory{CodecFactory: serializer.NewCodecFactory(scheme)},
+		APIRegistry:          api.Registry,
+		Client: fakerestclient.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			switch p, m := req.URL.Path, req.Method; {
+			case p == "/namespaces/rook-system/volumeattachments" && m == "GET":
+				return &http.Response{StatusCode: 200, Header: defaultHeader(), Body: objBody(volumeAttachments)}, nil
+			default:
+				t.Fatalf("unexpected request: %#v\n%#v", req.URL, req)
+				return nil, nil
+			}
+		}),
+	}
+
+	manager := &FakeVolumeManager{}
+	controller := &FlexvolumeController{
+		clientset:                  clientset,
+		volumeAttachmentController: crd.New(fakeClient),
+		volumeManager:              manager,
+		mounter:                    &mount.FakeMounter{},
+	}
+
+	// the attachments cannot be checked without the pod dirs and the attached image is left alone
+	err := controller.reconcileVolumes(filepath.Join(dir, "pods"), sysBusDir)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(manager.detached))
+}
+
+func TestReconcileCSIAttachments(t *testing.T) {
+	clientset := test.New(3)
+
+	os.Setenv(k8sutil.PodNamespaceEnvVar, "rook-system")
+	defer os.Unsetenv(k8sutil.PodNamespaceEnvVar)
+
+	os.Setenv(k8sutil.NodeNameEnvVar, "node1")
+	defer os.Unsetenv(k8sutil.NodeNameEnvVar)
+
+	dir, _ := ioutil.TempDir("", "TestReconcileCSIAttachments")
+	defer os.RemoveAll(dir)
+	sysBusDir := filepath.Join(dir, "rbd")
+	// the image of the staged CSI volume pvc-2 is mapped. the image of pvc-1 was unmapped when the node rebooted.
+	mapImages(sysBusDir, "pvc-2")
+
+	volumeAttachments := crd.VolumeAttachmentList{
+		Items: []crd.VolumeAttachment{newTestCSIVolumeAttachment("pvc-1", "node1"), newTestCSIVolumeAttachment("pvc-2", "node1")},
+	}
+	deleted := []string{}
+	scheme := crd.RegisterFakeAPI()
+	fakeClient := &fakerestclient.RESTClient{
+		NegotiatedSerializer: serializer.DirectCodecFactory{CodecFactory: serializer.NewCodecFactory(scheme)},
+		APIRegistry:          api.Registry,
+		Client: fakerestclient.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			switch p, m := req.URL.Path, req.Method; {
+			case p == "/namespaces/rook-system/volumeattachments" && m == "GET":
+				return &http.Response{StatusCode: 200, Header: defaultHeader(), Body: objBody(volumeAttachments)}, nil
+			case m == "DELETE":
+				deleted = append(deleted, filepath.Base(p))
+				return &http.Response{StatusCode: 200, Header: defaultHeader(), Body: objBody(req.Body)}, nil
+			default:
+				t.Fatalf("unexpected request: %#v\n%#v", req.URL, req)
+				return nil, nil
+			}
+		}),
+	}
+
+	manager := &FakeVolumeManager{}
+	controller := &FlexvolumeController{
+		clientset:                  clientset,
+		volumeAttachmentController: crd.New(fakeClient),
+		volumeManager:              manager,
+		mounter:                    &mount.FakeMounter{},
+	}
+
+	// the attachments of the CSI volumes are checked without the pod dirs. the mapped image of the CSI volume is
+	// left for the kubelet to unstage.
+	err := controller.reconcileVolumes(filepath.Join(dir, "pods"), sysBusDir)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"pvc-1"}, deleted)
+	assert.Equal(t, 0, len(manager.detached))
+}
+
+func newTestCSIVolumeAttachment(name, node string) crd.VolumeAttachment {
+	volumeAttach := crd.VolumeAttachment{
+		Attachments: []crd.Attachment{
+			{Node: node, MountDir: filepath.Join("/var/lib/kubelet/plugins/kubernetes.io/csi/pv", name, "globalmount"), CSI: true},
+		},
+	}
+	volumeAttach.Name = name
+	volumeAttach.Namespace = "rook-system"
+	return volumeAttach
+}
+
+func newTestVolumeAttachment(name, node, podName string) crd.VolumeAttachment {
+	return crd.NewVolumeAttachment(name, "rook-system", node, "default", podName,
+		filepath.Join("/var/lib/kubelet/pods", podName, "volumes/rook.io~rook", name), false)
+}
+
+// mapImages creates a mock rbd sys bus with the images mapped to /dev/rbd0, /dev/rbd1, ...
+func mapImages(sysBusDir string, images ...string) {
+	for i, image := range images {
+		devPath := filepath.Join(sysBusDir, "devices", strconv.Itoa(i))
+		os.MkdirAll(devPath, 0755)
+		ioutil.WriteFile(filepath.Join(devPath, "name"), []byte(image), 0644)
+		ioutil.WriteFile(filepath.Join(devPath, "pool"), []byte("replicapool"), 0644)
+	}
+}
+
+func createRookPV(clientset kubernetes.Interface, name string) {
+	clientset.CoreV1().PersistentVolumes().Create(&v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				FlexVolume: &v1.FlexVolumeSource{
+					Driver: "rook.io/rook",
+					Options: map[string]string{
+						PoolKey:        "replicapool",
+						ImageKey:       name,
+						ClusterNameKey: "rook",
+					},
+				},
+			},
+		},
+	})
+}
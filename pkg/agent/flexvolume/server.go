@@ -42,6 +42,10 @@ const (
 	usrBinDir                = "/usr/local/bin/"
 )
 
+// CSIBlockDriver is the name of the Rook CSI driver. The kubelet maps raw block volumes only with CSI drivers, so the
+// block volumes the flexvolume driver cannot attach are served by the CSI driver.
+const CSIBlockDriver = "block.csi.rook.io"
+
 var flexVolumeDriverDir = fmt.Sprintf("/flexmnt/%s~%s", FlexvolumeVendor, FlexvolumeDriver)
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "rook-flexvolume")
 
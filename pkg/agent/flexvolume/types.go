@@ -29,6 +29,18 @@ type VolumeManager interface {
 	Init() error
 	Attach(image, pool, clusterName string) (string, error)
 	Detach(image, pool, clusterName string) error
+	Expand(image, pool, clusterName string) error
+	AttachFilesystem(fsName, path, clusterName string, quota int64) (string, error)
+	PurgeFilesystem(fsName, path, clusterName string) error
+	Fence(image, pool, clusterName string, nodeAddresses []string) ([]string, error)
+	Unfence(clusterName string, addresses []string) error
+	RenewFence(clusterName string, addresses []string) error
+}
+
+// FilesystemCredentials is the ceph user that can access the directory of a shared file system volume and its key
+type FilesystemCredentials struct {
+	User string `json:"user"`
+	Key  string `json:"key"`
 }
 
 type AttachOptions struct {
@@ -36,6 +48,16 @@ type AttachOptions struct {
 	Pool         string `json:"pool"`
 	ClusterName  string `json:"ClusterName"`
 	StorageClass string `json:"storageClass"`
+	Filesystem   string `json:"filesystem"`
+	Path         string `json:"path"`
+	Quota        int64  `json:"quota"`
+	MultiWriter  string `json:"multiWriter"`
+	ReadIOPS     string `json:"readIOPSLimit"`
+	WriteIOPS    string `json:"writeIOPSLimit"`
+	ReadBPS      string `json:"readBPSLimit"`
+	WriteBPS     string `json:"writeBPSLimit"`
+	MountOptions string `json:"mountOptions"`
+	MkfsOptions  string `json:"mkfsOptions"`
 	MountDir     string `json:"mountDir"`
 	RW           string `json:"kubernetes.io/readwrite"`
 	FsType       string `json:"kubernetes.io/fsType"`
@@ -102,6 +102,9 @@ func ServeRoutes(context *clusterd.Context, c *Config) {
 	}()
 	defer h.Shutdown()
 
+	// revoke the old keys of rotated users once their overlap has passed
+	go h.startKeyRevocation(keyRevocationInterval)
+
 	r := newRouter(h.GetRoutes())
 	if err := http.ListenAndServe(fmt.Sprintf(":%d", c.port), r); err != nil {
 		logger.Errorf("API server error: %+v", err)
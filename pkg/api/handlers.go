@@ -21,6 +21,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
 	ceph "github.com/rook/rook/pkg/ceph/client"
@@ -32,6 +33,8 @@ type Handler struct {
 	context      *clusterd.Context
 	config       *Config
 	cephExporter *CephExporter
+	// serializes the changes to the scheduled key revocations
+	keyRevocationLock sync.Mutex
 }
 
 func newHandler(context *clusterd.Context, config *Config) *Handler {
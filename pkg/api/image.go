@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/gorilla/mux"
 	ceph "github.com/rook/rook/pkg/ceph/client"
 	"github.com/rook/rook/pkg/model"
 )
@@ -132,3 +133,82 @@ func (h *Handler) DeleteImage(w http.ResponseWriter, r *http.Request) {
 
 	w.Write([]byte(fmt.Sprintf("succeeded deleting image %s", deleteImageReq.Name)))
 }
+
+// Grows an image to the size in the request body.
+// PUT
+// /image/{pool}/{name}
+func (h *Handler) ResizeImage(w http.ResponseWriter, r *http.Request) {
+	poolName, imageName := getImageVars(r)
+
+	var image model.BlockImage
+	body, ok := handleReadBody(w, r, "resize image")
+	if !ok {
+		return
+	}
+	if err := json.Unmarshal(body, &image); err != nil {
+		logger.Errorf("failed to unmarshal resize image request body '%s': %+v", string(body), err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if image.Size == 0 {
+		logger.Errorf("image missing required fields: %+v", image)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := ceph.ResizeImage(h.context, h.config.clusterInfo.Name, imageName, poolName, image.Size); err != nil {
+		logger.Errorf("failed to resize image %s in pool %s: %+v", imageName, poolName, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Write([]byte(fmt.Sprintf("succeeded resizing image %s", imageName)))
+}
+
+// Copies the data of the parent snapshot into a cloned image.
+// POST
+// /image/{pool}/{name}/flatten
+func (h *Handler) FlattenImage(w http.ResponseWriter, r *http.Request) {
+	poolName, imageName := getImageVars(r)
+
+	if err := ceph.FlattenImage(h.context, h.config.clusterInfo.Name, imageName, poolName); err != nil {
+		logger.Errorf("failed to flatten image %s in pool %s: %+v", imageName, poolName, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Write([]byte(fmt.Sprintf("succeeded flattening image %s", imageName)))
+}
+
+// Gets the mirroring status of each mirrored image in the pool.
+// GET
+// /image/{pool}/mirror
+func (h *Handler) GetImageMirrorStatus(w http.ResponseWriter, r *http.Request) {
+	poolName := mux.Vars(r)["pool"]
+	if poolName == "" {
+		logger.Errorf("missing pool for the image mirror status")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	status, err := ceph.GetPoolMirrorStatus(h.context, h.config.clusterInfo.Name, poolName)
+	if err != nil {
+		logger.Errorf("failed to get mirror status for pool %s: %+v", poolName, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]model.BlockImageMirrorStatus, len(status.Images))
+	for i, image := range status.Images {
+		result[i] = model.BlockImageMirrorStatus{
+			Name:        image.Name,
+			PoolName:    poolName,
+			GlobalID:    image.GlobalID,
+			State:       image.State,
+			Description: image.Description,
+			LastUpdate:  image.LastUpdate,
+		}
+	}
+
+	FormatJsonResponse(w, result)
+}
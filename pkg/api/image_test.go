@@ -253,3 +253,35 @@ func TestDeleteImageHandlerFailure(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	assert.Equal(t, ``, w.Body.String())
 }
+
+func TestGetImageMirrorStatusHandler(t *testing.T) {
+	context, executor := testContext()
+
+	req, err := http.NewRequest("GET", "http://10.0.0.100/image/mypool/mirror", nil)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "mirror" && args[1] == "pool" && args[2] == "status" && args[3] == "mypool":
+			return `{"summary":{"health":"OK","states":{"replaying":1}},"images":[{"name":"image1","global_id":"abc",
+				"state":"up+replaying","description":"replaying, master_position=[]","last_update":"2017-11-01 10:00:00"}]}`, nil
+		}
+		return "", fmt.Errorf("unexpected rbd command '%v'", args)
+	}
+
+	// the status of each mirrored image is returned
+	w := httptest.NewRecorder()
+	h := newTestHandler(context)
+	r := newRouter(h.GetRoutes())
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[{"imageName":"image1","poolName":"mypool","globalId":"abc","state":"up+replaying","description":"replaying, master_position=[]","lastUpdate":"2017-11-01 10:00:00"}]`, w.Body.String())
+
+	// the status fails for an unknown pool
+	req, _ = http.NewRequest("GET", "http://10.0.0.100/image/otherpool/mirror", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rook/rook/pkg/ceph/rgw"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// the config map where the old keys of rotated users are kept until they are revoked
+	keyRevocationConfigMap = "rook-api-key-revocations"
+	keyRevocationsKey      = "revocations"
+	keyRevocationInterval  = time.Minute
+)
+
+// keyRevocation is an old S3 key of a rotated user that is revoked once the overlap with the new key has passed
+type keyRevocation struct {
+	ObjectStore string    `json:"objectStore"`
+	UserID      string    `json:"userId"`
+	AccessKey   string    `json:"accessKey"`
+	RevokeAfter time.Time `json:"revokeAfter"`
+}
+
+// startKeyRevocation revokes the scheduled keys whose overlap has passed, periodically
+func (h *Handler) startKeyRevocation(interval time.Duration) {
+	for {
+		if err := h.revokeExpiredKeys(time.Now()); err != nil {
+			logger.Errorf("failed to revoke the old keys of rotated users. %+v", err)
+		}
+		<-time.After(interval)
+	}
+}
+
+// scheduleKeyRevocations saves the keys to revoke in a config map so they are revoked after a restart of the API server
+func (h *Handler) scheduleKeyRevocations(revocations []keyRevocation) error {
+	if len(revocations) == 0 {
+		return nil
+	}
+
+	h.keyRevocationLock.Lock()
+	defer h.keyRevocationLock.Unlock()
+
+	configMap, scheduled, err := h.getKeyRevocations()
+	if err != nil {
+		return err
+	}
+	return h.saveKeyRevocations(configMap, append(scheduled, revocations...))
+}
+
+// revokeExpiredKeys revokes the scheduled keys whose overlap has passed. The keys that fail to be revoked are retried
+// by the next sweep.
+func (h *Handler) revokeExpiredKeys(now time.Time) error {
+	h.keyRevocationLock.Lock()
+	defer h.keyRevocationLock.Unlock()
+
+	configMap, scheduled, err := h.getKeyRevocations()
+	if err != nil {
+		return err
+	}
+
+	pending := []keyRevocation{}
+	for _, r := range scheduled {
+		if now.Before(r.RevokeAfter) {
+			pending = append(pending, r)
+			continue
+		}
+		if err := h.revokeKeys([]keyRevocation{r}); err != nil {
+			logger.Errorf("%+v", err)
+			pending = append(pending, r)
+		}
+	}
+	if len(pending) == len(scheduled) {
+		return nil
+	}
+	return h.saveKeyRevocations(configMap, pending)
+}
+
+// revokeKeys removes the keys from their users. Keys that were already removed are skipped.
+func (h *Handler) revokeKeys(revocations []keyRevocation) error {
+	for _, r := range revocations {
+		objContext := h.storeContext(r.ObjectStore)
+		code, err := rgw.DeleteUserKey(objContext, r.UserID, r.AccessKey)
+		if err != nil && code != rgw.RGWErrorNotFound {
+			return fmt.Errorf("failed to revoke key %s of user %s. %+v", r.AccessKey, r.UserID, err)
+		}
+		logger.Infof("revoked key %s of user %s in object store %s", r.AccessKey, r.UserID, r.ObjectStore)
+	}
+	return nil
+}
+
+// getKeyRevocations returns the config map of the scheduled key revocations, or nil if it does not exist yet, and the
+// revocations it contains
+func (h *Handler) getKeyRevocations() (*v1.ConfigMap, []keyRevocation, error) {
+	configMap, err := h.context.Clientset.CoreV1().ConfigMaps(h.config.namespace).Get(keyRevocationConfigMap, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, []keyRevocation{}, nil
+		}
+		return nil, nil, fmt.Errorf("failed to get the key revocations. %+v", err)
+	}
+
+	revocations := []keyRevocation{}
+	if data, ok := configMap.Data[keyRevocationsKey]; ok {
+		if err := json.Unmarshal([]byte(data), &revocations); err != nil {
+			return nil, nil, fmt.Errorf("failed to read the key revocations. %+v", err)
+		}
+	}
+	return configMap, revocations, nil
+}
+
+// saveKeyRevocations creates or updates the config map of the scheduled key revocations
+func (h *Handler) saveKeyRevocations(configMap *v1.ConfigMap, revocations []keyRevocation) error {
+	data, err := json.Marshal(revocations)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the key revocations. %+v", err)
+	}
+
+	configMaps := h.context.Clientset.CoreV1().ConfigMaps(h.config.namespace)
+	if configMap == nil {
+		configMap = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: keyRevocationConfigMap, Namespace: h.config.namespace},
+			Data:       map[string]string{keyRevocationsKey: string(data)},
+		}
+		if _, err := configMaps.Create(configMap); err != nil {
+			return fmt.Errorf("failed to create the key revocations. %+v", err)
+		}
+		return nil
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[keyRevocationsKey] = string(data)
+	if _, err := configMaps.Update(configMap); err != nil {
+		return fmt.Errorf("failed to update the key revocations. %+v", err)
+	}
+	return nil
+}
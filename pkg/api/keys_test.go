@@ -0,0 +1,99 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	testexec "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRotateUserKeys(t *testing.T) {
+	userInfo := `{"user_id":"foo","keys":[{"user":"foo","access_key":"key1","secret_key":"secret1"}]}`
+	newKeyInfo := `{"user_id":"foo","keys":[{"user":"foo","access_key":"key1","secret_key":"secret1"},` +
+		`{"user":"foo","access_key":"key2","secret_key":"secret2"}]}`
+
+	revoked := []string{}
+	executor := &testexec.MockExecutor{MockExecuteCommandWithCombinedOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+		switch {
+		case args[0] == "key" && args[1] == "create":
+			return newKeyInfo, nil
+		case args[0] == "key" && args[1] == "rm":
+			revoked = append(revoked, args[6])
+		}
+		return userInfo, nil
+	}}
+	context := &clusterd.Context{Executor: executor}
+	h := newTestHandler(context)
+	r := newRouter(h.GetRoutes())
+
+	rotate := func(body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "http://10.0.0.100/objectstore/default/users/foo/keys/rotate", bytes.NewBufferString(body))
+		if err != nil {
+			logger.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// the new key is returned right away and the old key is scheduled to be revoked
+	w := rotate(`{"overlapSeconds":600}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var result model.ObjectUserKeyRotationResult
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "key2", result.Key.AccessKey)
+	assert.Equal(t, []string{"key1"}, result.OldAccessKeys)
+	assert.Empty(t, revoked)
+
+	configMap, err := context.Clientset.CoreV1().ConfigMaps("default").Get(keyRevocationConfigMap, metav1.GetOptions{})
+	assert.Nil(t, err)
+	var scheduled []keyRevocation
+	assert.Nil(t, json.Unmarshal([]byte(configMap.Data[keyRevocationsKey]), &scheduled))
+	assert.Equal(t, 1, len(scheduled))
+	assert.Equal(t, keyRevocation{ObjectStore: "default", UserID: "foo", AccessKey: "key1", RevokeAfter: result.RevokeAfter}, scheduled[0])
+
+	// the key is kept until the overlap has passed
+	err = h.revokeExpiredKeys(time.Now())
+	assert.Nil(t, err)
+	assert.Empty(t, revoked)
+
+	err = h.revokeExpiredKeys(time.Now().Add(11 * time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"--access-key=key1"}, revoked)
+	_, scheduled, err = h.getKeyRevocations()
+	assert.Nil(t, err)
+	assert.Empty(t, scheduled)
+
+	// the old key is revoked immediately without overlap
+	revoked = []string{}
+	w = rotate("")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, []string{"--access-key=key1"}, revoked)
+
+	// the overlap cannot be negative
+	w = rotate(`{"overlapSeconds":-1}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
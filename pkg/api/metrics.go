@@ -24,7 +24,9 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/rook/rook/pkg/ceph/collectors"
+	"github.com/rook/rook/pkg/ceph/rgw"
 	"github.com/rook/rook/pkg/clusterd"
+	k8srgw "github.com/rook/rook/pkg/operator/rgw"
 )
 
 // CephExporter wraps all the ceph collectors and provides a single global
@@ -79,5 +81,8 @@ func createCollectors(context *clusterd.Context, clusterName string) []prometheu
 		collectors.NewMonitorCollector(context, clusterName),
 		collectors.NewOSDCollector(context, clusterName),
 		collectors.NewPoolUsageCollector(context, clusterName),
+		collectors.NewRGWCollector(context, clusterName, func() ([]*rgw.Context, error) {
+			return k8srgw.GetObjectContexts(context, clusterName)
+		}),
 	}
 }
@@ -18,8 +18,11 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"sort"
+	"time"
 
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -41,8 +44,18 @@ func (h *Handler) objectContext(r *http.Request) *rgw.Context {
 	if name, ok := mux.Vars(r)["name"]; ok {
 		storeName = name
 	}
+	return h.storeContext(storeName)
+}
 
-	return rgw.NewContext(h.context, storeName, h.config.clusterInfo.Name)
+// storeContext gets the context of the admin commands of the object store from its service
+func (h *Handler) storeContext(storeName string) *rgw.Context {
+	objContext, err := k8srgw.GetObjectContext(h.context, h.config.clusterInfo.Name, storeName)
+	if err != nil {
+		// fall back to the realm named after the store, so the admin command reports the error
+		logger.Warningf("failed to get context of object store %s. %+v", storeName, err)
+		return rgw.NewContext(h.context, storeName, h.config.clusterInfo.Name)
+	}
+	return objContext
 }
 
 // GetObjectStores gets the object stores in this cluster.
@@ -51,23 +64,22 @@ func (h *Handler) objectContext(r *http.Request) *rgw.Context {
 func (h *Handler) GetObjectStores(w http.ResponseWriter, r *http.Request) {
 	stores := []model.ObjectStoreResponse{}
 
-	// require both the realm and k8s service to exist to consider an object store available
-	realms, err := rgw.GetObjectStores(rgw.NewContext(h.config.context, "", h.config.clusterInfo.Name))
+	// the object stores are found by their services, since the realm of a secondary zone is not named after the store
+	objContexts, err := k8srgw.GetObjectContexts(h.config.context, h.config.clusterInfo.Name)
 	if err != nil {
-		logger.Errorf("failed to get rgw realms. %+v", err)
+		logger.Errorf("failed to get object stores. %+v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	// get the rgw service for each realm
-	for _, realm := range realms {
-		service, err := h.config.context.Clientset.CoreV1().Services(h.config.clusterInfo.Name).Get(k8srgw.InstanceName(realm), metav1.GetOptions{})
+	for _, objContext := range objContexts {
+		service, err := h.config.context.Clientset.CoreV1().Services(h.config.clusterInfo.Name).Get(k8srgw.InstanceName(objContext.Name), metav1.GetOptions{})
 		if err != nil {
-			logger.Warningf("RGW realm found, but no k8s service found for %s", realm)
+			logger.Warningf("failed to get service of object store %s. %+v", objContext.Name, err)
 			continue
 		}
 		stores = append(stores, model.ObjectStoreResponse{
-			Name:        realm,
+			Name:        objContext.Name,
 			Ports:       service.Spec.Ports,
 			ClusterIP:   service.Spec.ClusterIP,
 			ExternalIPs: service.Spec.ExternalIPs,
@@ -139,9 +151,8 @@ func (h *Handler) RemoveObjectStore(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetObjectStoreConnectionInfo(w http.ResponseWriter, r *http.Request) {
 	storeName := mux.Vars(r)["name"]
 
-	n := k8srgw.InstanceName(storeName)
-	logger.Infof("Getting the object store connection info for %s", n)
-	service, err := h.config.context.Clientset.CoreV1().Services(h.config.namespace).Get(n, metav1.GetOptions{})
+	logger.Infof("Getting the object store connection info for %s", k8srgw.InstanceName(storeName))
+	s3Info, err := k8srgw.GetConnectionInfo(h.config.context.Clientset, h.config.namespace, storeName)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			w.WriteHeader(http.StatusNotFound)
@@ -152,17 +163,6 @@ func (h *Handler) GetObjectStoreConnectionInfo(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	s3Info := &model.ObjectStoreConnectInfo{
-		Host:      k8srgw.InstanceName(storeName),
-		IPAddress: service.Spec.ClusterIP,
-		Ports:     []int32{},
-	}
-
-	// append all of the ports
-	for _, port := range service.Spec.Ports {
-		s3Info.Ports = append(s3Info.Ports, port.Port)
-	}
-
 	FormatJsonResponse(w, s3Info)
 }
 
@@ -230,7 +230,7 @@ func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		logger.Errorf("Error creating user: %+v", err)
 
-		if rgwError == rgw.RGWErrorBadData {
+		if rgwError == rgw.RGWErrorBadData || rgwError == rgw.RGWErrorExists {
 			w.WriteHeader(http.StatusUnprocessableEntity)
 			w.Write([]byte(err.Error()))
 		} else {
@@ -296,6 +296,196 @@ func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// ListUserKeys lists the S3 keys of the user and the swift keys of its subusers.
+// GET
+// /objectstore/{name}/users/{id}/keys
+func (h *Handler) ListUserKeys(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	keys, rgwError, err := rgw.ListUserKeys(h.objectContext(r), id)
+	if err != nil {
+		logger.Errorf("Error listing keys of user (%s): %+v", id, err)
+
+		if rgwError == rgw.RGWErrorNotFound {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	FormatJsonResponse(w, keys)
+}
+
+// CreateUserKey adds an S3 key to the user or replaces the swift key of a subuser. The keys are generated unless
+// they are passed.
+// POST
+// /objectstore/{name}/users/{id}/keys
+func (h *Handler) CreateUserKey(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	// the body is optional
+	var key model.ObjectUserKey
+	if err := json.NewDecoder(r.Body).Decode(&key); err != nil && err != io.EOF {
+		logger.Errorf("Error parsing key: %+v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	createdKey, rgwError, err := rgw.CreateUserKey(h.objectContext(r), id, key)
+	if err != nil {
+		logger.Errorf("Error creating key of user (%s): %+v", id, err)
+
+		switch rgwError {
+		case rgw.RGWErrorNotFound:
+			w.WriteHeader(http.StatusNotFound)
+		case rgw.RGWErrorBadData:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(err.Error()))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusCreated)
+	FormatJsonResponse(w, *createdKey)
+}
+
+// RotateUserKeys adds a new S3 key to the user and revokes the old S3 keys of the user once the overlap has passed.
+// The new key is returned right away and the old keys are revoked in the background.
+// POST
+// /objectstore/{name}/users/{id}/keys/rotate
+func (h *Handler) RotateUserKeys(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	// the body is optional
+	var rotation model.ObjectUserKeyRotation
+	if err := json.NewDecoder(r.Body).Decode(&rotation); err != nil && err != io.EOF {
+		logger.Errorf("Error parsing key rotation: %+v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if rotation.OverlapSeconds < 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte("the overlap cannot be negative"))
+		return
+	}
+
+	objContext := h.objectContext(r)
+	key, oldKeys, rgwError, err := rgw.RotateUserKeys(objContext, id)
+	if err != nil {
+		logger.Errorf("Error rotating keys of user (%s): %+v", id, err)
+
+		if rgwError == rgw.RGWErrorNotFound {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	result := model.ObjectUserKeyRotationResult{
+		Key:           *key,
+		OldAccessKeys: oldKeys,
+		RevokeAfter:   time.Now().Add(time.Duration(rotation.OverlapSeconds) * time.Second).UTC(),
+	}
+	revocations := []keyRevocation{}
+	for _, accessKey := range oldKeys {
+		revocations = append(revocations, keyRevocation{ObjectStore: objContext.Name, UserID: id, AccessKey: accessKey, RevokeAfter: result.RevokeAfter})
+	}
+	if rotation.OverlapSeconds == 0 {
+		err = h.revokeKeys(revocations)
+	} else {
+		err = h.scheduleKeyRevocations(revocations)
+	}
+	if err != nil {
+		logger.Errorf("Error revoking the old keys of user (%s): %+v", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusCreated)
+	FormatJsonResponse(w, result)
+}
+
+// DeleteUserKey removes the S3 key with the access key, or the swift key of the subuser, from the user.
+// DELETE
+// /objectstore/{name}/users/{id}/keys/{accessKey}
+func (h *Handler) DeleteUserKey(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	accessKey := mux.Vars(r)["accessKey"]
+
+	rgwError, err := rgw.DeleteUserKey(h.objectContext(r), id, accessKey)
+	if err != nil {
+		if rgwError == rgw.RGWErrorNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		logger.Errorf("Error deleting key of user (%s): %+v", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateSubuser creates a swift subuser of the user and returns its generated swift key.
+// POST
+// /objectstore/{name}/users/{id}/subusers
+func (h *Handler) CreateSubuser(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var subuser model.ObjectSubuser
+	if err := json.NewDecoder(r.Body).Decode(&subuser); err != nil {
+		logger.Errorf("Error parsing subuser: %+v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	key, rgwError, err := rgw.CreateSubuser(h.objectContext(r), id, subuser)
+	if err != nil {
+		logger.Errorf("Error creating subuser of user (%s): %+v", id, err)
+
+		if rgwError == rgw.RGWErrorBadData {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(err.Error()))
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusCreated)
+	FormatJsonResponse(w, *key)
+}
+
+// DeleteSubuser removes the subuser and its keys from the user.
+// DELETE
+// /objectstore/{name}/users/{id}/subusers/{subuser}
+func (h *Handler) DeleteSubuser(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	subuser := mux.Vars(r)["subuser"]
+
+	rgwError, err := rgw.DeleteSubuser(h.objectContext(r), id, subuser)
+	if err != nil {
+		if rgwError == rgw.RGWErrorNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		logger.Errorf("Error deleting subuser %s of user (%s): %+v", subuser, id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // Listbuckets lists the buckets in the object store in this cluster.
 // GET
 // /objectstore/{name}/buckets
@@ -358,6 +548,271 @@ func (h *Handler) DeleteBucket(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// SetBucketQuota sets and enables the quota of the bucket.
+// PUT
+// /objectstore/{name}/buckets/{bucketName}/quota
+func (h *Handler) SetBucketQuota(w http.ResponseWriter, r *http.Request) {
+	bucketName := mux.Vars(r)["bucketName"]
+
+	var quota model.ObjectBucketQuota
+	if err := json.NewDecoder(r.Body).Decode(&quota); err != nil {
+		logger.Errorf("Error parsing bucket quota: %+v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	rgwError, err := rgw.SetBucketQuota(h.objectContext(r), bucketName, &rgw.ObjectQuota{MaxSize: quota.MaxSize, MaxObjects: quota.MaxObjects})
+	if err != nil {
+		writeRGWError(w, rgwError, fmt.Errorf("Error setting quota of bucket %s: %+v", bucketName, err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteBucketQuota disables the quota of the bucket.
+// DELETE
+// /objectstore/{name}/buckets/{bucketName}/quota
+func (h *Handler) DeleteBucketQuota(w http.ResponseWriter, r *http.Request) {
+	bucketName := mux.Vars(r)["bucketName"]
+
+	rgwError, err := rgw.SetBucketQuota(h.objectContext(r), bucketName, nil)
+	if err != nil {
+		writeRGWError(w, rgwError, fmt.Errorf("Error disabling quota of bucket %s: %+v", bucketName, err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetBucketOwner transfers the bucket to another user.
+// PUT
+// /objectstore/{name}/buckets/{bucketName}/owner
+func (h *Handler) SetBucketOwner(w http.ResponseWriter, r *http.Request) {
+	bucketName := mux.Vars(r)["bucketName"]
+
+	var owner model.ObjectBucketOwner
+	if err := json.NewDecoder(r.Body).Decode(&owner); err != nil || owner.Owner == "" {
+		logger.Errorf("Error parsing bucket owner: %+v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	rgwError, err := rgw.LinkBucket(h.objectContext(r), bucketName, owner.Owner)
+	if err != nil {
+		writeRGWError(w, rgwError, fmt.Errorf("Error linking bucket %s to %s: %+v", bucketName, owner.Owner, err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReshardBucket reshards the index of the bucket.
+// POST
+// /objectstore/{name}/buckets/{bucketName}/reshard
+func (h *Handler) ReshardBucket(w http.ResponseWriter, r *http.Request) {
+	bucketName := mux.Vars(r)["bucketName"]
+
+	var reshard model.ObjectBucketReshard
+	if err := json.NewDecoder(r.Body).Decode(&reshard); err != nil {
+		logger.Errorf("Error parsing bucket reshard: %+v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	rgwError, err := rgw.ReshardBucket(h.objectContext(r), bucketName, reshard.NumShards)
+	if err != nil {
+		writeRGWError(w, rgwError, fmt.Errorf("Error resharding bucket %s: %+v", bucketName, err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CheckBucketIndex checks the index of the bucket, and repairs the index if fix=true.
+// POST
+// /objectstore/{name}/buckets/{bucketName}/check
+func (h *Handler) CheckBucketIndex(w http.ResponseWriter, r *http.Request) {
+	bucketName := mux.Vars(r)["bucketName"]
+
+	fixParams, found := r.URL.Query()["fix"]
+	fix := found && len(fixParams) == 1 && fixParams[0] == "true"
+
+	result, rgwError, err := rgw.CheckBucketIndex(h.objectContext(r), bucketName, fix)
+	if err != nil {
+		writeRGWError(w, rgwError, fmt.Errorf("Error checking index of bucket %s: %+v", bucketName, err))
+		return
+	}
+
+	FormatJsonResponse(w, model.ObjectBucketIndexCheck{Fixed: fix, Result: result})
+}
+
+// GetBucketPolicy gets the policy document of the bucket.
+// GET
+// /objectstore/{name}/buckets/{bucketName}/policy
+func (h *Handler) GetBucketPolicy(w http.ResponseWriter, r *http.Request) {
+	bucketName := mux.Vars(r)["bucketName"]
+
+	agent, err := h.s3Agent(r)
+	if err != nil {
+		writeRGWError(w, rgw.RGWErrorUnknown, err)
+		return
+	}
+	policy, rgwError, err := agent.GetBucketPolicy(bucketName)
+	if err != nil {
+		writeRGWError(w, rgwError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Write([]byte(policy))
+}
+
+// SetBucketPolicy replaces the policy of the bucket with the policy document in the body.
+// PUT
+// /objectstore/{name}/buckets/{bucketName}/policy
+func (h *Handler) SetBucketPolicy(w http.ResponseWriter, r *http.Request) {
+	bucketName := mux.Vars(r)["bucketName"]
+
+	policy, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		logger.Errorf("Error reading bucket policy: %+v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	agent, err := h.s3Agent(r)
+	if err != nil {
+		writeRGWError(w, rgw.RGWErrorUnknown, err)
+		return
+	}
+	if rgwError, err := agent.SetBucketPolicy(bucketName, string(policy)); err != nil {
+		writeRGWError(w, rgwError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteBucketPolicy removes the policy of the bucket.
+// DELETE
+// /objectstore/{name}/buckets/{bucketName}/policy
+func (h *Handler) DeleteBucketPolicy(w http.ResponseWriter, r *http.Request) {
+	bucketName := mux.Vars(r)["bucketName"]
+
+	agent, err := h.s3Agent(r)
+	if err != nil {
+		writeRGWError(w, rgw.RGWErrorUnknown, err)
+		return
+	}
+	if rgwError, err := agent.DeleteBucketPolicy(bucketName); err != nil {
+		writeRGWError(w, rgwError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetBucketLifecycle gets the lifecycle rules of the bucket.
+// GET
+// /objectstore/{name}/buckets/{bucketName}/lifecycle
+func (h *Handler) GetBucketLifecycle(w http.ResponseWriter, r *http.Request) {
+	bucketName := mux.Vars(r)["bucketName"]
+
+	agent, err := h.s3Agent(r)
+	if err != nil {
+		writeRGWError(w, rgw.RGWErrorUnknown, err)
+		return
+	}
+	rules, rgwError, err := agent.GetBucketLifecycle(bucketName)
+	if err != nil {
+		writeRGWError(w, rgwError, err)
+		return
+	}
+
+	FormatJsonResponse(w, rules)
+}
+
+// SetBucketLifecycle replaces the lifecycle rules of the bucket.
+// PUT
+// /objectstore/{name}/buckets/{bucketName}/lifecycle
+func (h *Handler) SetBucketLifecycle(w http.ResponseWriter, r *http.Request) {
+	bucketName := mux.Vars(r)["bucketName"]
+
+	var rules []model.ObjectBucketLifecycleRule
+	if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+		logger.Errorf("Error parsing lifecycle rules: %+v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	agent, err := h.s3Agent(r)
+	if err != nil {
+		writeRGWError(w, rgw.RGWErrorUnknown, err)
+		return
+	}
+	if rgwError, err := agent.SetBucketLifecycle(bucketName, rules); err != nil {
+		writeRGWError(w, rgwError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteBucketLifecycle removes the lifecycle rules of the bucket.
+// DELETE
+// /objectstore/{name}/buckets/{bucketName}/lifecycle
+func (h *Handler) DeleteBucketLifecycle(w http.ResponseWriter, r *http.Request) {
+	bucketName := mux.Vars(r)["bucketName"]
+
+	agent, err := h.s3Agent(r)
+	if err != nil {
+		writeRGWError(w, rgw.RGWErrorUnknown, err)
+		return
+	}
+	if rgwError, err := agent.DeleteBucketLifecycle(bucketName); err != nil {
+		writeRGWError(w, rgwError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// s3Agent creates an agent for the S3 API of the object store with the keys of the admin user, which the operator
+// saves in a secret when it creates the object store
+func (h *Handler) s3Agent(r *http.Request) (*rgw.S3Agent, error) {
+	storeName := mux.Vars(r)["name"]
+	host, port, secure, err := k8srgw.GetServiceEndpoint(h.context.Clientset, h.config.clusterInfo.Name, storeName)
+	if err != nil {
+		return nil, err
+	}
+	accessKey, secretKey, err := k8srgw.GetAdminUserKeys(h.context.Clientset, h.config.clusterInfo.Name, storeName)
+	if err != nil {
+		return nil, err
+	}
+	var caCert []byte
+	if secure {
+		if caCert, err = k8srgw.GetServiceCACert(h.context.Clientset, h.config.clusterInfo.Name, storeName); err != nil {
+			return nil, err
+		}
+	}
+	return rgw.NewS3Agent(rgw.S3Endpoint(host, port, secure), accessKey, secretKey, caCert)
+}
+
+// writeRGWError writes the status code of the rgw error. The error of invalid data is returned in the body.
+func writeRGWError(w http.ResponseWriter, rgwError int, err error) {
+	switch rgwError {
+	case rgw.RGWErrorNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case rgw.RGWErrorBadData:
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(err.Error()))
+	default:
+		logger.Errorf("%+v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
 func enableObjectStore(c *Config, config model.ObjectStore) error {
 	logger.Infof("Starting the Object store")
 
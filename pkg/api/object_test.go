@@ -40,6 +40,7 @@ import (
 
 func TestCreateObjectStoreHandler(t *testing.T) {
 	createdZone := false
+	createdAdmin := false
 	poolInit := true
 	executor := &testexec.MockExecutor{
 		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
@@ -77,6 +78,10 @@ func TestCreateObjectStoreHandler(t *testing.T) {
 			if args[0] == "period" && args[1] == "update" {
 				return "", nil
 			}
+			if args[0] == "user" && args[1] == "create" {
+				createdAdmin = true
+				return `{"user_id":"` + args[3] + `","keys":[{"access_key":"adminkey","secret_key":"adminsecret"}]}`, nil
+			}
 
 			return "", fmt.Errorf("unexpected combined output command '%s'", args[0])
 		},
@@ -90,6 +95,7 @@ func TestCreateObjectStoreHandler(t *testing.T) {
 	h.CreateObjectStore(w, req)
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.True(t, createdZone)
+	assert.True(t, createdAdmin)
 	assert.True(t, poolInit)
 
 	// Invalid parameters return a 400
@@ -485,6 +491,89 @@ func TestDeleteUser(t *testing.T) {
 	assert.Equal(t, "", w.Body.String())
 }
 
+func TestUserKeys(t *testing.T) {
+	userInfo := `{"user_id":"foo","keys":[{"user":"foo","access_key":"key1","secret_key":"secret1"}],` +
+		`"swift_keys":[{"user":"foo:swift","secret_key":"swiftsecret"}]}`
+	newKeyInfo := `{"user_id":"foo","keys":[{"user":"foo","access_key":"key1","secret_key":"secret1"},` +
+		`{"user":"foo","access_key":"key2","secret_key":"secret2"}]}`
+
+	runTest := func(method, url, body string, runner func(args ...string) (string, error)) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+		if err != nil {
+			logger.Fatal(err)
+		}
+		executor := &testexec.MockExecutor{MockExecuteCommandWithCombinedOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			return runner(args...)
+		}}
+		context := &clusterd.Context{Executor: executor}
+		w := httptest.NewRecorder()
+		h := newTestHandler(context)
+		r := newRouter(h.GetRoutes())
+
+		r.ServeHTTP(w, req)
+
+		return w
+	}
+	getUserInfo := func(args ...string) (string, error) {
+		if args[0] == "key" && args[1] == "create" {
+			return newKeyInfo, nil
+		}
+		return userInfo, nil
+	}
+
+	// List the keys
+	w := runTest("GET", "http://10.0.0.100/objectstore/default/users/foo/keys", "", getUserInfo)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[{"user":"foo","type":"s3","accessKey":"key1","secretKey":"secret1"},`+
+		`{"user":"foo:swift","type":"swift","accessKey":"","secretKey":"swiftsecret"}]`, w.Body.String())
+
+	// User not found
+	w = runTest("GET", "http://10.0.0.100/objectstore/default/users/foo/keys", "", func(args ...string) (string, error) {
+		return "could not fetch user info: no user info saved", nil
+	})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Create a key without a body
+	w = runTest("POST", "http://10.0.0.100/objectstore/default/users/foo/keys", "", getUserInfo)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, `{"user":"foo","type":"s3","accessKey":"key2","secretKey":"secret2"}`, w.Body.String())
+
+	// Invalid key type
+	w = runTest("POST", "http://10.0.0.100/objectstore/default/users/foo/keys", `{"type":"ftp"}`, getUserInfo)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "invalid key type ftp", w.Body.String())
+
+	// Delete a key
+	w = runTest("DELETE", "http://10.0.0.100/objectstore/default/users/foo/keys/key1", "", func(args ...string) (string, error) {
+		if args[0] == "key" {
+			checkArgs(t, args, []string{"key", "rm", "--uid", "foo", "--key-type", "s3", "--access-key=key1"})
+		}
+		return userInfo, nil
+	})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// Key not found
+	w = runTest("DELETE", "http://10.0.0.100/objectstore/default/users/foo/keys/key3", "", getUserInfo)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Create a subuser
+	w = runTest("POST", "http://10.0.0.100/objectstore/default/users/foo/subusers", `{"name":"swift","access":"read"}`,
+		func(args ...string) (string, error) {
+			checkArgs(t, args, []string{"subuser", "create", "--uid", "foo", "--subuser", "foo:swift", "--access", "read"})
+			return userInfo, nil
+		})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, `{"user":"foo:swift","type":"swift","accessKey":"","secretKey":"swiftsecret"}`, w.Body.String())
+
+	// Invalid access of the subuser
+	w = runTest("POST", "http://10.0.0.100/objectstore/default/users/foo/subusers", `{"name":"swift","access":"all"}`, getUserInfo)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// Subuser not found
+	w = runTest("DELETE", "http://10.0.0.100/objectstore/default/users/foo/subusers/swift", "", getUserInfo)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
 func TestListBuckets(t *testing.T) {
 	req, err := http.NewRequest("GET", "http://10.0.0.100/objectstore/default/buckets", nil)
 	if err != nil {
@@ -817,3 +906,85 @@ func getExectedConfigArg(configSubDir string) string {
 func getExpectedKeyringArg(configSubDir string) string {
 	return fmt.Sprintf("--keyring=%s/client.admin.keyring", configSubDir)
 }
+
+func TestBucketAdmin(t *testing.T) {
+	runTest := func(method, url, body string, runner func(args ...string) (string, error)) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+		if err != nil {
+			logger.Fatal(err)
+		}
+		executor := &testexec.MockExecutor{MockExecuteCommandWithCombinedOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			return runner(args...)
+		}}
+		context := &clusterd.Context{Executor: executor}
+		w := httptest.NewRecorder()
+		h := newTestHandler(context)
+		r := newRouter(h.GetRoutes())
+
+		r.ServeHTTP(w, req)
+
+		return w
+	}
+	bucketStats := `{"bucket":"photos","id":"a1b2.4567.1","owner":"alice","usage":{}}`
+	expectArgs := func(expectedArgs ...string) func(args ...string) (string, error) {
+		return func(args ...string) (string, error) {
+			switch {
+			case args[0] == "bucket" && args[1] == "stats":
+				return bucketStats, nil
+			case args[0] == "user" && args[1] == "info":
+				return `{"user_id":"bob"}`, nil
+			case args[0] == expectedArgs[0] && args[1] == expectedArgs[1]:
+				checkArgs(t, args, expectedArgs)
+			}
+			return "", nil
+		}
+	}
+
+	// Set the quota
+	w := runTest("PUT", "http://10.0.0.100/objectstore/default/buckets/photos/quota", `{"maxObjects":1000}`,
+		expectArgs("quota", "set", "--quota-scope", "bucket", "--bucket", "photos", "--max-size", "-1", "--max-objects", "1000"))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// Invalid quota
+	w = runTest("PUT", "http://10.0.0.100/objectstore/default/buckets/photos/quota", `{"maxSize":-5}`, expectArgs("quota", "set"))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "quotas cannot be negative", w.Body.String())
+
+	// Disable the quota
+	w = runTest("DELETE", "http://10.0.0.100/objectstore/default/buckets/photos/quota", "",
+		expectArgs("quota", "disable", "--quota-scope", "bucket", "--bucket", "photos"))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// Transfer the bucket
+	w = runTest("PUT", "http://10.0.0.100/objectstore/default/buckets/photos/owner", `{"owner":"bob"}`,
+		expectArgs("bucket", "link", "--bucket", "photos", "--bucket-id", "a1b2.4567.1", "--uid", "bob"))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// Owner is required
+	w = runTest("PUT", "http://10.0.0.100/objectstore/default/buckets/photos/owner", `{}`, expectArgs("bucket", "link"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Reshard the index
+	w = runTest("POST", "http://10.0.0.100/objectstore/default/buckets/photos/reshard", `{"numShards":32}`,
+		expectArgs("bucket", "reshard", "--bucket", "photos", "--num-shards", "32"))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// Repair the index
+	w = runTest("POST", "http://10.0.0.100/objectstore/default/buckets/photos/check?fix=true", "",
+		func(args ...string) (string, error) {
+			if args[0] == "bucket" && args[1] == "check" {
+				checkArgs(t, args, []string{"bucket", "check", "--bucket", "photos", "--check-objects", "--fix"})
+				return "{}", nil
+			}
+			return bucketStats, nil
+		})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"fixed":true,"result":"{}"}`, w.Body.String())
+
+	// Bucket not found
+	w = runTest("POST", "http://10.0.0.100/objectstore/default/buckets/photos/reshard", `{"numShards":32}`,
+		func(args ...string) (string, error) {
+			return "could not get bucket info for bucket=photos", nil
+		})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
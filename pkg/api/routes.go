@@ -71,6 +71,66 @@ func (h *Handler) GetRoutes() []Route {
 			"/image",
 			h.DeleteImage,
 		},
+		{
+			"GetImageMirrorStatus",
+			"GET",
+			"/image/{pool}/mirror",
+			h.GetImageMirrorStatus,
+		},
+		{
+			"ResizeImage",
+			"PUT",
+			"/image/{pool}/{name}",
+			h.ResizeImage,
+		},
+		{
+			"FlattenImage",
+			"POST",
+			"/image/{pool}/{name}/flatten",
+			h.FlattenImage,
+		},
+		{
+			"GetImageSnapshots",
+			"GET",
+			"/image/{pool}/{name}/snapshots",
+			h.GetImageSnapshots,
+		},
+		{
+			"CreateImageSnapshot",
+			"POST",
+			"/image/{pool}/{name}/snapshots",
+			h.CreateImageSnapshot,
+		},
+		{
+			"DeleteImageSnapshot",
+			"DELETE",
+			"/image/{pool}/{name}/snapshots/{snapshot}",
+			h.DeleteImageSnapshot,
+		},
+		{
+			"RollbackImageSnapshot",
+			"POST",
+			"/image/{pool}/{name}/snapshots/{snapshot}/rollback",
+			h.RollbackImageSnapshot,
+		},
+		{
+			"ProtectImageSnapshot",
+			"POST",
+			"/image/{pool}/{name}/snapshots/{snapshot}/protect",
+			h.ProtectImageSnapshot,
+		},
+		{
+			"UnprotectImageSnapshot",
+			"POST",
+			"/image/{pool}/{name}/snapshots/{snapshot}/unprotect",
+			h.UnprotectImageSnapshot,
+		},
+		{
+			"CloneImageSnapshot",
+			"POST",
+			"/image/{pool}/{name}/snapshots/{snapshot}/clone",
+			h.CloneImageSnapshot,
+		},
 		{
 			"GetClientAccessInfo",
 			"GET",
@@ -131,6 +191,42 @@ func (h *Handler) GetRoutes() []Route {
 			"/objectstore/{name}/users/{id}",
 			h.DeleteUser,
 		},
+		{
+			"ListUserKeys",
+			"GET",
+			"/objectstore/{name}/users/{id}/keys",
+			h.ListUserKeys,
+		},
+		{
+			"CreateUserKey",
+			"POST",
+			"/objectstore/{name}/users/{id}/keys",
+			h.CreateUserKey,
+		},
+		{
+			"RotateUserKeys",
+			"POST",
+			"/objectstore/{name}/users/{id}/keys/rotate",
+			h.RotateUserKeys,
+		},
+		{
+			"DeleteUserKey",
+			"DELETE",
+			"/objectstore/{name}/users/{id}/keys/{accessKey}",
+			h.DeleteUserKey,
+		},
+		{
+			"CreateSubuser",
+			"POST",
+			"/objectstore/{name}/users/{id}/subusers",
+			h.CreateSubuser,
+		},
+		{
+			"DeleteSubuser",
+			"DELETE",
+			"/objectstore/{name}/users/{id}/subusers/{subuser}",
+			h.DeleteSubuser,
+		},
 		{
 			"ListBuckets",
 			"GET",
@@ -149,6 +245,72 @@ func (h *Handler) GetRoutes() []Route {
 			"/objectstore/{name}/buckets/{bucketName}",
 			h.DeleteBucket,
 		},
+		{
+			"SetBucketQuota",
+			"PUT",
+			"/objectstore/{name}/buckets/{bucketName}/quota",
+			h.SetBucketQuota,
+		},
+		{
+			"DeleteBucketQuota",
+			"DELETE",
+			"/objectstore/{name}/buckets/{bucketName}/quota",
+			h.DeleteBucketQuota,
+		},
+		{
+			"SetBucketOwner",
+			"PUT",
+			"/objectstore/{name}/buckets/{bucketName}/owner",
+			h.SetBucketOwner,
+		},
+		{
+			"ReshardBucket",
+			"POST",
+			"/objectstore/{name}/buckets/{bucketName}/reshard",
+			h.ReshardBucket,
+		},
+		{
+			"CheckBucketIndex",
+			"POST",
+			"/objectstore/{name}/buckets/{bucketName}/check",
+			h.CheckBucketIndex,
+		},
+		{
+			"GetBucketPolicy",
+			"GET",
+			"/objectstore/{name}/buckets/{bucketName}/policy",
+			h.GetBucketPolicy,
+		},
+		{
+			"SetBucketPolicy",
+			"PUT",
+			"/objectstore/{name}/buckets/{bucketName}/policy",
+			h.SetBucketPolicy,
+		},
+		{
+			"DeleteBucketPolicy",
+			"DELETE",
+			"/objectstore/{name}/buckets/{bucketName}/policy",
+			h.DeleteBucketPolicy,
+		},
+		{
+			"GetBucketLifecycle",
+			"GET",
+			"/objectstore/{name}/buckets/{bucketName}/lifecycle",
+			h.GetBucketLifecycle,
+		},
+		{
+			"SetBucketLifecycle",
+			"PUT",
+			"/objectstore/{name}/buckets/{bucketName}/lifecycle",
+			h.SetBucketLifecycle,
+		},
+		{
+			"DeleteBucketLifecycle",
+			"DELETE",
+			"/objectstore/{name}/buckets/{bucketName}/lifecycle",
+			h.DeleteBucketLifecycle,
+		},
 		{
 			"GetFileSystems",
 			"GET",
@@ -0,0 +1,170 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	ceph "github.com/rook/rook/pkg/ceph/client"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+)
+
+// Gets the snapshots of an image.
+// GET
+// /image/{pool}/{name}/snapshots
+func (h *Handler) GetImageSnapshots(w http.ResponseWriter, r *http.Request) {
+	poolName, imageName := getImageVars(r)
+
+	cephSnapshots, err := ceph.ListSnapshots(h.context, h.config.clusterInfo.Name, imageName, poolName)
+	if err != nil {
+		logger.Errorf("failed to list snapshots of image %s in pool %s: %+v", imageName, poolName, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	snapshots := make([]model.BlockSnapshot, len(cephSnapshots))
+	for i, snap := range cephSnapshots {
+		snapshots[i] = model.BlockSnapshot{
+			Name:      snap.Name,
+			ImageName: imageName,
+			PoolName:  poolName,
+			ID:        snap.ID,
+			Size:      snap.Size,
+		}
+	}
+
+	FormatJsonResponse(w, snapshots)
+}
+
+// Creates a snapshot of an image.
+// POST
+// /image/{pool}/{name}/snapshots
+func (h *Handler) CreateImageSnapshot(w http.ResponseWriter, r *http.Request) {
+	poolName, imageName := getImageVars(r)
+
+	var snapshot model.BlockSnapshot
+	body, ok := handleReadBody(w, r, "create snapshot")
+	if !ok {
+		return
+	}
+	if err := json.Unmarshal(body, &snapshot); err != nil {
+		logger.Errorf("failed to unmarshal create snapshot request body '%s': %+v", string(body), err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if snapshot.Name == "" {
+		logger.Errorf("snapshot missing required fields: %+v", snapshot)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := ceph.CreateSnapshot(h.context, h.config.clusterInfo.Name, imageName, poolName, snapshot.Name); err != nil {
+		logger.Errorf("failed to create snapshot %s of image %s: %+v", snapshot.Name, imageName, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Write([]byte(fmt.Sprintf("succeeded creating snapshot %s", snapshot.Name)))
+}
+
+// Deletes a snapshot of an image.
+// DELETE
+// /image/{pool}/{name}/snapshots/{snapshot}
+func (h *Handler) DeleteImageSnapshot(w http.ResponseWriter, r *http.Request) {
+	h.handleSnapshotAction(w, r, "deleting", ceph.DeleteSnapshot)
+}
+
+// Reverts an image to the content of a snapshot.
+// POST
+// /image/{pool}/{name}/snapshots/{snapshot}/rollback
+func (h *Handler) RollbackImageSnapshot(w http.ResponseWriter, r *http.Request) {
+	h.handleSnapshotAction(w, r, "rolling back to", ceph.RollbackSnapshot)
+}
+
+// Protects a snapshot from deletion so it can be cloned.
+// POST
+// /image/{pool}/{name}/snapshots/{snapshot}/protect
+func (h *Handler) ProtectImageSnapshot(w http.ResponseWriter, r *http.Request) {
+	h.handleSnapshotAction(w, r, "protecting", ceph.ProtectSnapshot)
+}
+
+// Removes the protection of a snapshot.
+// POST
+// /image/{pool}/{name}/snapshots/{snapshot}/unprotect
+func (h *Handler) UnprotectImageSnapshot(w http.ResponseWriter, r *http.Request) {
+	h.handleSnapshotAction(w, r, "unprotecting", ceph.UnprotectSnapshot)
+}
+
+// Clones a protected snapshot to a new image. The request body is the image to create.
+// POST
+// /image/{pool}/{name}/snapshots/{snapshot}/clone
+func (h *Handler) CloneImageSnapshot(w http.ResponseWriter, r *http.Request) {
+	poolName, imageName := getImageVars(r)
+	snapName := mux.Vars(r)["snapshot"]
+
+	var clone model.BlockImage
+	body, ok := handleReadBody(w, r, "clone snapshot")
+	if !ok {
+		return
+	}
+	if err := json.Unmarshal(body, &clone); err != nil {
+		logger.Errorf("failed to unmarshal clone snapshot request body '%s': %+v", string(body), err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if clone.Name == "" {
+		logger.Errorf("clone missing required fields: %+v", clone)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if clone.PoolName == "" {
+		// clone into the same pool by default
+		clone.PoolName = poolName
+	}
+
+	err := ceph.CloneImage(h.context, h.config.clusterInfo.Name, imageName, poolName, snapName, clone.Name, clone.PoolName)
+	if err != nil {
+		logger.Errorf("failed to clone snapshot %s of image %s: %+v", snapName, imageName, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Write([]byte(fmt.Sprintf("succeeded cloning snapshot %s to image %s", snapName, clone.Name)))
+}
+
+func (h *Handler) handleSnapshotAction(w http.ResponseWriter, r *http.Request, action string,
+	snapshotFunc func(context *clusterd.Context, clusterName, imageName, poolName, snapName string) error) {
+
+	poolName, imageName := getImageVars(r)
+	snapName := mux.Vars(r)["snapshot"]
+
+	if err := snapshotFunc(h.context, h.config.clusterInfo.Name, imageName, poolName, snapName); err != nil {
+		logger.Errorf("failed %s snapshot %s of image %s: %+v", action, snapName, imageName, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Write([]byte(fmt.Sprintf("succeeded %s snapshot %s", action, snapName)))
+}
+
+func getImageVars(r *http.Request) (string, string) {
+	vars := mux.Vars(r)
+	return vars["pool"], vars["name"]
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetImageSnapshotsHandler(t *testing.T) {
+	context, executor := testContext()
+
+	req, err := http.NewRequest("GET", "http://10.0.0.100/image/mypool/myimage/snapshots", nil)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		if command == "rbd" && args[0] == "snap" && args[1] == "ls" && args[2] == "mypool/myimage" {
+			return `[{"id":4,"name":"snap1","size":1048576}]`, nil
+		}
+		return "", fmt.Errorf("unexpected rbd command '%v'", args)
+	}
+
+	w := httptest.NewRecorder()
+	h := newTestHandler(context)
+	r := newRouter(h.GetRoutes())
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[{"snapshotName":"snap1","imageName":"myimage","poolName":"mypool","id":4,"size":1048576}]`, w.Body.String())
+}
+
+func TestCreateImageSnapshotHandler(t *testing.T) {
+	context, executor := testContext()
+
+	var snapArgs []string
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		snapArgs = args
+		return "", nil
+	}
+
+	h := newTestHandler(context)
+	r := newRouter(h.GetRoutes())
+
+	// the snapshot name is required
+	req, _ := http.NewRequest("POST", "http://10.0.0.100/image/mypool/myimage/snapshots", strings.NewReader(`{}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest("POST", "http://10.0.0.100/image/mypool/myimage/snapshots", strings.NewReader(`{"snapshotName":"snap1"}`))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "succeeded creating snapshot snap1", w.Body.String())
+	assert.Equal(t, []string{"snap", "create", "mypool/myimage@snap1"}, snapArgs[:3])
+}
+
+func TestImageSnapshotActionHandlers(t *testing.T) {
+	context, executor := testContext()
+
+	var snapArgs []string
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		snapArgs = args
+		return "", nil
+	}
+
+	h := newTestHandler(context)
+	r := newRouter(h.GetRoutes())
+
+	tests := []struct {
+		method string
+		url    string
+		args   []string
+	}{
+		{"DELETE", "http://10.0.0.100/image/mypool/myimage/snapshots/snap1", []string{"snap", "rm", "mypool/myimage@snap1"}},
+		{"POST", "http://10.0.0.100/image/mypool/myimage/snapshots/snap1/rollback", []string{"snap", "rollback", "mypool/myimage@snap1"}},
+		{"POST", "http://10.0.0.100/image/mypool/myimage/snapshots/snap1/protect", []string{"snap", "protect", "mypool/myimage@snap1"}},
+		{"POST", "http://10.0.0.100/image/mypool/myimage/snapshots/snap1/unprotect", []string{"snap", "unprotect", "mypool/myimage@snap1"}},
+		{"POST", "http://10.0.0.100/image/mypool/myimage/flatten", []string{"flatten", "mypool/myimage"}},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, test.url, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, test.url)
+		assert.Equal(t, test.args, snapArgs[:len(test.args)])
+	}
+
+	// failures of the rbd tool are returned as server errors
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		return "", fmt.Errorf("mock failure")
+	}
+	req, _ := http.NewRequest("DELETE", "http://10.0.0.100/image/mypool/myimage/snapshots/snap1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestCloneImageSnapshotHandler(t *testing.T) {
+	context, executor := testContext()
+
+	var cloneArgs []string
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		cloneArgs = args
+		return "", nil
+	}
+
+	h := newTestHandler(context)
+	r := newRouter(h.GetRoutes())
+
+	// the clone is created in the pool of the parent by default
+	req, _ := http.NewRequest("POST", "http://10.0.0.100/image/mypool/myimage/snapshots/snap1/clone",
+		bytes.NewReader([]byte(`{"imageName":"myclone"}`)))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"clone", "mypool/myimage@snap1", "mypool/myclone"}, cloneArgs[:3])
+
+	req, _ = http.NewRequest("POST", "http://10.0.0.100/image/mypool/myimage/snapshots/snap1/clone",
+		bytes.NewReader([]byte(`{"imageName":"myclone","poolName":"otherpool"}`)))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"clone", "mypool/myimage@snap1", "otherpool/myclone"}, cloneArgs[:3])
+
+	// the clone name is required
+	req, _ = http.NewRequest("POST", "http://10.0.0.100/image/mypool/myimage/snapshots/snap1/clone", bytes.NewReader([]byte(`{}`)))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestResizeImageHandler(t *testing.T) {
+	context, executor := testContext()
+
+	var resizeArgs []string
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		resizeArgs = args
+		return "", nil
+	}
+
+	h := newTestHandler(context)
+	r := newRouter(h.GetRoutes())
+
+	req, _ := http.NewRequest("PUT", "http://10.0.0.100/image/mypool/myimage", strings.NewReader(`{"size":2097152}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"resize", "mypool/myimage", "--size", "2"}, resizeArgs[:4])
+
+	// the size is required
+	req, _ = http.NewRequest("PUT", "http://10.0.0.100/image/mypool/myimage", strings.NewReader(`{}`))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
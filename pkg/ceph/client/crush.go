@@ -200,6 +200,44 @@ func CreateDefaultCrushMap(context *clusterd.Context, clusterName string) (strin
 	return "", nil
 }
 
+// CreateCrushRule creates a simple replicated crush rule that spreads the replicas across the given failure domain
+func CreateCrushRule(context *clusterd.Context, clusterName, ruleName, failureDomain string) error {
+	args := []string{"osd", "crush", "rule", "create-simple", ruleName, "default", failureDomain}
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to create crush rule %s. %+v", ruleName, err)
+	}
+
+	return nil
+}
+
+// DeleteCrushRule removes the crush rule. Ceph will fail the request if a pool is still using the rule.
+func DeleteCrushRule(context *clusterd.Context, clusterName, ruleName string) error {
+	args := []string{"osd", "crush", "rule", "rm", ruleName}
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to delete crush rule %s. %+v", ruleName, err)
+	}
+
+	return nil
+}
+
+// GetCrushRuleFailureDomain returns the bucket type the crush rule spreads data across, or an empty
+// string if the rule is not in the crush map.
+func GetCrushRuleFailureDomain(crush CrushMap, ruleName string) string {
+	for _, rule := range crush.Rules {
+		if rule.Name != ruleName {
+			continue
+		}
+		for _, step := range rule.Steps {
+			if strings.HasPrefix(step.Operation, "choose") && step.Type != "" {
+				return step.Type
+			}
+		}
+	}
+	return ""
+}
+
 func FormatLocation(location string) ([]string, error) {
 	var pairs []string
 	if location == "" {
@@ -22,14 +22,24 @@ import (
 	"strconv"
 
 	"regexp"
+	"strings"
 
 	"github.com/rook/rook/pkg/clusterd"
 )
 
 const (
 	ImageMinSize = uint64(1048576) // 1 MB
+
+	minImageOrder = 12 // 4 KB objects
+	maxImageOrder = 25 // 32 MB objects
 )
 
+// ImageFeatures are the image features that can be enabled when an image is created
+var ImageFeatures = []string{"layering", "striping", "exclusive-lock", "object-map", "fast-diff", "deep-flatten", "journaling", "data-pool"}
+
+// ExclusiveLockFeatures are the image features that require the exclusive lock of the image
+var ExclusiveLockFeatures = []string{"exclusive-lock", "object-map", "fast-diff", "journaling"}
+
 type CephBlockImage struct {
 	Name   string `json:"image"`
 	Size   uint64 `json:"size"`
@@ -61,18 +71,102 @@ func ListImages(context *clusterd.Context, clusterName, poolName string) ([]Ceph
 	return images, nil
 }
 
+// ImageOptions are the optional settings of a new image. Settings that are not set keep the ceph defaults.
+type ImageOptions struct {
+	// The features to enable on the image, e.g. layering or exclusive-lock
+	Features []string
+	// The object size of the image in bytes or with a unit suffix such as 4M. Takes precedence over the order.
+	ObjectSize string
+	// The object size of the image as a power of two
+	Order int
+	// The size in bytes of a stripe unit, or with a unit suffix such as 64K
+	StripeUnit string
+	// The number of objects to stripe over before looping back to the first object
+	StripeCount int
+	// The pool where the image data is stored. The image metadata stays in the pool of the image.
+	DataPool string
+}
+
+// Validate the image options
+func (o *ImageOptions) Validate() error {
+	for _, feature := range o.Features {
+		if !isKnownImageFeature(feature) {
+			return fmt.Errorf("unknown image feature %s", feature)
+		}
+	}
+	if o.Order != 0 && (o.Order < minImageOrder || o.Order > maxImageOrder) {
+		return fmt.Errorf("image order %d must be between %d and %d", o.Order, minImageOrder, maxImageOrder)
+	}
+	if (o.StripeUnit == "") != (o.StripeCount == 0) {
+		return fmt.Errorf("stripe unit and stripe count must be set together")
+	}
+	if o.StripeCount < 0 {
+		return fmt.Errorf("invalid stripe count %d", o.StripeCount)
+	}
+	return nil
+}
+
+func (o *ImageOptions) args() []string {
+	var args []string
+	for _, feature := range o.Features {
+		args = append(args, "--image-feature", feature)
+	}
+	if o.ObjectSize != "" {
+		args = append(args, "--object-size", o.ObjectSize)
+	} else if o.Order != 0 {
+		args = append(args, "--order", strconv.Itoa(o.Order))
+	}
+	if o.StripeUnit != "" {
+		args = append(args, "--stripe-unit", o.StripeUnit, "--stripe-count", strconv.Itoa(o.StripeCount))
+	}
+	if o.DataPool != "" {
+		args = append(args, "--data-pool", o.DataPool)
+	}
+	return args
+}
+
+// RequiresExclusiveLock returns whether any of the features of the image require the exclusive lock
+func (o *ImageOptions) RequiresExclusiveLock() bool {
+	for _, feature := range o.Features {
+		for _, f := range ExclusiveLockFeatures {
+			if f == feature {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isKnownImageFeature(feature string) bool {
+	for _, f := range ImageFeatures {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
 func CreateImage(context *clusterd.Context, clusterName, name, poolName string, size uint64) (*CephBlockImage, error) {
+	return CreateImageWithOptions(context, clusterName, name, poolName, size, ImageOptions{})
+}
+
+// CreateImageWithOptions creates an image with the given features, layout and data pool
+func CreateImageWithOptions(context *clusterd.Context, clusterName, name, poolName string, size uint64, opts ImageOptions) (*CephBlockImage, error) {
 	if size > 0 && size < ImageMinSize {
 		// rbd tool uses MB as the smallest unit for size input.  0 is OK but anything else smaller
 		// than 1 MB should just be rounded up to 1 MB.
 		logger.Warningf("requested image size %d is less than the minimum size of %d, using the minimum.", size, ImageMinSize)
 		size = ImageMinSize
 	}
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid options for image %s. %+v", name, err)
+	}
 
 	sizeMB := int(size / 1024 / 1024)
 	imageSpec := getImageSpec(name, poolName)
 
 	args := []string{"create", imageSpec, "--size", strconv.Itoa(sizeMB)}
+	args = append(args, opts.args()...)
 	buf, err := ExecuteRBDCommandNoFormat(context, clusterName, args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create image %s in pool %s of size %d: %+v. output: %s",
@@ -105,6 +199,130 @@ func DeleteImage(context *clusterd.Context, clusterName, name, poolName string)
 	return nil
 }
 
+// CloneImage creates a new image from a protected snapshot. The clone shares the data of the snapshot
+// until it is flattened.
+func CloneImage(context *clusterd.Context, clusterName, imageName, poolName, snapName, cloneName, clonePoolName string) error {
+	snapSpec := getSnapshotSpec(imageName, poolName, snapName)
+	cloneSpec := getImageSpec(cloneName, clonePoolName)
+	args := []string{"clone", snapSpec, cloneSpec}
+	buf, err := ExecuteRBDCommandNoFormat(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to clone snapshot %s to image %s: %+v. output: %s", snapSpec, cloneSpec, err, string(buf))
+	}
+
+	return nil
+}
+
+// FlattenImage copies the data from the parent snapshot into the cloned image so the image no longer depends on its parent
+func FlattenImage(context *clusterd.Context, clusterName, name, poolName string) error {
+	imageSpec := getImageSpec(name, poolName)
+	args := []string{"flatten", imageSpec}
+	buf, err := ExecuteRBDCommandNoFormat(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to flatten image %s: %+v. output: %s", imageSpec, err, string(buf))
+	}
+
+	return nil
+}
+
+// ResizeImage grows the image to the given size. Images cannot be shrunk since the data at the end of
+// the image would be lost.
+func ResizeImage(context *clusterd.Context, clusterName, name, poolName string, size uint64) error {
+	if size < ImageMinSize {
+		logger.Warningf("requested image size %d is less than the minimum size of %d, using the minimum.", size, ImageMinSize)
+		size = ImageMinSize
+	}
+
+	// round up to the next MB so the image is never smaller than requested
+	sizeMB := int((size + ImageMinSize - 1) / ImageMinSize)
+	imageSpec := getImageSpec(name, poolName)
+	args := []string{"resize", imageSpec, "--size", strconv.Itoa(sizeMB)}
+	buf, err := ExecuteRBDCommandNoFormat(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to resize image %s to size %d: %+v. output: %s", imageSpec, size, err, string(buf))
+	}
+
+	return nil
+}
+
+// ImageQoS are the I/O limits of an image in operations or bytes per second. Zero is unlimited.
+type ImageQoS struct {
+	ReadIOPSLimit  uint64
+	WriteIOPSLimit uint64
+	ReadBPSLimit   uint64
+	WriteBPSLimit  uint64
+}
+
+// ImageWatcher is a client that has the image open
+type ImageWatcher struct {
+	Address string `json:"address"`
+	Client  int64  `json:"client"`
+	Cookie  uint64 `json:"cookie"`
+}
+
+// ImageLock is a lock on the image, such as the exclusive lock held by the client writing to the image
+type ImageLock struct {
+	ID      string
+	Locker  string `json:"locker"`
+	Address string `json:"address"`
+}
+
+// GetImageWatchers returns the clients that have the image open
+func GetImageWatchers(context *clusterd.Context, clusterName, name, poolName string) ([]ImageWatcher, error) {
+	imageSpec := getImageSpec(name, poolName)
+	args := []string{"status", imageSpec}
+	buf, err := ExecuteRBDCommand(context, clusterName, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status of image %s: %+v", imageSpec, err)
+	}
+
+	var status struct {
+		Watchers []ImageWatcher `json:"watchers"`
+	}
+	if err := json.Unmarshal(buf, &status); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal status of image %s: %+v. raw buffer response: %s", imageSpec, err, string(buf))
+	}
+
+	return status.Watchers, nil
+}
+
+// ListImageLocks returns the locks on the image
+func ListImageLocks(context *clusterd.Context, clusterName, name, poolName string) ([]ImageLock, error) {
+	imageSpec := getImageSpec(name, poolName)
+	args := []string{"lock", "list", imageSpec}
+	buf, err := ExecuteRBDCommand(context, clusterName, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list locks of image %s: %+v", imageSpec, err)
+	}
+
+	// the locks are returned by their id. nothing is returned if there are no locks.
+	locksByID := map[string]ImageLock{}
+	if len(strings.TrimSpace(string(buf))) > 0 {
+		if err := json.Unmarshal(buf, &locksByID); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal locks of image %s: %+v. raw buffer response: %s", imageSpec, err, string(buf))
+		}
+	}
+
+	locks := []ImageLock{}
+	for id, lock := range locksByID {
+		lock.ID = id
+		locks = append(locks, lock)
+	}
+	return locks, nil
+}
+
+// RemoveImageLock breaks the lock on the image held by the locker
+func RemoveImageLock(context *clusterd.Context, clusterName, name, poolName, lockID, locker string) error {
+	imageSpec := getImageSpec(name, poolName)
+	args := []string{"lock", "remove", imageSpec, lockID, locker}
+	buf, err := ExecuteRBDCommandNoFormat(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to remove lock %s of image %s: %+v. output: %s", lockID, imageSpec, err, string(buf))
+	}
+
+	return nil
+}
+
 // MapImage maps an RBD image using admin cephfx and returns the device path
 func MapImage(context *clusterd.Context, imageName, poolName, clusterName, keyring, monitors string) error {
 
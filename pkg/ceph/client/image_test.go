@@ -92,6 +92,57 @@ func TestCreateImage(t *testing.T) {
 	createCalled = false
 }
 
+func TestCreateImageWithOptions(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+
+	var createArgs []string
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "create":
+			createArgs = args
+			return "", nil
+		case command == "rbd" && args[0] == "ls" && args[1] == "-l":
+			return `[{"image":"image1","size":1048576,"format":2}]`, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	opts := ImageOptions{
+		Features:    []string{"layering", "exclusive-lock"},
+		Order:       22,
+		StripeUnit:  "64K",
+		StripeCount: 16,
+		DataPool:    "ecpool",
+	}
+	_, err := CreateImageWithOptions(context, "foocluster", "image1", "pool1", uint64(1048576), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"create", "pool1/image1", "--size", "1",
+		"--image-feature", "layering", "--image-feature", "exclusive-lock",
+		"--order", "22",
+		"--stripe-unit", "64K", "--stripe-count", "16",
+		"--data-pool", "ecpool"}, createArgs[:15])
+
+	// the object size takes precedence over the order
+	opts = ImageOptions{ObjectSize: "8M", Order: 22}
+	_, err = CreateImageWithOptions(context, "foocluster", "image1", "pool1", uint64(1048576), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"--object-size", "8M"}, createArgs[4:6])
+
+	// invalid options are rejected before the image is created
+	createArgs = nil
+	for _, opts := range []ImageOptions{
+		{Features: []string{"bogus"}},
+		{Order: 30},
+		{StripeUnit: "64K"},
+		{StripeCount: 4},
+	} {
+		_, err = CreateImageWithOptions(context, "foocluster", "image1", "pool1", uint64(1048576), opts)
+		assert.NotNil(t, err)
+	}
+	assert.Nil(t, createArgs)
+}
+
 func TestListImageLogLevelInfo(t *testing.T) {
 	executor := &exectest.MockExecutor{}
 	context := &clusterd.Context{Executor: executor}
@@ -192,3 +243,107 @@ func TestListImageLogLevelDebug(t *testing.T) {
 	assert.True(t, listCalled)
 	listCalled = false
 }
+
+func TestResizeImage(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+
+	expectedSizeArg := ""
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		if command == "rbd" && args[0] == "resize" {
+			assert.Equal(t, "pool1/image1", args[1])
+			assert.Equal(t, expectedSizeArg, args[3])
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	// sizes are rounded up to the next MB
+	expectedSizeArg = "1"
+	err := ResizeImage(context, "foocluster", "image1", "pool1", uint64(1))
+	assert.Nil(t, err)
+
+	expectedSizeArg = "2"
+	err = ResizeImage(context, "foocluster", "image1", "pool1", uint64(1048577))
+	assert.Nil(t, err)
+
+	expectedSizeArg = "1024"
+	err = ResizeImage(context, "foocluster", "image1", "pool1", uint64(1073741824))
+	assert.Nil(t, err)
+}
+
+func TestSnapshots(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+
+	var lastArgs []string
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		lastArgs = args
+		if command == "rbd" && args[0] == "snap" && args[1] == "ls" {
+			return `[{"id":4,"name":"snap1","size":1048576}]`, nil
+		}
+		return "", nil
+	}
+
+	snaps, err := ListSnapshots(context, "foocluster", "image1", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(snaps))
+	assert.Equal(t, "snap1", snaps[0].Name)
+	assert.Equal(t, uint64(1048576), snaps[0].Size)
+	assert.Equal(t, "pool1/image1", lastArgs[2])
+
+	err = ProtectSnapshot(context, "foocluster", "image1", "pool1", "snap1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"snap", "protect", "pool1/image1@snap1"}, lastArgs[:3])
+
+	err = CloneImage(context, "foocluster", "image1", "pool1", "snap1", "clone1", "pool2")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"clone", "pool1/image1@snap1", "pool2/clone1"}, lastArgs[:3])
+
+	// errors include the output of the rbd tool
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		return "snapshot is protected", fmt.Errorf("mock failure")
+	}
+	err = DeleteSnapshot(context, "foocluster", "image1", "pool1", "snap1")
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "snapshot is protected"))
+}
+
+func TestImageWatchersAndLocks(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+
+	removed := ""
+	locks := `{"auto 18446462598732840961":{"locker":"client.4123","address":"10.0.0.1:0/3421"}}`
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case args[0] == "status":
+			return `{"watchers":[{"address":"10.0.0.1:0/3421","client":4123,"cookie":18446462598732840961}]}`, nil
+		case args[0] == "lock" && args[1] == "list":
+			return locks, nil
+		case args[0] == "lock" && args[1] == "remove":
+			assert.Equal(t, "pool1/image1", args[2])
+			removed = args[3] + " " + args[4]
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected rbd command '%v'", args)
+	}
+
+	watchers, err := GetImageWatchers(context, "foocluster", "image1", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, []ImageWatcher{{Address: "10.0.0.1:0/3421", Client: 4123, Cookie: 18446462598732840961}}, watchers)
+
+	imageLocks, err := ListImageLocks(context, "foocluster", "image1", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, []ImageLock{{ID: "auto 18446462598732840961", Locker: "client.4123", Address: "10.0.0.1:0/3421"}}, imageLocks)
+
+	err = RemoveImageLock(context, "foocluster", "image1", "pool1", imageLocks[0].ID, imageLocks[0].Locker)
+	assert.Nil(t, err)
+	assert.Equal(t, "auto 18446462598732840961 client.4123", removed)
+
+	// no output when the image is not locked
+	locks = ""
+	imageLocks, err = ListImageLocks(context, "foocluster", "image1", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(imageLocks))
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rook/rook/pkg/clusterd"
+)
+
+const (
+	// MirrorModePool mirrors all images in the pool that have the journaling feature enabled
+	MirrorModePool = "pool"
+	// MirrorModeImage mirrors only the images that have mirroring explicitly enabled
+	MirrorModeImage = "image"
+	// MirrorModeDisabled is reported for pools that are not mirrored
+	MirrorModeDisabled = "disabled"
+)
+
+type CephMirrorPoolInfo struct {
+	Mode  string           `json:"mode"`
+	Peers []CephMirrorPeer `json:"peers"`
+}
+
+type CephMirrorPeer struct {
+	UUID        string `json:"uuid"`
+	ClusterName string `json:"cluster_name"`
+	ClientName  string `json:"client_name"`
+}
+
+type CephMirrorPoolStatus struct {
+	Summary struct {
+		Health string         `json:"health"`
+		States map[string]int `json:"states"`
+	} `json:"summary"`
+	Images []CephMirrorImageStatus `json:"images"`
+}
+
+type CephMirrorImageStatus struct {
+	Name        string `json:"name"`
+	GlobalID    string `json:"global_id"`
+	State       string `json:"state"`
+	Description string `json:"description"`
+	LastUpdate  string `json:"last_update"`
+}
+
+// GetPoolMirrorInfo gets the mirroring mode and the peers of the pool
+func GetPoolMirrorInfo(context *clusterd.Context, clusterName, poolName string) (*CephMirrorPoolInfo, error) {
+	args := []string{"mirror", "pool", "info", poolName}
+	buf, err := ExecuteRBDCommand(context, clusterName, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mirror info for pool %s. %+v", poolName, err)
+	}
+
+	var info CephMirrorPoolInfo
+	if err := json.Unmarshal(buf, &info); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal mirror info for pool %s: %+v. raw buffer response: %s", poolName, err, string(buf))
+	}
+
+	return &info, nil
+}
+
+// EnablePoolMirroring enables mirroring on the pool with the given mode (pool or image)
+func EnablePoolMirroring(context *clusterd.Context, clusterName, poolName, mode string) error {
+	args := []string{"mirror", "pool", "enable", poolName, mode}
+	buf, err := ExecuteRBDCommandNoFormat(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to enable %s mirroring on pool %s: %+v. output: %s", mode, poolName, err, string(buf))
+	}
+
+	return nil
+}
+
+// DisablePoolMirroring disables mirroring on the pool
+func DisablePoolMirroring(context *clusterd.Context, clusterName, poolName string) error {
+	args := []string{"mirror", "pool", "disable", poolName}
+	buf, err := ExecuteRBDCommandNoFormat(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to disable mirroring on pool %s: %+v. output: %s", poolName, err, string(buf))
+	}
+
+	return nil
+}
+
+// AddPoolMirrorPeer adds a peer cluster to the pool. The client name is the qualified ceph user
+// (i.e., client.rbd-mirror-peer) that the rbd-mirror daemon uses to connect to the peer cluster.
+func AddPoolMirrorPeer(context *clusterd.Context, clusterName, poolName, peerClientName, peerClusterName string) error {
+	peer := fmt.Sprintf("%s@%s", peerClientName, peerClusterName)
+	args := []string{"mirror", "pool", "peer", "add", poolName, peer}
+	buf, err := ExecuteRBDCommandNoFormat(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to add mirror peer %s to pool %s: %+v. output: %s", peer, poolName, err, string(buf))
+	}
+
+	return nil
+}
+
+// GetPoolMirrorStatus gets the summary of the mirroring health in the pool and the status of each mirrored image
+func GetPoolMirrorStatus(context *clusterd.Context, clusterName, poolName string) (*CephMirrorPoolStatus, error) {
+	args := []string{"mirror", "pool", "status", poolName, "--verbose"}
+	buf, err := ExecuteRBDCommand(context, clusterName, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mirror status for pool %s. %+v", poolName, err)
+	}
+
+	var status CephMirrorPoolStatus
+	if err := json.Unmarshal(buf, &status); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal mirror status for pool %s: %+v. raw buffer response: %s", poolName, err, string(buf))
+	}
+
+	return &status, nil
+}
@@ -21,6 +21,7 @@ package client
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"github.com/rook/rook/pkg/clusterd"
 )
@@ -109,3 +110,29 @@ func GetOSDDump(context *clusterd.Context, clusterName string) (*OSDDump, error)
 
 	return &osdDump, nil
 }
+
+// BlacklistAdd blacklists the client address so the client can no longer access the osds. The entry expires after the
+// given number of seconds, or after the ceph default of one hour if zero.
+func BlacklistAdd(context *clusterd.Context, clusterName, address string, expireSeconds int) error {
+	args := []string{"osd", "blacklist", "add", address}
+	if expireSeconds > 0 {
+		args = append(args, strconv.Itoa(expireSeconds))
+	}
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to blacklist client %s: %+v", address, err)
+	}
+
+	return nil
+}
+
+// BlacklistRemove removes the client address from the blacklist
+func BlacklistRemove(context *clusterd.Context, clusterName, address string) error {
+	args := []string{"osd", "blacklist", "rm", address}
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to remove client %s from the blacklist: %+v", address, err)
+	}
+
+	return nil
+}
@@ -42,6 +42,8 @@ type CephStoragePoolDetails struct {
 	Name               string `json:"pool"`
 	Number             int    `json:"pool_id"`
 	Size               uint   `json:"size"`
+	MinSize            uint   `json:"min_size"`
+	CrushRule          string `json:"crush_rule"`
 	ErasureCodeProfile string `json:"erasure_code_profile"`
 	FailureDomain      string
 }
@@ -64,6 +66,16 @@ type CephStoragePoolStats struct {
 	} `json:"pools"`
 }
 
+// CephPoolRecovery is the recovery of a pool from "ceph osd pool stats". The counts are omitted when the pool is clean.
+type CephPoolRecovery struct {
+	MisplacedObjects uint64  `json:"misplaced_objects"`
+	MisplacedTotal   uint64  `json:"misplaced_total"`
+	MisplacedRatio   float64 `json:"misplaced_ratio"`
+	DegradedObjects  uint64  `json:"degraded_objects"`
+	DegradedTotal    uint64  `json:"degraded_total"`
+	DegradedRatio    float64 `json:"degraded_ratio"`
+}
+
 func ListPoolSummaries(context *clusterd.Context, clusterName string) ([]CephStoragePoolSummary, error) {
 	args := []string{"osd", "lspools"}
 	buf, err := ExecuteCephCommand(context, clusterName, args)
@@ -153,10 +165,12 @@ func DeletePool(context *clusterd.Context, clusterName string, name string) erro
 	}
 
 	// remove the crush rule for this pool and ignore the error in case the rule is still in use or not found
-	args = []string{"osd", "crush", "rule", "rm", name}
-	_, err = ExecuteCephCommand(context, clusterName, args)
-	if err != nil {
-		logger.Infof("did not delete crush rule %s. %+v", name, err)
+	ruleName := name
+	if IsPoolCrushRule(name, pool.CrushRule) {
+		ruleName = pool.CrushRule
+	}
+	if err := DeleteCrushRule(context, clusterName, ruleName); err != nil {
+		logger.Infof("did not delete crush rule %s. %+v", ruleName, err)
 	}
 
 	logger.Infof("purge completed for pool %s", name)
@@ -173,10 +187,8 @@ func CreatePoolForApp(context *clusterd.Context, clusterName string, newPool Cep
 	replicated := newPool.ErasureCodeProfile == "" && newPool.Size > 0
 	ruleName := newPool.Name
 	if replicated && newPool.FailureDomain != "" {
-		args := []string{"osd", "crush", "rule", "create-simple", ruleName, "default", newPool.FailureDomain}
-		_, err := ExecuteCephCommand(context, clusterName, args)
-		if err != nil {
-			return fmt.Errorf("failed to crush rule %s. %+v", newPool.Name, err)
+		if err := CreateCrushRule(context, clusterName, ruleName, newPool.FailureDomain); err != nil {
+			return err
 		}
 	}
 
@@ -224,6 +236,57 @@ func SetPoolProperty(context *clusterd.Context, clusterName, name, propName stri
 	return nil
 }
 
+// SetPoolReplicatedSize sets the number of replicas for a replicated pool. The min_size is updated
+// to the same default ceph would choose for the size so that I/O continues while a single replica is lost.
+func SetPoolReplicatedSize(context *clusterd.Context, clusterName, name string, size uint) error {
+	if err := SetPoolProperty(context, clusterName, name, "size", strconv.FormatUint(uint64(size), 10)); err != nil {
+		return err
+	}
+
+	minSize := size - size/2
+	return SetPoolProperty(context, clusterName, name, "min_size", strconv.FormatUint(uint64(minSize), 10))
+}
+
+// SetPoolCrushRule associates the pool with a different crush rule. Ceph will start moving the data
+// in the pool as soon as the new rule is applied.
+func SetPoolCrushRule(context *clusterd.Context, clusterName, name, ruleName string) error {
+	return SetPoolProperty(context, clusterName, name, "crush_rule", ruleName)
+}
+
+// PoolCrushRuleName returns the name of the crush rule that is created when the failure domain of the pool is changed.
+// The rule that the pool was created with is named after the pool itself, which IsPoolCrushRule also recognizes.
+func PoolCrushRuleName(poolName, failureDomain string) string {
+	return fmt.Sprintf("%s_%s", poolName, failureDomain)
+}
+
+// IsPoolCrushRule returns whether the crush rule was created specifically for the pool
+func IsPoolCrushRule(poolName, ruleName string) bool {
+	return ruleName == poolName || strings.HasPrefix(ruleName, poolName+"_")
+}
+
+// GetPoolRecovery gets the objects of the pool that are misplaced or degraded while ceph moves its data
+func GetPoolRecovery(context *clusterd.Context, clusterName, name string) (*CephPoolRecovery, error) {
+	args := []string{"osd", "pool", "stats", name}
+	buf, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats of pool %s: %+v", name, err)
+	}
+
+	var stats []struct {
+		Name     string           `json:"pool_name"`
+		Recovery CephPoolRecovery `json:"recovery"`
+	}
+	if err := json.Unmarshal(buf, &stats); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal stats of pool %s: %+v", name, err)
+	}
+	for _, s := range stats {
+		if s.Name == name {
+			return &s.Recovery, nil
+		}
+	}
+	return nil, fmt.Errorf("pool %s not found in pool stats", name)
+}
+
 func GetPoolStats(context *clusterd.Context, clusterName string) (*CephStoragePoolStats, error) {
 	args := []string{"df", "detail"}
 	buf, err := ExecuteCephCommand(context, clusterName, args)
@@ -0,0 +1,94 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/rook/rook/pkg/clusterd"
+)
+
+type CephBlockImageSnapshot struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Size uint64 `json:"size"`
+}
+
+// ListSnapshots lists the snapshots of the image
+func ListSnapshots(context *clusterd.Context, clusterName, imageName, poolName string) ([]CephBlockImageSnapshot, error) {
+	imageSpec := getImageSpec(imageName, poolName)
+	args := []string{"snap", "ls", imageSpec}
+	buf, err := ExecuteRBDCommand(context, clusterName, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots for image %s: %+v", imageSpec, err)
+	}
+
+	// the json result is at the end of the output in case librados also logged to the output
+	res := regexp.MustCompile(`(?m)^\[(.*)\]`).FindStringSubmatch(string(buf))
+	if len(res) == 0 {
+		return []CephBlockImageSnapshot{}, nil
+	}
+	buf = []byte(res[0])
+
+	var snapshots []CephBlockImageSnapshot
+	if err = json.Unmarshal(buf, &snapshots); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v. raw buffer response: %s", err, string(buf))
+	}
+
+	return snapshots, nil
+}
+
+// CreateSnapshot creates a snapshot of the image
+func CreateSnapshot(context *clusterd.Context, clusterName, imageName, poolName, snapName string) error {
+	return executeSnapshotCommand(context, clusterName, "create", imageName, poolName, snapName)
+}
+
+// DeleteSnapshot deletes the snapshot. Protected snapshots cannot be deleted.
+func DeleteSnapshot(context *clusterd.Context, clusterName, imageName, poolName, snapName string) error {
+	return executeSnapshotCommand(context, clusterName, "rm", imageName, poolName, snapName)
+}
+
+// RollbackSnapshot reverts the image to the content of the snapshot
+func RollbackSnapshot(context *clusterd.Context, clusterName, imageName, poolName, snapName string) error {
+	return executeSnapshotCommand(context, clusterName, "rollback", imageName, poolName, snapName)
+}
+
+// ProtectSnapshot protects the snapshot from deletion so that it can be cloned
+func ProtectSnapshot(context *clusterd.Context, clusterName, imageName, poolName, snapName string) error {
+	return executeSnapshotCommand(context, clusterName, "protect", imageName, poolName, snapName)
+}
+
+// UnprotectSnapshot allows the snapshot to be deleted. The snapshot must not have any clones that are not flattened.
+func UnprotectSnapshot(context *clusterd.Context, clusterName, imageName, poolName, snapName string) error {
+	return executeSnapshotCommand(context, clusterName, "unprotect", imageName, poolName, snapName)
+}
+
+func executeSnapshotCommand(context *clusterd.Context, clusterName, action, imageName, poolName, snapName string) error {
+	snapSpec := getSnapshotSpec(imageName, poolName, snapName)
+	args := []string{"snap", action, snapSpec}
+	buf, err := ExecuteRBDCommandNoFormat(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to %s snapshot %s: %+v. output: %s", action, snapSpec, err, string(buf))
+	}
+
+	return nil
+}
+
+func getSnapshotSpec(imageName, poolName, snapName string) string {
+	return fmt.Sprintf("%s@%s", getImageSpec(imageName, poolName), snapName)
+}
@@ -0,0 +1,264 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collectors
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/rook/rook/pkg/ceph/rgw"
+	"github.com/rook/rook/pkg/clusterd"
+)
+
+// the stats of all buckets are expensive to get, so they are only refreshed after the interval
+const bucketUsageInterval = 5 * time.Minute
+
+// RGWCollector displays the usage of the users and buckets of each object store in the ceph cluster. The requests
+// are counted from the usage log of the object store.
+type RGWCollector struct {
+	// Context for executing commands against the Ceph cluster
+	context *clusterd.Context
+
+	// The name of the ceph cluster
+	clusterName string
+
+	// gets the contexts of the object stores, whose realms are not named after the stores if they are secondary zones
+	objectStores func() ([]*rgw.Context, error)
+
+	// the usage logs and bucket stats of the object stores, which are kept between scrapes
+	lock        sync.Mutex
+	usageLogs   map[string]*rgw.UsageLog
+	bucketUsage map[string]bucketUsage
+
+	// BucketSizeBytes tracks the size of the objects in each bucket.
+	BucketSizeBytes *prometheus.GaugeVec
+
+	// BucketObjects tracks the number of objects in each bucket.
+	BucketObjects *prometheus.GaugeVec
+
+	// UserSizeBytes tracks the size of the objects in the buckets of each user.
+	UserSizeBytes *prometheus.GaugeVec
+
+	// UserObjects tracks the number of objects in the buckets of each user.
+	UserObjects *prometheus.GaugeVec
+
+	// UserOps tracks the requests of each user by category, e.g. get_obj.
+	UserOps *prometheus.GaugeVec
+
+	// UserSuccessfulOps tracks the successful requests of each user by category.
+	UserSuccessfulOps *prometheus.GaugeVec
+
+	// UserSentBytes tracks the bytes sent to each user by category.
+	UserSentBytes *prometheus.GaugeVec
+
+	// UserReceivedBytes tracks the bytes received from each user by category.
+	UserReceivedBytes *prometheus.GaugeVec
+
+	// BucketOps tracks the requests to each bucket by category.
+	BucketOps *prometheus.GaugeVec
+
+	// BucketSuccessfulOps tracks the successful requests to each bucket by category.
+	BucketSuccessfulOps *prometheus.GaugeVec
+
+	// BucketSentBytes tracks the bytes sent from each bucket by category.
+	BucketSentBytes *prometheus.GaugeVec
+
+	// BucketReceivedBytes tracks the bytes received to each bucket by category.
+	BucketReceivedBytes *prometheus.GaugeVec
+}
+
+// bucketUsage is the usage of the buckets of an object store at the time it was read
+type bucketUsage struct {
+	buckets []rgw.BucketUsage
+	time    time.Time
+}
+
+// NewRGWCollector creates a new instance of RGWCollector and returns its reference. The object stores are listed by
+// the given function.
+func NewRGWCollector(context *clusterd.Context, clusterName string, objectStores func() ([]*rgw.Context, error)) *RGWCollector {
+	var (
+		subSystem       = "rgw"
+		bucketLabels    = []string{"store", "bucket", "owner"}
+		userLabels      = []string{"store", "user"}
+		userOpsLabels   = []string{"store", "user", "category"}
+		bucketOpsLabels = []string{"store", "bucket", "category"}
+		newGaugeVec     = func(name, help string, labels []string) *prometheus.GaugeVec {
+			return prometheus.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Namespace: cephNamespace,
+					Subsystem: subSystem,
+					Name:      name,
+					Help:      help,
+				},
+				labels,
+			)
+		}
+	)
+	return &RGWCollector{
+		context:      context,
+		clusterName:  clusterName,
+		objectStores: objectStores,
+		usageLogs:    map[string]*rgw.UsageLog{},
+		bucketUsage:  map[string]bucketUsage{},
+
+		BucketSizeBytes:     newGaugeVec("bucket_size_bytes", "Size of the objects in the bucket", bucketLabels),
+		BucketObjects:       newGaugeVec("bucket_objects_total", "Total no. of objects in the bucket", bucketLabels),
+		UserSizeBytes:       newGaugeVec("user_size_bytes", "Size of the objects in the buckets of the user", userLabels),
+		UserObjects:         newGaugeVec("user_objects_total", "Total no. of objects in the buckets of the user", userLabels),
+		UserOps:             newGaugeVec("user_ops_total", "Total requests of the user", userOpsLabels),
+		UserSuccessfulOps:   newGaugeVec("user_successful_ops_total", "Total successful requests of the user", userOpsLabels),
+		UserSentBytes:       newGaugeVec("user_sent_bytes_total", "Total bytes sent to the user", userOpsLabels),
+		UserReceivedBytes:   newGaugeVec("user_received_bytes_total", "Total bytes received from the user", userOpsLabels),
+		BucketOps:           newGaugeVec("bucket_ops_total", "Total requests to the bucket", bucketOpsLabels),
+		BucketSuccessfulOps: newGaugeVec("bucket_successful_ops_total", "Total successful requests to the bucket", bucketOpsLabels),
+		BucketSentBytes:     newGaugeVec("bucket_sent_bytes_total", "Total bytes sent from the bucket", bucketOpsLabels),
+		BucketReceivedBytes: newGaugeVec("bucket_received_bytes_total", "Total bytes received to the bucket", bucketOpsLabels),
+	}
+}
+
+func (r *RGWCollector) collectorList() []prometheus.Collector {
+	return []prometheus.Collector{
+		r.BucketSizeBytes,
+		r.BucketObjects,
+		r.UserSizeBytes,
+		r.UserObjects,
+		r.UserOps,
+		r.UserSuccessfulOps,
+		r.UserSentBytes,
+		r.UserReceivedBytes,
+		r.BucketOps,
+		r.BucketSuccessfulOps,
+		r.BucketSentBytes,
+		r.BucketReceivedBytes,
+	}
+}
+
+func (r *RGWCollector) collect() error {
+	stores, err := r.objectStores()
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	// the users and buckets that were deleted are no longer reported
+	for _, metric := range r.collectorList() {
+		metric.(*prometheus.GaugeVec).Reset()
+	}
+
+	now := time.Now()
+	exists := map[string]bool{}
+	for _, objContext := range stores {
+		exists[objContext.Name] = true
+		// an object store that fails is skipped so that the metrics of the other stores are still reported
+		if err := r.collectStore(objContext, now); err != nil {
+			logger.Errorf("failed collecting metrics of object store %s: %+v", objContext.Name, err)
+		}
+	}
+
+	// forget the usage of the object stores that were deleted
+	for store := range r.usageLogs {
+		if !exists[store] {
+			delete(r.usageLogs, store)
+			delete(r.bucketUsage, store)
+		}
+	}
+	return nil
+}
+
+func (r *RGWCollector) collectStore(objContext *rgw.Context, now time.Time) error {
+	store := objContext.Name
+
+	cached, ok := r.bucketUsage[store]
+	if !ok || now.Sub(cached.time) >= bucketUsageInterval {
+		buckets, err := rgw.GetBucketUsage(objContext)
+		if err != nil {
+			return err
+		}
+		cached = bucketUsage{buckets: buckets, time: now}
+		r.bucketUsage[store] = cached
+	}
+	buckets := cached.buckets
+	type userTotal struct {
+		size, objects uint64
+	}
+	users := map[string]*userTotal{}
+	for _, b := range buckets {
+		r.BucketSizeBytes.WithLabelValues(store, b.Bucket, b.Owner).Set(float64(b.Size))
+		r.BucketObjects.WithLabelValues(store, b.Bucket, b.Owner).Set(float64(b.NumberOfObjects))
+		if users[b.Owner] == nil {
+			users[b.Owner] = &userTotal{}
+		}
+		users[b.Owner].size += b.Size
+		users[b.Owner].objects += b.NumberOfObjects
+	}
+	for user, total := range users {
+		r.UserSizeBytes.WithLabelValues(store, user).Set(float64(total.size))
+		r.UserObjects.WithLabelValues(store, user).Set(float64(total.objects))
+	}
+
+	usageLog, ok := r.usageLogs[store]
+	if !ok {
+		usageLog = rgw.NewUsageLog()
+		r.usageLogs[store] = usageLog
+	}
+	usage, err := usageLog.GetUsage(objContext, now)
+	if err != nil {
+		return err
+	}
+	for user, categories := range usage.Users {
+		for _, c := range categories {
+			r.UserOps.WithLabelValues(store, user, c.Category).Set(float64(c.Ops))
+			r.UserSuccessfulOps.WithLabelValues(store, user, c.Category).Set(float64(c.SuccessfulOps))
+			r.UserSentBytes.WithLabelValues(store, user, c.Category).Set(float64(c.BytesSent))
+			r.UserReceivedBytes.WithLabelValues(store, user, c.Category).Set(float64(c.BytesReceived))
+		}
+	}
+	for bucket, categories := range usage.Buckets {
+		for _, c := range categories {
+			r.BucketOps.WithLabelValues(store, bucket, c.Category).Set(float64(c.Ops))
+			r.BucketSuccessfulOps.WithLabelValues(store, bucket, c.Category).Set(float64(c.SuccessfulOps))
+			r.BucketSentBytes.WithLabelValues(store, bucket, c.Category).Set(float64(c.BytesSent))
+			r.BucketReceivedBytes.WithLabelValues(store, bucket, c.Category).Set(float64(c.BytesReceived))
+		}
+	}
+	return nil
+}
+
+// Describe fulfills the prometheus.Collector's interface and sends the descriptors
+// of the object store metrics to the given channel.
+func (r *RGWCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, metric := range r.collectorList() {
+		metric.Describe(ch)
+	}
+}
+
+// Collect extracts the current values of all the metrics and sends them to the
+// prometheus channel.
+func (r *RGWCollector) Collect(ch chan<- prometheus.Metric) {
+	if err := r.collect(); err != nil {
+		logger.Errorf("failed collecting object store metrics: %+v", err)
+		return
+	}
+
+	for _, metric := range r.collectorList() {
+		metric.Collect(ch)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package collectors
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rook/rook/pkg/ceph/rgw"
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+const rgwBucketStats = `[
+	{"bucket": "photos", "id": "a1.1", "owner": "alice", "usage": {"rgw.main": {"size": 2000, "num_objects": 20}}},
+	{"bucket": "logs", "id": "a1.2", "owner": "alice", "usage": {"rgw.main": {"size": 500, "num_objects": 5}}},
+	{"bucket": "backups", "id": "a1.3", "owner": "bob", "usage": {}}
+]`
+
+const rgwUsage = `{
+	"entries": [
+		{"user": "alice", "buckets": [
+			{"bucket": "photos", "epoch": 1519898400, "owner": "alice", "categories": [
+				{"category": "get_obj", "bytes_sent": 700, "bytes_received": 0, "ops": 7, "successful_ops": 6}]}
+		]}
+	]
+}`
+
+func TestRGWCollector(t *testing.T) {
+	bucketStats := 0
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			// the commands run in the realm of the zone
+			assert.Contains(t, args, "--rgw-realm=my-realm")
+			switch {
+			case args[0] == "bucket" && args[1] == "stats":
+				bucketStats++
+				return rgwBucketStats, nil
+			case args[0] == "usage" && args[1] == "show":
+				// the entries of the complete hours are only read once
+				if args[3] == "--start-date" {
+					return `{"entries": []}`, nil
+				}
+				return rgwUsage, nil
+			}
+			return "", fmt.Errorf("unexpected command %v", args)
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+	collector := NewRGWCollector(context, "mycluster", func() ([]*rgw.Context, error) {
+		objContext := rgw.NewContext(context, "my-store", "mycluster")
+		objContext.Realm = "my-realm"
+		return []*rgw.Context{objContext}, nil
+	})
+	if err := prometheus.Register(collector); err != nil {
+		t.Fatalf("collector failed to register: %s", err)
+	}
+	defer prometheus.Unregister(collector)
+
+	server := httptest.NewServer(prometheus.Handler())
+	defer server.Close()
+
+	// the metrics are the same on the next scrape, but the stats of the buckets are not read again
+	for i := 0; i < 2; i++ {
+		buf := scrape(t, server.URL)
+		for _, re := range []*regexp.Regexp{
+			regexp.MustCompile(`ceph_rgw_bucket_size_bytes{bucket="photos",owner="alice",store="my-store"} 2000`),
+			regexp.MustCompile(`ceph_rgw_bucket_objects_total{bucket="backups",owner="bob",store="my-store"} 0`),
+			regexp.MustCompile(`ceph_rgw_user_size_bytes{store="my-store",user="alice"} 2500`),
+			regexp.MustCompile(`ceph_rgw_user_objects_total{store="my-store",user="alice"} 25`),
+			regexp.MustCompile(`ceph_rgw_user_ops_total{category="get_obj",store="my-store",user="alice"} 7`),
+			regexp.MustCompile(`ceph_rgw_user_successful_ops_total{category="get_obj",store="my-store",user="alice"} 6`),
+			regexp.MustCompile(`ceph_rgw_user_sent_bytes_total{category="get_obj",store="my-store",user="alice"} 700`),
+			regexp.MustCompile(`ceph_rgw_bucket_ops_total{bucket="photos",category="get_obj",store="my-store"} 7`),
+		} {
+			assert.True(t, re.Match(buf), fmt.Sprintf("failed matching: %q", re))
+		}
+	}
+	assert.Equal(t, 1, bucketStats)
+}
+
+func scrape(t *testing.T, url string) []byte {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("unexpected failed response from prometheus: %s", err)
+	}
+	defer resp.Body.Close()
+
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed reading server response: %s", err)
+	}
+	return buf
+}
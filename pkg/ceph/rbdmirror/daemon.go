@@ -0,0 +1,227 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rbdmirror
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/rook/rook/pkg/ceph/mon"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/util"
+	"github.com/rook/rook/pkg/util/proc"
+)
+
+var (
+	logger          = capnslog.NewPackageLogger("github.com/rook/rook", "cephrbdmirror")
+	keyringTemplate = `
+[client.rbd-mirror.%s]
+	key = %s
+	caps mon = "profile rbd"
+	caps osd = "profile rbd"
+`
+	peerKeyringTemplate = `
+[client.%s]
+	key = %s
+`
+	peerConfigTemplate = `[global]
+mon host = %s
+
+[client.%s]
+keyring = %s
+`
+)
+
+const (
+	rbdMirror = "rbd-mirror"
+
+	// PeerMonEndpointsKey is the key in the peer secret with the mon endpoints of the peer cluster
+	PeerMonEndpointsKey = "monEndpoints"
+	// PeerUserKey is the key in the peer secret with the name of the ceph user for connecting to the peer cluster
+	PeerUserKey = "user"
+	// PeerSecretKey is the key in the peer secret with the cephx key of the ceph user
+	PeerSecretKey = "key"
+
+	// DefaultPeerConfigDir is the directory where ceph looks for the config of clusters other than the local cluster
+	DefaultPeerConfigDir = "/etc/ceph"
+)
+
+type Config struct {
+	InProc      bool
+	ClusterInfo *mon.ClusterInfo
+	Name        string
+	Keyring     string
+	// The directory with one subdirectory per peer cluster. Each subdirectory is named after the peer
+	// cluster and contains the peer secret keys as files.
+	PeersDir string
+	// The directory where the config and keyring for each peer cluster are written
+	PeerConfigDir string
+}
+
+func Run(context *clusterd.Context, config *Config) error {
+	logger.Infof("Starting rbd-mirror %s", config.Name)
+	err := generateConfigFiles(context, config)
+	if err != nil {
+		return fmt.Errorf("failed to generate rbd-mirror config files. %+v", err)
+	}
+
+	err = generatePeerConfigFiles(config)
+	if err != nil {
+		return fmt.Errorf("failed to generate rbd-mirror peer config files. %+v", err)
+	}
+
+	_, err = startRBDMirror(context, config)
+	if err != nil {
+		return fmt.Errorf("failed to run rbd-mirror. %+v", err)
+	}
+
+	return err
+}
+
+func generateConfigFiles(context *clusterd.Context, config *Config) error {
+	keyringPath := getKeyringPath(context.ConfigDir, config.Name)
+	confDir := getConfDir(context.ConfigDir, config.Name)
+	username := fmt.Sprintf("client.rbd-mirror.%s", config.Name)
+	logger.Infof("Conf files: dir=%s keyring=%s", confDir, keyringPath)
+	_, err := mon.GenerateConfigFile(context, config.ClusterInfo, confDir, username, keyringPath, false, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create config file. %+v", err)
+	}
+
+	keyringEval := func(key string) string {
+		return fmt.Sprintf(keyringTemplate, config.Name, key)
+	}
+
+	err = mon.WriteKeyring(keyringPath, config.Keyring, keyringEval)
+	if err != nil {
+		return fmt.Errorf("failed to create rbd-mirror keyring. %+v", err)
+	}
+
+	return nil
+}
+
+// generate the config and keyring for each peer cluster where ceph will find them by the name of the peer cluster
+func generatePeerConfigFiles(config *Config) error {
+	if config.PeersDir == "" {
+		return nil
+	}
+
+	peers, err := ioutil.ReadDir(config.PeersDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			logger.Infof("no mirroring peers found in %s", config.PeersDir)
+			return nil
+		}
+		return fmt.Errorf("failed to read peers dir %s. %+v", config.PeersDir, err)
+	}
+
+	if err := os.MkdirAll(config.PeerConfigDir, 0744); err != nil {
+		return fmt.Errorf("failed to create peer config dir %s. %+v", config.PeerConfigDir, err)
+	}
+
+	for _, peer := range peers {
+		if !peer.IsDir() || strings.HasPrefix(peer.Name(), ".") {
+			// skip the files and hidden dirs created by the secret volume mount
+			continue
+		}
+		if err := generatePeerConfig(config, peer.Name()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func generatePeerConfig(config *Config, peerName string) error {
+	peerDir := path.Join(config.PeersDir, peerName)
+	values := map[string]string{}
+	for _, key := range []string{PeerMonEndpointsKey, PeerUserKey, PeerSecretKey} {
+		value, err := ioutil.ReadFile(path.Join(peerDir, key))
+		if err != nil {
+			return fmt.Errorf("failed to read %s for peer %s. %+v", key, peerName, err)
+		}
+		values[key] = strings.TrimSpace(string(value))
+	}
+
+	var monHosts []string
+	for _, m := range mon.ParseMonEndpoints(values[PeerMonEndpointsKey]) {
+		monHosts = append(monHosts, m.Endpoint)
+	}
+	if len(monHosts) == 0 {
+		return fmt.Errorf("no mon endpoints found for peer %s", peerName)
+	}
+
+	user := values[PeerUserKey]
+	keyringPath := path.Join(config.PeerConfigDir, fmt.Sprintf("%s.client.%s.keyring", peerName, user))
+	keyring := fmt.Sprintf(peerKeyringTemplate, user, values[PeerSecretKey])
+	if err := ioutil.WriteFile(keyringPath, []byte(keyring), 0600); err != nil {
+		return fmt.Errorf("failed to write keyring for peer %s. %+v", peerName, err)
+	}
+
+	confPath := path.Join(config.PeerConfigDir, fmt.Sprintf("%s.conf", peerName))
+	conf := fmt.Sprintf(peerConfigTemplate, strings.Join(monHosts, ","), user, keyringPath)
+	if err := ioutil.WriteFile(confPath, []byte(conf), 0644); err != nil {
+		return fmt.Errorf("failed to write config for peer %s. %+v", peerName, err)
+	}
+
+	logger.Infof("generated config for mirroring peer %s with user %s", peerName, user)
+	return nil
+}
+
+func startRBDMirror(context *clusterd.Context, config *Config) (mirrorProc *proc.MonitoredProc, err error) {
+
+	// start the rbd-mirror daemon in the foreground with the given config
+	logger.Infof("starting rbd-mirror")
+
+	confFile := getConfFilePath(context.ConfigDir, config.Name, config.ClusterInfo.Name)
+	util.WriteFileToLog(logger, confFile)
+
+	keyringPath := getKeyringPath(context.ConfigDir, config.Name)
+	args := []string{
+		"--foreground",
+		fmt.Sprintf("--cluster=%s", config.ClusterInfo.Name),
+		fmt.Sprintf("--conf=%s", confFile),
+		fmt.Sprintf("--keyring=%s", keyringPath),
+		"--id", fmt.Sprintf("rbd-mirror.%s", config.Name),
+	}
+
+	if config.InProc {
+		err = context.ProcMan.Run(rbdMirror, rbdMirror, args...)
+	} else {
+		mirrorProc, err = context.ProcMan.Start(rbdMirror, rbdMirror, regexp.QuoteMeta(rbdMirror), proc.ReuseExisting, args...)
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to start rbd-mirror: %+v", err)
+	}
+	return
+}
+
+func getConfDir(dir, name string) string {
+	return path.Join(dir, fmt.Sprintf("rbd-mirror%s", name))
+}
+
+func getConfFilePath(dir, name, clusterName string) string {
+	return path.Join(getConfDir(dir, name), fmt.Sprintf("%s.config", clusterName))
+}
+
+func getKeyringPath(dir, name string) string {
+	return path.Join(getConfDir(dir, name), "keyring")
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rbdmirror
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeneratePeerConfig(t *testing.T) {
+	peersDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(peersDir)
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	config := &Config{Name: "a", PeersDir: peersDir, PeerConfigDir: configDir}
+
+	// no peers is not an error
+	err := generatePeerConfigFiles(config)
+	assert.Nil(t, err)
+
+	// the secret volume creates hidden dirs that must be ignored
+	os.MkdirAll(path.Join(peersDir, "..data"), 0755)
+
+	// a peer with missing settings fails
+	peerDir := path.Join(peersDir, "remote")
+	os.MkdirAll(peerDir, 0755)
+	ioutil.WriteFile(path.Join(peerDir, PeerMonEndpointsKey), []byte("a=1.2.3.4:6790,b=1.2.3.5:6790"), 0644)
+	err = generatePeerConfigFiles(config)
+	assert.NotNil(t, err)
+
+	// succeed with all the settings
+	ioutil.WriteFile(path.Join(peerDir, PeerUserKey), []byte("rbd-mirror-peer"), 0644)
+	ioutil.WriteFile(path.Join(peerDir, PeerSecretKey), []byte("mykey\n"), 0644)
+	err = generatePeerConfigFiles(config)
+	assert.Nil(t, err)
+
+	keyring, err := ioutil.ReadFile(path.Join(configDir, "remote.client.rbd-mirror-peer.keyring"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(keyring), "[client.rbd-mirror-peer]\n\tkey = mykey\n")
+
+	conf, err := ioutil.ReadFile(path.Join(configDir, "remote.conf"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(conf), "mon host = 1.2.3.")
+	assert.Contains(t, string(conf), "[client.rbd-mirror-peer]")
+	_, err = os.Stat(path.Join(configDir, "..data.conf"))
+	assert.True(t, os.IsNotExist(err))
+}
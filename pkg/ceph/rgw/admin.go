@@ -26,17 +26,30 @@ type Context struct {
 	context     *clusterd.Context
 	Name        string
 	ClusterName string
+	// The realm and zone group of the object store. They are named after the object store unless the store is a
+	// secondary zone in the realm of another cluster.
+	Realm     string
+	ZoneGroup string
 }
 
 func NewContext(context *clusterd.Context, name, clusterName string) *Context {
-	return &Context{context: context, Name: name, ClusterName: clusterName}
+	return &Context{context: context, Name: name, ClusterName: clusterName, Realm: name, ZoneGroup: name}
 }
 
 func runAdminCommandNoRealm(c *Context, args ...string) (string, error) {
+	return runAdminCommandWithDebug(c, false, args...)
+}
+
+// runSecretAdminCommand runs an admin command with keys in its arguments. The command is only logged at debug level.
+func runSecretAdminCommand(c *Context, args ...string) (string, error) {
+	return runAdminCommandWithDebug(c, true, args...)
+}
+
+func runAdminCommandWithDebug(c *Context, debug bool, args ...string) (string, error) {
 	options := client.AppendAdminConnectionArgs(args, c.context.ConfigDir, c.ClusterName)
 
 	// start the rgw admin command
-	output, err := c.context.Executor.ExecuteCommandWithCombinedOutput(false, "", "radosgw-admin", options...)
+	output, err := c.context.Executor.ExecuteCommandWithCombinedOutput(debug, "", "radosgw-admin", options...)
 	if err != nil {
 		return "", fmt.Errorf("failed to run radosgw-admin: %+v", err)
 	}
@@ -45,9 +58,17 @@ func runAdminCommandNoRealm(c *Context, args ...string) (string, error) {
 }
 
 func runAdminCommand(c *Context, args ...string) (string, error) {
-	options := []string{
-		fmt.Sprintf("--rgw-realm=%s", c.Name),
-		fmt.Sprintf("--rgw-zonegroup=%s", c.Name),
+	return runAdminCommandNoRealm(c, append(args, realmArgs(c)...)...)
+}
+
+// runSecretRealmAdminCommand runs an admin command in the realm of the object store with keys in its arguments
+func runSecretRealmAdminCommand(c *Context, args ...string) (string, error) {
+	return runSecretAdminCommand(c, append(args, realmArgs(c)...)...)
+}
+
+func realmArgs(c *Context) []string {
+	return []string{
+		fmt.Sprintf("--rgw-realm=%s", c.Realm),
+		fmt.Sprintf("--rgw-zonegroup=%s", c.ZoneGroup),
 	}
-	return runAdminCommandNoRealm(c, append(args, options...)...)
 }
@@ -0,0 +1,125 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// the default keystone api version
+	defaultKeystoneAPIVersion = 3
+)
+
+var passwordSetting = regexp.MustCompile(`(?m)^(\s*\S*password\S*\s*=\s*).*$`)
+
+// LDAPConfig is the ldap server that authenticates the s3 requests of the users that are not local rgw users
+type LDAPConfig struct {
+	// The uri of the ldap server, e.g. ldaps://ldap.example.com:636
+	URI string
+	// The dn of the service account that searches the users
+	BindDN string
+	// The path of the file with the password of the service account
+	SecretPath string
+	// The base dn where the users are searched
+	SearchDN string
+	// The attribute of the user dn with the user name, e.g. uid
+	DNAttribute string
+	// An optional filter of the users, e.g. (memberOf=cn=s3,ou=groups,dc=example,dc=com)
+	SearchFilter string
+}
+
+// KeystoneConfig is the openstack keystone service that authenticates the swift and s3 requests
+type KeystoneConfig struct {
+	// The url of the keystone service, e.g. https://keystone.example.com:5000
+	URL string
+	// The keystone api version, 2 or 3. Default is 3.
+	APIVersion int
+	// The credentials of the keystone admin user that validates the tokens
+	AdminUser     string
+	AdminPassword string
+	AdminProject  string
+	AdminDomain   string
+	// The keystone roles of the users that are allowed to access the object store
+	AcceptedRoles []string
+	// Whether each keystone project gets its own rgw tenant
+	ImplicitTenants bool
+	// Whether the s3 requests are also authenticated with the ec2 credentials of keystone
+	S3 bool
+	// Whether the certificate of the keystone service is not verified
+	InsecureSkipVerify bool
+}
+
+// authSettings gets the rgw settings of the ldap and keystone authentication
+func authSettings(config *Config) map[string]string {
+	settings := map[string]string{}
+	if ldap := config.LDAP; ldap != nil {
+		settings["rgw_s3_auth_use_ldap"] = "true"
+		settings["rgw_ldap_uri"] = ldap.URI
+		settings["rgw_ldap_binddn"] = ldap.BindDN
+		settings["rgw_ldap_secret"] = ldap.SecretPath
+		settings["rgw_ldap_searchdn"] = ldap.SearchDN
+		if ldap.DNAttribute != "" {
+			settings["rgw_ldap_dnattr"] = ldap.DNAttribute
+		}
+		if ldap.SearchFilter != "" {
+			settings["rgw_ldap_searchfilter"] = ldap.SearchFilter
+		}
+	}
+
+	if keystone := config.Keystone; keystone != nil {
+		version := keystone.APIVersion
+		if version == 0 {
+			version = defaultKeystoneAPIVersion
+		}
+		settings["rgw_keystone_url"] = keystone.URL
+		settings["rgw_keystone_api_version"] = strconv.Itoa(version)
+		settings["rgw_keystone_admin_user"] = keystone.AdminUser
+		settings["rgw_keystone_admin_password"] = keystone.AdminPassword
+		if version == 2 {
+			// the projects are called tenants in the v2 api
+			settings["rgw_keystone_admin_tenant"] = keystone.AdminProject
+		} else {
+			settings["rgw_keystone_admin_project"] = keystone.AdminProject
+			settings["rgw_keystone_admin_domain"] = keystone.AdminDomain
+		}
+		if len(keystone.AcceptedRoles) > 0 {
+			settings["rgw_keystone_accepted_roles"] = strings.Join(keystone.AcceptedRoles, ",")
+		}
+		settings["rgw_keystone_implicit_tenants"] = strconv.FormatBool(keystone.ImplicitTenants)
+		settings["rgw_s3_auth_use_keystone"] = strconv.FormatBool(keystone.S3)
+		settings["rgw_keystone_verify_ssl"] = strconv.FormatBool(!keystone.InsecureSkipVerify)
+	}
+	return settings
+}
+
+// writeConfigToLog writes the config file to the log without the passwords
+func writeConfigToLog(path string) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		logger.Warningf("failed to write file %s to log: %+v", path, err)
+		return
+	}
+
+	logger.Infof("Config file %s:\n%s", path, redactPasswords(string(contents)))
+}
+
+func redactPasswords(config string) string {
+	return passwordSetting.ReplaceAllString(config, "${1}*****")
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthSettings(t *testing.T) {
+	// no external authentication
+	assert.Equal(t, 0, len(authSettings(&Config{})))
+
+	// ldap
+	cfg := &Config{LDAP: &LDAPConfig{
+		URI:        "ldaps://ldap.example.com:636",
+		BindDN:     "uid=rgw,ou=services,dc=example,dc=com",
+		SecretPath: "/etc/rook/ldap/password",
+		SearchDN:   "ou=users,dc=example,dc=com",
+	}}
+	settings := authSettings(cfg)
+	assert.Equal(t, 5, len(settings))
+	assert.Equal(t, "true", settings["rgw_s3_auth_use_ldap"])
+	assert.Equal(t, "ldaps://ldap.example.com:636", settings["rgw_ldap_uri"])
+	assert.Equal(t, "/etc/rook/ldap/password", settings["rgw_ldap_secret"])
+
+	cfg.LDAP.DNAttribute = "uid"
+	cfg.LDAP.SearchFilter = "(memberOf=cn=s3,ou=groups,dc=example,dc=com)"
+	settings = authSettings(cfg)
+	assert.Equal(t, "uid", settings["rgw_ldap_dnattr"])
+	assert.Equal(t, "(memberOf=cn=s3,ou=groups,dc=example,dc=com)", settings["rgw_ldap_searchfilter"])
+
+	// keystone v3 by default
+	cfg = &Config{Keystone: &KeystoneConfig{
+		URL:           "https://keystone.example.com:5000",
+		AdminUser:     "rgw",
+		AdminPassword: "pass",
+		AdminProject:  "service",
+		AdminDomain:   "default",
+		AcceptedRoles: []string{"member", "admin"},
+		S3:            true,
+	}}
+	settings = authSettings(cfg)
+	assert.Equal(t, "3", settings["rgw_keystone_api_version"])
+	assert.Equal(t, "service", settings["rgw_keystone_admin_project"])
+	assert.Equal(t, "default", settings["rgw_keystone_admin_domain"])
+	assert.Equal(t, "member,admin", settings["rgw_keystone_accepted_roles"])
+	assert.Equal(t, "false", settings["rgw_keystone_implicit_tenants"])
+	assert.Equal(t, "true", settings["rgw_s3_auth_use_keystone"])
+	assert.Equal(t, "true", settings["rgw_keystone_verify_ssl"])
+	_, ok := settings["rgw_keystone_admin_tenant"]
+	assert.False(t, ok)
+
+	// the project is a tenant in keystone v2
+	cfg.Keystone.APIVersion = 2
+	settings = authSettings(cfg)
+	assert.Equal(t, "service", settings["rgw_keystone_admin_tenant"])
+	_, ok = settings["rgw_keystone_admin_project"]
+	assert.False(t, ok)
+}
+
+func TestRedactPasswords(t *testing.T) {
+	config := `[client.radosgw.gateway]
+rgw_keystone_admin_user     = rgw
+rgw_keystone_admin_password = secret pass
+rgw_frontends               = civetweb port=80
+`
+	redacted := redactPasswords(config)
+	assert.Contains(t, redacted, "rgw_keystone_admin_user     = rgw\n")
+	assert.Contains(t, redacted, "rgw_keystone_admin_password = *****\n")
+	assert.NotContains(t, redacted, "secret pass")
+	assert.Contains(t, redacted, "rgw_frontends               = civetweb port=80\n")
+}
@@ -26,6 +26,8 @@ import (
 
 type rgwBucketStats struct {
 	Bucket string `json:"bucket"`
+	ID     string `json:"id"`
+	Owner  string `json:"owner"`
 	Usage  map[string]struct {
 		Size            uint64 `json:"size"`
 		NumberOfObjects uint64 `json:"num_objects"`
@@ -182,3 +184,105 @@ func DeleteBucket(c *Context, bucketName string, purge bool) (int, error) {
 
 	return RGWErrorUnknown, fmt.Errorf("failed to delete bucket: %+v", err)
 }
+
+// GetBucketOwner gets the owner and the id of the bucket. The id is different for each bucket that was created with
+// the name.
+func GetBucketOwner(c *Context, bucketName string) (string, string, int, error) {
+	stats, code, err := getBucketStats(c, bucketName)
+	if err != nil {
+		return "", "", code, err
+	}
+	return stats.Owner, stats.ID, RGWErrorNone, nil
+}
+
+// SetBucketQuota sets and enables the quota of the bucket, or disables the quota if nil
+func SetBucketQuota(c *Context, bucketName string, quota *ObjectQuota) (int, error) {
+	if _, code, err := getBucketStats(c, bucketName); err != nil {
+		return code, err
+	}
+	if quota != nil && (quota.MaxSize < 0 || quota.MaxObjects < 0) {
+		return RGWErrorBadData, fmt.Errorf("quotas cannot be negative")
+	}
+
+	logger.Infof("Setting quota of bucket %s", bucketName)
+	if err := setQuota(c, "bucket", []string{"--bucket", bucketName}, quota); err != nil {
+		return RGWErrorUnknown, fmt.Errorf("failed to set quota of bucket %s: %+v", bucketName, err)
+	}
+	return RGWErrorNone, nil
+}
+
+// LinkBucket transfers the bucket to the new owner. Linking the bucket by its id moves it from its current owner to
+// the new owner in a single command, so the bucket is never left without an owner. The objects in the bucket keep their
+// owner and ACLs.
+func LinkBucket(c *Context, bucketName, owner string) (int, error) {
+	stats, code, err := getBucketStats(c, bucketName)
+	if err != nil {
+		return code, err
+	}
+	if _, code, err := getUserInfo(c, owner); err != nil {
+		if code == RGWErrorNotFound {
+			return RGWErrorBadData, fmt.Errorf("owner %s not found", owner)
+		}
+		return code, err
+	}
+	if stats.Owner == owner {
+		return RGWErrorNone, nil
+	}
+
+	logger.Infof("Linking bucket %s to user %s", bucketName, owner)
+	if _, err := runAdminCommand(c, "bucket", "link", "--bucket", bucketName, "--bucket-id", stats.ID, "--uid", owner); err != nil {
+		return RGWErrorUnknown, fmt.Errorf("failed to link bucket %s to user %s: %+v", bucketName, owner, err)
+	}
+	return RGWErrorNone, nil
+}
+
+// ReshardBucket reshards the index of the bucket to the number of shards
+func ReshardBucket(c *Context, bucketName string, numShards int) (int, error) {
+	if numShards <= 0 {
+		return RGWErrorBadData, fmt.Errorf("the number of shards must be positive")
+	}
+	if _, code, err := getBucketStats(c, bucketName); err != nil {
+		return code, err
+	}
+
+	logger.Infof("Resharding index of bucket %s to %d shards", bucketName, numShards)
+	if _, err := runAdminCommand(c, "bucket", "reshard", "--bucket", bucketName, "--num-shards", fmt.Sprintf("%d", numShards)); err != nil {
+		return RGWErrorUnknown, fmt.Errorf("failed to reshard bucket %s: %+v", bucketName, err)
+	}
+	return RGWErrorNone, nil
+}
+
+// CheckBucketIndex checks the index of the bucket against its objects and repairs the index if fix is true. The
+// result is the report of radosgw-admin.
+func CheckBucketIndex(c *Context, bucketName string, fix bool) (string, int, error) {
+	if _, code, err := getBucketStats(c, bucketName); err != nil {
+		return "", code, err
+	}
+
+	args := []string{"bucket", "check", "--bucket", bucketName, "--check-objects"}
+	if fix {
+		logger.Infof("Repairing index of bucket %s", bucketName)
+		args = append(args, "--fix")
+	}
+	result, err := runAdminCommand(c, args...)
+	if err != nil {
+		return "", RGWErrorUnknown, fmt.Errorf("failed to check index of bucket %s: %+v", bucketName, err)
+	}
+	return result, RGWErrorNone, nil
+}
+
+func getBucketStats(c *Context, bucketName string) (*rgwBucketStats, int, error) {
+	result, err := runAdminCommand(c, "bucket", "stats", "--bucket", bucketName)
+	if err != nil {
+		return nil, RGWErrorUnknown, fmt.Errorf("failed to get bucket stats: %+v", err)
+	}
+	if strings.Contains(result, "could not get bucket info") {
+		return nil, RGWErrorNotFound, fmt.Errorf("Bucket not found")
+	}
+
+	var stats rgwBucketStats
+	if err := json.Unmarshal([]byte(result), &stats); err != nil {
+		return nil, RGWErrorParse, fmt.Errorf("failed to read bucket stats. %+v, result=%s", err, result)
+	}
+	return &stats, RGWErrorNone, nil
+}
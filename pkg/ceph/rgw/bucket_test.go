@@ -0,0 +1,90 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucketAdmin(t *testing.T) {
+	commands := []string{}
+	bucketExists := true
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			switch {
+			case args[0] == "bucket" && args[1] == "stats":
+				if !bucketExists {
+					return "failure: 2: could not get bucket info for bucket=photos", nil
+				}
+				return `{"bucket":"photos","id":"a1b2.4567.1","owner":"alice","usage":{}}`, nil
+			case args[0] == "user" && args[1] == "info":
+				return `{"user_id":"bob"}`, nil
+			}
+			// skip the realm and connection args
+			end := 0
+			for end < len(args) && !strings.HasPrefix(args[end], "--rgw-realm") {
+				end++
+			}
+			commands = append(commands, strings.Join(args[:end], " "))
+			return "", nil
+		},
+	}
+	objContext := NewContext(&clusterd.Context{Executor: executor}, "my-store", "rook")
+
+	_, err := SetBucketQuota(objContext, "photos", &ObjectQuota{MaxObjects: 1000})
+	assert.Nil(t, err)
+	_, err = SetBucketQuota(objContext, "photos", nil)
+	assert.Nil(t, err)
+	_, err = LinkBucket(objContext, "photos", "bob")
+	assert.Nil(t, err)
+	_, err = ReshardBucket(objContext, "photos", 64)
+	assert.Nil(t, err)
+	_, _, err = CheckBucketIndex(objContext, "photos", true)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"quota set --quota-scope bucket --bucket photos --max-size -1 --max-objects 1000",
+		"quota enable --quota-scope bucket --bucket photos",
+		"quota disable --quota-scope bucket --bucket photos",
+		"bucket link --bucket photos --bucket-id a1b2.4567.1 --uid bob",
+		"bucket reshard --bucket photos --num-shards 64",
+		"bucket check --bucket photos --check-objects --fix",
+	}, commands)
+
+	// the bucket is not changed when it is already owned by the user
+	commands = []string{}
+	_, err = LinkBucket(objContext, "photos", "alice")
+	assert.Nil(t, err)
+	assert.Empty(t, commands)
+
+	// invalid settings
+	code, err := ReshardBucket(objContext, "photos", 0)
+	assert.NotNil(t, err)
+	assert.Equal(t, RGWErrorBadData, code)
+	code, err = SetBucketQuota(objContext, "photos", &ObjectQuota{MaxSize: -1})
+	assert.NotNil(t, err)
+	assert.Equal(t, RGWErrorBadData, code)
+
+	// the bucket is not found
+	bucketExists = false
+	code, err = ReshardBucket(objContext, "photos", 64)
+	assert.NotNil(t, err)
+	assert.Equal(t, RGWErrorNotFound, code)
+}
@@ -21,27 +21,51 @@ import (
 	"os"
 	"path"
 	"regexp"
-
 	"strconv"
+	"strings"
 
 	"github.com/coreos/pkg/capnslog"
 	"github.com/rook/rook/pkg/ceph/mon"
 	"github.com/rook/rook/pkg/clusterd"
-	"github.com/rook/rook/pkg/util"
 	"github.com/rook/rook/pkg/util/proc"
 )
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "cephrgw")
 
+const (
+	// FrontendCivetweb is the civetweb http frontend of rgw, which is the default
+	FrontendCivetweb = "civetweb"
+	// FrontendBeast is the beast http frontend of rgw
+	FrontendBeast = "beast"
+)
+
 type Config struct {
-	Name            string
-	Host            string
+	Name      string
+	Realm     string
+	ZoneGroup string
+	Host      string
+	// The domain of the virtual-host-style requests to the buckets. Default is the host.
+	DNSName         string
 	Port            int
 	SecurePort      int
 	Keyring         string
 	CertificatePath string
-	InProc          bool
-	ClusterInfo     *mon.ClusterInfo
+	// The path of the private key when it is not in the certificate file
+	KeyPath string
+	// The http frontend, either civetweb or beast. Default is civetweb.
+	Frontend string
+	// The number of threads that serve the requests. Default is the rgw default.
+	ThreadPoolSize int
+	// The timeout of the requests in milliseconds. Default is the frontend default.
+	RequestTimeoutMs int
+	// Whether the http port redirects to the https port
+	RedirectHTTP bool
+	// The ldap authentication of the s3 users, if enabled
+	LDAP *LDAPConfig
+	// The keystone authentication of the swift and s3 users, if enabled
+	Keystone    *KeystoneConfig
+	InProc      bool
+	ClusterInfo *mon.ClusterInfo
 }
 
 func Run(context *clusterd.Context, config *Config) error {
@@ -64,6 +88,10 @@ func portString(config *Config) string {
 	var portString string
 	if config.Port != 0 {
 		portString = strconv.Itoa(config.Port)
+		if config.RedirectHTTP && config.SecurePort != 0 && config.CertificatePath != "" {
+			// with the suffix r, civetweb redirects the requests on the port to the secure port
+			portString += "r"
+		}
 	}
 	if config.SecurePort != 0 && config.CertificatePath != "" {
 		var separator string
@@ -78,6 +106,55 @@ func portString(config *Config) string {
 	return portString
 }
 
+// frontendString gets the rgw_frontends setting of the frontend with its ports, certificate and options
+func frontendString(config *Config) (string, error) {
+	var options []string
+	switch config.Frontend {
+	case "", FrontendCivetweb:
+		options = append(options, FrontendCivetweb, fmt.Sprintf("port=%s", portString(config)))
+	case FrontendBeast:
+		// the beast frontend of luminous only serves http on a port and has no other options
+		if config.RedirectHTTP {
+			return "", fmt.Errorf("the beast frontend does not redirect http to https")
+		}
+		if config.SecurePort != 0 {
+			return "", fmt.Errorf("the beast frontend does not support ssl")
+		}
+		if config.RequestTimeoutMs != 0 {
+			return "", fmt.Errorf("the beast frontend does not support the request timeout")
+		}
+		options = append(options, FrontendBeast, fmt.Sprintf("port=%d", config.Port))
+	default:
+		return "", fmt.Errorf("unknown frontend %s", config.Frontend)
+	}
+
+	if config.RequestTimeoutMs != 0 {
+		options = append(options, fmt.Sprintf("request_timeout_ms=%d", config.RequestTimeoutMs))
+	}
+	return strings.Join(options, " "), nil
+}
+
+// writeCertificate writes the private key and the certificate to a single pem file in the config dir, which is the
+// format expected by civetweb. The config is updated with the path of the pem file.
+func writeCertificate(context *clusterd.Context, config *Config) error {
+	key, err := ioutil.ReadFile(config.KeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read private key %s. %+v", config.KeyPath, err)
+	}
+	cert, err := ioutil.ReadFile(config.CertificatePath)
+	if err != nil {
+		return fmt.Errorf("failed to read certificate %s. %+v", config.CertificatePath, err)
+	}
+
+	pemPath := getCertificatePath(context.ConfigDir)
+	if err := ioutil.WriteFile(pemPath, append(append(key, '\n'), cert...), 0600); err != nil {
+		return fmt.Errorf("failed to write certificate to %s. %+v", pemPath, err)
+	}
+	config.CertificatePath = pemPath
+	config.KeyPath = ""
+	return nil
+}
+
 func generateConfigFiles(context *clusterd.Context, config *Config) error {
 
 	// create the rgw data directory
@@ -86,22 +163,53 @@ func generateConfigFiles(context *clusterd.Context, config *Config) error {
 		logger.Warningf("failed to create data directory %s: %+v", dataDir, err)
 	}
 
+	// civetweb only accepts the private key in the same file as the certificate
+	if config.KeyPath != "" && config.SecurePort != 0 {
+		if err := writeCertificate(context, config); err != nil {
+			return err
+		}
+	}
+	frontend, err := frontendString(config)
+	if err != nil {
+		return fmt.Errorf("invalid frontend. %+v", err)
+	}
+
+	dnsName := config.DNSName
+	if dnsName == "" {
+		dnsName = config.Host
+	}
 	settings := map[string]string{
 		"host":                           config.Host,
 		"rgw data":                       dataDir,
-		"rgw dns name":                   config.Host,
+		"rgw dns name":                   dnsName,
 		"rgw log nonexistent bucket":     "true",
 		"rgw intent log object name utc": "true",
 		"rgw enable usage log":           "true",
-		"rgw_frontends":                  fmt.Sprintf("civetweb port=%s", portString(config)),
+		"rgw_frontends":                  frontend,
 		"rgw_zone":                       config.Name,
 		"rgw_zonegroup":                  config.Name,
 	}
-	_, err := mon.GenerateConfigFile(context, config.ClusterInfo, getRGWConfDir(context.ConfigDir),
+	if config.Realm != "" {
+		// the zone of a store that joined the realm of another cluster is in the zone group of that realm
+		settings["rgw_realm"] = config.Realm
+		settings["rgw_zonegroup"] = config.ZoneGroup
+	}
+	for key, value := range authSettings(config) {
+		settings[key] = value
+	}
+	if config.ThreadPoolSize != 0 {
+		// civetweb and beast serve the requests with the threads of the rgw thread pool
+		settings["rgw_thread_pool_size"] = strconv.Itoa(config.ThreadPoolSize)
+	}
+	confFile, err := mon.GenerateConfigFile(context, config.ClusterInfo, getRGWConfDir(context.ConfigDir),
 		"client.radosgw.gateway", getRGWKeyringPath(context.ConfigDir), false, nil, settings)
 	if err != nil {
 		return fmt.Errorf("failed to create config file. %+v", err)
 	}
+	// rgw only reads the keystone admin password from the config, so only rgw can read the config
+	if err := os.Chmod(confFile, 0600); err != nil {
+		return fmt.Errorf("failed to restrict the permissions of config file %s. %+v", confFile, err)
+	}
 
 	keyringEval := func(key string) string {
 		return fmt.Sprintf(keyringTemplate, key)
@@ -128,7 +236,7 @@ func startRGW(context *clusterd.Context, config *Config) (rgwProc *proc.Monitore
 	logger.Infof("starting rgw")
 
 	confFile := getRGWConfFilePath(context.ConfigDir, config.ClusterInfo.Name)
-	util.WriteFileToLog(logger, confFile)
+	writeConfigToLog(confFile)
 
 	rgwNameArg := "--name=client.radosgw.gateway"
 	args := []string{
@@ -162,6 +270,10 @@ func getRGWKeyringPath(configDir string) string {
 	return path.Join(getRGWConfDir(configDir), "keyring")
 }
 
+func getCertificatePath(configDir string) string {
+	return path.Join(getRGWConfDir(configDir), "rgw-cert.pem")
+}
+
 func getMimeTypesPath(configDir string) string {
 	return path.Join(getRGWConfDir(configDir), "mime.types")
 }
@@ -16,8 +16,13 @@ limitations under the License.
 package rgw
 
 import (
+	"io/ioutil"
+	"os"
+	"path"
 	"testing"
 
+	"github.com/rook/rook/pkg/clusterd"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -46,4 +51,73 @@ func TestPortString(t *testing.T) {
 	cfg = &Config{SecurePort: 443}
 	result = portString(cfg)
 	assert.Equal(t, "", result)
+
+	// Redirect to the secure port
+	cfg = &Config{Port: 80, SecurePort: 443, CertificatePath: "/etc/rgw/cert.pem", RedirectHTTP: true}
+	result = portString(cfg)
+	assert.Equal(t, "80r+443s ssl_certificate=/etc/rgw/cert.pem", result)
+
+	// No redirect without the secure port
+	cfg = &Config{Port: 80, RedirectHTTP: true}
+	result = portString(cfg)
+	assert.Equal(t, "80", result)
+}
+
+func TestFrontendString(t *testing.T) {
+	// civetweb is the default
+	cfg := &Config{Port: 80, RequestTimeoutMs: 30000}
+	result, err := frontendString(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "civetweb port=80 request_timeout_ms=30000", result)
+
+	// beast only serves http
+	cfg = &Config{Frontend: FrontendBeast, Port: 80}
+	result, err = frontendString(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "beast port=80", result)
+
+	// beast does not support ssl or the request timeout
+	cfg = &Config{Frontend: FrontendBeast, Port: 80, SecurePort: 443, CertificatePath: "/etc/rgw/tls.crt", KeyPath: "/etc/rgw/tls.key"}
+	_, err = frontendString(cfg)
+	assert.NotNil(t, err)
+	cfg = &Config{Frontend: FrontendBeast, Port: 80, RequestTimeoutMs: 30000}
+	_, err = frontendString(cfg)
+	assert.NotNil(t, err)
+
+	// beast does not redirect
+	cfg = &Config{Frontend: FrontendBeast, Port: 80, SecurePort: 443, CertificatePath: "/etc/rgw/cert.pem", RedirectHTTP: true}
+	_, err = frontendString(cfg)
+	assert.NotNil(t, err)
+
+	// unknown frontend
+	cfg = &Config{Frontend: "apache", Port: 80}
+	_, err = frontendString(cfg)
+	assert.NotNil(t, err)
+}
+
+func TestWriteCertificate(t *testing.T) {
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	os.MkdirAll(getRGWConfDir(configDir), 0744)
+	context := &clusterd.Context{ConfigDir: configDir}
+
+	keyPath := path.Join(configDir, "tls.key")
+	certPath := path.Join(configDir, "tls.crt")
+	ioutil.WriteFile(keyPath, []byte("mykey"), 0600)
+	ioutil.WriteFile(certPath, []byte("mycert"), 0600)
+
+	// the key and the cert are combined in a single pem for civetweb
+	cfg := &Config{Port: 80, SecurePort: 443, CertificatePath: certPath, KeyPath: keyPath}
+	err := writeCertificate(context, cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, getCertificatePath(configDir), cfg.CertificatePath)
+	assert.Equal(t, "", cfg.KeyPath)
+	pem, err := ioutil.ReadFile(cfg.CertificatePath)
+	assert.Nil(t, err)
+	assert.Equal(t, "mykey\nmycert", string(pem))
+
+	// a missing key fails
+	cfg = &Config{SecurePort: 443, CertificatePath: certPath, KeyPath: path.Join(configDir, "missing")}
+	err = writeCertificate(context, cfg)
+	assert.NotNil(t, err)
 }
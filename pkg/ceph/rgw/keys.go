@@ -0,0 +1,263 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rook/rook/pkg/model"
+)
+
+const (
+	// KeyTypeS3 is the type of the keys to access the S3 API
+	KeyTypeS3 = "s3"
+	// KeyTypeSwift is the type of the keys of subusers to access the swift API
+	KeyTypeSwift = "swift"
+)
+
+// the access of a subuser to the --access arg of radosgw-admin and the permissions in the user info
+var subuserAccess = map[string]string{
+	"read":      "read",
+	"write":     "write",
+	"readwrite": "read-write",
+	"full":      "full-control",
+}
+
+// ListUserKeys lists the S3 keys of the user and the swift keys of its subusers
+func ListUserKeys(c *Context, id string) ([]model.ObjectUserKey, int, error) {
+	user, code, err := getUserInfo(c, id)
+	if err != nil {
+		return nil, code, err
+	}
+	return userKeys(user), RGWErrorNone, nil
+}
+
+// CreateUserKey adds a key to the user. An S3 key is added to the user and a swift key replaces the key of the
+// subuser of the key. The access and secret keys are generated unless they are set in the key.
+func CreateUserKey(c *Context, id string, key model.ObjectUserKey) (*model.ObjectUserKey, int, error) {
+	logger.Infof("Creating %s key for user: %s", key.Type, id)
+
+	args := []string{"key", "create", "--uid", id}
+	switch key.Type {
+	case "", KeyTypeS3:
+		key.Type = KeyTypeS3
+		if key.AccessKey == "" {
+			args = append(args, "--gen-access-key")
+		} else {
+			args = append(args, fmt.Sprintf("--access-key=%s", key.AccessKey))
+		}
+	case KeyTypeSwift:
+		if !strings.HasPrefix(key.User, id+":") {
+			return nil, RGWErrorBadData, fmt.Errorf("the subuser of a swift key is required, e.g. %s:swift", id)
+		}
+		args = append(args, "--subuser", key.User)
+	default:
+		return nil, RGWErrorBadData, fmt.Errorf("invalid key type %s", key.Type)
+	}
+	args = append(args, "--key-type", key.Type)
+	if key.SecretKey == "" {
+		args = append(args, "--gen-secret")
+	} else {
+		args = append(args, fmt.Sprintf("--secret=%s", key.SecretKey))
+	}
+
+	before, code, err := ListUserKeys(c, id)
+	if err != nil {
+		return nil, code, err
+	}
+
+	result, err := runSecretRealmAdminCommand(c, args...)
+	if err != nil {
+		return nil, RGWErrorUnknown, fmt.Errorf("failed to create key: %+v", err)
+	}
+	var user rgwUserInfo
+	if err := json.Unmarshal([]byte(result), &user); err != nil {
+		return nil, RGWErrorParse, fmt.Errorf("failed to read user info. %+v", err)
+	}
+
+	// the new key is the key that was not in the keys before
+	for _, created := range userKeys(&user) {
+		if created.Type == key.Type && !containsKey(before, created) {
+			return &created, RGWErrorNone, nil
+		}
+	}
+	return nil, RGWErrorBadData, fmt.Errorf("the key was not created. the access key may already be in use")
+}
+
+// DeleteUserKey removes the S3 key with the access key, or the swift key of the subuser if the access key is the name
+// of a subuser, e.g. "alice:swift"
+func DeleteUserKey(c *Context, id, accessKey string) (int, error) {
+	logger.Infof("Deleting key of user: %s", id)
+
+	keys, code, err := ListUserKeys(c, id)
+	if err != nil {
+		return code, err
+	}
+
+	var args []string
+	for _, key := range keys {
+		if key.Type == KeyTypeS3 && key.AccessKey == accessKey {
+			args = []string{"key", "rm", "--uid", id, "--key-type", KeyTypeS3, fmt.Sprintf("--access-key=%s", accessKey)}
+			break
+		}
+		if key.Type == KeyTypeSwift && key.User == accessKey {
+			args = []string{"key", "rm", "--uid", id, "--subuser", accessKey, "--key-type", KeyTypeSwift}
+			break
+		}
+	}
+	if args == nil {
+		return RGWErrorNotFound, fmt.Errorf("key not found")
+	}
+
+	if _, err := runSecretRealmAdminCommand(c, args...); err != nil {
+		return RGWErrorUnknown, fmt.Errorf("failed to delete key: %+v", err)
+	}
+	return RGWErrorNone, nil
+}
+
+// RotateUserKeys adds a generated S3 key to the user and returns the new key and the access keys of the old S3 keys.
+// The old keys are not revoked so the apps can switch to the new key first.
+func RotateUserKeys(c *Context, id string) (*model.ObjectUserKey, []string, int, error) {
+	keys, code, err := ListUserKeys(c, id)
+	if err != nil {
+		return nil, nil, code, err
+	}
+
+	created, code, err := CreateUserKey(c, id, model.ObjectUserKey{Type: KeyTypeS3})
+	if err != nil {
+		return nil, nil, code, err
+	}
+
+	oldKeys := []string{}
+	for _, key := range keys {
+		if key.Type == KeyTypeS3 && key.AccessKey != created.AccessKey {
+			oldKeys = append(oldKeys, key.AccessKey)
+		}
+	}
+	return created, oldKeys, RGWErrorNone, nil
+}
+
+// CreateSubuser creates a swift subuser of the user with a generated swift key
+func CreateSubuser(c *Context, id string, subuser model.ObjectSubuser) (*model.ObjectUserKey, int, error) {
+	logger.Infof("Creating subuser %s of user: %s", subuser.Name, id)
+	if IsReservedUser(id) {
+		return nil, RGWErrorNotFound, fmt.Errorf("user not found")
+	}
+
+	if subuser.Name == "" || strings.Contains(subuser.Name, ":") {
+		return nil, RGWErrorBadData, fmt.Errorf("invalid subuser name %q", subuser.Name)
+	}
+	if _, ok := subuserAccess[subuser.Access]; !ok {
+		return nil, RGWErrorBadData, fmt.Errorf("invalid access %q. the access must be read, write, readwrite or full", subuser.Access)
+	}
+
+	name := fmt.Sprintf("%s:%s", id, subuser.Name)
+	result, err := runAdminCommand(c, "subuser", "create", "--uid", id, "--subuser", name,
+		"--access", subuser.Access, "--key-type", KeyTypeSwift, "--gen-secret")
+	if err != nil {
+		return nil, RGWErrorUnknown, fmt.Errorf("failed to create subuser: %+v", err)
+	}
+	var user rgwUserInfo
+	if err := json.Unmarshal([]byte(result), &user); err != nil {
+		return nil, RGWErrorParse, fmt.Errorf("failed to read user info. %+v, result=%s", err, result)
+	}
+
+	for _, key := range userKeys(&user) {
+		if key.Type == KeyTypeSwift && key.User == name {
+			return &key, RGWErrorNone, nil
+		}
+	}
+	return nil, RGWErrorUnknown, fmt.Errorf("swift key of subuser %s not found", name)
+}
+
+// DeleteSubuser removes the subuser and its keys
+func DeleteSubuser(c *Context, id, name string) (int, error) {
+	logger.Infof("Deleting subuser %s of user: %s", name, id)
+
+	user, code, err := getUserInfo(c, id)
+	if err != nil {
+		return code, err
+	}
+	found := false
+	for _, subuser := range subusers(user) {
+		found = found || subuser.Name == name
+	}
+	if !found {
+		return RGWErrorNotFound, fmt.Errorf("subuser not found")
+	}
+
+	_, err = runAdminCommand(c, "subuser", "rm", "--uid", id, "--subuser", fmt.Sprintf("%s:%s", id, name), "--purge-keys")
+	if err != nil {
+		return RGWErrorUnknown, fmt.Errorf("failed to delete subuser: %+v", err)
+	}
+	return RGWErrorNone, nil
+}
+
+func getUserInfo(c *Context, id string) (*rgwUserInfo, int, error) {
+	if IsReservedUser(id) {
+		return nil, RGWErrorNotFound, fmt.Errorf("user not found")
+	}
+	result, err := runAdminCommand(c, "user", "info", "--uid", id)
+	if err != nil {
+		return nil, RGWErrorUnknown, fmt.Errorf("failed to get user: %+v", err)
+	}
+	if result == "could not fetch user info: no user info saved" {
+		return nil, RGWErrorNotFound, fmt.Errorf("user not found")
+	}
+
+	var user rgwUserInfo
+	if err := json.Unmarshal([]byte(result), &user); err != nil {
+		return nil, RGWErrorParse, fmt.Errorf("failed to read user info. %+v, result=%s", err, result)
+	}
+	return &user, RGWErrorNone, nil
+}
+
+func userKeys(user *rgwUserInfo) []model.ObjectUserKey {
+	keys := []model.ObjectUserKey{}
+	for _, key := range user.Keys {
+		keys = append(keys, model.ObjectUserKey{User: key.User, Type: KeyTypeS3, AccessKey: key.AccessKey, SecretKey: key.SecretKey})
+	}
+	for _, key := range user.SwiftKeys {
+		keys = append(keys, model.ObjectUserKey{User: key.User, Type: KeyTypeSwift, SecretKey: key.SecretKey})
+	}
+	return keys
+}
+
+func subusers(user *rgwUserInfo) []model.ObjectSubuser {
+	var result []model.ObjectSubuser
+	for _, subuser := range user.Subusers {
+		access := subuser.Permissions
+		for name, perm := range subuserAccess {
+			if perm == subuser.Permissions {
+				access = name
+			}
+		}
+		name := strings.TrimPrefix(subuser.ID, user.UserID+":")
+		result = append(result, model.ObjectSubuser{Name: name, Access: access})
+	}
+	return result
+}
+
+func containsKey(keys []model.ObjectUserKey, key model.ObjectUserKey) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+const userWithKeys = `{"user_id":"alice",
+"subusers":[{"id":"alice:swift","permissions":"full-control"}],
+"keys":[{"user":"alice","access_key":"key1","secret_key":"secret1"}],
+"swift_keys":[{"user":"alice:swift","secret_key":"swiftsecret"}]}`
+
+const userWithNewKey = `{"user_id":"alice",
+"subusers":[{"id":"alice:swift","permissions":"full-control"}],
+"keys":[{"user":"alice","access_key":"key1","secret_key":"secret1"},{"user":"alice","access_key":"key2","secret_key":"secret2"}],
+"swift_keys":[{"user":"alice:swift","secret_key":"swiftsecret"}]}`
+
+func TestUserKeys(t *testing.T) {
+	var lastArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			lastArgs = args
+			if args[0] == "key" && args[1] == "create" {
+				// the keys are not logged
+				assert.True(t, debug)
+				return userWithNewKey, nil
+			}
+			return userWithKeys, nil
+		},
+	}
+	objContext := NewContext(&clusterd.Context{Executor: executor}, "my-store", "rook")
+
+	keys, _, err := ListUserKeys(objContext, "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, []model.ObjectUserKey{
+		{User: "alice", Type: "s3", AccessKey: "key1", SecretKey: "secret1"},
+		{User: "alice:swift", Type: "swift", SecretKey: "swiftsecret"},
+	}, keys)
+
+	// the new key is returned
+	key, _, err := CreateUserKey(objContext, "alice", model.ObjectUserKey{})
+	assert.Nil(t, err)
+	assert.Equal(t, model.ObjectUserKey{User: "alice", Type: "s3", AccessKey: "key2", SecretKey: "secret2"}, *key)
+	assert.Equal(t, []string{"key", "create", "--uid", "alice", "--gen-access-key", "--key-type", "s3", "--gen-secret"}, lastArgs[:8])
+
+	// a swift key requires a subuser
+	_, code, err := CreateUserKey(objContext, "alice", model.ObjectUserKey{Type: "swift"})
+	assert.NotNil(t, err)
+	assert.Equal(t, RGWErrorBadData, code)
+
+	// keys are removed by access key or by the name of the subuser
+	_, err = DeleteUserKey(objContext, "alice", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"key", "rm", "--uid", "alice", "--key-type", "s3", "--access-key=key1"}, lastArgs[:7])
+	_, err = DeleteUserKey(objContext, "alice", "alice:swift")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"key", "rm", "--uid", "alice", "--subuser", "alice:swift", "--key-type", "swift"}, lastArgs[:8])
+	code, err = DeleteUserKey(objContext, "alice", "unknown")
+	assert.NotNil(t, err)
+	assert.Equal(t, RGWErrorNotFound, code)
+
+	// the new S3 key and the old S3 keys of a rotation are returned without revoking the old keys
+	key, oldKeys, _, err := RotateUserKeys(objContext, "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, "key2", key.AccessKey)
+	assert.Equal(t, []string{"key1"}, oldKeys)
+	assert.Equal(t, "create", lastArgs[1])
+}
+
+func TestSubusers(t *testing.T) {
+	var lastArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			lastArgs = args
+			return userWithKeys, nil
+		},
+	}
+	objContext := NewContext(&clusterd.Context{Executor: executor}, "my-store", "rook")
+
+	user, _, err := GetUser(objContext, "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, []model.ObjectSubuser{{Name: "swift", Access: "full"}}, user.Subusers)
+
+	key, _, err := CreateSubuser(objContext, "alice", model.ObjectSubuser{Name: "swift", Access: "full"})
+	assert.Nil(t, err)
+	assert.Equal(t, "swiftsecret", key.SecretKey)
+	assert.Equal(t, []string{"subuser", "create", "--uid", "alice", "--subuser", "alice:swift", "--access", "full"}, lastArgs[:8])
+
+	_, code, err := CreateSubuser(objContext, "alice", model.ObjectSubuser{Name: "swift", Access: "all"})
+	assert.NotNil(t, err)
+	assert.Equal(t, RGWErrorBadData, code)
+
+	// the admin users cannot be given subusers
+	lastArgs = nil
+	_, code, err = CreateSubuser(objContext, "rook-admin-1234", model.ObjectSubuser{Name: "swift", Access: "full"})
+	assert.NotNil(t, err)
+	assert.Equal(t, RGWErrorNotFound, code)
+	assert.Nil(t, lastArgs)
+
+	_, err = DeleteSubuser(objContext, "alice", "swift")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"subuser", "rm", "--uid", "alice", "--subuser", "alice:swift", "--purge-keys"}, lastArgs[:7])
+	code, err = DeleteSubuser(objContext, "alice", "other")
+	assert.NotNil(t, err)
+	assert.Equal(t, RGWErrorNotFound, code)
+}
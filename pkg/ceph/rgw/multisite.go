@@ -0,0 +1,196 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rook/rook/pkg/model"
+)
+
+// ZoneConfig is the configuration of a secondary zone that joins the realm of a master zone in another cluster
+type ZoneConfig struct {
+	// The endpoint of the master zone, e.g. http://10.0.0.1:80
+	MasterEndpoint string
+	// The keys of the system user of the realm
+	AccessKey string
+	SecretKey string
+	// The endpoints of the secondary zone for the other zones of the realm
+	Endpoints []string
+}
+
+// SyncStatus is the replication status of a secondary zone
+type SyncStatus struct {
+	// The status of the metadata sync from the master zone, e.g. "metadata is caught up with master"
+	MetadataSync string
+	// The status of the data sync from each source zone
+	DataSync []DataSyncStatus
+}
+
+// DataSyncStatus is the status of the data sync from a source zone
+type DataSyncStatus struct {
+	Source string
+	Status string
+}
+
+// the name of a realm, zone group or zone in the sync status, e.g. "zone 0b8a0f4e-... (us-west)"
+var syncStatusNamePattern = regexp.MustCompile(`\(([^)]*)\)\s*$`)
+
+// CreateSecondaryZone creates the pools of the object store and adds the store as a secondary zone to the realm of the
+// master zone. The realm and its period are pulled from the master zone with the keys of the system user.
+func CreateSecondaryZone(context *Context, metadataSpec, dataSpec model.Pool, placement Placement, config ZoneConfig) error {
+	err := createPools(context, metadataSpec, dataSpec, placement)
+	if err != nil {
+		return fmt.Errorf("failed to create object pools. %+v", err)
+	}
+
+	err = joinRealm(context, config)
+	if err != nil {
+		return fmt.Errorf("failed to join realm %s. %+v", context.Realm, err)
+	}
+
+	err = configurePlacement(context, placement, false)
+	if err != nil {
+		return fmt.Errorf("failed to configure placement. %+v", err)
+	}
+	return nil
+}
+
+// DeleteSecondaryZone removes the zone of the object store from the realm and deletes its pools. The realm and the
+// zone group are left to the master zone.
+func DeleteSecondaryZone(context *Context, placement Placement) error {
+	zoneArg := fmt.Sprintf("--rgw-zone=%s", context.Name)
+	if _, err := runAdminCommand(context, "zonegroup", "remove", zoneArg); err != nil {
+		logger.Warningf("failed to remove zone %s from zonegroup %s. %+v", context.Name, context.ZoneGroup, err)
+	} else if _, err := runAdminCommand(context, "period", "update", "--commit"); err != nil {
+		logger.Warningf("failed to commit the period after removing zone %s. %+v", context.Name, err)
+	}
+
+	if _, err := runAdminCommand(context, "zone", "delete", zoneArg); err != nil {
+		logger.Warningf("failed to delete rgw zone %s. %+v", context.Name, err)
+	}
+
+	err := deletePools(context, placement)
+	if err != nil {
+		return fmt.Errorf("failed to delete object store pools. %+v", err)
+	}
+	return nil
+}
+
+func joinRealm(context *Context, config ZoneConfig) error {
+	realmArg := fmt.Sprintf("--rgw-realm=%s", context.Realm)
+	zoneArg := fmt.Sprintf("--rgw-zone=%s", context.Name)
+	urlArg := fmt.Sprintf("--url=%s", config.MasterEndpoint)
+	keyArgs := []string{fmt.Sprintf("--access-key=%s", config.AccessKey), fmt.Sprintf("--secret=%s", config.SecretKey)}
+	updatePeriod := false
+
+	// pull the realm from the master zone if it doesn't exist yet
+	if _, err := runAdminCommandNoRealm(context, "realm", "get", realmArg); err != nil {
+		args := append([]string{"realm", "pull", realmArg, urlArg}, keyArgs...)
+		if _, err := runSecretAdminCommand(context, args...); err != nil {
+			return fmt.Errorf("failed to pull realm %s from %s. %+v", context.Realm, config.MasterEndpoint, err)
+		}
+	}
+
+	// pull the current period so the zone is created in the latest configuration of the realm
+	args := append([]string{"period", "pull", realmArg, urlArg}, keyArgs...)
+	if _, err := runSecretAdminCommand(context, args...); err != nil {
+		return fmt.Errorf("failed to pull the period of realm %s from %s. %+v", context.Realm, config.MasterEndpoint, err)
+	}
+
+	// create the zone if it doesn't exist yet. the zone syncs from the master zone with the keys of the system user.
+	output, err := runAdminCommand(context, "zone", "get", zoneArg)
+	if err != nil {
+		updatePeriod = true
+		args := append([]string{"zone", "create", zoneArg, fmt.Sprintf("--endpoints=%s", strings.Join(config.Endpoints, ","))}, keyArgs...)
+		output, err = runSecretAdminCommand(context, append(args, realmArg, fmt.Sprintf("--rgw-zonegroup=%s", context.ZoneGroup))...)
+		if err != nil {
+			return fmt.Errorf("failed to create rgw zone %s in zonegroup %s. %+v", context.Name, context.ZoneGroup, err)
+		}
+	}
+	zoneID, err := decodeID(output)
+	if err != nil {
+		return fmt.Errorf("failed to parse zone id. %+v", err)
+	}
+
+	if updatePeriod {
+		// the period is committed to the master zone, which notifies the other zones of the new zone
+		_, err := runAdminCommand(context, "period", "update", "--commit")
+		if err != nil {
+			return fmt.Errorf("failed to update period. %+v", err)
+		}
+	}
+
+	logger.Infof("RGW: joined realm=%s, zonegroup=%s as zone=%s (%s)", context.Realm, context.ZoneGroup, context.Name, zoneID)
+	return nil
+}
+
+// GetSyncStatus gets the replication status of the zone of the object store
+func GetSyncStatus(context *Context) (*SyncStatus, error) {
+	output, err := runAdminCommand(context, "sync", "status", fmt.Sprintf("--rgw-zone=%s", context.Name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the sync status of zone %s. %+v", context.Name, err)
+	}
+	return parseSyncStatus(output), nil
+}
+
+// parseSyncStatus parses the output of "radosgw-admin sync status". The metadata sync is reported in a section that
+// starts with "metadata sync" and the data sync in a section for each source zone that starts with "data sync source:".
+// The status of a section is its summary, e.g. "data is caught up with source" or "data is behind on 3 shards".
+func parseSyncStatus(output string) *SyncStatus {
+	status := &SyncStatus{}
+	var dataSync *DataSyncStatus
+	section := ""
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "metadata sync"):
+			section = "metadata"
+			status.MetadataSync = strings.TrimSpace(strings.TrimPrefix(line, "metadata sync"))
+			continue
+		case strings.HasPrefix(line, "data sync source:"):
+			section = "data"
+			source := strings.TrimSpace(strings.TrimPrefix(line, "data sync source:"))
+			if match := syncStatusNamePattern.FindStringSubmatch(source); match != nil {
+				source = match[1]
+			}
+			status.DataSync = append(status.DataSync, DataSyncStatus{Source: source})
+			dataSync = &status.DataSync[len(status.DataSync)-1]
+			continue
+		case strings.HasPrefix(line, "realm ") || strings.HasPrefix(line, "zonegroup ") || strings.HasPrefix(line, "zone "):
+			section = ""
+			continue
+		}
+
+		// the summary replaces the state of the section, e.g. "syncing". the shard counts and other details are skipped.
+		summary := strings.Contains(line, " is caught up") || strings.Contains(line, " is behind")
+		switch section {
+		case "metadata":
+			if summary || status.MetadataSync == "" {
+				status.MetadataSync = line
+			}
+		case "data":
+			if summary || dataSync.Status == "" {
+				dataSync.Status = line
+			}
+		}
+	}
+	return status
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoinRealm(t *testing.T) {
+	realmExists := false
+	commands := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			logger.Infof("Execute: %s %v", command, args)
+			commands = append(commands, args[0]+" "+args[1])
+			switch {
+			case args[0] == "realm" && args[1] == "get":
+				if !realmExists {
+					return "", fmt.Errorf("induce a pull")
+				}
+			case args[0] == "zone" && args[1] == "get":
+				if !realmExists {
+					return "", fmt.Errorf("induce a create")
+				}
+			case args[0] == "realm" && args[1] == "pull", args[0] == "zone" && args[1] == "create":
+				// the keys are not logged
+				assert.True(t, debug)
+				assert.Contains(t, args, "--access-key=access")
+				assert.Contains(t, args, "--secret=secret")
+				assert.Contains(t, args, "--rgw-realm=gold")
+				if args[1] == "create" {
+					assert.Contains(t, args, "--rgw-zone=site-b")
+					assert.Contains(t, args, "--rgw-zonegroup=us")
+					assert.Contains(t, args, "--endpoints=http://10.1.0.1:80,http://10.1.0.2:80")
+				} else {
+					assert.Contains(t, args, "--url=http://10.0.0.1:80")
+				}
+			case args[0] == "period" && args[1] == "update":
+				assert.Contains(t, args, "--rgw-realm=gold")
+			}
+			return `{"id":"test-id"}`, nil
+		},
+	}
+
+	objContext := NewContext(&clusterd.Context{Executor: executor}, "site-b", "mycluster")
+	objContext.Realm = "gold"
+	objContext.ZoneGroup = "us"
+	config := ZoneConfig{
+		MasterEndpoint: "http://10.0.0.1:80",
+		AccessKey:      "access",
+		SecretKey:      "secret",
+		Endpoints:      []string{"http://10.1.0.1:80", "http://10.1.0.2:80"},
+	}
+
+	// the realm is pulled and the zone created and committed to the period
+	err := joinRealm(objContext, config)
+	assert.Nil(t, err)
+	assert.Equal(t, "realm get,realm pull,period pull,zone get,zone create,period update", strings.Join(commands, ","))
+
+	// the latest period is pulled when the zone already exists
+	realmExists = true
+	commands = []string{}
+	err = joinRealm(objContext, config)
+	assert.Nil(t, err)
+	assert.Equal(t, "realm get,period pull,zone get", strings.Join(commands, ","))
+}
+
+func TestParseSyncStatus(t *testing.T) {
+	output := `          realm 8f1ba9a6-2bd4-4ac7-9f8c-0b2a6bd6e4a1 (gold)
+      zonegroup 4b6cb4d4-3df0-4f4e-8a26-b6bd4b56f8a4 (us)
+           zone 0b8a0f4e-8b5a-4e52-b3f2-52c4c1a5ab56 (site-b)
+  metadata sync syncing
+                full sync: 0/64 shards
+                incremental sync: 64/64 shards
+                metadata is caught up with master
+      data sync source: 3a7c8b0c-7c5d-4d0c-9f5c-24a6e2d7d8f0 (site-a)
+                        syncing
+                        full sync: 0/128 shards
+                        incremental sync: 128/128 shards
+                        data is behind on 2 shards
+                        behind shards: [31,87]
+`
+	status := parseSyncStatus(output)
+	assert.Equal(t, "metadata is caught up with master", status.MetadataSync)
+	assert.Equal(t, []DataSyncStatus{{Source: "site-a", Status: "data is behind on 2 shards"}}, status.DataSync)
+
+	// the master zone does not sync the metadata
+	output = `          realm 8f1ba9a6-2bd4-4ac7-9f8c-0b2a6bd6e4a1 (gold)
+      zonegroup 4b6cb4d4-3df0-4f4e-8a26-b6bd4b56f8a4 (us)
+           zone 3a7c8b0c-7c5d-4d0c-9f5c-24a6e2d7d8f0 (site-a)
+  metadata sync no sync (zone is master)
+      data sync source: 0b8a0f4e-8b5a-4e52-b3f2-52c4c1a5ab56 (site-b)
+                        syncing
+                        full sync: 0/128 shards
+                        incremental sync: 128/128 shards
+                        data is caught up with source
+`
+	status = parseSyncStatus(output)
+	assert.Equal(t, "no sync (zone is master)", status.MetadataSync)
+	assert.Equal(t, []DataSyncStatus{{Source: "site-b", Status: "data is caught up with source"}}, status.DataSync)
+}
@@ -50,8 +50,8 @@ type realmType struct {
 	Realms []string `json:"realms"`
 }
 
-func CreateObjectStore(context *Context, metadataSpec, dataSpec model.Pool, serviceIP string, port int32) error {
-	err := createPools(context, metadataSpec, dataSpec)
+func CreateObjectStore(context *Context, metadataSpec, dataSpec model.Pool, placement Placement, serviceIP string, port int32) error {
+	err := createPools(context, metadataSpec, dataSpec, placement)
 	if err != nil {
 		return fmt.Errorf("failed to create object pools. %+v", err)
 	}
@@ -60,16 +60,21 @@ func CreateObjectStore(context *Context, metadataSpec, dataSpec model.Pool, serv
 	if err != nil {
 		return fmt.Errorf("failed to create object store realm. %+v", err)
 	}
+
+	err = configurePlacement(context, placement, true)
+	if err != nil {
+		return fmt.Errorf("failed to configure placement. %+v", err)
+	}
 	return nil
 }
 
-func DeleteObjectStore(context *Context) error {
+func DeleteObjectStore(context *Context, placement Placement) error {
 	err := deleteRealm(context)
 	if err != nil {
 		return fmt.Errorf("failed to delete realm. %+v", err)
 	}
 
-	err = deletePools(context)
+	err = deletePools(context, placement)
 	if err != nil {
 		return fmt.Errorf("failed to delete object store pools. %+v", err)
 	}
@@ -195,8 +200,22 @@ func GetObjectStores(context *Context) ([]string, error) {
 	return r.Realms, nil
 }
 
-func deletePools(context *Context) error {
-	pools := append(metadataPools, dataPools...)
+// deletePools deletes the pools of the object store and the pools of its placement targets and storage classes. The
+// pools are not found by the prefix of the object store, which is also the prefix of the pools of other object stores,
+// e.g. the pools of the store "a.rgw" start with "a.rgw.".
+func deletePools(context *Context, placement Placement) error {
+	pools := append(append([]string{}, metadataPools...), dataPools...)
+	// rgw creates the pool of the incomplete multipart uploads of the default placement target
+	pools = append(pools, PlacementTarget{Name: DefaultPlacementTarget}.dataExtraPool())
+	for _, target := range placement.Targets {
+		if !target.isDefault() {
+			pools = append(pools, target.indexPool(), target.dataPool(), target.dataExtraPool())
+		}
+		for _, class := range target.StorageClasses {
+			pools = append(pools, target.storageClassPool(class.Name))
+		}
+	}
+
 	for _, pool := range pools {
 		name := poolName(context.Name, pool)
 		if err := ceph.DeletePool(context.context, context.ClusterName, name); err != nil {
@@ -207,20 +226,39 @@ func deletePools(context *Context) error {
 	return nil
 }
 
-func createPools(context *Context, metadataSpec, dataSpec model.Pool) error {
-	if err := createSimilarPools(context, metadataPools, metadataSpec); err != nil {
+func createPools(context *Context, metadataSpec, dataSpec model.Pool, placement Placement) error {
+	pools := metadataPools
+	if placement.IndexPool != nil {
+		// the index of the default placement target is created with the index pool settings
+		pools = []string{}
+		for _, pool := range metadataPools {
+			if pool != defaultPlacementPrefix+".index" {
+				pools = append(pools, pool)
+			}
+		}
+		if err := createSimilarPools(context, context.Name+".index", []string{defaultPlacementPrefix + ".index"}, *placement.IndexPool); err != nil {
+			return fmt.Errorf("failed to create index pool. %+v", err)
+		}
+	}
+	if err := createSimilarPools(context, context.Name, pools, metadataSpec); err != nil {
 		return fmt.Errorf("failed to create metadata pools. %+v", err)
 	}
 
-	if err := createSimilarPools(context, dataPools, dataSpec); err != nil {
+	if err := createSimilarPools(context, context.Name, dataPools, dataSpec); err != nil {
 		return fmt.Errorf("failed to create data pool. %+v", err)
 	}
 
+	if err := createPlacementPools(context, metadataSpec, placement); err != nil {
+		return fmt.Errorf("failed to create placement pools. %+v", err)
+	}
+
 	return nil
 }
 
-func createSimilarPools(context *Context, pools []string, poolSpec model.Pool) error {
-	poolSpec.Name = context.Name
+// createSimilarPools creates the pools with the same settings. The erasure code profile of the pools is named after
+// the profile name.
+func createSimilarPools(context *Context, profileName string, pools []string, poolSpec model.Pool) error {
+	poolSpec.Name = profileName
 	cephConfig := ceph.ModelPoolToCephPool(poolSpec)
 	if cephConfig.ErasureCodeProfile != "" {
 		// create a new erasure code profile for the new pool
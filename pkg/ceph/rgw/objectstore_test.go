@@ -69,7 +69,7 @@ func TestDeleteStore(t *testing.T) {
 	realmDeleted := false
 	zoneDeleted := false
 	zoneGroupDeleted := false
-	poolsDeleted := 0
+	poolsDeleted := []string{}
 	rulesDeleted := 0
 	executor := &exectest.MockExecutor{}
 	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
@@ -80,7 +80,7 @@ func TestDeleteStore(t *testing.T) {
 					return `{"pool_id":1}`, nil
 				}
 				if args[2] == "delete" {
-					poolsDeleted++
+					poolsDeleted = append(poolsDeleted, args[3])
 					return "", nil
 				}
 			}
@@ -114,10 +114,27 @@ func TestDeleteStore(t *testing.T) {
 	}
 	context := &Context{context: &clusterd.Context{Executor: executor}, Name: "myobj", ClusterName: "ns"}
 
-	err := DeleteObjectStore(context)
+	// the pools of the placement targets and storage classes are named after the placement
+	placement := Placement{Targets: []PlacementTarget{
+		{Name: DefaultPlacementTarget, StorageClasses: []StorageClass{{Name: "COLD"}}},
+		{Name: "fast"},
+	}}
+	err := DeleteObjectStore(context, placement)
 	assert.Nil(t, err)
-	assert.Equal(t, 6, poolsDeleted)
-	assert.Equal(t, 6, rulesDeleted)
+	assert.Equal(t, []string{
+		".rgw.root",
+		"myobj.rgw.control",
+		"myobj.rgw.meta",
+		"myobj.rgw.log",
+		"myobj.rgw.buckets.index",
+		"myobj.rgw.buckets.data",
+		"myobj.rgw.buckets.non-ec",
+		"myobj.rgw.buckets.cold.data",
+		"myobj.rgw.fast.index",
+		"myobj.rgw.fast.data",
+		"myobj.rgw.fast.non-ec",
+	}, poolsDeleted)
+	assert.Equal(t, 11, rulesDeleted)
 	assert.True(t, realmDeleted)
 	assert.True(t, zoneGroupDeleted)
 	assert.True(t, zoneDeleted)
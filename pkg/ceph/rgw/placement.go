@@ -0,0 +1,203 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+)
+
+const (
+	// DefaultPlacementTarget is the placement target that rgw creates in each zone
+	DefaultPlacementTarget = "default-placement"
+	// StandardStorageClass is the storage class of the objects that don't select a storage class
+	StandardStorageClass = "STANDARD"
+
+	defaultPlacementPrefix = "rgw.buckets"
+
+	// the major version of ceph (nautilus) that added the storage classes of the placement targets
+	storageClassesMinVersion = 14
+)
+
+var cephVersion = regexp.MustCompile(`ceph version (\d+)\.`)
+
+// Placement is the placement of the buckets and objects of the object store in its pools. A bucket selects its
+// placement target with the location constraint of the s3 bucket creation, e.g. "<zonegroup>:cold-placement", and an
+// object selects a storage class of the placement target of its bucket with the x-amz-storage-class header.
+type Placement struct {
+	// The pool settings of the bucket indexes. If nil, the indexes are in pools with the metadata pool settings.
+	IndexPool *model.Pool
+	// The placement targets. The default placement target can be added to configure its storage classes.
+	Targets []PlacementTarget
+	// The placement target of the buckets that don't select one. Default is default-placement.
+	DefaultTarget string
+}
+
+// PlacementTarget is a placement target of the zone with its own pools
+type PlacementTarget struct {
+	Name string
+	// The pool settings of the objects in the STANDARD storage class. Ignored for the default placement target, whose
+	// objects are in the data pool of the object store.
+	DataPool model.Pool
+	// The storage classes in addition to STANDARD, e.g. COLD
+	StorageClasses []StorageClass
+}
+
+// StorageClass is a storage class of a placement target with its own data pool
+type StorageClass struct {
+	Name     string
+	DataPool model.Pool
+}
+
+func (t PlacementTarget) isDefault() bool {
+	return t.Name == DefaultPlacementTarget
+}
+
+// poolPrefix gets the prefix of the pools of the placement target. The pools of the default placement target are
+// the pools rgw creates by default, e.g. rgw.buckets.data.
+func (t PlacementTarget) poolPrefix() string {
+	if t.isDefault() {
+		return defaultPlacementPrefix
+	}
+	return fmt.Sprintf("rgw.%s", t.Name)
+}
+
+func (t PlacementTarget) indexPool() string {
+	return t.poolPrefix() + ".index"
+}
+
+func (t PlacementTarget) dataPool() string {
+	return t.poolPrefix() + ".data"
+}
+
+// dataExtraPool is the pool of the incomplete multipart uploads, which must not be erasure coded
+func (t PlacementTarget) dataExtraPool() string {
+	return t.poolPrefix() + ".non-ec"
+}
+
+func (t PlacementTarget) storageClassPool(class string) string {
+	return fmt.Sprintf("%s.%s.data", t.poolPrefix(), strings.ToLower(class))
+}
+
+// SupportsStorageClasses checks whether the ceph version of the object stores supports the storage classes of the
+// placement targets, which were added in nautilus
+func SupportsStorageClasses(context *clusterd.Context) (bool, error) {
+	output, err := context.Executor.ExecuteCommandWithOutput(false, "", "radosgw-admin", "--version")
+	if err != nil {
+		return false, fmt.Errorf("failed to get the ceph version. %+v", err)
+	}
+	match := cephVersion.FindStringSubmatch(output)
+	if match == nil {
+		return false, fmt.Errorf("failed to parse the ceph version %s", output)
+	}
+	major, err := strconv.Atoi(match[1])
+	if err != nil {
+		return false, fmt.Errorf("failed to parse the ceph version %s. %+v", output, err)
+	}
+	return major >= storageClassesMinVersion, nil
+}
+
+// createPlacementPools creates the pools of the placement targets and storage classes. The erasure code profile of
+// each pool is named after the object store, the placement target and the storage class.
+func createPlacementPools(context *Context, metadataSpec model.Pool, placement Placement) error {
+	indexSpec := metadataSpec
+	if placement.IndexPool != nil {
+		indexSpec = *placement.IndexPool
+	}
+
+	for _, target := range placement.Targets {
+		profile := fmt.Sprintf("%s.%s", context.Name, target.Name)
+		if !target.isDefault() {
+			if err := createSimilarPools(context, profile+".index", []string{target.indexPool()}, indexSpec); err != nil {
+				return fmt.Errorf("failed to create index pool of placement target %s. %+v", target.Name, err)
+			}
+			if err := createSimilarPools(context, profile+".non-ec", []string{target.dataExtraPool()}, metadataSpec); err != nil {
+				return fmt.Errorf("failed to create data extra pool of placement target %s. %+v", target.Name, err)
+			}
+			if err := createSimilarPools(context, profile, []string{target.dataPool()}, target.DataPool); err != nil {
+				return fmt.Errorf("failed to create data pool of placement target %s. %+v", target.Name, err)
+			}
+		}
+
+		for _, class := range target.StorageClasses {
+			classProfile := fmt.Sprintf("%s.%s", profile, strings.ToLower(class.Name))
+			if err := createSimilarPools(context, classProfile, []string{target.storageClassPool(class.Name)}, class.DataPool); err != nil {
+				return fmt.Errorf("failed to create data pool of storage class %s. %+v", class.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// configurePlacement adds the placement targets and storage classes to the zone. The placement targets are also added
+// to the zone group of a master zone, while a secondary zone gets them from the zone group of the master zone.
+func configurePlacement(context *Context, placement Placement, master bool) error {
+	if len(placement.Targets) == 0 && placement.DefaultTarget == "" {
+		return nil
+	}
+	zoneArg := fmt.Sprintf("--rgw-zone=%s", context.Name)
+
+	for _, target := range placement.Targets {
+		placementArg := fmt.Sprintf("--placement-id=%s", target.Name)
+		if master && !target.isDefault() {
+			if _, err := runAdminCommand(context, "zonegroup", "placement", "add", placementArg); err != nil {
+				return fmt.Errorf("failed to add placement target %s to the zone group. %+v", target.Name, err)
+			}
+		}
+		if !target.isDefault() {
+			_, err := runAdminCommand(context, "zone", "placement", "add", zoneArg, placementArg,
+				fmt.Sprintf("--index-pool=%s", poolName(context.Name, target.indexPool())),
+				fmt.Sprintf("--data-pool=%s", poolName(context.Name, target.dataPool())),
+				fmt.Sprintf("--data-extra-pool=%s", poolName(context.Name, target.dataExtraPool())))
+			if err != nil {
+				return fmt.Errorf("failed to add placement target %s to the zone. %+v", target.Name, err)
+			}
+		}
+
+		for _, class := range target.StorageClasses {
+			classArg := fmt.Sprintf("--storage-class=%s", class.Name)
+			if master {
+				if _, err := runAdminCommand(context, "zonegroup", "placement", "add", placementArg, classArg); err != nil {
+					return fmt.Errorf("failed to add storage class %s to the zone group. %+v", class.Name, err)
+				}
+			}
+			_, err := runAdminCommand(context, "zone", "placement", "add", zoneArg, placementArg, classArg,
+				fmt.Sprintf("--data-pool=%s", poolName(context.Name, target.storageClassPool(class.Name))))
+			if err != nil {
+				return fmt.Errorf("failed to add storage class %s to the zone. %+v", class.Name, err)
+			}
+		}
+	}
+
+	if master && placement.DefaultTarget != "" {
+		_, err := runAdminCommand(context, "zonegroup", "placement", "default", fmt.Sprintf("--placement-id=%s", placement.DefaultTarget))
+		if err != nil {
+			return fmt.Errorf("failed to set the default placement target %s. %+v", placement.DefaultTarget, err)
+		}
+	}
+
+	// the rgw daemons load the placement from the period
+	if _, err := runAdminCommand(context, "period", "update", "--commit"); err != nil {
+		return fmt.Errorf("failed to update period. %+v", err)
+	}
+	logger.Infof("configured %d placement targets of object store %s", len(placement.Targets), context.Name)
+	return nil
+}
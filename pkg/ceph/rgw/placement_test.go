@@ -0,0 +1,124 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func testPlacement() Placement {
+	ec := model.Pool{Type: model.ErasureCoded, ErasureCodedConfig: model.ErasureCodedPoolConfig{DataChunkCount: 4, CodingChunkCount: 2}}
+	replicated := model.Pool{Type: model.Replicated, ReplicatedConfig: model.ReplicatedPoolConfig{Size: 3}}
+	return Placement{
+		IndexPool: &model.Pool{Type: model.Replicated, ReplicatedConfig: model.ReplicatedPoolConfig{Size: 2}},
+		Targets: []PlacementTarget{
+			{Name: DefaultPlacementTarget, StorageClasses: []StorageClass{{Name: "COLD", DataPool: ec}}},
+			{Name: "fast", DataPool: replicated},
+		},
+		DefaultTarget: "fast",
+	}
+}
+
+func TestCreatePlacementPools(t *testing.T) {
+	created := map[string]string{}
+	profiles := []string{}
+	executor := &exectest.MockExecutor{}
+	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+		logger.Infof("Command: %s %v", command, args)
+		switch {
+		case args[0] == "osd" && args[1] == "pool" && args[2] == "get":
+			return "", fmt.Errorf("induce a create")
+		case args[0] == "osd" && args[1] == "pool" && args[2] == "create":
+			created[args[3]] = args[5]
+			if args[5] == "erasure" {
+				created[args[3]] += " " + args[6]
+			}
+		case args[0] == "osd" && args[1] == "erasure-code-profile" && args[2] == "get":
+			return `{"plugin":"jerasure","technique":"reed_sol_van"}`, nil
+		case args[0] == "osd" && args[1] == "erasure-code-profile" && args[2] == "set":
+			profiles = append(profiles, args[3])
+		}
+		return "", nil
+	}
+	context := &Context{context: &clusterd.Context{Executor: executor}, Name: "myobj", ClusterName: "ns"}
+
+	metadataSpec := model.Pool{Type: model.Replicated, ReplicatedConfig: model.ReplicatedPoolConfig{Size: 3}}
+	dataSpec := model.Pool{Type: model.ErasureCoded, ErasureCodedConfig: model.ErasureCodedPoolConfig{DataChunkCount: 2, CodingChunkCount: 1}}
+	err := createPools(context, metadataSpec, dataSpec, testPlacement())
+	assert.Nil(t, err)
+
+	// the default pools, the storage class of the default target, and the pools of the fast target
+	assert.Equal(t, 10, len(created))
+	assert.Equal(t, "replicated", created["myobj.rgw.buckets.index"])
+	assert.Equal(t, "erasure myobj_ecprofile", created["myobj.rgw.buckets.data"])
+	assert.Equal(t, "erasure myobj.default-placement.cold_ecprofile", created["myobj.rgw.buckets.cold.data"])
+	assert.Equal(t, "replicated", created["myobj.rgw.fast.index"])
+	assert.Equal(t, "replicated", created["myobj.rgw.fast.non-ec"])
+	assert.Equal(t, "replicated", created["myobj.rgw.fast.data"])
+	assert.Equal(t, []string{"myobj_ecprofile", "myobj.default-placement.cold_ecprofile"}, profiles)
+}
+
+func TestConfigurePlacement(t *testing.T) {
+	commands := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			logger.Infof("Execute: %s %v", command, args)
+			// the command without the realm and connection args
+			for i, arg := range args {
+				if arg == "--rgw-realm=myobj" {
+					commands = append(commands, strings.Join(args[:i], " "))
+					return "", nil
+				}
+			}
+			return "", fmt.Errorf("missing realm in %v", args)
+		},
+	}
+	context := NewContext(&clusterd.Context{Executor: executor}, "myobj", "ns")
+
+	// no placement is configured by default
+	err := configurePlacement(context, Placement{}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(commands))
+
+	// the master zone adds the targets and storage classes to the zone group
+	err = configurePlacement(context, testPlacement(), true)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"zonegroup placement add --placement-id=default-placement --storage-class=COLD",
+		"zone placement add --rgw-zone=myobj --placement-id=default-placement --storage-class=COLD --data-pool=myobj.rgw.buckets.cold.data",
+		"zonegroup placement add --placement-id=fast",
+		"zone placement add --rgw-zone=myobj --placement-id=fast --index-pool=myobj.rgw.fast.index --data-pool=myobj.rgw.fast.data --data-extra-pool=myobj.rgw.fast.non-ec",
+		"zonegroup placement default --placement-id=fast",
+		"period update --commit",
+	}, commands)
+
+	// a secondary zone only adds the pools to its zone
+	commands = []string{}
+	err = configurePlacement(context, testPlacement(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"zone placement add --rgw-zone=myobj --placement-id=default-placement --storage-class=COLD --data-pool=myobj.rgw.buckets.cold.data",
+		"zone placement add --rgw-zone=myobj --placement-id=fast --index-pool=myobj.rgw.fast.index --data-pool=myobj.rgw.fast.data --data-extra-pool=myobj.rgw.fast.non-ec",
+		"period update --commit",
+	}, commands)
+}
@@ -0,0 +1,279 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/rook/rook/pkg/model"
+)
+
+const (
+	// the prefix of the ids of the system users whose keys are used by the admin operations on buckets with the S3 API.
+	// the ids with the prefix are reserved and the users are hidden from the user APIs.
+	adminUserPrefix = "rook-admin"
+
+	// the error codes of the S3 API when the bucket has no policy or lifecycle
+	errCodeNoSuchBucketPolicy = "NoSuchBucketPolicy"
+	errCodeNoSuchLifecycle    = "NoSuchLifecycleConfiguration"
+)
+
+// S3Agent runs operations on buckets with the S3 API of an object store
+type S3Agent struct {
+	client *s3.S3
+}
+
+// NewS3Agent creates an agent for the S3 API of the object store at the given endpoint, e.g. 10.0.0.1:80. The endpoint
+// is reached with https if it has the https scheme, e.g. https://10.0.0.1:443. The certificate of the object store is
+// verified with the given CA certificates in PEM format, or with the CAs of the system if none are given.
+func NewS3Agent(endpoint, accessKey, secretKey string, caCert []byte) (*S3Agent, error) {
+	// the default aws region must be used for the ceph object store
+	config := aws.NewConfig().
+		WithRegion("us-east-1").
+		WithCredentials(credentials.NewStaticCredentials(accessKey, secretKey, "")).
+		WithEndpoint(endpoint).
+		WithS3ForcePathStyle(true).
+		WithDisableSSL(!strings.HasPrefix(endpoint, "https://"))
+
+	if len(caCert) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("failed to read the ca certificate of the object store")
+		}
+		config = config.WithHTTPClient(&http.Client{Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		}})
+	}
+
+	return &S3Agent{client: s3.New(session.New(), config)}, nil
+}
+
+// CreateAdminUser creates the admin system user of the object store and returns its keys. System users are allowed to
+// change the policy and lifecycle of the buckets of all users. The id of the user has a random suffix so it cannot be
+// guessed.
+func CreateAdminUser(c *Context) (*model.ObjectUser, error) {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("failed to generate admin user id. %+v", err)
+	}
+	id := fmt.Sprintf("%s-%s", adminUserPrefix, hex.EncodeToString(suffix))
+
+	logger.Infof("creating admin user %s", id)
+	result, err := runAdminCommand(c, "user", "create", "--uid", id, "--display-name", "Rook admin", "--system")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin user: %+v", err)
+	}
+	user, _, err := decodeUser(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read admin user. %+v", err)
+	}
+	if user.AccessKey == nil || user.SecretKey == nil {
+		return nil, fmt.Errorf("keys of admin user %s not found", id)
+	}
+	return user, nil
+}
+
+// DeleteAdminUser removes the admin system user of the object store
+func DeleteAdminUser(c *Context, id string) error {
+	if !IsReservedUser(id) {
+		return fmt.Errorf("user %s is not an admin user", id)
+	}
+
+	logger.Infof("deleting admin user %s", id)
+	if _, err := runAdminCommand(c, "user", "rm", "--uid", id); err != nil {
+		return fmt.Errorf("failed to delete admin user: %+v", err)
+	}
+	return nil
+}
+
+// IsReservedUser checks if the user id is reserved for the admin users of the object stores
+func IsReservedUser(id string) bool {
+	return strings.HasPrefix(id, adminUserPrefix)
+}
+
+// S3Endpoint gets the endpoint of the S3 API of the object store at the given host and port
+func S3Endpoint(host string, port int32, secure bool) string {
+	if secure {
+		return fmt.Sprintf("https://%s:%d", host, port)
+	}
+	return fmt.Sprintf("%s:%d", host, port)
+}
+
+// CreateBucket creates a bucket with the S3 API of the object store at the given endpoint. The bucket is owned by the
+// user of the keys. Creating a bucket that the user already owns is not an error.
+func CreateBucket(endpoint, accessKey, secretKey string, caCert []byte, bucketName string) error {
+	agent, err := NewS3Agent(endpoint, accessKey, secretKey, caCert)
+	if err != nil {
+		return err
+	}
+	_, err = agent.client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(bucketName)})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == s3.ErrCodeBucketAlreadyOwnedByYou {
+			return nil
+		}
+		return fmt.Errorf("failed to create bucket %s: %+v", bucketName, err)
+	}
+	return nil
+}
+
+// GetBucketPolicy gets the policy document of the bucket
+func (a *S3Agent) GetBucketPolicy(bucketName string) (string, int, error) {
+	output, err := a.client.GetBucketPolicy(&s3.GetBucketPolicyInput{Bucket: aws.String(bucketName)})
+	if err != nil {
+		return "", s3ErrorCode(err, errCodeNoSuchBucketPolicy), fmt.Errorf("failed to get policy of bucket %s: %+v", bucketName, err)
+	}
+	return aws.StringValue(output.Policy), RGWErrorNone, nil
+}
+
+// SetBucketPolicy replaces the policy of the bucket with the policy document
+func (a *S3Agent) SetBucketPolicy(bucketName, policy string) (int, error) {
+	var document map[string]interface{}
+	if err := json.Unmarshal([]byte(policy), &document); err != nil {
+		return RGWErrorBadData, fmt.Errorf("invalid policy document. %+v", err)
+	}
+
+	_, err := a.client.PutBucketPolicy(&s3.PutBucketPolicyInput{Bucket: aws.String(bucketName), Policy: aws.String(policy)})
+	if err != nil {
+		return s3ErrorCode(err), fmt.Errorf("failed to set policy of bucket %s: %+v", bucketName, err)
+	}
+	return RGWErrorNone, nil
+}
+
+// DeleteBucketPolicy removes the policy of the bucket
+func (a *S3Agent) DeleteBucketPolicy(bucketName string) (int, error) {
+	_, err := a.client.DeleteBucketPolicy(&s3.DeleteBucketPolicyInput{Bucket: aws.String(bucketName)})
+	if err != nil {
+		return s3ErrorCode(err), fmt.Errorf("failed to delete policy of bucket %s: %+v", bucketName, err)
+	}
+	return RGWErrorNone, nil
+}
+
+// GetBucketLifecycle gets the lifecycle rules of the bucket
+func (a *S3Agent) GetBucketLifecycle(bucketName string) ([]model.ObjectBucketLifecycleRule, int, error) {
+	output, err := a.client.GetBucketLifecycleConfiguration(&s3.GetBucketLifecycleConfigurationInput{Bucket: aws.String(bucketName)})
+	if err != nil {
+		return nil, s3ErrorCode(err, errCodeNoSuchLifecycle), fmt.Errorf("failed to get lifecycle of bucket %s: %+v", bucketName, err)
+	}
+
+	rules := []model.ObjectBucketLifecycleRule{}
+	for _, r := range output.Rules {
+		rule := model.ObjectBucketLifecycleRule{
+			ID:       aws.StringValue(r.ID),
+			Prefix:   aws.StringValue(r.Prefix),
+			Disabled: aws.StringValue(r.Status) != s3.ExpirationStatusEnabled,
+		}
+		if r.Filter != nil && r.Filter.Prefix != nil {
+			rule.Prefix = *r.Filter.Prefix
+		}
+		if r.Expiration != nil {
+			rule.ExpirationDays = aws.Int64Value(r.Expiration.Days)
+		}
+		if r.NoncurrentVersionExpiration != nil {
+			rule.NoncurrentVersionExpirationDays = aws.Int64Value(r.NoncurrentVersionExpiration.NoncurrentDays)
+		}
+		if r.AbortIncompleteMultipartUpload != nil {
+			rule.AbortIncompleteMultipartUploadDays = aws.Int64Value(r.AbortIncompleteMultipartUpload.DaysAfterInitiation)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, RGWErrorNone, nil
+}
+
+// SetBucketLifecycle replaces the lifecycle rules of the bucket
+func (a *S3Agent) SetBucketLifecycle(bucketName string, rules []model.ObjectBucketLifecycleRule) (int, error) {
+	if len(rules) == 0 {
+		return RGWErrorBadData, fmt.Errorf("at least one lifecycle rule is required")
+	}
+
+	config := &s3.BucketLifecycleConfiguration{}
+	for _, rule := range rules {
+		if rule.ExpirationDays <= 0 && rule.NoncurrentVersionExpirationDays <= 0 && rule.AbortIncompleteMultipartUploadDays <= 0 {
+			return RGWErrorBadData, fmt.Errorf("lifecycle rule %q has no action", rule.ID)
+		}
+
+		status := s3.ExpirationStatusEnabled
+		if rule.Disabled {
+			status = s3.ExpirationStatusDisabled
+		}
+		// the prefix of the rule is used instead of the filter, which is not supported by all versions of rgw
+		r := &s3.LifecycleRule{Prefix: aws.String(rule.Prefix), Status: aws.String(status)}
+		if rule.ID != "" {
+			r.ID = aws.String(rule.ID)
+		}
+		if rule.ExpirationDays > 0 {
+			r.Expiration = &s3.LifecycleExpiration{Days: aws.Int64(rule.ExpirationDays)}
+		}
+		if rule.NoncurrentVersionExpirationDays > 0 {
+			r.NoncurrentVersionExpiration = &s3.NoncurrentVersionExpiration{NoncurrentDays: aws.Int64(rule.NoncurrentVersionExpirationDays)}
+		}
+		if rule.AbortIncompleteMultipartUploadDays > 0 {
+			r.AbortIncompleteMultipartUpload = &s3.AbortIncompleteMultipartUpload{DaysAfterInitiation: aws.Int64(rule.AbortIncompleteMultipartUploadDays)}
+		}
+		config.Rules = append(config.Rules, r)
+	}
+
+	_, err := a.client.PutBucketLifecycleConfiguration(&s3.PutBucketLifecycleConfigurationInput{
+		Bucket:                 aws.String(bucketName),
+		LifecycleConfiguration: config,
+	})
+	if err != nil {
+		return s3ErrorCode(err), fmt.Errorf("failed to set lifecycle of bucket %s: %+v", bucketName, err)
+	}
+	return RGWErrorNone, nil
+}
+
+// DeleteBucketLifecycle removes the lifecycle rules of the bucket
+func (a *S3Agent) DeleteBucketLifecycle(bucketName string) (int, error) {
+	_, err := a.client.DeleteBucketLifecycle(&s3.DeleteBucketLifecycleInput{Bucket: aws.String(bucketName)})
+	if err != nil {
+		return s3ErrorCode(err), fmt.Errorf("failed to delete lifecycle of bucket %s: %+v", bucketName, err)
+	}
+	return RGWErrorNone, nil
+}
+
+// s3ErrorCode converts the error of the S3 API to an rgw error. The bucket not being found and the other codes are
+// reported as not found.
+func s3ErrorCode(err error, notFoundCodes ...string) int {
+	awsErr, ok := err.(awserr.Error)
+	if !ok {
+		return RGWErrorUnknown
+	}
+	switch awsErr.Code() {
+	case s3.ErrCodeNoSuchBucket:
+		return RGWErrorNotFound
+	case "MalformedPolicy", "MalformedXML", "InvalidArgument":
+		return RGWErrorBadData
+	}
+	for _, code := range notFoundCodes {
+		if awsErr.Code() == code {
+			return RGWErrorNotFound
+		}
+	}
+	return RGWErrorUnknown
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rook/rook/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const lifecycleResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<LifecycleConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+<Rule><ID>logs</ID><Prefix>logs/</Prefix><Status>Enabled</Status><Expiration><Days>30</Days></Expiration></Rule>
+</LifecycleConfiguration>`
+
+const noSuchPolicyResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<Error><Code>NoSuchBucketPolicy</Code><BucketName>photos</BucketName></Error>`
+
+func TestS3Agent(t *testing.T) {
+	var method, query, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, query = r.Method, r.URL.RawQuery
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+		assert.Equal(t, "/photos", r.URL.Path)
+
+		switch {
+		case r.Method == "GET" && strings.HasPrefix(query, "lifecycle"):
+			w.Write([]byte(lifecycleResponse))
+		case r.Method == "GET" && strings.HasPrefix(query, "policy"):
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(noSuchPolicyResponse))
+		case r.Method == "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+	agent, err := NewS3Agent(strings.TrimPrefix(server.URL, "http://"), "access", "secret", nil)
+	assert.Nil(t, err)
+
+	// the policy must be a json document
+	code, err := agent.SetBucketPolicy("photos", "allow all")
+	assert.NotNil(t, err)
+	assert.Equal(t, RGWErrorBadData, code)
+
+	policy := `{"Version":"2012-10-17","Statement":[]}`
+	_, err = agent.SetBucketPolicy("photos", policy)
+	assert.Nil(t, err)
+	assert.Equal(t, "PUT", method)
+	assert.True(t, strings.HasPrefix(query, "policy"))
+	assert.Equal(t, policy, body)
+
+	_, code, err = agent.GetBucketPolicy("photos")
+	assert.NotNil(t, err)
+	assert.Equal(t, RGWErrorNotFound, code)
+
+	_, err = agent.DeleteBucketPolicy("photos")
+	assert.Nil(t, err)
+	assert.Equal(t, "DELETE", method)
+
+	rules, _, err := agent.GetBucketLifecycle("photos")
+	assert.Nil(t, err)
+	assert.Equal(t, []model.ObjectBucketLifecycleRule{{ID: "logs", Prefix: "logs/", ExpirationDays: 30}}, rules)
+
+	// a rule needs an action
+	code, err = agent.SetBucketLifecycle("photos", []model.ObjectBucketLifecycleRule{{ID: "logs"}})
+	assert.NotNil(t, err)
+	assert.Equal(t, RGWErrorBadData, code)
+
+	_, err = agent.SetBucketLifecycle("photos", []model.ObjectBucketLifecycleRule{{ID: "uploads", AbortIncompleteMultipartUploadDays: 7}})
+	assert.Nil(t, err)
+	assert.Equal(t, "PUT", method)
+	assert.True(t, strings.HasPrefix(query, "lifecycle"))
+	assert.Contains(t, body, "<DaysAfterInitiation>7</DaysAfterInitiation>")
+	assert.Contains(t, body, "<Status>Enabled</Status>")
+}
+
+func TestS3AgentCACert(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// the self-signed certificate of the object store is not trusted by default
+	agent, err := NewS3Agent(server.URL, "access", "secret", nil)
+	assert.Nil(t, err)
+	_, err = agent.DeleteBucketPolicy("photos")
+	assert.NotNil(t, err)
+
+	caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	agent, err = NewS3Agent(server.URL, "access", "secret", caCert)
+	assert.Nil(t, err)
+	_, err = agent.DeleteBucketPolicy("photos")
+	assert.Nil(t, err)
+
+	_, err = NewS3Agent(server.URL, "access", "secret", []byte("not a certificate"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// the format of the dates of the usage log
+	usageDateFormat = "2006-01-02 15:04:05"
+
+	// rgw flushes the usage log periodically, so the entries of an hour can still change a little after the hour
+	usageFlushDelay = 5 * time.Minute
+)
+
+// OpsUsage is the number of requests of a category, e.g. get_obj or put_obj, and their bytes
+type OpsUsage struct {
+	Category      string
+	Ops           uint64
+	SuccessfulOps uint64
+	BytesSent     uint64
+	BytesReceived uint64
+}
+
+// Usage is the usage log of the object store summed up by user and by bucket. The usage log is enabled in the rgw
+// config with "rgw enable usage log".
+type Usage struct {
+	// The requests of each user by category
+	Users map[string][]OpsUsage
+	// The requests to each bucket by category
+	Buckets map[string][]OpsUsage
+}
+
+// BucketUsage is the size and number of objects of a bucket
+type BucketUsage struct {
+	Bucket          string
+	Owner           string
+	Size            uint64
+	NumberOfObjects uint64
+}
+
+type rgwOpsUsage struct {
+	Category      string `json:"category"`
+	BytesSent     uint64 `json:"bytes_sent"`
+	BytesReceived uint64 `json:"bytes_received"`
+	Ops           uint64 `json:"ops"`
+	SuccessfulOps uint64 `json:"successful_ops"`
+}
+
+type rgwUsage struct {
+	Entries []struct {
+		User    string           `json:"user"`
+		Buckets []rgwBucketUsage `json:"buckets"`
+	} `json:"entries"`
+}
+
+type rgwBucketUsage struct {
+	Bucket     string        `json:"bucket"`
+	Epoch      int64         `json:"epoch"`
+	Categories []rgwOpsUsage `json:"categories"`
+}
+
+// UsageLog sums up the requests of the users and buckets from the usage log of an object store. The usage log has an
+// entry for each user and bucket every hour. The entries of the hours that are complete are only read once and kept in
+// the totals, so the usage log is not read from the start every time.
+type UsageLog struct {
+	// the requests of the complete hours
+	complete *Usage
+	// the start of the first hour that is not complete, or zero if the usage log was not read yet
+	since time.Time
+}
+
+// NewUsageLog creates a reader of the usage log of an object store
+func NewUsageLog() *UsageLog {
+	return &UsageLog{complete: newUsage()}
+}
+
+// GetUsage gets the requests of the users and buckets since the usage log was started. Only the entries since the
+// first hour that was not complete at the last call are read from the usage log.
+func (l *UsageLog) GetUsage(c *Context, now time.Time) (*Usage, error) {
+	args := []string{"usage", "show", "--show-log-sum=false"}
+	if !l.since.IsZero() {
+		args = append(args, "--start-date", l.since.UTC().Format(usageDateFormat))
+	}
+	result, err := runAdminCommand(c, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage: %+v", err)
+	}
+	var u rgwUsage
+	if err := json.Unmarshal([]byte(result), &u); err != nil {
+		return nil, fmt.Errorf("failed to read usage. %+v, result=%s", err, result)
+	}
+
+	// the hours that ended before the last flush of the usage log are complete
+	since := now.Add(-usageFlushDelay).Truncate(time.Hour)
+	if since.Before(l.since) {
+		since = l.since
+	}
+	usage := l.complete.copy()
+	for _, entry := range u.Entries {
+		for _, bucket := range entry.Buckets {
+			if time.Unix(bucket.Epoch, 0).Before(since) {
+				l.complete.add(entry.User, bucket)
+			}
+			usage.add(entry.User, bucket)
+		}
+	}
+	l.since = since
+	return usage, nil
+}
+
+func newUsage() *Usage {
+	return &Usage{Users: map[string][]OpsUsage{}, Buckets: map[string][]OpsUsage{}}
+}
+
+// add adds the requests of an entry of the usage log to the user and the bucket
+func (u *Usage) add(user string, bucket rgwBucketUsage) {
+	u.Users[user] = addOpsUsage(u.Users[user], bucket.Categories)
+	// the requests that are not to a bucket, e.g. list_buckets, have an empty bucket name
+	if bucket.Bucket != "" {
+		u.Buckets[bucket.Bucket] = addOpsUsage(u.Buckets[bucket.Bucket], bucket.Categories)
+	}
+}
+
+func (u *Usage) copy() *Usage {
+	c := newUsage()
+	for user, usage := range u.Users {
+		c.Users[user] = append([]OpsUsage{}, usage...)
+	}
+	for bucket, usage := range u.Buckets {
+		c.Buckets[bucket] = append([]OpsUsage{}, usage...)
+	}
+	return c
+}
+
+// addOpsUsage adds the requests to the usage of the same category. The categories are kept in order.
+func addOpsUsage(usage []OpsUsage, categories []rgwOpsUsage) []OpsUsage {
+	for _, category := range categories {
+		i := sort.Search(len(usage), func(i int) bool { return usage[i].Category >= category.Category })
+		if i == len(usage) || usage[i].Category != category.Category {
+			usage = append(usage, OpsUsage{})
+			copy(usage[i+1:], usage[i:])
+			usage[i] = OpsUsage{Category: category.Category}
+		}
+		usage[i].Ops += category.Ops
+		usage[i].SuccessfulOps += category.SuccessfulOps
+		usage[i].BytesSent += category.BytesSent
+		usage[i].BytesReceived += category.BytesReceived
+	}
+	return usage
+}
+
+// GetBucketUsage gets the size, number of objects and owner of all buckets in the object store
+func GetBucketUsage(c *Context) ([]BucketUsage, error) {
+	result, err := runAdminCommand(c, "bucket", "stats")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bucket stats: %+v", err)
+	}
+
+	var rgwStats []rgwBucketStats
+	if err := json.Unmarshal([]byte(result), &rgwStats); err != nil {
+		return nil, fmt.Errorf("failed to read buckets stats. %+v, result=%s", err, result)
+	}
+
+	usage := []BucketUsage{}
+	for _, stats := range rgwStats {
+		s := bucketStatsFromRGW(stats)
+		usage = append(usage, BucketUsage{Bucket: stats.Bucket, Owner: stats.Owner, Size: s.Size, NumberOfObjects: s.NumberOfObjects})
+	}
+	return usage, nil
+}
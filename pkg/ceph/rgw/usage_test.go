@@ -0,0 +1,100 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsageLog(t *testing.T) {
+	output := `{
+    "entries": [
+        {"user": "alice", "buckets": [
+            {"bucket": "", "time": "2018-03-01 10:00:00.000000Z", "epoch": 1519898400, "owner": "alice", "categories": [
+                {"category": "list_buckets", "bytes_sent": 300, "bytes_received": 0, "ops": 3, "successful_ops": 3}]},
+            {"bucket": "photos", "time": "2018-03-01 10:00:00.000000Z", "epoch": 1519898400, "owner": "alice", "categories": [
+                {"category": "put_obj", "bytes_sent": 0, "bytes_received": 1000, "ops": 10, "successful_ops": 9}]},
+            {"bucket": "photos", "time": "2018-03-01 11:00:00.000000Z", "epoch": 1519902000, "owner": "alice", "categories": [
+                {"category": "get_obj", "bytes_sent": 500, "bytes_received": 0, "ops": 5, "successful_ops": 5},
+                {"category": "put_obj", "bytes_sent": 0, "bytes_received": 200, "ops": 2, "successful_ops": 2}]}
+        ]}
+    ]
+}`
+	// the entries of the hour that is not complete yet
+	newOutput := `{
+    "entries": [
+        {"user": "alice", "buckets": [
+            {"bucket": "photos", "time": "2018-03-01 11:00:00.000000Z", "epoch": 1519902000, "owner": "alice", "categories": [
+                {"category": "get_obj", "bytes_sent": 800, "bytes_received": 0, "ops": 8, "successful_ops": 8}]}
+        ]}
+    ]
+}`
+	var commands [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			commands = append(commands, args)
+			if len(commands) == 1 {
+				return output, nil
+			}
+			return newOutput, nil
+		},
+	}
+	objContext := NewContext(&clusterd.Context{Executor: executor}, "my-store", "rook")
+	log := NewUsageLog()
+
+	// the whole usage log is read the first time
+	now := time.Date(2018, 3, 1, 11, 30, 0, 0, time.UTC)
+	usage, err := log.GetUsage(objContext, now)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"usage", "show", "--show-log-sum=false"}, commands[0][:3])
+	assert.NotEqual(t, "--start-date", commands[0][3])
+	assert.Equal(t, 3, len(usage.Users["alice"]))
+	assert.Equal(t, OpsUsage{Category: "put_obj", Ops: 12, SuccessfulOps: 11, BytesReceived: 1200}, usage.Users["alice"][2])
+
+	// the hourly entries are summed up by bucket and the requests without a bucket are skipped
+	assert.Equal(t, 1, len(usage.Buckets))
+	assert.Equal(t, []OpsUsage{
+		{Category: "get_obj", Ops: 5, SuccessfulOps: 5, BytesSent: 500},
+		{Category: "put_obj", Ops: 12, SuccessfulOps: 11, BytesReceived: 1200},
+	}, usage.Buckets["photos"])
+
+	// only the hour that was not complete is read again
+	usage, err = log.GetUsage(objContext, now.Add(time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"--start-date", "2018-03-01 11:00:00"}, commands[1][3:5])
+	assert.Equal(t, []OpsUsage{
+		{Category: "get_obj", Ops: 8, SuccessfulOps: 8, BytesSent: 800},
+		{Category: "put_obj", Ops: 10, SuccessfulOps: 9, BytesReceived: 1000},
+	}, usage.Buckets["photos"])
+
+	// the usage log fails to be read
+	executor.MockExecuteCommandWithCombinedOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		return "not json", nil
+	}
+	_, err = log.GetUsage(objContext, now)
+	assert.NotNil(t, err)
+	executor.MockExecuteCommandWithCombinedOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		return "", fmt.Errorf("mock failure")
+	}
+	_, err = log.GetUsage(objContext, now)
+	assert.NotNil(t, err)
+}
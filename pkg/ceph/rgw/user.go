@@ -18,7 +18,7 @@ package rgw
 import (
 	"encoding/json"
 	"fmt"
-
+	"sort"
 	"strings"
 
 	"github.com/rook/rook/pkg/model"
@@ -30,6 +30,7 @@ const (
 	RGWErrorNotFound = iota
 	RGWErrorBadData  = iota
 	RGWErrorParse    = iota
+	RGWErrorExists   = iota
 )
 
 func ListUsers(c *Context) ([]string, int, error) {
@@ -38,11 +39,18 @@ func ListUsers(c *Context) ([]string, int, error) {
 		return nil, RGWErrorUnknown, fmt.Errorf("failed to list users: %+v", err)
 	}
 
-	var s []string
-	if err := json.Unmarshal([]byte(result), &s); err != nil {
+	var users []string
+	if err := json.Unmarshal([]byte(result), &users); err != nil {
 		return nil, RGWErrorParse, fmt.Errorf("failed to read users info. %+v, result=%s", err, result)
 	}
 
+	// the admin users are hidden
+	s := []string{}
+	for _, user := range users {
+		if !IsReservedUser(user) {
+			s = append(s, user)
+		}
+	}
 	return s, RGWErrorNone, nil
 }
 
@@ -51,9 +59,44 @@ type rgwUserInfo struct {
 	DisplayName string `json:"display_name"`
 	Email       string `json:"email"`
 	Keys        []struct {
+		User      string `json:"user"`
 		AccessKey string `json:"access_key"`
 		SecretKey string `json:"secret_key"`
 	}
+	SwiftKeys []struct {
+		User      string `json:"user"`
+		SecretKey string `json:"secret_key"`
+	} `json:"swift_keys"`
+	Subusers []struct {
+		ID          string `json:"id"`
+		Permissions string `json:"permissions"`
+	} `json:"subusers"`
+	Caps []struct {
+		Type string `json:"type"`
+		Perm string `json:"perm"`
+	} `json:"caps"`
+}
+
+// ObjectQuota is a quota of a user or of each bucket of a user
+type ObjectQuota struct {
+	// The maximum size of the objects in bytes. 0 is unlimited.
+	MaxSize int64
+	// The maximum number of objects. 0 is unlimited.
+	MaxObjects int64
+}
+
+// UserConfig is the configuration of a user in addition to its name and email
+type UserConfig struct {
+	// The maximum number of buckets of the user. The rgw default is kept if nil.
+	MaxBuckets *int
+	// Whether the user is suspended
+	Suspended bool
+	// The permissions of the user to the admin API by type, e.g. users=read
+	Capabilities map[string]string
+	// The quota of the user. The quota is disabled if nil.
+	Quota *ObjectQuota
+	// The quota of each bucket of the user. The quota is disabled if nil.
+	BucketQuota *ObjectQuota
 }
 
 func decodeUser(data string) (*model.ObjectUser, int, error) {
@@ -69,12 +112,16 @@ func decodeUser(data string) (*model.ObjectUser, int, error) {
 		rookUser.AccessKey = &user.Keys[0].AccessKey
 		rookUser.SecretKey = &user.Keys[0].SecretKey
 	}
+	rookUser.Subusers = subusers(&user)
 
 	return &rookUser, RGWErrorNone, nil
 }
 
 func GetUser(c *Context, id string) (*model.ObjectUser, int, error) {
 	logger.Infof("Getting user: %s", id)
+	if IsReservedUser(id) {
+		return nil, RGWErrorNotFound, fmt.Errorf("user not found")
+	}
 
 	result, err := runAdminCommand(c, "user", "info", "--uid", id)
 	if err != nil {
@@ -92,6 +139,9 @@ func CreateUser(c *Context, user model.ObjectUser) (*model.ObjectUser, int, erro
 	if strings.TrimSpace(user.UserID) == "" {
 		return nil, RGWErrorBadData, fmt.Errorf("userId cannot be empty")
 	}
+	if IsReservedUser(user.UserID) {
+		return nil, RGWErrorBadData, fmt.Errorf("userId %s is reserved", user.UserID)
+	}
 
 	if user.DisplayName == nil {
 		return nil, RGWErrorBadData, fmt.Errorf("displayName is required")
@@ -114,7 +164,7 @@ func CreateUser(c *Context, user model.ObjectUser) (*model.ObjectUser, int, erro
 	}
 
 	if strings.HasPrefix(result, "could not create user: unable to create user, user: ") && strings.HasSuffix(result, " exists") {
-		return nil, RGWErrorBadData, fmt.Errorf("user already exists")
+		return nil, RGWErrorExists, fmt.Errorf("user already exists")
 	}
 
 	if strings.HasPrefix(result, "could not create user: unable to create user, email: ") && strings.HasSuffix(result, " is the email address an existing user") {
@@ -126,6 +176,9 @@ func CreateUser(c *Context, user model.ObjectUser) (*model.ObjectUser, int, erro
 
 func UpdateUser(c *Context, user model.ObjectUser) (*model.ObjectUser, int, error) {
 	logger.Infof("Updating user: %s", user.UserID)
+	if IsReservedUser(user.UserID) {
+		return nil, RGWErrorNotFound, fmt.Errorf("user not found")
+	}
 
 	args := []string{"user", "modify", "--uid", user.UserID}
 
@@ -150,6 +203,9 @@ func UpdateUser(c *Context, user model.ObjectUser) (*model.ObjectUser, int, erro
 
 func DeleteUser(c *Context, id string) (string, int, error) {
 	logger.Infof("Deleting user: %s", id)
+	if IsReservedUser(id) {
+		return "", RGWErrorNotFound, fmt.Errorf("user not found")
+	}
 	result, err := runAdminCommand(c, "user", "rm", "--uid", id)
 	if err != nil {
 		return "", RGWErrorUnknown, fmt.Errorf("failed to delete user: %+v", err)
@@ -161,3 +217,110 @@ func DeleteUser(c *Context, id string) (string, int, error) {
 
 	return result, RGWErrorNone, nil
 }
+
+// ConfigureUser sets the max buckets, suspended state, capabilities and quotas of the user
+func ConfigureUser(c *Context, id string, config UserConfig) error {
+	if config.MaxBuckets != nil {
+		if _, err := runAdminCommand(c, "user", "modify", "--uid", id, "--max-buckets", fmt.Sprintf("%d", *config.MaxBuckets)); err != nil {
+			return fmt.Errorf("failed to set max buckets of user %s: %+v", id, err)
+		}
+	}
+
+	state := "enable"
+	if config.Suspended {
+		state = "suspend"
+	}
+	if _, err := runAdminCommand(c, "user", state, "--uid", id); err != nil {
+		return fmt.Errorf("failed to %s user %s: %+v", state, id, err)
+	}
+
+	if err := setUserQuota(c, id, "user", config.Quota); err != nil {
+		return err
+	}
+	if err := setUserQuota(c, id, "bucket", config.BucketQuota); err != nil {
+		return err
+	}
+	return setUserCaps(c, id, config.Capabilities)
+}
+
+// setUserQuota sets and enables the quota of the scope, or disables the quota if nil
+func setUserQuota(c *Context, id, scope string, quota *ObjectQuota) error {
+	if err := setQuota(c, scope, []string{"--uid", id}, quota); err != nil {
+		return fmt.Errorf("failed to set %s quota of user %s: %+v", scope, id, err)
+	}
+	return nil
+}
+
+// setQuota sets and enables the quota of the scope for the user or bucket in the args, or disables the quota if nil
+func setQuota(c *Context, scope string, args []string, quota *ObjectQuota) error {
+	scopeArgs := append([]string{"--quota-scope", scope}, args...)
+	if quota == nil {
+		if _, err := runAdminCommand(c, append([]string{"quota", "disable"}, scopeArgs...)...); err != nil {
+			return fmt.Errorf("failed to disable quota: %+v", err)
+		}
+		return nil
+	}
+
+	// rgw does not limit a quota that is negative
+	maxSize, maxObjects := quota.MaxSize, quota.MaxObjects
+	if maxSize == 0 {
+		maxSize = -1
+	}
+	if maxObjects == 0 {
+		maxObjects = -1
+	}
+	setArgs := append([]string{"quota", "set"}, scopeArgs...)
+	setArgs = append(setArgs, "--max-size", fmt.Sprintf("%d", maxSize), "--max-objects", fmt.Sprintf("%d", maxObjects))
+	if _, err := runAdminCommand(c, setArgs...); err != nil {
+		return fmt.Errorf("failed to set quota: %+v", err)
+	}
+	if _, err := runAdminCommand(c, append([]string{"quota", "enable"}, scopeArgs...)...); err != nil {
+		return fmt.Errorf("failed to enable quota: %+v", err)
+	}
+	return nil
+}
+
+// setUserCaps adds the missing capabilities of the user and removes the capabilities that are not expected
+func setUserCaps(c *Context, id string, caps map[string]string) error {
+	result, err := runAdminCommand(c, "user", "info", "--uid", id)
+	if err != nil {
+		return fmt.Errorf("failed to get user %s: %+v", id, err)
+	}
+	var user rgwUserInfo
+	if err := json.Unmarshal([]byte(result), &user); err != nil {
+		return fmt.Errorf("failed to read user info. %+v, result=%s", err, result)
+	}
+
+	current := map[string]string{}
+	for _, cap := range user.Caps {
+		current[cap.Type] = cap.Perm
+	}
+	for _, capType := range sortedKeys(current) {
+		if perm, ok := caps[capType]; ok && perm == current[capType] {
+			continue
+		}
+		capArg := fmt.Sprintf("%s=%s", capType, current[capType])
+		if _, err := runAdminCommand(c, "caps", "rm", "--uid", id, "--caps", capArg); err != nil {
+			return fmt.Errorf("failed to remove caps %s of user %s: %+v", capArg, id, err)
+		}
+	}
+	for _, capType := range sortedKeys(caps) {
+		if perm, ok := current[capType]; ok && perm == caps[capType] {
+			continue
+		}
+		capArg := fmt.Sprintf("%s=%s", capType, caps[capType])
+		if _, err := runAdminCommand(c, "caps", "add", "--uid", id, "--caps", capArg); err != nil {
+			return fmt.Errorf("failed to add caps %s to user %s: %+v", capArg, id, err)
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigureUser(t *testing.T) {
+	commands := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "info" {
+				return `{"user_id":"alice","caps":[{"type":"buckets","perm":"*"},{"type":"users","perm":"read"}]}`, nil
+			}
+			// skip the realm and connection args
+			end := 0
+			for end < len(args) && !strings.HasPrefix(args[end], "--rgw-realm") {
+				end++
+			}
+			commands = append(commands, strings.Join(args[:end], " "))
+			return "", nil
+		},
+	}
+	objContext := NewContext(&clusterd.Context{Executor: executor}, "my-store", "rook")
+
+	maxBuckets := 10
+	config := UserConfig{
+		MaxBuckets:   &maxBuckets,
+		Suspended:    true,
+		Capabilities: map[string]string{"users": "*", "buckets": "*", "usage": "read"},
+		Quota:        &ObjectQuota{MaxSize: 1073741824},
+	}
+	err := ConfigureUser(objContext, "alice", config)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"user modify --uid alice --max-buckets 10",
+		"user suspend --uid alice",
+		"quota set --quota-scope user --uid alice --max-size 1073741824 --max-objects -1",
+		"quota enable --quota-scope user --uid alice",
+		"quota disable --quota-scope bucket --uid alice",
+		// the caps are only changed when they differ
+		"caps rm --uid alice --caps users=read",
+		"caps add --uid alice --caps usage=read",
+		"caps add --uid alice --caps users=*",
+	}, commands)
+
+	// the caps that are not expected are removed
+	commands = []string{}
+	err = ConfigureUser(objContext, "alice", UserConfig{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"user enable --uid alice",
+		"quota disable --quota-scope user --uid alice",
+		"quota disable --quota-scope bucket --uid alice",
+		"caps rm --uid alice --caps buckets=*",
+		"caps rm --uid alice --caps users=read",
+	}, commands)
+}
+
+func TestReservedUsers(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "list" {
+				return `["alice","rook-admin-0a1b2c3d4e5f6a7b"]`, nil
+			}
+			return `{"user_id":"rook-admin-0a1b2c3d4e5f6a7b","keys":[{"access_key":"adminkey","secret_key":"adminsecret"}]}`, nil
+		},
+	}
+	objContext := NewContext(&clusterd.Context{Executor: executor}, "my-store", "rook")
+
+	// the admin users are hidden
+	users, _, err := ListUsers(objContext)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"alice"}, users)
+
+	_, code, err := GetUser(objContext, "rook-admin-0a1b2c3d4e5f6a7b")
+	assert.NotNil(t, err)
+	assert.Equal(t, RGWErrorNotFound, code)
+	_, code, err = DeleteUser(objContext, "rook-admin-0a1b2c3d4e5f6a7b")
+	assert.NotNil(t, err)
+	assert.Equal(t, RGWErrorNotFound, code)
+
+	// the ids are reserved
+	_, code, err = CreateUser(objContext, model.ObjectUser{UserID: "rook-admin"})
+	assert.NotNil(t, err)
+	assert.Equal(t, RGWErrorBadData, code)
+}
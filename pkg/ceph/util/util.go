@@ -60,3 +60,43 @@ func FindRBDMappedFile(imageName, poolName, sysBusDir string) (string, error) {
 	}
 	return "", nil
 }
+
+// RBDMappedImage is an image mapped on this host
+type RBDMappedImage struct {
+	ID    string
+	Image string
+	Pool  string
+}
+
+// ListRBDMappedImages returns the images mapped on this host. The device of an image is RBDDevicePathPrefix followed by its ID.
+func ListRBDMappedImages(sysBusDir string) ([]RBDMappedImage, error) {
+	images := []RBDMappedImage{}
+
+	sysBusDeviceDir := filepath.Join(sysBusDir, RBDDevicesDir)
+	// if sysPath does not exist, no attachments has happened
+	if _, err := os.Stat(sysBusDeviceDir); os.IsNotExist(err) {
+		return images, nil
+	}
+
+	files, err := ioutil.ReadDir(sysBusDeviceDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rbd device dir: %+v", err)
+	}
+
+	for _, idFile := range files {
+		nameContent, err := ioutil.ReadFile(filepath.Join(sysBusDeviceDir, idFile.Name(), "name"))
+		if err != nil {
+			continue
+		}
+		poolContent, err := ioutil.ReadFile(filepath.Join(sysBusDeviceDir, idFile.Name(), "pool"))
+		if err != nil {
+			continue
+		}
+		images = append(images, RBDMappedImage{
+			ID:    idFile.Name(),
+			Image: strings.TrimSpace(string(nameContent)),
+			Pool:  strings.TrimSpace(string(poolContent)),
+		})
+	}
+	return images, nil
+}
@@ -41,3 +41,26 @@ func TestFindDevicePath(t *testing.T) {
 	mappedImageFile, _ := FindRBDMappedFile("myimage1", "mypool1", mockRBDSysBusPath)
 	assert.Equal(t, "3", mappedImageFile)
 }
+
+func TestListRBDMappedImages(t *testing.T) {
+	mockRBDSysBusPath, err := ioutil.TempDir("", "TestListRBDMappedImages")
+	if err != nil {
+		t.Fatalf("failed to create temp rbd sys bus dir: %+v", err)
+	}
+	defer os.RemoveAll(mockRBDSysBusPath)
+
+	// no device has been mapped yet
+	images, err := ListRBDMappedImages(mockRBDSysBusPath)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(images))
+
+	for id, image := range map[string]string{"0": "myimage1", "3": "myimage2"} {
+		devPath := filepath.Join(mockRBDSysBusPath, "devices", id)
+		os.MkdirAll(devPath, 0777)
+		ioutil.WriteFile(filepath.Join(devPath, "name"), []byte(image+"\n"), 0777)
+		ioutil.WriteFile(filepath.Join(devPath, "pool"), []byte("mypool1\n"), 0777)
+	}
+	images, err = ListRBDMappedImages(mockRBDSysBusPath)
+	assert.Nil(t, err)
+	assert.Equal(t, []RBDMappedImage{{ID: "0", Image: "myimage1", Pool: "mypool1"}, {ID: "3", Image: "myimage2", Pool: "mypool1"}}, images)
+}
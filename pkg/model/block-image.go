@@ -23,6 +23,23 @@ type BlockImage struct {
 	MountPoint string `json:"mountPoint"`
 }
 
+type BlockSnapshot struct {
+	Name      string `json:"snapshotName"`
+	ImageName string `json:"imageName"`
+	PoolName  string `json:"poolName"`
+	ID        int    `json:"id"`
+	Size      uint64 `json:"size"`
+}
+
+type BlockImageMirrorStatus struct {
+	Name        string `json:"imageName"`
+	PoolName    string `json:"poolName"`
+	GlobalID    string `json:"globalId"`
+	State       string `json:"state"`
+	Description string `json:"description"`
+	LastUpdate  string `json:"lastUpdate"`
+}
+
 // DevicePathFinder is used to find the device path after the volume has been attached
 type DevicePathFinder interface {
 	FindDevicePath(image, pool, clusterName string) (string, error)
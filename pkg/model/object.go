@@ -58,6 +58,78 @@ type ObjectUser struct {
 	Email       *string `json:"email"`
 	AccessKey   *string `json:"accessKey"`
 	SecretKey   *string `json:"secretKey"`
+	// The swift subusers of the user
+	Subusers []ObjectSubuser `json:"subusers,omitempty"`
+}
+
+// ObjectUserKey is an S3 or swift key of an object store user
+type ObjectUserKey struct {
+	// The user or subuser of the key, e.g. "alice" or "alice:swift"
+	User string `json:"user"`
+	// The type of the key, "s3" or "swift"
+	Type string `json:"type"`
+	// The access key of an S3 key. Swift keys are accessed with the name of the subuser.
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey"`
+}
+
+// ObjectUserKeyRotation is a request to rotate the S3 keys of an object store user
+type ObjectUserKeyRotation struct {
+	// How long the old S3 keys remain valid after the new key is created, in seconds. The old keys are revoked
+	// immediately if 0.
+	OverlapSeconds int `json:"overlapSeconds"`
+}
+
+// ObjectUserKeyRotationResult is the new S3 key of a rotation and when the old S3 keys are revoked
+type ObjectUserKeyRotationResult struct {
+	Key ObjectUserKey `json:"key"`
+	// The access keys of the old S3 keys
+	OldAccessKeys []string  `json:"oldAccessKeys"`
+	RevokeAfter   time.Time `json:"revokeAfter"`
+}
+
+// ObjectSubuser is a swift subuser of an object store user
+type ObjectSubuser struct {
+	// The name of the subuser without the user id, e.g. "swift"
+	Name string `json:"name"`
+	// The access of the subuser: read, write, readwrite or full
+	Access string `json:"access"`
+}
+
+// ObjectBucketQuota is the quota of a bucket. A limit of 0 is unlimited.
+type ObjectBucketQuota struct {
+	MaxSize    int64 `json:"maxSize"`
+	MaxObjects int64 `json:"maxObjects"`
+}
+
+// ObjectBucketOwner is the new owner of a bucket that is transferred to another user
+type ObjectBucketOwner struct {
+	Owner string `json:"owner"`
+}
+
+// ObjectBucketReshard is the new number of shards of the index of a bucket
+type ObjectBucketReshard struct {
+	NumShards int `json:"numShards"`
+}
+
+// ObjectBucketIndexCheck is the result of checking or repairing the index of a bucket
+type ObjectBucketIndexCheck struct {
+	Fixed  bool   `json:"fixed"`
+	Result string `json:"result"`
+}
+
+// ObjectBucketLifecycleRule is a lifecycle rule of a bucket. The days are not set if 0.
+type ObjectBucketLifecycleRule struct {
+	ID string `json:"id"`
+	// The rule applies to the objects with the prefix, or to all objects if empty
+	Prefix   string `json:"prefix"`
+	Disabled bool   `json:"disabled,omitempty"`
+	// The objects expire the number of days after they are created
+	ExpirationDays int64 `json:"expirationDays,omitempty"`
+	// The noncurrent versions of the objects expire the number of days after they become noncurrent
+	NoncurrentVersionExpirationDays int64 `json:"noncurrentVersionExpirationDays,omitempty"`
+	// The incomplete multipart uploads are aborted the number of days after they are started
+	AbortIncompleteMultipartUploadDays int64 `json:"abortIncompleteMultipartUploadDays,omitempty"`
 }
 
 type ObjectBucketMetadata struct {
@@ -24,6 +24,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 
 	"github.com/coreos/pkg/capnslog"
 	"github.com/rook/rook/pkg/operator/k8sutil"
@@ -38,6 +39,7 @@ const (
 	agentDaemonsetName       = "rook-agent"
 	flexvolumePathDirEnv     = "FLEXVOLUME_DIR_PATH"
 	flexvolumeDefaultDirPath = "/usr/libexec/kubernetes/kubelet-plugins/volume/exec/"
+	kubeletDefaultRootDir    = "/var/lib/kubelet"
 )
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-agent")
@@ -68,6 +70,8 @@ func (a *Agent) createAgentDaemonSet(namespace string) error {
 		return err
 	}
 	privileged := true
+	// the kubelet pods dir is mounted at the same path as on the host so the mount dirs of the volumes can be checked
+	kubeletPodsDir := path.Join(a.discoverKubeletRootDir(), "pods")
 	ds := &extensions.DaemonSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: agentDaemonsetName,
@@ -114,6 +118,11 @@ func (a *Agent) createAgentDaemonSet(namespace string) error {
 									Name:      "modinfo",
 									MountPath: "/sbin/modinfo",
 								},
+								{
+									Name:      "kubelet-pods",
+									MountPath: kubeletPodsDir,
+									ReadOnly:  true,
+								},
 							},
 							Env: []v1.EnvVar{
 								k8sutil.NamespaceEnvVar(),
@@ -170,6 +179,14 @@ func (a *Agent) createAgentDaemonSet(namespace string) error {
 								},
 							},
 						},
+						{
+							Name: "kubelet-pods",
+							VolumeSource: v1.VolumeSource{
+								HostPath: &v1.HostPathVolumeSource{
+									Path: kubeletPodsDir,
+								},
+							},
+						},
 					},
 					HostNetwork: true,
 					Tolerations: []v1.Toleration{
@@ -241,6 +258,30 @@ func (a *Agent) discoverFlexvolumeDir() string {
 	return flexvolumeDirPath
 }
 
+// discoverKubeletRootDir queries the kubelet configuration to find the kubelet root dir. Defaults to /var/lib/kubelet
+func (a *Agent) discoverKubeletRootDir() string {
+	nodeConfigURI, err := k8sutil.NodeConfigURI()
+	if err != nil {
+		logger.Warningf(err.Error())
+		return kubeletDefaultRootDir
+	}
+	nodeConfig, err := a.clientset.Core().RESTClient().Get().RequestURI(nodeConfigURI).DoRaw()
+	if err != nil {
+		logger.Warningf("unable to query node configuration: %v", err)
+		return kubeletDefaultRootDir
+	}
+
+	configKubelet := NodeConfigKubelet{}
+	if err := json.Unmarshal(nodeConfig, &configKubelet); err != nil {
+		logger.Warningf("unable to parse node config from Kubelet: %+v", err)
+		return kubeletDefaultRootDir
+	}
+	if configKubelet.ComponentConfig.RootDirectory == "" {
+		return kubeletDefaultRootDir
+	}
+	return configKubelet.ComponentConfig.RootDirectory
+}
+
 func getDefaultFlexvolumeDir() string {
 	logger.Info("getting flexvolume dir path from provided env var")
 	flexvolumeDirPath := os.Getenv("FLEXVOLUME_DIR_PATH")
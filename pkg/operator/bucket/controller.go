@@ -0,0 +1,359 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bucket to provision object store buckets for claims.
+package bucket
+
+import (
+	"fmt"
+
+	"github.com/coreos/pkg/capnslog"
+	cephrgw "github.com/rook/rook/pkg/ceph/rgw"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/operator/cluster"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/kit"
+	"github.com/rook/rook/pkg/operator/rgw"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// the keys in the secret and config map created for a claim
+	AccessKeyIDKey     = "AWS_ACCESS_KEY_ID"
+	SecretAccessKeyKey = "AWS_SECRET_ACCESS_KEY"
+	BucketHostKey      = "BUCKET_HOST"
+	BucketPortKey      = "BUCKET_PORT"
+	BucketNameKey      = "BUCKET_NAME"
+	BucketSSLKey       = "BUCKET_SSL"
+
+	// the length limits of S3 bucket names
+	minBucketNameLength = 3
+	maxBucketNameLength = 63
+)
+
+var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-bucket")
+
+// ObjectBucketClaimController represents a controller object for object bucket claim custom resources
+type ObjectBucketClaimController struct {
+	context *clusterd.Context
+	scheme  *runtime.Scheme
+	client  rest.Interface
+	// creates the bucket with the S3 API of the object store
+	createBucket func(endpoint, accessKey, secretKey string, caCert []byte, bucketName string) error
+	// saves the status of the claim
+	updateStatus func(claim *ObjectBucketClaim) error
+}
+
+// NewObjectBucketClaimController create controller for watching object bucket claim custom resources created
+func NewObjectBucketClaimController(context *clusterd.Context) *ObjectBucketClaimController {
+	c := &ObjectBucketClaimController{
+		context:      context,
+		createBucket: cephrgw.CreateBucket,
+	}
+	c.updateStatus = c.putClaim
+	return c
+}
+
+// StartWatch watches for instances of ObjectBucketClaim custom resources and acts on them
+func (c *ObjectBucketClaimController) StartWatch(namespace string, stopCh chan struct{}) error {
+	client, scheme, err := kit.NewHTTPClient(k8sutil.CustomResourceGroup, k8sutil.V1Alpha1, schemeBuilder)
+	if err != nil {
+		return fmt.Errorf("failed to get a k8s client for watching object bucket claim resources: %v", err)
+	}
+	c.scheme = scheme
+	c.client = client
+
+	resourceHandlerFuncs := cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.onAdd,
+		UpdateFunc: c.onUpdate,
+		DeleteFunc: c.onDelete,
+	}
+	watcher := kit.NewWatcher(ObjectBucketClaimResource, namespace, resourceHandlerFuncs, client)
+	go watcher.Watch(&ObjectBucketClaim{}, stopCh)
+	return nil
+}
+
+func (c *ObjectBucketClaimController) onAdd(obj interface{}) {
+	claim := obj.(*ObjectBucketClaim)
+
+	// NEVER modify objects from the store. It's a read-only, local cache.
+	// Use scheme.Copy() to make a deep copy of original object.
+	copyObj, err := c.scheme.Copy(claim)
+	if err != nil {
+		logger.Errorf("failed to create a deep copy of object bucket claim: %v", err)
+		return
+	}
+	claimCopy := copyObj.(*ObjectBucketClaim)
+
+	if err := c.provision(claimCopy); err != nil {
+		logger.Errorf("failed to provision bucket for claim %s/%s. %+v", claim.Namespace, claim.Name, err)
+	}
+}
+
+func (c *ObjectBucketClaimController) onUpdate(oldObj, newObj interface{}) {
+	oldClaim := oldObj.(*ObjectBucketClaim)
+	claim := newObj.(*ObjectBucketClaim)
+
+	if oldClaim.Spec.ObjectStore != claim.Spec.ObjectStore || oldClaim.Spec.ClusterName != claim.Spec.ClusterName ||
+		oldClaim.Spec.BucketName != claim.Spec.BucketName {
+		logger.Errorf("failed to update object bucket claim %s/%s. the object store and bucket cannot be changed", claim.Namespace, claim.Name)
+		return
+	}
+
+	// NEVER modify objects from the store. It's a read-only, local cache.
+	copyObj, err := c.scheme.Copy(claim)
+	if err != nil {
+		logger.Errorf("failed to create a deep copy of object bucket claim: %v", err)
+		return
+	}
+	claimCopy := copyObj.(*ObjectBucketClaim)
+
+	// if the claim is modified, allow the bucket to be provisioned if it wasn't already
+	if err := c.provision(claimCopy); err != nil {
+		logger.Errorf("failed to provision bucket for claim %s/%s. %+v", claim.Namespace, claim.Name, err)
+	}
+}
+
+func (c *ObjectBucketClaimController) onDelete(obj interface{}) {
+	claim := obj.(*ObjectBucketClaim)
+	if err := c.delete(claim); err != nil {
+		logger.Errorf("failed to delete bucket of claim %s/%s. %+v", claim.Namespace, claim.Name, err)
+	}
+}
+
+// provision creates the user and bucket of the claim and saves the connection info in the namespace of the claim
+func (c *ObjectBucketClaimController) provision(claim *ObjectBucketClaim) error {
+	if claim.Spec.ObjectStore == "" {
+		return fmt.Errorf("objectStore is required")
+	}
+	if len(claim.bucketName()) < minBucketNameLength || len(claim.bucketName()) > maxBucketNameLength {
+		return fmt.Errorf("bucket name %s must be between %d and %d characters. set bucketName in the claim",
+			claim.bucketName(), minBucketNameLength, maxBucketNameLength)
+	}
+
+	host, port, secure, err := rgw.GetServiceEndpoint(c.context.Clientset, claim.clusterName(), claim.Spec.ObjectStore)
+	if err != nil {
+		return err
+	}
+
+	objContext, err := rgw.GetObjectContext(c.context, claim.clusterName(), claim.Spec.ObjectStore)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("provisioning bucket %s in object store %s for claim %s/%s", claim.bucketName(), claim.Spec.ObjectStore, claim.Namespace, claim.Name)
+	user, err := createUser(objContext, claim)
+	if err != nil {
+		return err
+	}
+	if user.AccessKey == nil || user.SecretKey == nil {
+		return fmt.Errorf("keys of user %s not found", user.UserID)
+	}
+
+	// creating a bucket that is owned by another user fails
+	var caCert []byte
+	if secure {
+		if caCert, err = rgw.GetServiceCACert(c.context.Clientset, claim.clusterName(), claim.Spec.ObjectStore); err != nil {
+			return err
+		}
+	}
+	endpoint := cephrgw.S3Endpoint(host, port, secure)
+	if err := c.createBucket(endpoint, *user.AccessKey, *user.SecretKey, caCert, claim.bucketName()); err != nil {
+		return err
+	}
+	if err := c.recordBucket(objContext, claim); err != nil {
+		return err
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: claim.Name, Namespace: claim.Namespace},
+		StringData: map[string]string{
+			AccessKeyIDKey:     *user.AccessKey,
+			SecretAccessKeyKey: *user.SecretKey,
+		},
+		Type: k8sutil.RookType,
+	}
+	if _, err := c.context.Clientset.CoreV1().Secrets(claim.Namespace).Create(secret); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create secret for claim. %+v", err)
+		}
+		if _, err := c.context.Clientset.CoreV1().Secrets(claim.Namespace).Update(secret); err != nil {
+			return fmt.Errorf("failed to update secret for claim. %+v", err)
+		}
+	}
+
+	configMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: claim.Name, Namespace: claim.Namespace},
+		Data: map[string]string{
+			BucketHostKey: host,
+			BucketPortKey: fmt.Sprintf("%d", port),
+			BucketNameKey: claim.bucketName(),
+			BucketSSLKey:  fmt.Sprintf("%t", secure),
+		},
+	}
+	if _, err := c.context.Clientset.CoreV1().ConfigMaps(claim.Namespace).Create(configMap); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create config map for claim. %+v", err)
+		}
+		if _, err := c.context.Clientset.CoreV1().ConfigMaps(claim.Namespace).Update(configMap); err != nil {
+			return fmt.Errorf("failed to update config map for claim. %+v", err)
+		}
+	}
+
+	logger.Infof("provisioned bucket %s for claim %s/%s", claim.bucketName(), claim.Namespace, claim.Name)
+	return nil
+}
+
+// recordBucket saves the id and owner of the bucket in the status of the claim, so the claim only deletes the bucket
+// it provisioned
+func (c *ObjectBucketClaimController) recordBucket(objContext *cephrgw.Context, claim *ObjectBucketClaim) error {
+	owner, id, _, err := cephrgw.GetBucketOwner(objContext, claim.bucketName())
+	if err != nil {
+		return fmt.Errorf("failed to get owner of bucket %s. %+v", claim.bucketName(), err)
+	}
+	if owner != claim.userID() {
+		return fmt.Errorf("bucket %s is owned by user %s", claim.bucketName(), owner)
+	}
+	if claim.Status.BucketID == id && claim.Status.Owner == owner {
+		return nil
+	}
+
+	claim.Status = ObjectBucketClaimStatus{BucketID: id, Owner: owner}
+	if err := c.updateStatus(claim); err != nil {
+		return fmt.Errorf("failed to save status of claim. %+v", err)
+	}
+	return nil
+}
+
+func (c *ObjectBucketClaimController) putClaim(claim *ObjectBucketClaim) error {
+	return c.client.Put().
+		Namespace(claim.Namespace).
+		Resource(ObjectBucketClaimResource.Plural).
+		Name(claim.Name).
+		Body(claim).
+		Do().Error()
+}
+
+// delete removes the bucket and user of the claim and the connection info. If the objects are not purged, the bucket
+// and user are only removed if the bucket is empty. The bucket is only removed if it is the bucket that was
+// provisioned for the claim and is still owned by the user of the claim.
+func (c *ObjectBucketClaimController) delete(claim *ObjectBucketClaim) error {
+	objContext, err := rgw.GetObjectContext(c.context, claim.clusterName(), claim.Spec.ObjectStore)
+	if err != nil {
+		return fmt.Errorf("the bucket and its user are kept. %+v", err)
+	}
+	logger.Infof("deleting bucket %s of claim %s/%s. purge=%t", claim.bucketName(), claim.Namespace, claim.Name, claim.Spec.Purge)
+
+	owner, id, code, err := cephrgw.GetBucketOwner(objContext, claim.bucketName())
+	if err != nil && code != cephrgw.RGWErrorNotFound {
+		return fmt.Errorf("failed to get owner of bucket %s. the bucket and its user are kept. %+v", claim.bucketName(), err)
+	}
+	if err == nil {
+		if owner != claim.userID() || (claim.Status.BucketID != "" && id != claim.Status.BucketID) {
+			return fmt.Errorf("bucket %s with id %s and owner %s was not provisioned for the claim. the bucket and the user are kept",
+				claim.bucketName(), id, owner)
+		}
+		code, err = cephrgw.DeleteBucket(objContext, claim.bucketName(), claim.Spec.Purge)
+		if err != nil && code != cephrgw.RGWErrorNotFound {
+			return fmt.Errorf("failed to delete bucket %s. the bucket and its user are kept. %+v", claim.bucketName(), err)
+		}
+	}
+
+	user, code, err := cephrgw.GetUser(objContext, claim.userID())
+	if err != nil && code != cephrgw.RGWErrorNotFound {
+		return fmt.Errorf("failed to get user %s. %+v", claim.userID(), err)
+	}
+	if err == nil {
+		if !claim.createdUser(user) {
+			return fmt.Errorf("user %s was not created for the claim. the user is kept", claim.userID())
+		}
+		_, code, err = cephrgw.DeleteUser(objContext, claim.userID())
+		if err != nil && code != cephrgw.RGWErrorNotFound {
+			return fmt.Errorf("failed to delete user %s. %+v", claim.userID(), err)
+		}
+	}
+
+	err = c.context.Clientset.CoreV1().Secrets(claim.Namespace).Delete(claim.Name, &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete secret of claim. %+v", err)
+	}
+	err = c.context.Clientset.CoreV1().ConfigMaps(claim.Namespace).Delete(claim.Name, &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete config map of claim. %+v", err)
+	}
+
+	logger.Infof("deleted bucket %s of claim %s/%s", claim.bucketName(), claim.Namespace, claim.Name)
+	return nil
+}
+
+// create the user or get the existing user if it was created for the claim. A user that exists but was not created
+// for the claim is never adopted.
+func createUser(objContext *cephrgw.Context, claim *ObjectBucketClaim) (*model.ObjectUser, error) {
+	userID := claim.userID()
+	displayName := claim.userDisplayName()
+	user, code, err := cephrgw.CreateUser(objContext, model.ObjectUser{UserID: userID, DisplayName: &displayName})
+	if err == nil {
+		return user, nil
+	}
+	if code != cephrgw.RGWErrorExists {
+		return nil, fmt.Errorf("failed to create user %s. %+v", userID, err)
+	}
+
+	user, _, err = cephrgw.GetUser(objContext, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user %s. %+v", userID, err)
+	}
+	if !claim.createdUser(user) {
+		return nil, fmt.Errorf("user %s already exists and was not created for the claim", userID)
+	}
+	return user, nil
+}
+
+func (c *ObjectBucketClaim) clusterName() string {
+	if c.Spec.ClusterName == "" {
+		return cluster.DefaultClusterName
+	}
+	return c.Spec.ClusterName
+}
+
+func (c *ObjectBucketClaim) bucketName() string {
+	if c.Spec.BucketName == "" {
+		// namespaces cannot contain dots, so the name is unique
+		return fmt.Sprintf("%s.%s", c.Namespace, c.Name)
+	}
+	return c.Spec.BucketName
+}
+
+// the id of the user of the claim. underscores are not allowed in the names of namespaces and claims, so the id is
+// unique.
+func (c *ObjectBucketClaim) userID() string {
+	return fmt.Sprintf("bucket-claim_%s_%s", c.Namespace, c.Name)
+}
+
+// the display name of the user marks the user as created for this instance of the claim
+func (c *ObjectBucketClaim) userDisplayName() string {
+	return fmt.Sprintf("bucket claim %s/%s %s", c.Namespace, c.Name, c.UID)
+}
+
+func (c *ObjectBucketClaim) createdUser(user *model.ObjectUser) bool {
+	return user.DisplayName != nil && *user.DisplayName == c.userDisplayName()
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bucket
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	userInfo    = `{"user_id":"bucket-claim_apps_photos","display_name":"bucket claim apps/photos uid-1","keys":[{"access_key":"myaccess","secret_key":"mysecret"}]}`
+	otherUser   = `{"user_id":"bucket-claim_apps_photos","display_name":"someone else","keys":[{"access_key":"other","secret_key":"other"}]}`
+	bucketStats = `{"bucket":"apps.photos","id":"bucket-id-1","owner":"bucket-claim_apps_photos"}`
+)
+
+func TestProvisionBucket(t *testing.T) {
+	clientset := test.New(3)
+	var commands [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			commands = append(commands, args[:2])
+			if args[0] == "bucket" {
+				return bucketStats, nil
+			}
+			return userInfo, nil
+		},
+	}
+	context := &clusterd.Context{Clientset: clientset, Executor: executor}
+
+	var endpoint, bucket string
+	var status *ObjectBucketClaimStatus
+	c := &ObjectBucketClaimController{
+		context: context,
+		createBucket: func(e, accessKey, secretKey string, caCert []byte, bucketName string) error {
+			assert.Equal(t, "myaccess", accessKey)
+			assert.Equal(t, "mysecret", secretKey)
+			endpoint = e
+			bucket = bucketName
+			return nil
+		},
+		updateStatus: func(claim *ObjectBucketClaim) error {
+			status = &claim.Status
+			return nil
+		},
+	}
+
+	claim := &ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "photos", Namespace: "apps", UID: "uid-1"},
+		Spec:       ObjectBucketClaimSpec{ObjectStore: "my-store"},
+	}
+
+	// the object store service must exist
+	err := c.provision(claim)
+	assert.NotNil(t, err)
+
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-rgw-my-store", Namespace: "rook"},
+		Spec:       v1.ServiceSpec{Ports: []v1.ServicePort{{Name: "https", Port: 443}, {Name: "http", Port: 8080}}},
+	}
+	clientset.CoreV1().Services("rook").Create(svc)
+
+	err = c.provision(claim)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"user", "create"}, {"bucket", "stats"}}, commands)
+	assert.Equal(t, "rook-ceph-rgw-my-store.rook:8080", endpoint)
+	assert.Equal(t, "apps.photos", bucket)
+
+	// the bucket is recorded in the status of the claim
+	assert.Equal(t, &ObjectBucketClaimStatus{BucketID: "bucket-id-1", Owner: "bucket-claim_apps_photos"}, status)
+
+	secret, err := clientset.CoreV1().Secrets("apps").Get("photos", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "myaccess", secret.StringData[AccessKeyIDKey])
+	assert.Equal(t, "mysecret", secret.StringData[SecretAccessKeyKey])
+
+	cm, err := clientset.CoreV1().ConfigMaps("apps").Get("photos", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "rook-ceph-rgw-my-store.rook", cm.Data[BucketHostKey])
+	assert.Equal(t, "8080", cm.Data[BucketPortKey])
+	assert.Equal(t, "apps.photos", cm.Data[BucketNameKey])
+	assert.Equal(t, "false", cm.Data[BucketSSLKey])
+
+	// provisioning again uses the existing user that was created for the claim
+	commands = nil
+	status = nil
+	executor.MockExecuteCommandWithCombinedOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		commands = append(commands, args[:2])
+		switch {
+		case args[1] == "create":
+			return "could not create user: unable to create user, user: bucket-claim_apps_photos exists", nil
+		case args[0] == "bucket":
+			return bucketStats, nil
+		}
+		return userInfo, nil
+	}
+	err = c.provision(claim)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"user", "create"}, {"user", "info"}, {"bucket", "stats"}}, commands)
+	assert.Nil(t, status)
+
+	// a user that was not created for the claim is not adopted
+	executor.MockExecuteCommandWithCombinedOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		if args[1] == "create" {
+			return "could not create user: unable to create user, user: bucket-claim_apps_photos exists", nil
+		}
+		return otherUser, nil
+	}
+	err = c.provision(claim)
+	assert.NotNil(t, err)
+
+	// a gateway without an http port is reached with https
+	clientset.CoreV1().Services("rook").Delete(svc.Name, &metav1.DeleteOptions{})
+	svc.Spec.Ports = []v1.ServicePort{{Name: "https", Port: 443}}
+	clientset.CoreV1().Services("rook").Create(svc)
+	executor.MockExecuteCommandWithCombinedOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		if args[0] == "bucket" {
+			return bucketStats, nil
+		}
+		return userInfo, nil
+	}
+	err = c.provision(claim)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://rook-ceph-rgw-my-store.rook:443", endpoint)
+
+	// the generated bucket name must not exceed the limit of S3
+	commands = nil
+	claim.Name = strings.Repeat("a", 60)
+	err = c.provision(claim)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, len(commands))
+
+	// a bucket name in the claim is used instead
+	claim.Spec.BucketName = "photos"
+	err = c.provision(claim)
+	assert.Nil(t, err)
+	assert.Equal(t, "photos", bucket)
+}
+
+func TestDeleteBucket(t *testing.T) {
+	clientset := test.New(3)
+	var commands [][]string
+	stats := `{"bucket":"mybucket","id":"bucket-id-1","owner":"bucket-claim_apps_photos"}`
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			commands = append(commands, args)
+			switch {
+			case args[0] == "bucket" && args[1] == "stats":
+				return stats, nil
+			case args[0] == "user" && args[1] == "info":
+				return userInfo, nil
+			}
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{Clientset: clientset, Executor: executor}
+	c := NewObjectBucketClaimController(context)
+	clientset.CoreV1().Secrets("apps").Create(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "photos", Namespace: "apps"}})
+	clientset.CoreV1().ConfigMaps("apps").Create(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "photos", Namespace: "apps"}})
+
+	claim := &ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "photos", Namespace: "apps", UID: "uid-1"},
+		Spec:       ObjectBucketClaimSpec{ObjectStore: "my-store", BucketName: "mybucket", Purge: true},
+		Status:     ObjectBucketClaimStatus{BucketID: "bucket-id-1", Owner: "bucket-claim_apps_photos"},
+	}
+
+	// the object store service must exist
+	err := c.delete(claim)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, len(commands))
+	svc := &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-rgw-my-store", Namespace: "rook"}}
+	clientset.CoreV1().Services("rook").Create(svc)
+
+	// the bucket is not deleted if it is owned by another user
+	stats = `{"bucket":"mybucket","id":"bucket-id-1","owner":"someone"}`
+	err = c.delete(claim)
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, len(commands))
+
+	// the bucket is not deleted if it was created again
+	commands = nil
+	stats = `{"bucket":"mybucket","id":"bucket-id-2","owner":"bucket-claim_apps_photos"}`
+	err = c.delete(claim)
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, len(commands))
+
+	commands = nil
+	stats = `{"bucket":"mybucket","id":"bucket-id-1","owner":"bucket-claim_apps_photos"}`
+	err = c.delete(claim)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(commands))
+	assert.Equal(t, []string{"bucket", "rm", "--bucket", "mybucket", "--purge-objects"}, commands[1][:5])
+	assert.Equal(t, []string{"user", "rm", "--uid", "bucket-claim_apps_photos"}, commands[3][:4])
+
+	_, err = clientset.CoreV1().Secrets("apps").Get("photos", metav1.GetOptions{})
+	assert.NotNil(t, err)
+	_, err = clientset.CoreV1().ConfigMaps("apps").Get("photos", metav1.GetOptions{})
+	assert.NotNil(t, err)
+
+	// the user is kept if the bucket cannot be deleted
+	commands = nil
+	executor.MockExecuteCommandWithCombinedOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		commands = append(commands, args)
+		if args[1] == "stats" {
+			return stats, nil
+		}
+		return "ERROR: could not remove non-empty bucket mybucket", nil
+	}
+	claim.Spec.Purge = false
+	err = c.delete(claim)
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, len(commands))
+
+	// a user that was not created for the claim is not deleted
+	commands = nil
+	executor.MockExecuteCommandWithCombinedOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		commands = append(commands, args)
+		if args[1] == "stats" {
+			return "could not get bucket info for bucket=mybucket", nil
+		}
+		return otherUser, nil
+	}
+	err = c.delete(claim)
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, len(commands))
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bucket to provision object store buckets for claims.
+package bucket
+
+import (
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// schemeGroupVersion is group version used to register these objects
+var schemeGroupVersion = schema.GroupVersion{Group: k8sutil.CustomResourceGroup, Version: k8sutil.V1Alpha1}
+
+// ObjectBucketClaim is the definition of the object bucket claim custom resource
+type ObjectBucketClaim struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              ObjectBucketClaimSpec   `json:"spec"`
+	Status            ObjectBucketClaimStatus `json:"status,omitempty"`
+}
+
+// ObjectBucketClaimList is the definition of a list of object bucket claims
+type ObjectBucketClaimList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []ObjectBucketClaim `json:"items"`
+}
+
+// ObjectBucketClaimSpec represent the spec of an object bucket claim
+type ObjectBucketClaimSpec struct {
+	// The object store where the bucket is created
+	ObjectStore string `json:"objectStore"`
+
+	// The namespace of the Rook cluster where the object store is found. Default is "rook".
+	ClusterName string `json:"clusterName"`
+
+	// The name of the bucket. Default is the namespace and name of the claim separated by a dot.
+	BucketName string `json:"bucketName"`
+
+	// Whether the objects in the bucket are deleted with the claim. If false, the bucket is only deleted if it is empty.
+	Purge bool `json:"purge"`
+}
+
+// ObjectBucketClaimStatus is the bucket that was provisioned for the claim
+type ObjectBucketClaimStatus struct {
+	// The id of the bucket, which changes when a bucket with the same name is created again
+	BucketID string `json:"bucketId,omitempty"`
+
+	// The user that owns the bucket
+	Owner string `json:"owner,omitempty"`
+}
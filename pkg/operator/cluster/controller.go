@@ -35,6 +35,7 @@ import (
 	"github.com/rook/rook/pkg/operator/mon"
 	"github.com/rook/rook/pkg/operator/osd"
 	"github.com/rook/rook/pkg/operator/pool"
+	"github.com/rook/rook/pkg/operator/rbdmirror"
 	"github.com/rook/rook/pkg/operator/rgw"
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -166,7 +167,25 @@ func (c *ClusterController) onAdd(obj interface{}) {
 }
 
 func (c *ClusterController) onUpdate(oldObj, newObj interface{}) {
-	logger.Infof("modifying a cluster not implemented")
+	oldCluster := oldObj.(*Cluster)
+	newCluster := newObj.(*Cluster)
+	if oldCluster.Spec.RBDMirroring.Workers == newCluster.Spec.RBDMirroring.Workers {
+		logger.Infof("modifying a cluster not implemented")
+		return
+	}
+
+	// the number of rbd-mirror daemons is the only setting that can be modified
+	copyObj, err := c.scheme.Copy(newCluster)
+	if err != nil {
+		logger.Errorf("failed to create a deep copy of cluster object: %v\n", err)
+		return
+	}
+	cluster := copyObj.(*Cluster)
+	cluster.init(c.context)
+	logger.Infof("changing the rbd-mirror workers of cluster %s from %d to %d", cluster.Name, oldCluster.Spec.RBDMirroring.Workers, cluster.Spec.RBDMirroring.Workers)
+	if err := cluster.startRBDMirrors(); err != nil {
+		logger.Errorf("failed to update cluster %s in namespace %s. %+v", cluster.Name, cluster.Namespace, err)
+	}
 }
 
 func (c *ClusterController) onDelete(obj interface{}) {
@@ -222,10 +241,24 @@ func (c *Cluster) createInstance() error {
 		return fmt.Errorf("failed to start the osds. %+v", err)
 	}
 
+	// Start the rbd-mirror daemons if mirroring is enabled
+	if err := c.startRBDMirrors(); err != nil {
+		return err
+	}
+
 	logger.Infof("Done creating rook instance in namespace %s", c.Namespace)
 	return nil
 }
 
+// start the rbd-mirror daemons, or remove them if the number of workers was reduced
+func (c *Cluster) startRBDMirrors() error {
+	c.rbdMirrors = rbdmirror.New(c.context, c.Namespace, c.Spec.VersionTag, c.Spec.RBDMirroring.Workers, c.Spec.Placement.GetRBDMirror(), c.Spec.HostNetwork)
+	if err := c.rbdMirrors.Start(); err != nil {
+		return fmt.Errorf("failed to start the rbd-mirror daemons. %+v", err)
+	}
+	return nil
+}
+
 func (c *Cluster) createInitialCrushMap() error {
 	configMapExists := false
 	createCrushMap := false
@@ -27,6 +27,7 @@ import (
 	"github.com/rook/rook/pkg/operator/mgr"
 	"github.com/rook/rook/pkg/operator/mon"
 	"github.com/rook/rook/pkg/operator/osd"
+	"github.com/rook/rook/pkg/operator/rbdmirror"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
@@ -44,6 +45,7 @@ type Cluster struct {
 	mgrs              *mgr.Cluster
 	osds              *osd.Cluster
 	apis              *api.Cluster
+	rbdMirrors        *rbdmirror.Cluster
 	stopCh            chan struct{}
 }
 
@@ -78,17 +80,27 @@ type ClusterSpec struct {
 
 	// MonCount sets the mon size
 	MonCount int `json:"monCount"`
+
+	// RBDMirroring sets the rbd-mirror daemons that replicate block images to peer clusters
+	RBDMirroring RBDMirroringSpec `json:"rbdMirroring,omitempty"`
+}
+
+// RBDMirroringSpec represents the settings for the rbd-mirror daemons
+type RBDMirroringSpec struct {
+	// Workers is the number of rbd-mirror daemons to run. No daemons are started if zero.
+	Workers int `json:"workers"`
 }
 
 // PlacementSpec is a set of Placement configurations for the rook cluster.
 type PlacementSpec struct {
-	All k8sutil.Placement `json:"all,omitempty"`
-	API k8sutil.Placement `json:"api,omitempty"`
-	MDS k8sutil.Placement `json:"mds,omitempty"`
-	MGR k8sutil.Placement `json:"mgr,omitempty"`
-	MON k8sutil.Placement `json:"mon,omitempty"`
-	OSD k8sutil.Placement `json:"osd,omitempty"`
-	RGW k8sutil.Placement `json:"rgw,omitempty"`
+	All       k8sutil.Placement `json:"all,omitempty"`
+	API       k8sutil.Placement `json:"api,omitempty"`
+	MDS       k8sutil.Placement `json:"mds,omitempty"`
+	MGR       k8sutil.Placement `json:"mgr,omitempty"`
+	MON       k8sutil.Placement `json:"mon,omitempty"`
+	OSD       k8sutil.Placement `json:"osd,omitempty"`
+	RGW       k8sutil.Placement `json:"rgw,omitempty"`
+	RBDMirror k8sutil.Placement `json:"rbdmirror,omitempty"`
 }
 
 // GetAPI returns the placement for the API service
@@ -108,3 +120,6 @@ func (p PlacementSpec) GetOSD() k8sutil.Placement { return p.All.Merge(p.OSD) }
 
 // GetRGW returns the placement for the RGW service
 func (p PlacementSpec) GetRGW() k8sutil.Placement { return p.All.Merge(p.RGW) }
+
+// GetRBDMirror returns the placement for the rbd-mirror daemons
+func (p PlacementSpec) GetRBDMirror() k8sutil.Placement { return p.All.Merge(p.RBDMirror) }
@@ -0,0 +1,321 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package objectuser to manage the users of object stores.
+package objectuser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreos/pkg/capnslog"
+	cephrgw "github.com/rook/rook/pkg/ceph/rgw"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/operator/cluster"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/kit"
+	"github.com/rook/rook/pkg/operator/rgw"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// the keys in the secret created for a user
+	AccessKeyIDKey     = "AWS_ACCESS_KEY_ID"
+	SecretAccessKeyKey = "AWS_SECRET_ACCESS_KEY"
+)
+
+var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-object-user")
+
+// the admin API capabilities of a user and their permissions
+var (
+	capabilityTypes = map[string]bool{"users": true, "buckets": true, "metadata": true, "usage": true, "zone": true}
+	capabilityPerms = map[string]bool{"read": true, "write": true, "*": true}
+)
+
+// ObjectStoreUserController represents a controller object for object store user custom resources
+type ObjectStoreUserController struct {
+	context           *clusterd.Context
+	scheme            *runtime.Scheme
+	operatorNamespace string
+}
+
+// NewObjectStoreUserController create controller for watching object store user custom resources created. Only the
+// resources in the namespace of the operator can set the id of their user.
+func NewObjectStoreUserController(context *clusterd.Context, operatorNamespace string) *ObjectStoreUserController {
+	return &ObjectStoreUserController{
+		context:           context,
+		operatorNamespace: operatorNamespace,
+	}
+}
+
+// StartWatch watches for instances of ObjectStoreUser custom resources and acts on them
+func (c *ObjectStoreUserController) StartWatch(namespace string, stopCh chan struct{}) error {
+	client, scheme, err := kit.NewHTTPClient(k8sutil.CustomResourceGroup, k8sutil.V1Alpha1, schemeBuilder)
+	if err != nil {
+		return fmt.Errorf("failed to get a k8s client for watching object store user resources: %v", err)
+	}
+	c.scheme = scheme
+
+	resourceHandlerFuncs := cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.onAdd,
+		UpdateFunc: c.onUpdate,
+		DeleteFunc: c.onDelete,
+	}
+	watcher := kit.NewWatcher(ObjectStoreUserResource, namespace, resourceHandlerFuncs, client)
+	go watcher.Watch(&ObjectStoreUser{}, stopCh)
+	return nil
+}
+
+func (c *ObjectStoreUserController) onAdd(obj interface{}) {
+	user := obj.(*ObjectStoreUser)
+
+	// NEVER modify objects from the store. It's a read-only, local cache.
+	// Use scheme.Copy() to make a deep copy of original object.
+	copyObj, err := c.scheme.Copy(user)
+	if err != nil {
+		logger.Errorf("failed to create a deep copy of object store user: %v", err)
+		return
+	}
+	userCopy := copyObj.(*ObjectStoreUser)
+
+	if err := c.reconcile(userCopy); err != nil {
+		logger.Errorf("failed to create object store user %s/%s. %+v", user.Namespace, user.Name, err)
+	}
+}
+
+func (c *ObjectStoreUserController) onUpdate(oldObj, newObj interface{}) {
+	oldUser := oldObj.(*ObjectStoreUser)
+	user := newObj.(*ObjectStoreUser)
+
+	if oldUser.Spec.ObjectStore != user.Spec.ObjectStore || oldUser.Spec.ClusterName != user.Spec.ClusterName ||
+		oldUser.userID() != user.userID() {
+		logger.Errorf("failed to update object store user %s/%s. the object store and user id cannot be changed", user.Namespace, user.Name)
+		return
+	}
+
+	if err := c.reconcile(user); err != nil {
+		logger.Errorf("failed to update object store user %s/%s. %+v", user.Namespace, user.Name, err)
+	}
+}
+
+func (c *ObjectStoreUserController) onDelete(obj interface{}) {
+	user := obj.(*ObjectStoreUser)
+	if err := c.delete(user); err != nil {
+		logger.Errorf("failed to delete object store user %s/%s. %+v", user.Namespace, user.Name, err)
+	}
+}
+
+// reconcile creates or updates the user in the object store and saves the keys of the user in a secret
+func (c *ObjectStoreUserController) reconcile(user *ObjectStoreUser) error {
+	if err := user.validate(c.operatorNamespace); err != nil {
+		return fmt.Errorf("invalid object store user. %+v", err)
+	}
+
+	objContext, err := rgw.GetObjectContext(c.context, user.clusterName(), user.Spec.ObjectStore)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("reconciling user %s in object store %s for %s/%s", user.userID(), user.Spec.ObjectStore, user.Namespace, user.Name)
+	objectUser, err := createOrUpdateUser(objContext, user)
+	if err != nil {
+		return err
+	}
+	if objectUser.AccessKey == nil || objectUser.SecretKey == nil {
+		return fmt.Errorf("keys of user %s not found", objectUser.UserID)
+	}
+
+	if err := cephrgw.ConfigureUser(objContext, user.userID(), user.config()); err != nil {
+		return fmt.Errorf("failed to configure user %s. %+v", user.userID(), err)
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: user.Name, Namespace: user.Namespace},
+		StringData: map[string]string{
+			AccessKeyIDKey:     *objectUser.AccessKey,
+			SecretAccessKeyKey: *objectUser.SecretKey,
+		},
+		Type: k8sutil.RookType,
+	}
+	if _, err := c.context.Clientset.CoreV1().Secrets(user.Namespace).Create(secret); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create secret for user. %+v", err)
+		}
+		if _, err := c.context.Clientset.CoreV1().Secrets(user.Namespace).Update(secret); err != nil {
+			return fmt.Errorf("failed to update secret for user. %+v", err)
+		}
+	}
+
+	logger.Infof("reconciled user %s for %s/%s", user.userID(), user.Namespace, user.Name)
+	return nil
+}
+
+// delete removes the user from the object store and its secret. A user that still owns buckets or that was not created
+// for the resource is not removed.
+func (c *ObjectStoreUserController) delete(user *ObjectStoreUser) error {
+	objContext, err := rgw.GetObjectContext(c.context, user.clusterName(), user.Spec.ObjectStore)
+	if err != nil {
+		return fmt.Errorf("the user is kept. %+v", err)
+	}
+	logger.Infof("deleting user %s of %s/%s", user.userID(), user.Namespace, user.Name)
+	objectUser, code, err := cephrgw.GetUser(objContext, user.userID())
+	if err != nil && code != cephrgw.RGWErrorNotFound {
+		return fmt.Errorf("failed to get user %s. %+v", user.userID(), err)
+	}
+	if err == nil {
+		if user.createdUser(objectUser) {
+			_, code, err := cephrgw.DeleteUser(objContext, user.userID())
+			if err != nil && code != cephrgw.RGWErrorNotFound {
+				return fmt.Errorf("failed to delete user %s. %+v", user.userID(), err)
+			}
+		} else {
+			logger.Warningf("user %s was not created for %s/%s. the user is kept", user.userID(), user.Namespace, user.Name)
+		}
+	}
+
+	err = c.context.Clientset.CoreV1().Secrets(user.Namespace).Delete(user.Name, &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete secret of user. %+v", err)
+	}
+
+	logger.Infof("deleted user %s of %s/%s", user.userID(), user.Namespace, user.Name)
+	return nil
+}
+
+// create the user or update the name and email of the user if it was created for the resource before. An existing user
+// that was not created for the resource is not taken over.
+func createOrUpdateUser(objContext *cephrgw.Context, user *ObjectStoreUser) (*model.ObjectUser, error) {
+	created, code, err := cephrgw.CreateUser(objContext, user.toModel())
+	if err == nil {
+		return created, nil
+	}
+	if code != cephrgw.RGWErrorExists {
+		return nil, fmt.Errorf("failed to create user %s. %+v", user.userID(), err)
+	}
+
+	existing, _, err := cephrgw.GetUser(objContext, user.userID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user %s. %+v", user.userID(), err)
+	}
+	if !user.createdUser(existing) {
+		return nil, fmt.Errorf("user %s already exists and was not created for %s/%s", user.userID(), user.Namespace, user.Name)
+	}
+
+	updated, _, err := cephrgw.UpdateUser(objContext, user.toModel())
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user %s. %+v", user.userID(), err)
+	}
+	return updated, nil
+}
+
+func (u *ObjectStoreUser) validate(operatorNamespace string) error {
+	if u.Spec.ObjectStore == "" {
+		return fmt.Errorf("objectStore is required")
+	}
+	// the users of other namespaces get an id derived from their resource so they cannot take over an existing user
+	if u.Spec.UserID != "" && u.Namespace != operatorNamespace {
+		return fmt.Errorf("userId can only be set in namespace %s", operatorNamespace)
+	}
+	// the admin API gives access to all the users and buckets of the object store
+	if len(u.Spec.Capabilities) > 0 && u.Namespace != u.clusterName() {
+		return fmt.Errorf("capabilities can only be granted in namespace %s of the cluster", u.clusterName())
+	}
+	for capType, perm := range u.Spec.Capabilities {
+		if !capabilityTypes[capType] {
+			return fmt.Errorf("invalid capability %s", capType)
+		}
+		if !capabilityPerms[perm] {
+			return fmt.Errorf("invalid permission %s of capability %s", perm, capType)
+		}
+	}
+	if u.Spec.MaxBuckets != nil && *u.Spec.MaxBuckets < 0 {
+		return fmt.Errorf("maxBuckets cannot be negative")
+	}
+	for _, quota := range []*QuotaSpec{u.Spec.Quota, u.Spec.BucketQuota} {
+		if quota != nil && (quota.MaxSize < 0 || quota.MaxObjects < 0) {
+			return fmt.Errorf("quotas cannot be negative")
+		}
+	}
+	return nil
+}
+
+func (u *ObjectStoreUser) toModel() model.ObjectUser {
+	displayName := u.displayName()
+	user := model.ObjectUser{UserID: u.userID(), DisplayName: &displayName}
+	if u.Spec.Email != "" {
+		user.Email = &u.Spec.Email
+	}
+	return user
+}
+
+func (u *ObjectStoreUser) config() cephrgw.UserConfig {
+	return cephrgw.UserConfig{
+		MaxBuckets:   u.Spec.MaxBuckets,
+		Suspended:    u.Spec.Suspended,
+		Capabilities: u.Spec.Capabilities,
+		Quota:        toQuota(u.Spec.Quota),
+		BucketQuota:  toQuota(u.Spec.BucketQuota),
+	}
+}
+
+func toQuota(quota *QuotaSpec) *cephrgw.ObjectQuota {
+	if quota == nil {
+		return nil
+	}
+	return &cephrgw.ObjectQuota{MaxSize: quota.MaxSize, MaxObjects: quota.MaxObjects}
+}
+
+func (u *ObjectStoreUser) clusterName() string {
+	if u.Spec.ClusterName == "" {
+		return cluster.DefaultClusterName
+	}
+	return u.Spec.ClusterName
+}
+
+// userID returns the id of the user. The default id joins the namespace and name with an underscore, which cannot be
+// part of either, so the ids of different resources never match.
+func (u *ObjectStoreUser) userID() string {
+	if u.Spec.UserID == "" {
+		return fmt.Sprintf("%s_%s", u.Namespace, u.Name)
+	}
+	return u.Spec.UserID
+}
+
+// displayName returns the display name of the user, which ends with the marker of the resource the user is created for
+func (u *ObjectStoreUser) displayName() string {
+	if u.Spec.DisplayName == "" {
+		return u.marker()
+	}
+	return fmt.Sprintf("%s (%s)", u.Spec.DisplayName, u.marker())
+}
+
+func (u *ObjectStoreUser) marker() string {
+	return fmt.Sprintf("object store user %s/%s", u.Namespace, u.Name)
+}
+
+// createdUser checks if the user was created for the resource. The display name of the user keeps the marker of the
+// resource when the display name in the spec changes.
+func (u *ObjectStoreUser) createdUser(user *model.ObjectUser) bool {
+	if user.DisplayName == nil {
+		return false
+	}
+	return *user.DisplayName == u.marker() || strings.HasSuffix(*user.DisplayName, fmt.Sprintf("(%s)", u.marker()))
+}
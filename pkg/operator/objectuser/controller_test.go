@@ -0,0 +1,158 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package objectuser
+
+import (
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const userInfo = `{"user_id":"apps_backup","display_name":"Backup (object store user apps/backup)","keys":[{"access_key":"myaccess","secret_key":"mysecret"}]}`
+
+func TestReconcileUser(t *testing.T) {
+	clientset := test.New(3)
+	userExists := false
+	info := userInfo
+	var commands [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			commands = append(commands, args[:2])
+			if args[0] == "user" && args[1] == "create" {
+				assert.Equal(t, []string{"--uid", "apps_backup", "--display-name", "Backup (object store user apps/backup)"}, args[2:6])
+				if userExists {
+					return "could not create user: unable to create user, user: apps_backup exists", nil
+				}
+			}
+			return info, nil
+		},
+	}
+	context := &clusterd.Context{Clientset: clientset, Executor: executor}
+	c := NewObjectStoreUserController(context, "rook-system")
+
+	maxBuckets := 5
+	user := &ObjectStoreUser{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "apps"},
+		Spec: ObjectStoreUserSpec{
+			DisplayName: "Backup",
+			MaxBuckets:  &maxBuckets,
+			Quota:       &QuotaSpec{MaxSize: 1024},
+		},
+	}
+
+	// the object store is required
+	err := c.reconcile(user)
+	assert.NotNil(t, err)
+
+	// the service of the object store is required
+	user.Spec.ObjectStore = "my-store"
+	err = c.reconcile(user)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, len(commands))
+
+	svc := &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-rgw-my-store", Namespace: "rook"}}
+	clientset.CoreV1().Services("rook").Create(svc)
+	err = c.reconcile(user)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"user", "create"}, commands[0])
+	assert.Contains(t, commands, []string{"quota", "set"})
+
+	secret, err := clientset.CoreV1().Secrets("apps").Get("backup", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "myaccess", secret.StringData[AccessKeyIDKey])
+	assert.Equal(t, "mysecret", secret.StringData[SecretAccessKeyKey])
+
+	// the existing user that was created for the resource is updated
+	userExists = true
+	commands = nil
+	err = c.reconcile(user)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"user", "create"}, {"user", "info"}, {"user", "modify"}}, commands[:3])
+
+	// an existing user that was not created for the resource is not taken over
+	info = `{"user_id":"apps_backup","display_name":"Backup","keys":[{"access_key":"otheraccess","secret_key":"othersecret"}]}`
+	commands = nil
+	err = c.reconcile(user)
+	assert.NotNil(t, err)
+	assert.Equal(t, [][]string{{"user", "create"}, {"user", "info"}}, commands)
+
+	// the user is kept when the resource is deleted, but its secret is deleted
+	commands = nil
+	err = c.delete(user)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"user", "info"}}, commands)
+	_, err = clientset.CoreV1().Secrets("apps").Get("backup", metav1.GetOptions{})
+	assert.NotNil(t, err)
+
+	// the user created for the resource is deleted
+	info = userInfo
+	commands = nil
+	err = c.delete(user)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"user", "info"}, {"user", "rm"}}, commands)
+}
+
+func TestValidateUser(t *testing.T) {
+	user := &ObjectStoreUser{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "rook"},
+		Spec:       ObjectStoreUserSpec{ObjectStore: "my-store", Capabilities: map[string]string{"usage": "*"}},
+	}
+	assert.Nil(t, user.validate("rook-system"))
+	assert.Equal(t, "rook_backup", user.userID())
+	assert.Equal(t, "rook", user.clusterName())
+	assert.Equal(t, "object store user rook/backup", *user.toModel().DisplayName)
+
+	user.Spec.Capabilities["usage"] = "all"
+	assert.NotNil(t, user.validate("rook-system"))
+
+	user.Spec.Capabilities = map[string]string{"pools": "read"}
+	assert.NotNil(t, user.validate("rook-system"))
+
+	// capabilities are only granted in the namespace of the cluster
+	user.Spec.Capabilities = map[string]string{"usage": "read"}
+	user.Namespace = "apps"
+	assert.NotNil(t, user.validate("rook-system"))
+
+	user.Spec.Capabilities = nil
+	user.Spec.BucketQuota = &QuotaSpec{MaxObjects: -1}
+	assert.NotNil(t, user.validate("rook-system"))
+
+	// the user id is only set in the namespace of the operator
+	user.Spec.BucketQuota = nil
+	user.Spec.UserID = "admin"
+	assert.NotNil(t, user.validate("rook-system"))
+	user.Namespace = "rook-system"
+	assert.Nil(t, user.validate("rook-system"))
+	assert.Equal(t, "admin", user.userID())
+}
+
+func TestCreatedUser(t *testing.T) {
+	user := &ObjectStoreUser{ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "apps"}}
+	displayName := func(name string) *model.ObjectUser { return &model.ObjectUser{DisplayName: &name} }
+
+	assert.True(t, user.createdUser(displayName("object store user apps/backup")))
+	// the display name in the spec was changed
+	assert.True(t, user.createdUser(displayName("Old name (object store user apps/backup)")))
+	assert.False(t, user.createdUser(displayName("Backup")))
+	assert.False(t, user.createdUser(displayName("object store user apps/backup2")))
+	assert.False(t, user.createdUser(&model.ObjectUser{}))
+}
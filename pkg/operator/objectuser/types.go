@@ -0,0 +1,84 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package objectuser to manage the users of object stores.
+package objectuser
+
+import (
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// schemeGroupVersion is group version used to register these objects
+var schemeGroupVersion = schema.GroupVersion{Group: k8sutil.CustomResourceGroup, Version: k8sutil.V1Alpha1}
+
+// ObjectStoreUser is the definition of the object store user custom resource
+type ObjectStoreUser struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              ObjectStoreUserSpec `json:"spec"`
+}
+
+// ObjectStoreUserList is the definition of a list of object store users
+type ObjectStoreUserList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []ObjectStoreUser `json:"items"`
+}
+
+// ObjectStoreUserSpec represent the spec of an object store user
+type ObjectStoreUserSpec struct {
+	// The object store where the user is created
+	ObjectStore string `json:"objectStore"`
+
+	// The namespace of the Rook cluster where the object store is found. Default is "rook".
+	ClusterName string `json:"clusterName"`
+
+	// The id of the user. Default is the namespace and name of the resource. Only set in the namespace of the operator.
+	UserID string `json:"userId"`
+
+	// The display name of the user. The marker of the resource is appended to it.
+	DisplayName string `json:"displayName"`
+
+	// The email of the user
+	Email string `json:"email"`
+
+	// The maximum number of buckets of the user. Default is the rgw default of 1000.
+	MaxBuckets *int `json:"maxBuckets,omitempty"`
+
+	// The quota of all the objects of the user. Default is no quota.
+	Quota *QuotaSpec `json:"quota,omitempty"`
+
+	// The quota of each bucket of the user. Default is no quota.
+	BucketQuota *QuotaSpec `json:"bucketQuota,omitempty"`
+
+	// The permissions of the user to the admin API by type: users, buckets, metadata, usage or zone.
+	// The permission is read, write or *. Only granted in the namespace of the cluster.
+	Capabilities map[string]string `json:"capabilities"`
+
+	// Whether the user is suspended
+	Suspended bool `json:"suspended"`
+}
+
+// QuotaSpec represents a quota of the objects of a user or bucket
+type QuotaSpec struct {
+	// The maximum size of the objects in bytes. Default is unlimited.
+	MaxSize int64 `json:"maxSize"`
+
+	// The maximum number of objects. Default is unlimited.
+	MaxObjects int64 `json:"maxObjects"`
+}
@@ -32,10 +32,12 @@ import (
 	"github.com/rook/rook/pkg/agent/flexvolume/crd"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/operator/agent"
+	"github.com/rook/rook/pkg/operator/bucket"
 	"github.com/rook/rook/pkg/operator/cluster"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	"github.com/rook/rook/pkg/operator/kit"
 	"github.com/rook/rook/pkg/operator/mds"
+	"github.com/rook/rook/pkg/operator/objectuser"
 	"github.com/rook/rook/pkg/operator/pool"
 	"github.com/rook/rook/pkg/operator/provisioner"
 	"github.com/rook/rook/pkg/operator/rgw"
@@ -48,7 +50,8 @@ const (
 
 // volume provisioner constant
 const (
-	provisionerName = "rook.io/block"
+	provisionerName           = "rook.io/block"
+	filesystemProvisionerName = "rook.io/filesystem"
 )
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "operator")
@@ -61,6 +64,7 @@ type Operator struct {
 	// The cluster is global because you create multiple clusers in k8s
 	clusterController *cluster.ClusterController
 	volumeProvisioner controller.Provisioner
+	fsProvisioner     controller.Provisioner
 }
 
 // New creates an operator instance
@@ -72,12 +76,13 @@ func New(context *clusterd.Context) *Operator {
 	}
 	volumeProvisioner := provisioner.New(context)
 
-	schemes := []kit.CustomResource{cluster.ClusterResource, pool.PoolResource, rgw.ObjectStoreResource, mds.FilesystemResource, crd.VolumeAttachmentResource}
+	schemes := []kit.CustomResource{cluster.ClusterResource, pool.PoolResource, rgw.ObjectStoreResource, mds.FilesystemResource, crd.VolumeAttachmentResource, bucket.ObjectBucketClaimResource, objectuser.ObjectStoreUserResource}
 	return &Operator{
 		context:           context,
 		clusterController: clusterController,
 		resources:         schemes,
 		volumeProvisioner: volumeProvisioner,
+		fsProvisioner:     provisioner.NewFilesystemProvisioner(context),
 	}
 }
 
@@ -124,9 +129,30 @@ func (o *Operator) Run() error {
 	go pc.Run(stopChan)
 	logger.Infof("rook-provisioner started")
 
+	fspc := controller.NewProvisionController(
+		o.context.Clientset,
+		filesystemProvisionerName,
+		o.fsProvisioner,
+		serverVersion.GitVersion,
+	)
+	go fspc.Run(stopChan)
+	logger.Infof("rook file system provisioner started")
+
+	// resize the provisioned volumes when their claims request more storage
+	volumeExpander := provisioner.NewVolumeExpander(o.context)
+	go volumeExpander.StartWatch(stopChan)
+
 	// watch for changes to the rook clusters
 	o.clusterController.StartWatch(v1.NamespaceAll, stopChan)
 
+	// provision buckets for the object bucket claims in all namespaces
+	bucketController := bucket.NewObjectBucketClaimController(o.context)
+	bucketController.StartWatch(v1.NamespaceAll, stopChan)
+
+	// manage the object store users in all namespaces
+	userController := objectuser.NewObjectStoreUserController(o.context, namespace)
+	userController.StartWatch(v1.NamespaceAll, stopChan)
+
 	for {
 		select {
 		case <-signalChan:
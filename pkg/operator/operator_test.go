@@ -21,8 +21,10 @@ import (
 
 	"github.com/rook/rook/pkg/agent/flexvolume/crd"
 	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/bucket"
 	"github.com/rook/rook/pkg/operator/cluster"
 	"github.com/rook/rook/pkg/operator/mds"
+	"github.com/rook/rook/pkg/operator/objectuser"
 	"github.com/rook/rook/pkg/operator/pool"
 	"github.com/rook/rook/pkg/operator/rgw"
 	"github.com/rook/rook/pkg/operator/test"
@@ -39,9 +41,9 @@ func TestOperator(t *testing.T) {
 	assert.NotNil(t, o.resources)
 	assert.NotNil(t, o.volumeProvisioner)
 	assert.Equal(t, context, o.context)
-	assert.Equal(t, len(o.resources), 5)
+	assert.Equal(t, len(o.resources), 7)
 	for _, r := range o.resources {
-		if r.Name != cluster.ClusterResource.Name && r.Name != pool.PoolResource.Name && r.Name != rgw.ObjectStoreResource.Name && r.Name != mds.FilesystemResource.Name && r.Name != crd.VolumeAttachmentResource.Name {
+		if r.Name != cluster.ClusterResource.Name && r.Name != pool.PoolResource.Name && r.Name != rgw.ObjectStoreResource.Name && r.Name != mds.FilesystemResource.Name && r.Name != crd.VolumeAttachmentResource.Name && r.Name != bucket.ObjectBucketClaimResource.Name && r.Name != objectuser.ObjectStoreUserResource.Name {
 			assert.Fail(t, fmt.Sprintf("Resource %s is not valid", r.Name))
 		}
 	}
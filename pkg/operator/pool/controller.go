@@ -22,14 +22,21 @@ package pool
 
 import (
 	"fmt"
+	"reflect"
+	"sync"
+	"time"
 
 	"github.com/coreos/pkg/capnslog"
 	ceph "github.com/rook/rook/pkg/ceph/client"
+	cephrbdmirror "github.com/rook/rook/pkg/ceph/rbdmirror"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/model"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	"github.com/rook/rook/pkg/operator/kit"
+	"github.com/rook/rook/pkg/operator/rbdmirror"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -42,16 +49,28 @@ const (
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-pool")
 
+var (
+	// the interval and number of checks for reporting the progress of data movement after a pool is updated
+	dataMovementCheckInterval = 15 * time.Second
+	dataMovementMaxChecks     = 240
+)
+
 // PoolController represents a controller object for pool custom resources
 type PoolController struct {
 	context *clusterd.Context
 	scheme  *runtime.Scheme
+	client  rest.Interface
+
+	// the pools whose data movement is being reported
+	dataMovement     map[string]bool
+	dataMovementLock sync.Mutex
 }
 
 // NewPoolController create controller for watching pool custom resources created
 func NewPoolController(context *clusterd.Context) *PoolController {
 	return &PoolController{
-		context: context,
+		context:      context,
+		dataMovement: map[string]bool{},
 	}
 }
 
@@ -62,6 +81,7 @@ func (c *PoolController) StartWatch(namespace string, stopCh chan struct{}) erro
 		return fmt.Errorf("failed to get a k8s client for watching pool resources: %v", err)
 	}
 	c.scheme = scheme
+	c.client = client
 
 	resourceHandlerFuncs := cache.ResourceEventHandlerFuncs{
 		AddFunc:    c.onAdd,
@@ -88,6 +108,11 @@ func (c *PoolController) onAdd(obj interface{}) {
 	err = poolCopy.create(c.context)
 	if err != nil {
 		logger.Errorf("failed to create pool %s. %+v", pool.ObjectMeta.Name, err)
+		return
+	}
+
+	if err := poolCopy.configureMirroring(c.context, false); err != nil {
+		logger.Errorf("failed to configure mirroring for pool %s. %+v", pool.ObjectMeta.Name, err)
 	}
 }
 
@@ -95,6 +120,10 @@ func (c *PoolController) onUpdate(oldObj, newObj interface{}) {
 	oldPool := oldObj.(*Pool)
 	pool := newObj.(*Pool)
 
+	// the status is updated by the operator and does not require an update of the pool
+	if reflect.DeepEqual(oldPool.Spec, pool.Spec) {
+		return
+	}
 	if oldPool.Name != pool.Name {
 		logger.Errorf("failed to update pool %s. name update not allowed", pool.Name)
 		return
@@ -105,8 +134,18 @@ func (c *PoolController) onUpdate(oldObj, newObj interface{}) {
 	}
 
 	// if the pool is modified, allow the pool to be created if it wasn't already
-	if err := pool.create(c.context); err != nil {
-		logger.Errorf("failed to create (modify) pool %s. %+v", pool.ObjectMeta.Name, err)
+	dataMoving, err := pool.update(c.context)
+	if err != nil {
+		logger.Errorf("failed to update pool %s. %+v", pool.ObjectMeta.Name, err)
+		return
+	}
+	if dataMoving && c.trackDataMovement(pool) {
+		go c.reportDataMovement(pool)
+	}
+
+	disable := oldPool.Spec.Mirroring.Mode != "" && pool.Spec.Mirroring.Mode == ""
+	if err := pool.configureMirroring(c.context, disable); err != nil {
+		logger.Errorf("failed to configure mirroring for pool %s. %+v", pool.ObjectMeta.Name, err)
 	}
 }
 
@@ -129,11 +168,238 @@ func (p *Pool) create(context *clusterd.Context) error {
 	if err := ceph.CreatePoolWithProfile(context, p.Namespace, *p.Spec.ToModel(p.Name), p.Name); err != nil {
 		return fmt.Errorf("failed to create pool %s. %+v", p.Name, err)
 	}
+	if p.Spec.erasureCode() != nil {
+		// An erasure coded pool must allow overwrites to be used as the data pool of block images
+		if err := ceph.SetPoolProperty(context, p.Namespace, p.Name, "allow_ec_overwrites", "true"); err != nil {
+			logger.Warningf("failed to set ec pool property. %+v", err)
+		}
+	}
 
 	logger.Infof("created pool %s", p.Name)
 	return nil
 }
 
+// Update the pool settings that can be changed after the pool is created. If the pool does not exist yet, it
+// will be created. Returns whether the update caused ceph to move data between OSDs.
+func (p *Pool) update(context *clusterd.Context) (bool, error) {
+	exists, err := p.exists(context)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if pool %s exists. %+v", p.Name, err)
+	}
+	if !exists {
+		return false, p.create(context)
+	}
+
+	if err := p.validate(context); err != nil {
+		return false, fmt.Errorf("invalid pool %s arguments. %+v", p.Name, err)
+	}
+
+	r := p.Spec.replication()
+	if r == nil {
+		// the erasure code profile of a pool cannot be changed
+		logger.Infof("no changes to apply for erasure coded pool %s", p.Name)
+		return false, nil
+	}
+
+	details, err := ceph.GetPoolDetails(context, p.Namespace, p.Name)
+	if err != nil {
+		return false, fmt.Errorf("failed to get pool %s details. %+v", p.Name, err)
+	}
+	if details.ErasureCodeProfile != "" {
+		return false, fmt.Errorf("cannot convert erasure coded pool %s to a replicated pool", p.Name)
+	}
+
+	dataMoving := false
+	if details.Size != r.Size {
+		logger.Infof("changing size of pool %s from %d to %d", p.Name, details.Size, r.Size)
+		if err := ceph.SetPoolReplicatedSize(context, p.Namespace, p.Name, r.Size); err != nil {
+			return false, fmt.Errorf("failed to change size of pool %s. %+v", p.Name, err)
+		}
+		dataMoving = true
+	}
+
+	if p.Spec.FailureDomain != "" {
+		moved, err := p.updateFailureDomain(context, details.CrushRule)
+		if err != nil {
+			return false, err
+		}
+		dataMoving = dataMoving || moved
+	}
+
+	logger.Infof("updated pool %s", p.Name)
+	return dataMoving, nil
+}
+
+// Switch the pool to a crush rule for the failure domain in the spec if the current rule spreads data
+// across a different failure domain. Returns whether a new rule was applied.
+func (p *Pool) updateFailureDomain(context *clusterd.Context, currentRule string) (bool, error) {
+	crush, err := ceph.GetCrushMap(context, p.Namespace)
+	if err != nil {
+		return false, fmt.Errorf("failed to get crush map. %+v", err)
+	}
+	currentDomain := ceph.GetCrushRuleFailureDomain(crush, currentRule)
+	if currentDomain == p.Spec.FailureDomain {
+		return false, nil
+	}
+
+	ruleName := ceph.PoolCrushRuleName(p.Name, p.Spec.FailureDomain)
+	logger.Infof("changing failure domain of pool %s from %s to %s with crush rule %s", p.Name, currentDomain, p.Spec.FailureDomain, ruleName)
+	if err := ceph.CreateCrushRule(context, p.Namespace, ruleName, p.Spec.FailureDomain); err != nil {
+		return false, err
+	}
+	if err := ceph.SetPoolCrushRule(context, p.Namespace, p.Name, ruleName); err != nil {
+		return false, fmt.Errorf("failed to set crush rule for pool %s. %+v", p.Name, err)
+	}
+
+	// the previous rule is no longer needed if it was only created for this pool
+	if currentRule != ruleName && ceph.IsPoolCrushRule(p.Name, currentRule) {
+		if err := ceph.DeleteCrushRule(context, p.Namespace, currentRule); err != nil {
+			logger.Infof("did not delete previous crush rule %s. %+v", currentRule, err)
+		}
+	}
+
+	return true, nil
+}
+
+// trackDataMovement returns whether the data movement of the pool needs to be reported. The data movement of each
+// pool is reported by a single goroutine, which also reports the movement caused by later updates of the pool.
+func (c *PoolController) trackDataMovement(p *Pool) bool {
+	c.dataMovementLock.Lock()
+	defer c.dataMovementLock.Unlock()
+	key := p.Namespace + "/" + p.Name
+	if c.dataMovement[key] {
+		return false
+	}
+	c.dataMovement[key] = true
+	return true
+}
+
+func (c *PoolController) untrackDataMovement(p *Pool) {
+	c.dataMovementLock.Lock()
+	defer c.dataMovementLock.Unlock()
+	delete(c.dataMovement, p.Namespace+"/"+p.Name)
+}
+
+// Report the progress of the data that is moved between OSDs after the pool was updated in the status of the pool
+// until the objects of the pool are clean again.
+func (c *PoolController) reportDataMovement(p *Pool) {
+	defer c.untrackDataMovement(p)
+	for i := 0; i < dataMovementMaxChecks; i++ {
+		<-time.After(dataMovementCheckInterval)
+
+		status := p.dataMovementStatus(c.context)
+		if err := c.updateStatus(p, status); err != nil {
+			logger.Warningf("failed to update the data movement status of pool %s. %+v", p.Name, err)
+		}
+		if status.Completed {
+			return
+		}
+	}
+
+	logger.Warningf("stopped reporting data movement for pool %s. check the ceph status for progress.", p.Name)
+	status := p.dataMovementStatus(c.context)
+	if !status.Completed {
+		status.Error = "stopped reporting the data movement"
+	}
+	if err := c.updateStatus(p, status); err != nil {
+		logger.Warningf("failed to update the data movement status of pool %s. %+v", p.Name, err)
+	}
+}
+
+// dataMovementStatus gets the objects of the pool that are still misplaced or degraded
+func (p *Pool) dataMovementStatus(context *clusterd.Context) *DataMovementStatus {
+	status := &DataMovementStatus{LastChecked: metav1.Now()}
+	recovery, err := ceph.GetPoolRecovery(context, p.Namespace, p.Name)
+	if err != nil {
+		logger.Warningf("failed to get recovery while data is moving for pool %s. %+v", p.Name, err)
+		status.Error = err.Error()
+		return status
+	}
+
+	status.MisplacedObjects = recovery.MisplacedObjects
+	status.MisplacedTotal = recovery.MisplacedTotal
+	status.DegradedObjects = recovery.DegradedObjects
+	status.DegradedTotal = recovery.DegradedTotal
+	if recovery.MisplacedObjects == 0 && recovery.DegradedObjects == 0 {
+		logger.Infof("data movement completed for pool %s", p.Name)
+		status.Completed = true
+		return status
+	}
+	logger.Infof("data movement in progress for pool %s: %d/%d objects misplaced (%.2f%%), %d/%d objects degraded (%.2f%%)",
+		p.Name, recovery.MisplacedObjects, recovery.MisplacedTotal, recovery.MisplacedRatio*100,
+		recovery.DegradedObjects, recovery.DegradedTotal, recovery.DegradedRatio*100)
+	return status
+}
+
+// updateStatus sets the data movement status in the latest version of the pool resource
+func (c *PoolController) updateStatus(p *Pool, status *DataMovementStatus) error {
+	var pool Pool
+	err := c.client.Get().Namespace(p.Namespace).Resource(PoolResource.Plural).Name(p.Name).Do().Into(&pool)
+	if err != nil {
+		return fmt.Errorf("failed to get pool %s. %+v", p.Name, err)
+	}
+	pool.Status.DataMovement = status
+
+	return c.client.Put().
+		Namespace(pool.Namespace).
+		Resource(PoolResource.Plural).
+		Name(pool.Name).
+		Body(&pool).
+		Do().Error()
+}
+
+// Enable mirroring on the pool and add the peer cluster if mirroring is configured in the spec. Mirroring
+// is only disabled when requested since it may have been enabled outside the pool spec.
+func (p *Pool) configureMirroring(context *clusterd.Context, disable bool) error {
+	mirroring := p.Spec.Mirroring
+	if mirroring.Mode == "" {
+		if disable {
+			logger.Infof("disabling mirroring on pool %s", p.Name)
+			return ceph.DisablePoolMirroring(context, p.Namespace, p.Name)
+		}
+		return nil
+	}
+
+	logger.Infof("enabling %s mirroring on pool %s", mirroring.Mode, p.Name)
+	if err := ceph.EnablePoolMirroring(context, p.Namespace, p.Name, mirroring.Mode); err != nil {
+		return err
+	}
+	if mirroring.PeerSecret == "" {
+		return nil
+	}
+
+	secret, err := context.Clientset.CoreV1().Secrets(p.Namespace).Get(mirroring.PeerSecret, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get peer secret %s. %+v", mirroring.PeerSecret, err)
+	}
+	user := string(secret.Data[cephrbdmirror.PeerUserKey])
+	if user == "" {
+		return fmt.Errorf("peer secret %s is missing the %s", mirroring.PeerSecret, cephrbdmirror.PeerUserKey)
+	}
+
+	// add the peer if it is not already a peer of the pool
+	info, err := ceph.GetPoolMirrorInfo(context, p.Namespace, p.Name)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, peer := range info.Peers {
+		if peer.ClusterName == mirroring.PeerSecret {
+			found = true
+			break
+		}
+	}
+	if !found {
+		logger.Infof("adding mirroring peer %s to pool %s", mirroring.PeerSecret, p.Name)
+		if err := ceph.AddPoolMirrorPeer(context, p.Namespace, p.Name, fmt.Sprintf("client.%s", user), mirroring.PeerSecret); err != nil {
+			return err
+		}
+	}
+
+	// make the peer connection settings available to the rbd-mirror daemons
+	return rbdmirror.AddPeer(context, p.Namespace, mirroring.PeerSecret)
+}
+
 // Delete the pool
 func (p *Pool) delete(context *clusterd.Context) error {
 
@@ -211,6 +477,9 @@ func (p *PoolSpec) Validate(context *clusterd.Context, namespace string) error {
 	if p.replication() == nil && p.erasureCode() == nil {
 		return fmt.Errorf("neither replication nor erasure code settings were specified")
 	}
+	if err := p.Mirroring.validate(namespace); err != nil {
+		return err
+	}
 
 	// validate the failure domain if specified
 	if p.FailureDomain != "" {
@@ -233,6 +502,24 @@ func (p *PoolSpec) Validate(context *clusterd.Context, namespace string) error {
 	return nil
 }
 
+func (m *MirroringSpec) validate(namespace string) error {
+	switch m.Mode {
+	case "":
+		if m.PeerSecret != "" {
+			return fmt.Errorf("mirroring mode is required for peer %s", m.PeerSecret)
+		}
+	case ceph.MirrorModePool, ceph.MirrorModeImage:
+	default:
+		return fmt.Errorf("unrecognized mirroring mode %s", m.Mode)
+	}
+
+	// ceph finds the peer cluster config by the peer name, which cannot be the same as the local cluster
+	if m.PeerSecret == namespace {
+		return fmt.Errorf("mirroring peer cannot have the same name as the cluster %s", namespace)
+	}
+	return nil
+}
+
 func ModelToSpec(pool model.Pool) PoolSpec {
 	ec := pool.ErasureCodedConfig
 	return PoolSpec{
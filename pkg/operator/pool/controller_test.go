@@ -152,3 +152,102 @@ func TestDeletePool(t *testing.T) {
 	err = p.delete(context)
 	assert.Nil(t, err)
 }
+
+func TestUpdatePool(t *testing.T) {
+	var commands []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outfile string, args ...string) (string, error) {
+			logger.Infof("Command: %s %v", command, args)
+			if args[0] == "osd" && args[1] == "lspools" {
+				return `[{"poolnum":1,"poolname":"mypool"}]`, nil
+			} else if args[0] == "osd" && args[1] == "pool" && args[2] == "get" {
+				return `{"pool": "mypool","pool_id": 1,"size":1}{"pool":"mypool","min_size":1}{"pool":"mypool","crush_rule":"mypool"}`, nil
+			} else if args[0] == "osd" && args[1] == "crush" && args[2] == "dump" {
+				return `{"types":[{"type_id": 0,"name": "osd"},{"type_id": 1,"name": "host"}],
+					"rules":[{"rule_id":1,"rule_name":"mypool","steps":[{"op":"take","item":-1,"item_name":"default"},
+					{"op":"chooseleaf_firstn","num":0,"type":"host"},{"op":"emit"}]}]}`, nil
+			}
+			commands = append(commands, fmt.Sprintf("%v", args[:5]))
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	// no changes when the spec matches the pool
+	p := Pool{ObjectMeta: metav1.ObjectMeta{Name: "mypool", Namespace: "myns"}}
+	p.Spec.Replicated.Size = 1
+	p.Spec.FailureDomain = "host"
+	dataMoving, err := p.update(context)
+	assert.Nil(t, err)
+	assert.False(t, dataMoving)
+	assert.Equal(t, 0, len(commands))
+
+	// change the size and the failure domain
+	p.Spec.Replicated.Size = 3
+	p.Spec.FailureDomain = "osd"
+	dataMoving, err = p.update(context)
+	assert.Nil(t, err)
+	assert.True(t, dataMoving)
+	assert.Equal(t, 5, len(commands))
+	assert.Equal(t, "[osd pool set mypool size]", commands[0])
+	assert.Equal(t, "[osd pool set mypool min_size]", commands[1])
+	assert.Equal(t, "[osd crush rule create-simple mypool_osd]", commands[2])
+	assert.Equal(t, "[osd pool set mypool crush_rule]", commands[3])
+	assert.Equal(t, "[osd crush rule rm mypool]", commands[4])
+
+	// the pool is created if it does not exist yet
+	commands = []string{}
+	p = Pool{ObjectMeta: metav1.ObjectMeta{Name: "otherpool", Namespace: "myns"}}
+	p.Spec.Replicated.Size = 1
+	dataMoving, err = p.update(context)
+	assert.Nil(t, err)
+	assert.False(t, dataMoving)
+	assert.Equal(t, "[osd pool create otherpool 0]", commands[0])
+}
+
+func TestDataMovementStatus(t *testing.T) {
+	stats := `[{"pool_name":"mypool","pool_id":1,"recovery":{"misplaced_objects":10,"misplaced_total":100,"misplaced_ratio":0.1},"recovery_rate":{},"client_io_rate":{}}]`
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outfile string, args ...string) (string, error) {
+			logger.Infof("Command: %s %v", command, args)
+			if args[0] == "osd" && args[1] == "pool" && args[2] == "stats" {
+				// the recovery of the pool, not of the cluster
+				assert.Equal(t, "mypool", args[3])
+				return stats, nil
+			}
+			return "", fmt.Errorf("unexpected command %v", args)
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+	p := &Pool{ObjectMeta: metav1.ObjectMeta{Name: "mypool", Namespace: "myns"}}
+
+	status := p.dataMovementStatus(context)
+	assert.False(t, status.Completed)
+	assert.Equal(t, uint64(10), status.MisplacedObjects)
+	assert.Equal(t, uint64(100), status.MisplacedTotal)
+	assert.Equal(t, "", status.Error)
+
+	// the recovery is omitted when the pool is clean
+	stats = `[{"pool_name":"mypool","pool_id":1,"recovery":{},"recovery_rate":{},"client_io_rate":{}}]`
+	status = p.dataMovementStatus(context)
+	assert.True(t, status.Completed)
+
+	stats = `[]`
+	status = p.dataMovementStatus(context)
+	assert.False(t, status.Completed)
+	assert.NotEqual(t, "", status.Error)
+}
+
+func TestTrackDataMovement(t *testing.T) {
+	c := NewPoolController(&clusterd.Context{})
+	p := &Pool{ObjectMeta: metav1.ObjectMeta{Name: "mypool", Namespace: "myns"}}
+	other := &Pool{ObjectMeta: metav1.ObjectMeta{Name: "mypool", Namespace: "otherns"}}
+
+	// a single report per pool
+	assert.True(t, c.trackDataMovement(p))
+	assert.False(t, c.trackDataMovement(p))
+	assert.True(t, c.trackDataMovement(other))
+
+	c.untrackDataMovement(p)
+	assert.True(t, c.trackDataMovement(p))
+}
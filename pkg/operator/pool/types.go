@@ -33,7 +33,31 @@ var schemeGroupVersion = schema.GroupVersion{Group: k8sutil.CustomResourceGroup,
 type Pool struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
-	Spec              PoolSpec `json:"spec"`
+	Spec              PoolSpec   `json:"spec"`
+	Status            PoolStatus `json:"status,omitempty"`
+}
+
+// PoolStatus represents the status of a pool
+type PoolStatus struct {
+	// The progress of the data movement after the last update of the pool
+	DataMovement *DataMovementStatus `json:"dataMovement,omitempty"`
+}
+
+// DataMovementStatus is the recovery of the objects of the pool from "ceph osd pool stats"
+type DataMovementStatus struct {
+	MisplacedObjects uint64 `json:"misplacedObjects"`
+	MisplacedTotal   uint64 `json:"misplacedTotal"`
+	DegradedObjects  uint64 `json:"degradedObjects"`
+	DegradedTotal    uint64 `json:"degradedTotal"`
+
+	// Whether all the objects of the pool are clean again
+	Completed bool `json:"completed"`
+
+	// The error when the recovery could not be retrieved or is no longer reported
+	Error string `json:"error,omitempty"`
+
+	// When the recovery was last retrieved
+	LastChecked metav1.Time `json:"lastChecked"`
 }
 
 // PoolList is the definition of a list of pools
@@ -53,6 +77,9 @@ type PoolSpec struct {
 
 	// The erasure code setteings
 	ErasureCoded ErasureCodedSpec `json:"erasureCoded"`
+
+	// The mirroring settings to replicate the block images in the pool to a peer cluster
+	Mirroring MirroringSpec `json:"mirroring,omitempty"`
 }
 
 // ReplicationSpec represents the spec for replication in a pool
@@ -72,3 +99,14 @@ type ErasureCodedSpec struct {
 	// The algorithm for erasure coding
 	Algorithm string `json:"algorithm"`
 }
+
+// MirroringSpec represents the spec for mirroring the images in a pool to a peer cluster
+type MirroringSpec struct {
+	// The mirroring mode: "pool" to mirror all images with journaling enabled, or "image" to mirror only
+	// the images with mirroring enabled. Mirroring is disabled if not set.
+	Mode string `json:"mode,omitempty"`
+
+	// The name of the secret with the mon endpoints, user and key to connect to the peer cluster.
+	// The secret name is used as the name of the peer cluster.
+	PeerSecret string `json:"peerSecret,omitempty"`
+}
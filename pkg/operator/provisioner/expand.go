@@ -0,0 +1,133 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package provisioner
+
+import (
+	"fmt"
+
+	"github.com/rook/rook/pkg/agent/flexvolume"
+	ceph "github.com/rook/rook/pkg/ceph/client"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/kit"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+var persistentVolumeClaimResource = kit.CustomResource{
+	Name:    "persistentvolumeclaim",
+	Plural:  "persistentvolumeclaims",
+	Version: "v1",
+}
+
+// VolumeExpander resizes the block images of provisioned volumes when the requested size of their claim is increased.
+// The rook agent on the node where the volume is attached grows the file system when the PV capacity is updated.
+type VolumeExpander struct {
+	context *clusterd.Context
+}
+
+// NewVolumeExpander creates a VolumeExpander
+func NewVolumeExpander(context *clusterd.Context) *VolumeExpander {
+	return &VolumeExpander{context: context}
+}
+
+// StartWatch watches the claims in all namespaces. The call blocks until the stop channel is closed.
+func (e *VolumeExpander) StartWatch(stopCh chan struct{}) error {
+	handlers := cache.ResourceEventHandlerFuncs{
+		AddFunc:    e.onAdd,
+		UpdateFunc: e.onUpdate,
+	}
+
+	logger.Infof("start watching persistent volume claims for volume expansion")
+	watcher := kit.NewWatcher(persistentVolumeClaimResource, v1.NamespaceAll, handlers, e.context.Clientset.CoreV1().RESTClient())
+	return watcher.Watch(&v1.PersistentVolumeClaim{}, stopCh)
+}
+
+// onAdd expands the volumes of the claims that were resized while the operator was not running
+func (e *VolumeExpander) onAdd(obj interface{}) {
+	e.checkExpand(obj.(*v1.PersistentVolumeClaim))
+}
+
+func (e *VolumeExpander) onUpdate(oldObj, newObj interface{}) {
+	e.checkExpand(newObj.(*v1.PersistentVolumeClaim))
+}
+
+func (e *VolumeExpander) checkExpand(pvc *v1.PersistentVolumeClaim) {
+	if pvc.Status.Phase != v1.ClaimBound || pvc.Spec.VolumeName == "" {
+		return
+	}
+
+	// the claim is owned by the informer cache, so its status is updated on a copy
+	pvc = pvc.DeepCopy()
+
+	if err := e.expand(pvc); err != nil {
+		logger.Errorf("failed to expand volume %s of claim %s/%s. %+v", pvc.Spec.VolumeName, pvc.Namespace, pvc.Name, err)
+	}
+}
+
+// expand resizes the image of the claim's volume if the claim requests more than the capacity of the volume
+func (e *VolumeExpander) expand(pvc *v1.PersistentVolumeClaim) error {
+	pv, err := e.context.Clientset.CoreV1().PersistentVolumes().Get(pvc.Spec.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get volume. %+v", err)
+	}
+
+	flexVolume := pv.Spec.PersistentVolumeSource.FlexVolume
+	if flexVolume == nil || flexVolume.Driver != flexdriver {
+		// not provisioned by rook
+		return nil
+	}
+
+	requested := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+	capacity := pv.Spec.Capacity[v1.ResourceStorage]
+	if requested.Cmp(capacity) <= 0 {
+		return nil
+	}
+
+	if flexVolume.Options[flexvolume.FilesystemKey] != "" {
+		logger.Infof("not expanding volume %s. expanding shared file system volumes is not supported", pv.Name)
+		return nil
+	}
+
+	clusterName, err := getClusterName(e.context, flexVolume)
+	if err != nil {
+		return err
+	}
+
+	image := flexVolume.Options[flexvolume.ImageKey]
+	pool := flexVolume.Options[flexvolume.PoolKey]
+	logger.Infof("expanding volume %s (image %s/%s) from %s to %s", pv.Name, pool, image, capacity.String(), requested.String())
+	if err := ceph.ResizeImage(e.context, clusterName, image, pool, uint64(requested.Value())); err != nil {
+		return err
+	}
+
+	// the agent grows the file system when it sees the new capacity of the volume
+	pv.Spec.Capacity[v1.ResourceStorage] = requested
+	if _, err := e.context.Clientset.CoreV1().PersistentVolumes().Update(pv); err != nil {
+		return fmt.Errorf("failed to update capacity of volume. %+v", err)
+	}
+
+	if pvc.Status.Capacity == nil {
+		pvc.Status.Capacity = v1.ResourceList{}
+	}
+	pvc.Status.Capacity[v1.ResourceStorage] = requested
+	if _, err := e.context.Clientset.CoreV1().PersistentVolumeClaims(pvc.Namespace).UpdateStatus(pvc); err != nil {
+		return fmt.Errorf("failed to update capacity of claim. %+v", err)
+	}
+
+	logger.Infof("expanded volume %s to %s", pv.Name, requested.String())
+	return nil
+}
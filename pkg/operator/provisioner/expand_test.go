@@ -0,0 +1,107 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package provisioner
+
+import (
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExpandVolume(t *testing.T) {
+	clientset := test.New(3)
+	var resizeArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if command == "rbd" && args[0] == "resize" {
+				resizeArgs = args
+			}
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{Clientset: clientset, Executor: executor}
+
+	sc := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "class-1"},
+		Provisioner: "rook.io/block",
+		Parameters:  map[string]string{"pool": "testpool", "clusterName": "testCluster"},
+	}
+	clientset.StorageV1().StorageClasses().Create(sc)
+
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pvc-uid-1-1"},
+		Spec: v1.PersistentVolumeSpec{
+			Capacity: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Mi")},
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				FlexVolume: &v1.FlexVolumeSource{
+					Driver:  flexdriver,
+					Options: map[string]string{"storageClass": "class-1", "pool": "testpool", "image": "pvc-uid-1-1"},
+				},
+			},
+		},
+	}
+	clientset.CoreV1().PersistentVolumes().Create(pv)
+
+	claim := newClaim("claim-1", "uid-1-1", "class-1", "pvc-uid-1-1", "class-1", nil)
+	claim.Status.Phase = v1.ClaimBound
+	clientset.CoreV1().PersistentVolumeClaims(claim.Namespace).Create(claim)
+	expander := NewVolumeExpander(context)
+
+	// nothing to do when the requested size is not increased
+	expander.onUpdate(claim, claim)
+	assert.Nil(t, resizeArgs)
+
+	// the image is resized and the new capacity is saved in the volume
+	expanded := claim.DeepCopy()
+	expanded.Spec.Resources.Requests[v1.ResourceStorage] = resource.MustParse("2Mi")
+	expander.onUpdate(claim, expanded)
+	assert.Equal(t, []string{"resize", "testpool/pvc-uid-1-1", "--size", "2"}, resizeArgs[:4])
+	assert.Equal(t, "--cluster=testCluster", resizeArgs[4])
+
+	pv, err := clientset.CoreV1().PersistentVolumes().Get("pvc-uid-1-1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	capacity := pv.Spec.Capacity[v1.ResourceStorage]
+	assert.Equal(t, "2Mi", capacity.String())
+	claim, err = clientset.CoreV1().PersistentVolumeClaims(claim.Namespace).Get("claim-1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	capacity = claim.Status.Capacity[v1.ResourceStorage]
+	assert.Equal(t, "2Mi", capacity.String())
+
+	// volumes that are not provisioned by rook are ignored
+	resizeArgs = nil
+	pv.Spec.PersistentVolumeSource.FlexVolume.Driver = "other/driver"
+	clientset.CoreV1().PersistentVolumes().Update(pv)
+	expanded.Spec.Resources.Requests[v1.ResourceStorage] = resource.MustParse("3Mi")
+	expander.onUpdate(claim, expanded)
+	assert.Nil(t, resizeArgs)
+
+	// the claims that were resized while the operator was not running are expanded when they are added
+	pv.Spec.PersistentVolumeSource.FlexVolume.Driver = flexdriver
+	clientset.CoreV1().PersistentVolumes().Update(pv)
+	expander.onAdd(expanded)
+	assert.Equal(t, []string{"resize", "testpool/pvc-uid-1-1", "--size", "3"}, resizeArgs[:4])
+	pv, err = clientset.CoreV1().PersistentVolumes().Get("pvc-uid-1-1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	capacity = pv.Spec.Capacity[v1.ResourceStorage]
+	assert.Equal(t, "3Mi", capacity.String())
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package provisioner
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/kubernetes-incubator/external-storage/lib/controller"
+	"github.com/rook/rook/pkg/agent/flexvolume"
+	ceph "github.com/rook/rook/pkg/ceph/client"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/cluster"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// the directory in the file system under which a directory is created for each volume
+	filesystemVolumesDir = "/volumes"
+)
+
+// RookFilesystemProvisioner is used to provision shared Rook file system volumes on Kubernetes. Each volume is a
+// directory of a Rook file system with a quota of the requested size and a ceph user that can only access the directory.
+// The directory is created by the agent when the volume is first mounted and removed by an agent when the volume is
+// deleted.
+type RookFilesystemProvisioner struct {
+	context *clusterd.Context
+}
+
+type filesystemConfig struct {
+	// Required: The file system to provision volumes from.
+	filesystem string
+
+	// Optional: Name of the cluster. Default is `rook`
+	clusterName string
+}
+
+// NewFilesystemProvisioner creates RookFilesystemProvisioner
+func NewFilesystemProvisioner(context *clusterd.Context) controller.Provisioner {
+	return &RookFilesystemProvisioner{
+		context: context,
+	}
+}
+
+// Provision creates a ceph user for the directory of the volume and returns a PV object representing it.
+func (p *RookFilesystemProvisioner) Provision(options controller.VolumeOptions) (*v1.PersistentVolume, error) {
+	if options.PVC.Spec.Selector != nil {
+		return nil, fmt.Errorf("claim Selector is not supported")
+	}
+	if isBlockVolumeMode(options.PVC) {
+		return nil, fmt.Errorf("file system volumes cannot be provisioned as raw block volumes")
+	}
+
+	cfg, err := parseFilesystemClassParameters(options.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	storageClass, err := parseStorageClass(options)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := ceph.GetFilesystem(p.context, cfg.clusterName, cfg.filesystem); err != nil {
+		return nil, fmt.Errorf("failed to find file system %s in cluster %s. %+v", cfg.filesystem, cfg.clusterName, err)
+	}
+
+	volumeName := options.PVName
+	volumePath := path.Join(filesystemVolumesDir, volumeName)
+	logger.Infof("creating volume %s in file system %s with configuration %+v", volumePath, cfg.filesystem, *cfg)
+
+	if err := p.createUser(cfg, volumeName, volumePath); err != nil {
+		return nil, err
+	}
+
+	capacity := options.PVC.Spec.Resources.Requests[v1.ResourceName(v1.ResourceStorage)]
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: volumeName,
+		},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeReclaimPolicy: options.PersistentVolumeReclaimPolicy,
+			AccessModes:                   options.PVC.Spec.AccessModes,
+			Capacity: v1.ResourceList{
+				v1.ResourceName(v1.ResourceStorage): capacity,
+			},
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				FlexVolume: &v1.FlexVolumeSource{
+					Driver: flexdriver,
+					Options: map[string]string{
+						flexvolume.StorageClassKey: storageClass,
+						flexvolume.FilesystemKey:   cfg.filesystem,
+						flexvolume.PathKey:         volumePath,
+						flexvolume.ClusterNameKey:  cfg.clusterName,
+					},
+				},
+			},
+		},
+	}
+	logger.Infof("successfully created Rook file system volume %+v", pv.Spec.PersistentVolumeSource.FlexVolume)
+	return pv, nil
+}
+
+// create the ceph user that can only access the directory of the volume and save its key in a secret for the agent
+func (p *RookFilesystemProvisioner) createUser(cfg *filesystemConfig, volumeName, volumePath string) error {
+	caps := []string{
+		"mon", "allow r",
+		"mds", fmt.Sprintf("allow rw path=%s", volumePath),
+		"osd", fmt.Sprintf("allow rw tag cephfs data=%s", cfg.filesystem),
+	}
+	user := filesystemUserName(volumeName)
+	key, err := ceph.AuthGetOrCreateKey(p.context, cfg.clusterName, "client."+user, caps)
+	if err != nil {
+		return fmt.Errorf("failed to create user for volume %s. %+v", volumeName, err)
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: volumeName, Namespace: cfg.clusterName},
+		StringData: map[string]string{
+			flexvolume.FilesystemUserKey:   user,
+			flexvolume.FilesystemSecretKey: key,
+		},
+		Type: k8sutil.RookType,
+	}
+	_, err = p.context.Clientset.CoreV1().Secrets(cfg.clusterName).Create(secret)
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to save key for volume %s. %+v", volumeName, err)
+	}
+	return nil
+}
+
+// Delete removes the directory of the volume represented by the given PV and its ceph user. The operator does not mount
+// the file system, so an agent removes the directory. The deletion fails until the directory is removed and is
+// retried by the provision controller.
+func (p *RookFilesystemProvisioner) Delete(volume *v1.PersistentVolume) error {
+	logger.Infof("Deleting volume %s", volume.Name)
+	flexVolume := volume.Spec.PersistentVolumeSource.FlexVolume
+	if flexVolume == nil {
+		return fmt.Errorf("volume %s is not a rook flexvolume", volume.Name)
+	}
+
+	clusterName, err := getClusterName(p.context, flexVolume)
+	if err != nil {
+		return fmt.Errorf("Failed to delete rook file system volume %s: %v", volume.Name, err)
+	}
+
+	if err := p.purgeVolume(volume, clusterName); err != nil {
+		return fmt.Errorf("Failed to delete rook file system volume %s: %v", volume.Name, err)
+	}
+
+	if err := ceph.AuthDelete(p.context, clusterName, "client."+filesystemUserName(volume.Name)); err != nil {
+		return fmt.Errorf("Failed to delete user of rook file system volume %s: %v", volume.Name, err)
+	}
+
+	err = p.context.Clientset.CoreV1().Secrets(clusterName).Delete(volume.Name, &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("Failed to delete key of rook file system volume %s: %v", volume.Name, err)
+	}
+
+	logger.Infof("succeeded deleting volume %s", volume.Name)
+	return nil
+}
+
+// purgeVolume requests the agents to remove the directory of the volume and returns an error until one of them did
+func (p *RookFilesystemProvisioner) purgeVolume(volume *v1.PersistentVolume, clusterName string) error {
+	fsName := volume.Spec.PersistentVolumeSource.FlexVolume.Options[flexvolume.FilesystemKey]
+	volumePath := volume.Spec.PersistentVolumeSource.FlexVolume.Options[flexvolume.PathKey]
+
+	switch volume.Annotations[flexvolume.PurgeAnnotation] {
+	case flexvolume.PurgeCompleted:
+		return nil
+	case flexvolume.PurgeRequested:
+		return fmt.Errorf("waiting for an agent to remove directory %s of file system %s", volumePath, fsName)
+	}
+
+	// the data was removed with the file system
+	filesystems, err := ceph.ListFilesystems(p.context, clusterName)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, fs := range filesystems {
+		if fs.Name == fsName {
+			found = true
+			break
+		}
+	}
+	if !found {
+		logger.Infof("file system %s of volume %s not found. nothing to remove.", fsName, volume.Name)
+		return nil
+	}
+
+	if err := flexvolume.SetPurgeState(p.context.Clientset, volume, flexvolume.PurgeRequested, ""); err != nil {
+		return fmt.Errorf("failed to request removal of directory %s of file system %s. %+v", volumePath, fsName, err)
+	}
+	return fmt.Errorf("requested an agent to remove directory %s of file system %s", volumePath, fsName)
+}
+
+// the name of the ceph user that can access the directory of the volume
+func filesystemUserName(volumeName string) string {
+	return fmt.Sprintf("rook-%s", volumeName)
+}
+
+func parseFilesystemClassParameters(params map[string]string) (*filesystemConfig, error) {
+	var cfg filesystemConfig
+
+	for k, v := range params {
+		switch strings.ToLower(k) {
+		case "filesystem":
+			cfg.filesystem = v
+		case "clustername":
+			cfg.clusterName = v
+		default:
+			return nil, fmt.Errorf("invalid option %q for volume plugin %s", k, "rookFilesystemProvisioner")
+		}
+	}
+
+	if len(cfg.filesystem) == 0 {
+		return nil, fmt.Errorf("StorageClass for provisioner %s must contain 'filesystem' parameter", "rookFilesystemProvisioner")
+	}
+
+	if len(cfg.clusterName) == 0 {
+		cfg.clusterName = cluster.DefaultClusterName
+	}
+
+	return &cfg, nil
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package provisioner
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/agent/flexvolume"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestProvisionFilesystemVolume(t *testing.T) {
+	clientset := test.New(3)
+	var caps []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+			switch {
+			case args[0] == "fs" && args[1] == "get":
+				assert.Equal(t, "myfs", args[2])
+				return `{"mdsmap":{"fs_name":"myfs"}}`, nil
+			case args[0] == "auth" && args[1] == "get-or-create-key":
+				assert.Equal(t, "client.rook-pvc-uid-1-1", args[2])
+				caps = args[3:9]
+				return `{"key":"mykey"}`, nil
+			}
+			return "", fmt.Errorf("unexpected ceph command '%v'", args)
+		},
+	}
+	context := &clusterd.Context{Clientset: clientset, Executor: executor}
+	provisioner := NewFilesystemProvisioner(context)
+
+	claim := newClaim("claim-1", "uid-1-1", "class-1", "", "class-1", nil)
+	claim.Spec.AccessModes = []v1.PersistentVolumeAccessMode{v1.ReadWriteMany}
+	volume := newVolumeOptions(newStorageClass("class-1", "rook.io/filesystem", map[string]string{"filesystem": "myfs", "clusterName": "testCluster"}), claim)
+
+	pv, err := provisioner.Provision(volume)
+	assert.Nil(t, err)
+	assert.Equal(t, "pvc-uid-1-1", pv.Name)
+	assert.Equal(t, []v1.PersistentVolumeAccessMode{v1.ReadWriteMany}, pv.Spec.AccessModes)
+	assert.Equal(t, "rook.io/rook", pv.Spec.PersistentVolumeSource.FlexVolume.Driver)
+	options := pv.Spec.PersistentVolumeSource.FlexVolume.Options
+	assert.Equal(t, "myfs", options[flexvolume.FilesystemKey])
+	assert.Equal(t, "/volumes/pvc-uid-1-1", options[flexvolume.PathKey])
+	assert.Equal(t, "testCluster", options[flexvolume.ClusterNameKey])
+	assert.Equal(t, "class-1", options[flexvolume.StorageClassKey])
+
+	// the user can only access the directory of the volume
+	assert.Equal(t, []string{"mon", "allow r", "mds", "allow rw path=/volumes/pvc-uid-1-1", "osd", "allow rw tag cephfs data=myfs"}, caps)
+
+	// the key of the user is stored for the agent
+	secret, err := clientset.CoreV1().Secrets("testCluster").Get("pvc-uid-1-1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "rook-pvc-uid-1-1", secret.StringData[flexvolume.FilesystemUserKey])
+	assert.Equal(t, "mykey", secret.StringData[flexvolume.FilesystemSecretKey])
+}
+
+func TestDeleteFilesystemVolume(t *testing.T) {
+	clientset := test.New(3)
+	var deleted []string
+	filesystems := `[{"name":"myfs"}]`
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+			switch {
+			case args[0] == "auth" && args[1] == "del":
+				deleted = append(deleted, args[2])
+			case args[0] == "fs" && args[1] == "ls":
+				return filesystems, nil
+			}
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{Clientset: clientset, Executor: executor}
+	clientset.CoreV1().Secrets("testCluster").Create(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "pvc-1", Namespace: "testCluster"}})
+
+	provisioner := NewFilesystemProvisioner(context)
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pvc-1"},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				FlexVolume: &v1.FlexVolumeSource{Driver: flexdriver, Options: map[string]string{
+					flexvolume.FilesystemKey:  "myfs",
+					flexvolume.PathKey:        "/volumes/pvc-1",
+					flexvolume.ClusterNameKey: "testCluster",
+				}},
+			},
+		},
+	}
+	clientset.CoreV1().PersistentVolumes().Create(pv)
+
+	// the removal of the directory is requested from the agents
+	err := provisioner.Delete(pv)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, len(deleted))
+	pv, _ = clientset.CoreV1().PersistentVolumes().Get("pvc-1", metav1.GetOptions{})
+	assert.Equal(t, flexvolume.PurgeRequested, pv.Annotations[flexvolume.PurgeAnnotation])
+
+	// the volume is not deleted until an agent removed the directory
+	err = provisioner.Delete(pv)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, len(deleted))
+
+	pv.Annotations[flexvolume.PurgeAnnotation] = flexvolume.PurgeCompleted
+	err = provisioner.Delete(pv)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"client.rook-pvc-1"}, deleted)
+	_, err = clientset.CoreV1().Secrets("testCluster").Get("pvc-1", metav1.GetOptions{})
+	assert.NotNil(t, err)
+
+	// there is nothing to remove when the file system was deleted
+	deleted = nil
+	filesystems = `[]`
+	pv.Annotations = nil
+	err = provisioner.Delete(pv)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"client.rook-pvc-1"}, deleted)
+}
+
+func TestParseFilesystemClassParameters(t *testing.T) {
+	cfg, err := parseFilesystemClassParameters(map[string]string{"filesystem": "myfs"})
+	assert.Nil(t, err)
+	assert.Equal(t, "myfs", cfg.filesystem)
+	assert.Equal(t, "rook", cfg.clusterName)
+
+	_, err = parseFilesystemClassParameters(map[string]string{"clusterName": "mycluster"})
+	assert.NotNil(t, err)
+
+	_, err = parseFilesystemClassParameters(map[string]string{"filesystem": "myfs", "pool": "mypool"})
+	assert.NotNil(t, err)
+}
@@ -19,6 +19,7 @@ package provisioner
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/coreos/pkg/capnslog"
@@ -39,12 +40,11 @@ const (
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-provisioner")
 var flexdriver = fmt.Sprintf("%s/%s", flexvolume.FlexvolumeVendor, flexvolume.FlexvolumeDriver)
 
-// RookVolumeProvisioner is used to provision Rook volumes on Kubernetes
+// RookVolumeProvisioner is used to provision Rook volumes on Kubernetes. The provisioner does not keep any state
+// between calls. Everything needed to delete a volume is stored in the PV, so volumes from any number of storage
+// classes and clusters can be provisioned and deleted concurrently.
 type RookVolumeProvisioner struct {
 	context *clusterd.Context
-
-	// Configuration of rook volume provisioner
-	provConfig provisionerConfig
 }
 
 type provisionerConfig struct {
@@ -56,6 +56,33 @@ type provisionerConfig struct {
 
 	// Optional: File system type used for mounting the image. Default is `ext4`
 	fstype string
+
+	// Optional: The features, object size, striping and data pool of the image. Default is the ceph defaults.
+	imageOptions ceph.ImageOptions
+
+	// Optional: Whether the volume can be attached read-write by multiple pods at the same time. Default is false
+	multiWriter bool
+
+	// Optional: The I/O limits and bursts of the volume. Default is unlimited
+	qos ceph.ImageQoS
+
+	// Optional: The options to mount the file system of the volume. Default is no options
+	mountOptions []string
+
+	// Optional: The arguments to create the file system of the volume. Default is the mkfs defaults
+	mkfsOptions []string
+}
+
+// BlockVolumeOptions are the pool, cluster and image options of a block volume from the parameters of its storage class
+type BlockVolumeOptions struct {
+	Pool         string
+	ClusterName  string
+	FsType       string
+	ImageOptions ceph.ImageOptions
+	MultiWriter  bool
+	QoS          ceph.ImageQoS
+	MountOptions []string
+	MkfsOptions  []string
 }
 
 // New creates RookVolumeProvisioner
@@ -72,14 +99,16 @@ func (p *RookVolumeProvisioner) Provision(options controller.VolumeOptions) (*v1
 	if options.PVC.Spec.Selector != nil {
 		return nil, fmt.Errorf("claim Selector is not supported")
 	}
+	if isBlockVolumeMode(options.PVC) {
+		return nil, fmt.Errorf("raw block volumes are not supported by the flexvolume driver. use a storage class of the CSI driver %s", flexvolume.CSIBlockDriver)
+	}
 
 	cfg, err := parseClassParameters(options.Parameters)
 	if err != nil {
 		return nil, err
 	}
-	p.provConfig = *cfg
 
-	logger.Infof("creating volume with configuration %+v", p.provConfig)
+	logger.Infof("creating volume with configuration %+v", *cfg)
 
 	capacity := options.PVC.Spec.Resources.Requests[v1.ResourceName(v1.ResourceStorage)]
 	requestBytes := capacity.Value()
@@ -91,7 +120,11 @@ func (p *RookVolumeProvisioner) Provision(options controller.VolumeOptions) (*v1
 		return nil, err
 	}
 
-	if err := p.createVolume(imageName, p.provConfig.pool, requestBytes); err != nil {
+	if cfg.multiWriter {
+		logger.Warningf("volume %s can be attached read-write by multiple pods. the pods must coordinate their writes, for example with a cluster file system, or the data will be corrupted", imageName)
+	}
+
+	if err := p.createVolume(cfg, imageName, requestBytes); err != nil {
 		return nil, err
 	}
 
@@ -108,29 +141,51 @@ func (p *RookVolumeProvisioner) Provision(options controller.VolumeOptions) (*v1
 			PersistentVolumeSource: v1.PersistentVolumeSource{
 				FlexVolume: &v1.FlexVolumeSource{
 					Driver: flexdriver,
-					FSType: p.provConfig.fstype,
+					FSType: cfg.fstype,
 					Options: map[string]string{
 						flexvolume.StorageClassKey: storageClass,
-						flexvolume.PoolKey:         p.provConfig.pool,
+						flexvolume.PoolKey:         cfg.pool,
 						flexvolume.ImageKey:        imageName,
+						flexvolume.ClusterNameKey:  cfg.clusterName,
 					},
 				},
 			},
 		},
 	}
+	if cfg.multiWriter {
+		pv.Spec.PersistentVolumeSource.FlexVolume.Options[flexvolume.MultiWriterKey] = "true"
+	}
+	// the agent throttles the device of the volume in the cgroup of the pod with the limits
+	for key, limit := range map[string]uint64{
+		flexvolume.ReadIOPSLimitKey:  cfg.qos.ReadIOPSLimit,
+		flexvolume.WriteIOPSLimitKey: cfg.qos.WriteIOPSLimit,
+		flexvolume.ReadBPSLimitKey:   cfg.qos.ReadBPSLimit,
+		flexvolume.WriteBPSLimitKey:  cfg.qos.WriteBPSLimit,
+	} {
+		if limit != 0 {
+			pv.Spec.PersistentVolumeSource.FlexVolume.Options[key] = strconv.FormatUint(limit, 10)
+		}
+	}
+	// the mount and mkfs options are validated again by the flex driver when the volume is mounted
+	if len(cfg.mountOptions) > 0 {
+		pv.Spec.PersistentVolumeSource.FlexVolume.Options[flexvolume.MountOptionsKey] = strings.Join(cfg.mountOptions, ",")
+	}
+	if len(cfg.mkfsOptions) > 0 {
+		pv.Spec.PersistentVolumeSource.FlexVolume.Options[flexvolume.MkfsOptionsKey] = strings.Join(cfg.mkfsOptions, " ")
+	}
 	logger.Infof("successfully created Rook Block volume %+v", pv.Spec.PersistentVolumeSource.FlexVolume)
 	return pv, nil
 }
 
 // createVolume creates a rook block volume.
-func (p *RookVolumeProvisioner) createVolume(image, pool string, size int64) error {
-	if image == "" || pool == "" || size == 0 {
-		return fmt.Errorf("image missing required fields (image=%s, pool=%s, size=%d)", image, pool, size)
+func (p *RookVolumeProvisioner) createVolume(cfg *provisionerConfig, image string, size int64) error {
+	if image == "" || cfg.pool == "" || size == 0 {
+		return fmt.Errorf("image missing required fields (image=%s, pool=%s, size=%d)", image, cfg.pool, size)
 	}
 
-	createdImage, err := ceph.CreateImage(p.context, p.provConfig.clusterName, image, pool, uint64(size))
+	createdImage, err := ceph.CreateImageWithOptions(p.context, cfg.clusterName, image, cfg.pool, uint64(size), cfg.imageOptions)
 	if err != nil {
-		return fmt.Errorf("Failed to create rook block image %s/%s: %v", pool, image, err)
+		return fmt.Errorf("Failed to create rook block image %s/%s: %v", cfg.pool, image, err)
 	}
 	logger.Infof("Rook block image created: %s", createdImage.Name)
 
@@ -141,15 +196,51 @@ func (p *RookVolumeProvisioner) createVolume(image, pool string, size int64) err
 // by the given PV.
 func (p *RookVolumeProvisioner) Delete(volume *v1.PersistentVolume) error {
 	logger.Infof("Deleting volume %s", volume.Name)
-	name := volume.Spec.PersistentVolumeSource.FlexVolume.Options[flexvolume.ImageKey]
-	err := ceph.DeleteImage(p.context, p.provConfig.clusterName, name, p.provConfig.pool)
+	flexVolume := volume.Spec.PersistentVolumeSource.FlexVolume
+	if flexVolume == nil {
+		return fmt.Errorf("volume %s is not a rook flexvolume", volume.Name)
+	}
+
+	name := flexVolume.Options[flexvolume.ImageKey]
+	pool := flexVolume.Options[flexvolume.PoolKey]
+	clusterName, err := getClusterName(p.context, flexVolume)
+	if err != nil {
+		return fmt.Errorf("Failed to delete rook block image %s/%s: %v", pool, name, err)
+	}
+
+	err = ceph.DeleteImage(p.context, clusterName, name, pool)
 	if err != nil {
-		return fmt.Errorf("Failed to delete rook block image %s/%s: %v", p.provConfig.pool, volume.Name, err)
+		return fmt.Errorf("Failed to delete rook block image %s/%s: %v", pool, volume.Name, err)
 	}
 	logger.Infof("succeeded deleting volume %+v", volume)
 	return nil
 }
 
+// getClusterName gets the cluster of the volume from the PV. Volumes that were provisioned before the cluster was
+// stored in the PV get it from their storage class.
+func getClusterName(context *clusterd.Context, flexVolume *v1.FlexVolumeSource) (string, error) {
+	if clusterName := flexVolume.Options[flexvolume.ClusterNameKey]; clusterName != "" {
+		return clusterName, nil
+	}
+
+	storageClassName := flexVolume.Options[flexvolume.StorageClassKey]
+	storageClass, err := context.Clientset.StorageV1().StorageClasses().Get(storageClassName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get storage class %s. %+v", storageClassName, err)
+	}
+	cfg, err := parseClassParameters(storageClass.Parameters)
+	if err != nil {
+		return "", err
+	}
+	return cfg.clusterName, nil
+}
+
+// isBlockVolumeMode checks if the claim requests a raw block volume. The kubelet only maps raw block volumes with CSI
+// drivers, never with flexvolume drivers.
+func isBlockVolumeMode(claim *v1.PersistentVolumeClaim) bool {
+	return claim.Spec.VolumeMode != nil && *claim.Spec.VolumeMode == v1.PersistentVolumeBlock
+}
+
 func parseStorageClass(options controller.VolumeOptions) (string, error) {
 	if options.PVC.Spec.StorageClassName != nil {
 		return *options.PVC.Spec.StorageClassName, nil
@@ -163,9 +254,29 @@ func parseStorageClass(options controller.VolumeOptions) (string, error) {
 	return "", fmt.Errorf("failed to get storageclass from PVC %s/%s", options.PVC.Namespace, options.PVC.Name)
 }
 
+// ParseBlockVolumeOptions parses the parameters of a storage class of block volumes. The parameters are the same
+// for the rook.io/block provisioner and the CSI driver.
+func ParseBlockVolumeOptions(parameters map[string]string) (*BlockVolumeOptions, error) {
+	cfg, err := parseClassParameters(parameters)
+	if err != nil {
+		return nil, err
+	}
+	return &BlockVolumeOptions{
+		Pool:         cfg.pool,
+		ClusterName:  cfg.clusterName,
+		FsType:       cfg.fstype,
+		ImageOptions: cfg.imageOptions,
+		MultiWriter:  cfg.multiWriter,
+		QoS:          cfg.qos,
+		MountOptions: cfg.mountOptions,
+		MkfsOptions:  cfg.mkfsOptions,
+	}, nil
+}
+
 func parseClassParameters(params map[string]string) (*provisionerConfig, error) {
 	var cfg provisionerConfig
 
+	var err error
 	for k, v := range params {
 		switch strings.ToLower(k) {
 		case "pool":
@@ -174,6 +285,41 @@ func parseClassParameters(params map[string]string) (*provisionerConfig, error)
 			cfg.clusterName = v
 		case "fstype":
 			cfg.fstype = v
+		case "imagefeatures":
+			for _, feature := range strings.Split(v, ",") {
+				if feature = strings.TrimSpace(feature); feature != "" {
+					cfg.imageOptions.Features = append(cfg.imageOptions.Features, feature)
+				}
+			}
+		case "objectsize":
+			cfg.imageOptions.ObjectSize = v
+		case "imageorder":
+			if cfg.imageOptions.Order, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("invalid imageOrder %q. %+v", v, err)
+			}
+		case "stripeunit":
+			cfg.imageOptions.StripeUnit = v
+		case "stripecount":
+			if cfg.imageOptions.StripeCount, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("invalid stripeCount %q. %+v", v, err)
+			}
+		case "datapool":
+			cfg.imageOptions.DataPool = v
+		case "multiwriter":
+			if cfg.multiWriter, err = strconv.ParseBool(v); err != nil {
+				return nil, fmt.Errorf("invalid multiWriter %q. %+v", v, err)
+			}
+		case "readiopslimit", "writeiopslimit", "readbpslimit", "writebpslimit":
+			if err := cfg.setQoS(strings.ToLower(k), v); err != nil {
+				return nil, fmt.Errorf("invalid %s %q. %+v", k, v, err)
+			}
+		case "readiopsburst", "writeiopsburst", "readbpsburst", "writebpsburst":
+			// the blkio throttles of the agent have no burst and the kernel rbd client ignores the librbd QoS
+			return nil, fmt.Errorf("%s is not supported. the kernel rbd client cannot enforce bursts", k)
+		case "mountoptions":
+			cfg.mountOptions = flexvolume.ParseMountOptions(v)
+		case "mkfsoptions":
+			cfg.mkfsOptions = flexvolume.ParseMkfsOptions(v)
 		default:
 			return nil, fmt.Errorf("invalid option %q for volume plugin %s", k, "rookVolumeProvisioner")
 		}
@@ -187,5 +333,46 @@ func parseClassParameters(params map[string]string) (*provisionerConfig, error)
 		cfg.clusterName = cluster.DefaultClusterName
 	}
 
+	if err := cfg.imageOptions.Validate(); err != nil {
+		return nil, fmt.Errorf("StorageClass for provisioner %s has invalid image parameters. %+v", "rookVolumeProvisioner", err)
+	}
+
+	if err := flexvolume.ValidateMountOptions(cfg.mountOptions); err != nil {
+		return nil, fmt.Errorf("StorageClass for provisioner %s has invalid mountOptions. %+v", "rookVolumeProvisioner", err)
+	}
+
+	if err := flexvolume.ValidateMkfsOptions(cfg.fstype, cfg.mkfsOptions); err != nil {
+		return nil, fmt.Errorf("StorageClass for provisioner %s has invalid mkfsOptions. %+v", "rookVolumeProvisioner", err)
+	}
+
+	if cfg.multiWriter {
+		// the image of a volume with multiple writers must not have the exclusive lock
+		if cfg.imageOptions.RequiresExclusiveLock() {
+			return nil, fmt.Errorf("StorageClass for provisioner %s with multiWriter cannot enable image features %v", "rookVolumeProvisioner", ceph.ExclusiveLockFeatures)
+		}
+		if len(cfg.imageOptions.Features) == 0 {
+			cfg.imageOptions.Features = []string{"layering"}
+		}
+	}
+
 	return &cfg, nil
 }
+
+// setQoS sets the I/O limit of the lower case parameter
+func (cfg *provisionerConfig) setQoS(key, value string) error {
+	v, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return err
+	}
+	switch key {
+	case "readiopslimit":
+		cfg.qos.ReadIOPSLimit = v
+	case "writeiopslimit":
+		cfg.qos.WriteIOPSLimit = v
+	case "readbpslimit":
+		cfg.qos.ReadBPSLimit = v
+	case "writebpslimit":
+		cfg.qos.WriteBPSLimit = v
+	}
+	return nil
+}
@@ -24,12 +24,14 @@ import (
 	"testing"
 
 	"github.com/kubernetes-incubator/external-storage/lib/controller"
+	ceph "github.com/rook/rook/pkg/ceph/client"
 	cephtest "github.com/rook/rook/pkg/ceph/test"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/operator/test"
 	exectest "github.com/rook/rook/pkg/util/exec/test"
 	"github.com/stretchr/testify/assert"
 	"k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	storagebeta "k8s.io/api/storage/v1beta1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -77,6 +79,42 @@ func TestProvisionImage(t *testing.T) {
 	assert.Equal(t, "class-1", pv.Spec.PersistentVolumeSource.FlexVolume.Options["storageClass"])
 	assert.Equal(t, "testpool", pv.Spec.PersistentVolumeSource.FlexVolume.Options["pool"])
 	assert.Equal(t, "pvc-uid-1-1", pv.Spec.PersistentVolumeSource.FlexVolume.Options["image"])
+	assert.Equal(t, "testCluster", pv.Spec.PersistentVolumeSource.FlexVolume.Options["clusterName"])
+}
+
+func TestDeleteImage(t *testing.T) {
+	clientset := test.New(3)
+	var deleted []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if command == "rbd" && args[0] == "rm" {
+				deleted = append(deleted, args[1]+" "+args[2])
+			}
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{Clientset: clientset, Executor: executor}
+	provisioner := New(context)
+
+	// each volume is deleted from the pool and cluster stored in its PV
+	err := provisioner.Delete(newVolume("pvc-1", "pool1", "cluster1", "class-1"))
+	assert.Nil(t, err)
+	err = provisioner.Delete(newVolume("pvc-2", "pool2", "cluster2", "class-2"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"pool1/pvc-1 --cluster=cluster1", "pool2/pvc-2 --cluster=cluster2"}, deleted)
+
+	// volumes without the cluster in the PV get it from their storage class
+	clientset.StorageV1().StorageClasses().Create(&storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "class-3"},
+		Parameters: map[string]string{"pool": "pool3", "clusterName": "cluster3"},
+	})
+	err = provisioner.Delete(newVolume("pvc-3", "pool3", "", "class-3"))
+	assert.Nil(t, err)
+	assert.Equal(t, "pool3/pvc-3 --cluster=cluster3", deleted[2])
+
+	// the delete fails if the storage class is not found
+	err = provisioner.Delete(newVolume("pvc-4", "pool4", "", "class-4"))
+	assert.NotNil(t, err)
 }
 
 func TestParseClassParameters(t *testing.T) {
@@ -105,6 +143,37 @@ func TestParseClassParametersDefault(t *testing.T) {
 	assert.Equal(t, "", provConfig.fstype)
 }
 
+func TestParseClassParametersImageOptions(t *testing.T) {
+	cfg := map[string]string{
+		"pool":          "replicapool",
+		"dataPool":      "ecpool",
+		"imageFeatures": "layering, exclusive-lock,object-map",
+		"imageOrder":    "23",
+		"stripeUnit":    "64K",
+		"stripeCount":   "8",
+	}
+
+	provConfig, err := parseClassParameters(cfg)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "replicapool", provConfig.pool)
+	assert.Equal(t, "ecpool", provConfig.imageOptions.DataPool)
+	assert.Equal(t, []string{"layering", "exclusive-lock", "object-map"}, provConfig.imageOptions.Features)
+	assert.Equal(t, 23, provConfig.imageOptions.Order)
+	assert.Equal(t, "64K", provConfig.imageOptions.StripeUnit)
+	assert.Equal(t, 8, provConfig.imageOptions.StripeCount)
+
+	// invalid image options are rejected
+	cfg["stripeCount"] = "eight"
+	_, err = parseClassParameters(cfg)
+	assert.NotNil(t, err)
+
+	cfg["stripeCount"] = "8"
+	cfg["imageFeatures"] = "layering,bogus"
+	_, err = parseClassParameters(cfg)
+	assert.NotNil(t, err)
+}
+
 func TestParseClassParametersNoPool(t *testing.T) {
 	cfg := make(map[string]string)
 	cfg["clustername"] = "myname"
@@ -132,6 +201,21 @@ func newVolumeOptions(storageClass *storagebeta.StorageClass, claim *v1.Persiste
 	}
 }
 
+func newVolume(name, pool, clusterName, storageClass string) *v1.PersistentVolume {
+	options := map[string]string{"storageClass": storageClass, "pool": pool, "image": name}
+	if clusterName != "" {
+		options["clusterName"] = clusterName
+	}
+	return &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				FlexVolume: &v1.FlexVolumeSource{Driver: flexdriver, Options: options},
+			},
+		},
+	}
+}
+
 func newStorageClass(name, provisioner string, parameters map[string]string) *storagebeta.StorageClass {
 	return &storagebeta.StorageClass{
 		ObjectMeta: metav1.ObjectMeta{
@@ -170,3 +254,134 @@ func newClaim(name, claimUID, provisioner, volumeName, storageclassName string,
 	}
 	return claim
 }
+
+func TestParseClassParametersMultiWriter(t *testing.T) {
+	cfg := map[string]string{"pool": "testPool", "multiWriter": "true"}
+	provConfig, err := parseClassParameters(cfg)
+	assert.Nil(t, err)
+	assert.True(t, provConfig.multiWriter)
+	// the default image features do not include the exclusive lock
+	assert.Equal(t, []string{"layering"}, provConfig.imageOptions.Features)
+
+	cfg["imageFeatures"] = "layering,exclusive-lock"
+	_, err = parseClassParameters(cfg)
+	assert.NotNil(t, err)
+
+	cfg["multiWriter"] = "yes please"
+	_, err = parseClassParameters(cfg)
+	assert.NotNil(t, err)
+}
+
+func TestParseClassParametersQoS(t *testing.T) {
+	cfg := map[string]string{"pool": "testPool", "readIOPSLimit": "100", "writeBPSLimit": "1048576"}
+	provConfig, err := parseClassParameters(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, ceph.ImageQoS{ReadIOPSLimit: 100, WriteBPSLimit: 1048576}, provConfig.qos)
+
+	// bursts cannot be enforced by the kernel rbd client
+	cfg["readIOPSBurst"] = "200"
+	_, err = parseClassParameters(cfg)
+	assert.NotNil(t, err)
+
+	cfg = map[string]string{"pool": "testPool", "readBPSLimit": "fast"}
+	_, err = parseClassParameters(cfg)
+	assert.NotNil(t, err)
+}
+
+func TestProvisionImageWithQoS(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if command == "rbd" && args[0] == "ls" && args[1] == "-l" {
+				return `[{"image":"pvc-uid-1-1","size":1048576,"format":2}]`, nil
+			}
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{Clientset: test.New(3), Executor: executor}
+	provisioner := New(context)
+
+	// the limits are stored in the PV for the agent
+	volume := newVolumeOptions(newStorageClass("class-1", "rook.io/block", map[string]string{"pool": "testpool", "readIOPSLimit": "100", "writeBPSLimit": "1048576"}), newClaim("claim-1", "uid-1-1", "class-1", "", "class-1", nil))
+	pv, err := provisioner.Provision(volume)
+	assert.Nil(t, err)
+	assert.Equal(t, "100", pv.Spec.PersistentVolumeSource.FlexVolume.Options["readIOPSLimit"])
+	assert.Equal(t, "1048576", pv.Spec.PersistentVolumeSource.FlexVolume.Options["writeBPSLimit"])
+	_, ok := pv.Spec.PersistentVolumeSource.FlexVolume.Options["writeIOPSLimit"]
+	assert.False(t, ok)
+}
+
+func TestParseClassParametersMountAndMkfsOptions(t *testing.T) {
+	cfg := map[string]string{"pool": "testPool", "fstype": "xfs", "mountOptions": "noatime, discard", "mkfsOptions": "-n ftype=1 -K"}
+	provConfig, err := parseClassParameters(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"noatime", "discard"}, provConfig.mountOptions)
+	assert.Equal(t, []string{"-n", "ftype=1", "-K"}, provConfig.mkfsOptions)
+
+	// the mkfs options must be allowed for the file system
+	cfg["fstype"] = "ext4"
+	_, err = parseClassParameters(cfg)
+	assert.NotNil(t, err)
+
+	cfg = map[string]string{"pool": "testPool", "mkfsOptions": "-m 1"}
+	provConfig, err = parseClassParameters(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"-m", "1"}, provConfig.mkfsOptions)
+
+	cfg = map[string]string{"pool": "testPool", "mountOptions": "noatime,remount"}
+	_, err = parseClassParameters(cfg)
+	assert.NotNil(t, err)
+}
+
+func TestProvisionImageWithMountAndMkfsOptions(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if command == "rbd" && args[0] == "ls" && args[1] == "-l" {
+				return `[{"image":"pvc-uid-1-1","size":1048576,"format":2}]`, nil
+			}
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{Clientset: test.New(3), Executor: executor}
+	provisioner := New(context)
+
+	// the options are stored in the PV for the flex driver
+	volume := newVolumeOptions(newStorageClass("class-1", "rook.io/block", map[string]string{"pool": "testpool", "mountOptions": "noatime,discard", "mkfsOptions": "-m 1 -E lazy_itable_init=0"}), newClaim("claim-1", "uid-1-1", "class-1", "", "class-1", nil))
+	pv, err := provisioner.Provision(volume)
+	assert.Nil(t, err)
+	assert.Equal(t, "noatime,discard", pv.Spec.PersistentVolumeSource.FlexVolume.Options["mountOptions"])
+	assert.Equal(t, "-m 1 -E lazy_itable_init=0", pv.Spec.PersistentVolumeSource.FlexVolume.Options["mkfsOptions"])
+}
+
+func TestParseBlockVolumeOptions(t *testing.T) {
+	opts, err := ParseBlockVolumeOptions(map[string]string{"pool": "testPool", "fstype": "xfs", "dataPool": "ecpool"})
+	assert.Nil(t, err)
+	assert.Equal(t, "testPool", opts.Pool)
+	assert.Equal(t, "rook", opts.ClusterName)
+	assert.Equal(t, "xfs", opts.FsType)
+	assert.Equal(t, "ecpool", opts.ImageOptions.DataPool)
+	assert.False(t, opts.MultiWriter)
+
+	_, err = ParseBlockVolumeOptions(map[string]string{"clusterName": "myname"})
+	assert.NotNil(t, err)
+}
+
+func TestProvisionBlockVolumeMode(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			assert.Fail(t, "no image should be created")
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{Clientset: test.New(3), Executor: executor}
+	provisioner := New(context)
+
+	// raw block volumes cannot be attached by the flexvolume driver
+	claim := newClaim("claim-1", "uid-1-1", "class-1", "", "class-1", nil)
+	volumeMode := v1.PersistentVolumeBlock
+	claim.Spec.VolumeMode = &volumeMode
+	volume := newVolumeOptions(newStorageClass("class-1", "rook.io/block", map[string]string{"pool": "testpool"}), claim)
+
+	_, err := provisioner.Provision(volume)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "block.csi.rook.io")
+}
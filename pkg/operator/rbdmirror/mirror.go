@@ -0,0 +1,339 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package rbdmirror for the Ceph rbd-mirror daemons that replicate block images to peer clusters.
+package rbdmirror
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/rook/rook/pkg/ceph/client"
+	"github.com/rook/rook/pkg/ceph/rbdmirror"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	opmon "github.com/rook/rook/pkg/operator/mon"
+	"k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-rbd-mirror")
+
+const (
+	appName     = "rook-ceph-rbd-mirror"
+	keyringName = "keyring"
+	peersDir    = "/etc/rook/rbd-mirror-peers"
+
+	// PeerSecretName is the name of the secret with the settings a peer cluster needs to connect to this cluster
+	PeerSecretName = "rook-rbd-mirror-peer"
+	// PeerUser is the ceph user that the rbd-mirror daemons of peer clusters connect as
+	PeerUser = "rbd-mirror-peer"
+)
+
+// Cluster is the ceph rbd-mirror manager
+type Cluster struct {
+	Namespace   string
+	Version     string
+	Workers     int
+	placement   k8sutil.Placement
+	context     *clusterd.Context
+	HostNetwork bool
+}
+
+// New creates an instance of the rbd-mirror manager
+func New(context *clusterd.Context, namespace, version string, workers int, placement k8sutil.Placement, hostNetwork bool) *Cluster {
+	return &Cluster{
+		context:     context,
+		Namespace:   namespace,
+		placement:   placement,
+		Version:     version,
+		Workers:     workers,
+		HostNetwork: hostNetwork,
+	}
+}
+
+// Start the rbd-mirror instances. The daemons beyond the number of workers are removed, so Start also applies a
+// change of the number of workers.
+func (c *Cluster) Start() error {
+	logger.Infof("start running %d rbd-mirror daemons", c.Workers)
+
+	existing, err := c.context.Clientset.ExtensionsV1beta1().Deployments(c.Namespace).List(metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", k8sutil.AppAttr, appName)})
+	if err != nil {
+		return fmt.Errorf("failed to list rbd-mirror deployments. %+v", err)
+	}
+
+	if c.Workers > 0 {
+		if err := c.createPeerSecret(); err != nil {
+			return fmt.Errorf("failed to create the rbd-mirror peer secret. %+v", err)
+		}
+	}
+
+	// the new daemons replicate to the peers that were added to the existing daemons
+	peers := peerSecretNames(existing.Items)
+	workers := map[string]bool{}
+	for i := 0; i < c.Workers; i++ {
+		name := fmt.Sprintf("%s%d", appName, i)
+		workers[name] = true
+		err := c.createKeyring(name)
+		if err != nil {
+			return fmt.Errorf("failed to create rbd-mirror keyring. %+v", err)
+		}
+
+		// start the deployment
+		deployment := c.makeDeployment(name)
+		for _, peer := range peers {
+			addPeerVolume(&deployment.Spec.Template.Spec, peer)
+		}
+		_, err = c.context.Clientset.ExtensionsV1beta1().Deployments(c.Namespace).Create(deployment)
+		if err != nil {
+			if !errors.IsAlreadyExists(err) {
+				return fmt.Errorf("failed to create rbd-mirror deployment. %+v", err)
+			}
+			logger.Infof("%s deployment already exists", name)
+		} else {
+			logger.Infof("%s deployment started", name)
+		}
+	}
+
+	for _, d := range existing.Items {
+		if !workers[d.Name] {
+			if err := c.removeDaemon(d.Name); err != nil {
+				return fmt.Errorf("failed to remove rbd-mirror daemon %s. %+v", d.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// remove the deployment, the keyring secret and the ceph user of an rbd-mirror daemon
+func (c *Cluster) removeDaemon(name string) error {
+	logger.Infof("removing rbd-mirror daemon %s", name)
+	if err := k8sutil.DeleteDeployment(c.context.Clientset, c.Namespace, name); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete deployment. %+v", err)
+	}
+	if err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Delete(name, &metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete keyring secret. %+v", err)
+	}
+	if err := client.AuthDelete(c.context, c.Namespace, fmt.Sprintf("client.rbd-mirror.%s", name)); err != nil {
+		logger.Warningf("failed to delete the ceph user of %s. %+v", name, err)
+	}
+	return nil
+}
+
+// get the names of the peer secrets mounted into the rbd-mirror daemons
+func peerSecretNames(deployments []extensions.Deployment) []string {
+	var peers []string
+	found := map[string]bool{}
+	for _, d := range deployments {
+		for _, v := range d.Spec.Template.Spec.Volumes {
+			if v.Secret != nil && strings.HasPrefix(v.Name, "peer-") && !found[v.Secret.SecretName] {
+				found[v.Secret.SecretName] = true
+				peers = append(peers, v.Secret.SecretName)
+			}
+		}
+	}
+	return peers
+}
+
+// AddPeer mounts the secret with the connection settings of a peer cluster into the rbd-mirror daemons.
+// The daemons are restarted by the deployment to pick up the new peer.
+func AddPeer(context *clusterd.Context, namespace, peerSecretName string) error {
+	selector := fmt.Sprintf("%s=%s", k8sutil.AppAttr, appName)
+	deployments, err := context.Clientset.ExtensionsV1beta1().Deployments(namespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return fmt.Errorf("failed to list rbd-mirror deployments. %+v", err)
+	}
+	if len(deployments.Items) == 0 {
+		logger.Warningf("no rbd-mirror daemons are running in namespace %s to replicate to peer %s", namespace, peerSecretName)
+		return nil
+	}
+
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		if !addPeerVolume(&d.Spec.Template.Spec, peerSecretName) {
+			continue
+		}
+
+		logger.Infof("adding mirroring peer %s to %s", peerSecretName, d.Name)
+		if _, err := context.Clientset.ExtensionsV1beta1().Deployments(namespace).Update(d); err != nil {
+			return fmt.Errorf("failed to add peer %s to %s. %+v", peerSecretName, d.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// add the volume and mount for the peer secret to the pod spec. Returns false if the peer was already added.
+func addPeerVolume(podSpec *v1.PodSpec, peerSecretName string) bool {
+	volumeName := fmt.Sprintf("peer-%s", peerSecretName)
+	for _, v := range podSpec.Volumes {
+		if v.Name == volumeName {
+			return false
+		}
+	}
+
+	podSpec.Volumes = append(podSpec.Volumes, v1.Volume{
+		Name:         volumeName,
+		VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: peerSecretName}},
+	})
+	for i := range podSpec.Containers {
+		podSpec.Containers[i].VolumeMounts = append(podSpec.Containers[i].VolumeMounts,
+			v1.VolumeMount{Name: volumeName, MountPath: path.Join(peersDir, peerSecretName), ReadOnly: true})
+	}
+	return true
+}
+
+func (c *Cluster) makeDeployment(name string) *extensions.Deployment {
+	deployment := &extensions.Deployment{}
+	deployment.Name = name
+	deployment.Namespace = c.Namespace
+	deployment.Labels = c.getLabels()
+
+	podSpec := v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      c.getLabels(),
+			Annotations: map[string]string{},
+		},
+		Spec: v1.PodSpec{
+			Containers:    []v1.Container{c.rbdMirrorContainer(name)},
+			RestartPolicy: v1.RestartPolicyAlways,
+			Volumes: []v1.Volume{
+				{Name: k8sutil.DataDirVolume, VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+				k8sutil.ConfigOverrideVolume(),
+			},
+			HostNetwork: c.HostNetwork,
+		},
+	}
+	if c.HostNetwork {
+		podSpec.Spec.DNSPolicy = v1.DNSClusterFirstWithHostNet
+	}
+	c.placement.ApplyToPodSpec(&podSpec.Spec)
+
+	replicas := int32(1)
+	deployment.Spec = extensions.DeploymentSpec{Template: podSpec, Replicas: &replicas}
+	return deployment
+}
+
+func (c *Cluster) rbdMirrorContainer(name string) v1.Container {
+
+	return v1.Container{
+		Args: []string{
+			"rbd-mirror",
+			fmt.Sprintf("--config-dir=%s", k8sutil.DataDir),
+			fmt.Sprintf("--rbd-mirror-peers-dir=%s", peersDir),
+		},
+		Name:  name,
+		Image: k8sutil.MakeRookImage(c.Version),
+		VolumeMounts: []v1.VolumeMount{
+			{Name: k8sutil.DataDirVolume, MountPath: k8sutil.DataDir},
+			k8sutil.ConfigOverrideMount(),
+		},
+		Env: []v1.EnvVar{
+			{Name: "ROOK_RBD_MIRROR_NAME", Value: name},
+			{Name: "ROOK_RBD_MIRROR_KEYRING", ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: name}, Key: keyringName}}},
+			opmon.ClusterNameEnvVar(c.Namespace),
+			opmon.EndpointEnvVar(),
+			opmon.SecretEnvVar(),
+			opmon.AdminSecretEnvVar(),
+			k8sutil.ConfigOverrideEnvVar(),
+		},
+	}
+}
+
+func (c *Cluster) getLabels() map[string]string {
+	return map[string]string{
+		k8sutil.AppAttr:     appName,
+		k8sutil.ClusterAttr: c.Namespace,
+	}
+}
+
+func (c *Cluster) createKeyring(name string) error {
+	_, err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Get(name, metav1.GetOptions{})
+	if err == nil {
+		logger.Infof("the rbd-mirror keyring was already generated")
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get rbd-mirror secrets. %+v", err)
+	}
+
+	// get-or-create-key for the user account
+	username := fmt.Sprintf("client.rbd-mirror.%s", name)
+	keyring, err := client.AuthGetOrCreateKey(c.context, c.Namespace, username, rbdAccess())
+	if err != nil {
+		return fmt.Errorf("failed to get or create auth key for %s. %+v", username, err)
+	}
+
+	// Store the keyring in a secret
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: c.Namespace},
+		StringData: map[string]string{keyringName: keyring},
+		Type:       k8sutil.RookType,
+	}
+	_, err = c.context.Clientset.CoreV1().Secrets(c.Namespace).Create(secret)
+	if err != nil {
+		return fmt.Errorf("failed to save rbd-mirror secrets. %+v", err)
+	}
+
+	return nil
+}
+
+// create or refresh the secret that holds the mon endpoints and the key for peer clusters to connect to
+// this cluster. The secret can be copied to the peer cluster to bootstrap mirroring in the other direction.
+func (c *Cluster) createPeerSecret() error {
+	key, err := client.AuthGetOrCreateKey(c.context, c.Namespace, fmt.Sprintf("client.%s", PeerUser), rbdAccess())
+	if err != nil {
+		return fmt.Errorf("failed to get or create auth key for %s. %+v", PeerUser, err)
+	}
+
+	endpoints, err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Get(opmon.EndpointConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get mon endpoints. %+v", err)
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: PeerSecretName, Namespace: c.Namespace},
+		StringData: map[string]string{
+			rbdmirror.PeerMonEndpointsKey: endpoints.Data[opmon.EndpointDataKey],
+			rbdmirror.PeerUserKey:         PeerUser,
+			rbdmirror.PeerSecretKey:       key,
+		},
+		Type: k8sutil.RookType,
+	}
+	_, err = c.context.Clientset.CoreV1().Secrets(c.Namespace).Create(secret)
+	if err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create peer secret. %+v", err)
+		}
+		// the mon endpoints may have changed since the secret was created
+		if _, err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Update(secret); err != nil {
+			return fmt.Errorf("failed to update peer secret. %+v", err)
+		}
+	}
+
+	logger.Infof("rbd-mirror peer secret %s is ready to be copied to the peer cluster", PeerSecretName)
+	return nil
+}
+
+func rbdAccess() []string {
+	return []string{"mon", "profile rbd", "osd", "profile rbd"}
+}
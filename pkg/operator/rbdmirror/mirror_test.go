@@ -0,0 +1,134 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rbdmirror
+
+import (
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	opmon "github.com/rook/rook/pkg/operator/mon"
+	testop "github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStartRBDMirror(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			return `{"key":"mysecurekey"}`, nil
+		},
+	}
+	clientset := testop.New(3)
+	context := &clusterd.Context{Executor: executor, Clientset: clientset}
+	endpoints := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: opmon.EndpointConfigMapName, Namespace: "ns"},
+		Data:       map[string]string{opmon.EndpointDataKey: "mon0=1.2.3.4:6790"},
+	}
+	clientset.CoreV1().ConfigMaps("ns").Create(endpoints)
+
+	c := New(context, "ns", "myversion", 2, k8sutil.Placement{}, false)
+	err := c.Start()
+	assert.Nil(t, err)
+
+	for _, name := range []string{"rook-ceph-rbd-mirror0", "rook-ceph-rbd-mirror1"} {
+		_, err := clientset.ExtensionsV1beta1().Deployments("ns").Get(name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		_, err = clientset.CoreV1().Secrets("ns").Get(name, metav1.GetOptions{})
+		assert.Nil(t, err)
+	}
+
+	// the peer secret is created for the peer cluster to connect to this cluster
+	secret, err := clientset.CoreV1().Secrets("ns").Get(PeerSecretName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "mon0=1.2.3.4:6790", secret.StringData["monEndpoints"])
+	assert.Equal(t, PeerUser, secret.StringData["user"])
+	assert.Equal(t, "mysecurekey", secret.StringData["key"])
+
+	// starting again succeeds
+	err = c.Start()
+	assert.Nil(t, err)
+
+	// a daemon added later replicates to the peers of the existing daemons
+	err = AddPeer(context, "ns", "remote")
+	assert.Nil(t, err)
+	c.Workers = 3
+	err = c.Start()
+	assert.Nil(t, err)
+	d, err := clientset.ExtensionsV1beta1().Deployments("ns").Get("rook-ceph-rbd-mirror2", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "remote", d.Spec.Template.Spec.Volumes[2].Secret.SecretName)
+
+	// the daemons beyond the number of workers are removed
+	c.Workers = 1
+	err = c.Start()
+	assert.Nil(t, err)
+	deployments, err := clientset.ExtensionsV1beta1().Deployments("ns").List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(deployments.Items))
+	assert.Equal(t, "rook-ceph-rbd-mirror0", deployments.Items[0].Name)
+	_, err = clientset.CoreV1().Secrets("ns").Get("rook-ceph-rbd-mirror1", metav1.GetOptions{})
+	assert.NotNil(t, err)
+}
+
+func TestAddPeer(t *testing.T) {
+	clientset := testop.New(1)
+	context := &clusterd.Context{Clientset: clientset}
+
+	// no error if there are no rbd-mirror daemons
+	err := AddPeer(context, "ns", "remote")
+	assert.Nil(t, err)
+
+	c := New(context, "ns", "myversion", 1, k8sutil.Placement{}, false)
+	clientset.ExtensionsV1beta1().Deployments("ns").Create(c.makeDeployment("rook-ceph-rbd-mirror0"))
+
+	// the peer secret is mounted into the daemon
+	err = AddPeer(context, "ns", "remote")
+	assert.Nil(t, err)
+	d, err := clientset.ExtensionsV1beta1().Deployments("ns").Get("rook-ceph-rbd-mirror0", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(d.Spec.Template.Spec.Volumes))
+	assert.Equal(t, "remote", d.Spec.Template.Spec.Volumes[2].Secret.SecretName)
+	mounts := d.Spec.Template.Spec.Containers[0].VolumeMounts
+	assert.Equal(t, 3, len(mounts))
+	assert.Equal(t, "/etc/rook/rbd-mirror-peers/remote", mounts[2].MountPath)
+
+	// adding the peer again does not change the daemon
+	err = AddPeer(context, "ns", "remote")
+	assert.Nil(t, err)
+	d, _ = clientset.ExtensionsV1beta1().Deployments("ns").Get("rook-ceph-rbd-mirror0", metav1.GetOptions{})
+	assert.Equal(t, 3, len(d.Spec.Template.Spec.Volumes))
+}
+
+func TestRBDMirrorPodSpec(t *testing.T) {
+	c := New(nil, "ns", "myversion", 1, k8sutil.Placement{}, true)
+
+	d := c.makeDeployment("rook-ceph-rbd-mirror0")
+	assert.Equal(t, "rook-ceph-rbd-mirror0", d.Name)
+	assert.Equal(t, appName, d.Labels["app"])
+	assert.Equal(t, appName, d.Spec.Template.ObjectMeta.Labels["app"])
+	assert.Equal(t, "ns", d.Spec.Template.ObjectMeta.Labels["rook_cluster"])
+	assert.Equal(t, true, d.Spec.Template.Spec.HostNetwork)
+	assert.Equal(t, v1.DNSClusterFirstWithHostNet, d.Spec.Template.Spec.DNSPolicy)
+
+	cont := d.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "rook/rook:myversion", cont.Image)
+	assert.Equal(t, "rbd-mirror", cont.Args[0])
+	assert.Equal(t, "--config-dir=/var/lib/rook", cont.Args[1])
+	assert.Equal(t, "--rbd-mirror-peers-dir=/etc/rook/rbd-mirror-peers", cont.Args[2])
+}
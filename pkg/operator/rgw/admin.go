@@ -0,0 +1,94 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"fmt"
+
+	cephrgw "github.com/rook/rook/pkg/ceph/rgw"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// the key of the id of the admin user in its secret. the keys of the user are saved with the same keys as the keys of
+// the system user of a secondary zone.
+const userIDKeyName = "user-id"
+
+// createAdminUser creates the admin system user of the object store and saves its id and keys in a secret, which the
+// API server reads for the admin operations on buckets. The user is only created once. The users of a secondary zone
+// are created in the master zone, so a secondary zone has no admin user.
+func (s *ObjectStore) createAdminUser(context *clusterd.Context) error {
+	if s.Spec.Zone != nil {
+		return nil
+	}
+
+	_, err := context.Clientset.CoreV1().Secrets(s.Namespace).Get(AdminUserSecretName(s.Name), metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get admin user secret. %+v", err)
+	}
+
+	objContext := s.objectContext(context)
+	user, err := cephrgw.CreateAdminUser(objContext)
+	if err != nil {
+		return err
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: AdminUserSecretName(s.Name), Namespace: s.Namespace},
+		StringData: map[string]string{
+			userIDKeyName: user.UserID,
+			accessKeyName: *user.AccessKey,
+			secretKeyName: *user.SecretKey,
+		},
+		Type: k8sutil.RookType,
+	}
+	if _, err := context.Clientset.CoreV1().Secrets(s.Namespace).Create(secret); err != nil {
+		// the user would be orphaned since another user is created when the secret is not found
+		if err := cephrgw.DeleteAdminUser(objContext, user.UserID); err != nil {
+			logger.Errorf("failed to delete admin user %s after failing to save its secret. %+v", user.UserID, err)
+		}
+		return fmt.Errorf("failed to save admin user secret. %+v", err)
+	}
+	logger.Infof("created admin user of object store %s", s.Name)
+	return nil
+}
+
+// AdminUserSecretName gets the name of the secret with the keys of the admin user of the object store. The name does
+// not start with the instance name prefix, so it cannot collide with the resources of another object store.
+func AdminUserSecretName(name string) string {
+	return fmt.Sprintf("rook-ceph-object-admin-%s", name)
+}
+
+// GetAdminUserKeys gets the access and secret keys of the admin user of the object store in the cluster namespace
+func GetAdminUserKeys(clientset kubernetes.Interface, namespace, name string) (string, string, error) {
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(AdminUserSecretName(name), metav1.GetOptions{})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get admin user secret of object store %s. %+v", name, err)
+	}
+	accessKey := string(secret.Data[accessKeyName])
+	secretKey := string(secret.Data[secretKeyName])
+	if accessKey == "" || secretKey == "" {
+		return "", "", fmt.Errorf("admin user secret of object store %s must contain %s and %s", name, accessKeyName, secretKeyName)
+	}
+	return accessKey, secretKey, nil
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	testop "github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCreateAdminUser(t *testing.T) {
+	created := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			created = append(created, args[3])
+			return `{"user_id":"` + args[3] + `","keys":[{"access_key":"adminkey","secret_key":"adminsecret"}]}`, nil
+		},
+	}
+	clientset := testop.New(3)
+	context := &clusterd.Context{Clientset: clientset, Executor: executor}
+	store := simpleStore()
+
+	// the user gets a random id with the reserved prefix
+	err := store.createAdminUser(context)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(created))
+	assert.True(t, strings.HasPrefix(created[0], "rook-admin-"))
+	assert.True(t, len(created[0]) > len("rook-admin-"))
+
+	secret, err := clientset.CoreV1().Secrets(store.Namespace).Get(AdminUserSecretName(store.Name), metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, created[0], secret.StringData[userIDKeyName])
+	assert.Equal(t, "adminkey", secret.StringData[accessKeyName])
+	assert.Equal(t, "adminsecret", secret.StringData[secretKeyName])
+
+	// the user is only created once
+	err = store.createAdminUser(context)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(created))
+
+	// a secondary zone has no admin user
+	zone := simpleStore()
+	zone.Name = "zone"
+	zone.Spec.Zone = &ZoneSpec{}
+	err = zone.createAdminUser(context)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(created))
+}
+
+func TestCreateAdminUserSecretFailure(t *testing.T) {
+	var commands [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			commands = append(commands, args[:4])
+			return `{"user_id":"` + args[3] + `","keys":[{"access_key":"adminkey","secret_key":"adminsecret"}]}`, nil
+		},
+	}
+	clientset := testop.New(3)
+	clientset.PrependReactor("create", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("mock failure")
+	})
+	context := &clusterd.Context{Clientset: clientset, Executor: executor}
+	store := simpleStore()
+
+	// the user is removed when its secret cannot be saved
+	err := store.createAdminUser(context)
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, len(commands))
+	assert.Equal(t, []string{"user", "create", "--uid"}, commands[0][:3])
+	assert.Equal(t, []string{"user", "rm", "--uid", commands[0][3]}, commands[1])
+}
+
+func TestAdminUserSecretName(t *testing.T) {
+	// the secret of a store cannot have the name of the keyring secret of another store
+	assert.NotEqual(t, InstanceName("default-admin"), AdminUserSecretName("default"))
+	assert.Equal(t, "rook-ceph-object-admin-default", AdminUserSecretName("default"))
+}
+
+func TestGetAdminUserKeys(t *testing.T) {
+	clientset := testop.New(3)
+	_, _, err := GetAdminUserKeys(clientset, "ns", "default")
+	assert.NotNil(t, err)
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: AdminUserSecretName("default"), Namespace: "ns"},
+		Data:       map[string][]byte{accessKeyName: []byte("adminkey"), secretKeyName: []byte("adminsecret")},
+	}
+	_, err = clientset.CoreV1().Secrets("ns").Create(secret)
+	assert.Nil(t, err)
+	accessKey, secretKey, err := GetAdminUserKeys(clientset, "ns", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, "adminkey", accessKey)
+	assert.Equal(t, "adminsecret", secretKey)
+}
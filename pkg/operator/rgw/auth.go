@@ -0,0 +1,153 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"k8s.io/api/core/v1"
+)
+
+const (
+	ldapVolumeName = "rook-rgw-ldap"
+	ldapMountPath  = "/etc/rook/ldap"
+	ldapSecretKey  = "password"
+	caVolumeName   = "rook-rgw-ca"
+	caMountPath    = "/etc/rook/ca"
+	caKeyName      = "ca.crt"
+
+	// the keys of the keystone admin user in the secret
+	keystoneUserKey     = "username"
+	keystonePasswordKey = "password"
+	keystoneProjectKey  = "project"
+	keystoneDomainKey   = "domain"
+)
+
+// validateAuth validates the ldap and keystone settings
+func (s *ObjectStore) validateAuth() error {
+	auth := s.Spec.Auth
+	if auth == nil {
+		return nil
+	}
+	if ldap := auth.LDAP; ldap != nil {
+		if ldap.URI == "" || ldap.SearchDN == "" {
+			return fmt.Errorf("missing ldap uri or search dn")
+		}
+		if ldap.BindDN == "" || ldap.BindSecretRef == "" {
+			return fmt.Errorf("missing ldap bind dn or bind secret")
+		}
+	}
+	if keystone := auth.Keystone; keystone != nil {
+		if keystone.URL == "" || keystone.AdminSecretRef == "" {
+			return fmt.Errorf("missing keystone url or admin secret")
+		}
+		if keystone.APIVersion != 0 && keystone.APIVersion != 2 && keystone.APIVersion != 3 {
+			return fmt.Errorf("unsupported keystone api version %d", keystone.APIVersion)
+		}
+	}
+	if auth.CASecretRef != "" && auth.LDAP == nil {
+		return fmt.Errorf("the ca certificate is only supported with ldap. set insecureSkipVerify for a keystone service with a private ca")
+	}
+	return nil
+}
+
+// authVolumes gets the volumes of the secrets with the ldap password and the ca certificate of the ldap server
+func (s *ObjectStore) authVolumes() []v1.Volume {
+	auth := s.Spec.Auth
+	if auth == nil {
+		return nil
+	}
+
+	var volumes []v1.Volume
+	if auth.LDAP != nil {
+		volumes = append(volumes, v1.Volume{Name: ldapVolumeName, VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{
+			SecretName: auth.LDAP.BindSecretRef,
+			Items:      []v1.KeyToPath{{Key: ldapSecretKey, Path: ldapSecretKey}},
+		}}})
+	}
+	if auth.CASecretRef != "" {
+		volumes = append(volumes, v1.Volume{Name: caVolumeName, VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{
+			SecretName: auth.CASecretRef,
+			Items:      []v1.KeyToPath{{Key: caKeyName, Path: caKeyName}},
+		}}})
+	}
+	return volumes
+}
+
+// addAuth adds the ldap and keystone settings to the rgw container. The password of the ldap service account is
+// mounted from its secret, while the credentials of the keystone admin user are passed in env vars from their secret.
+func (s *ObjectStore) addAuth(container *v1.Container) {
+	auth := s.Spec.Auth
+	if auth == nil {
+		return
+	}
+
+	if ldap := auth.LDAP; ldap != nil {
+		container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{Name: ldapVolumeName, MountPath: ldapMountPath, ReadOnly: true})
+		container.Args = append(container.Args,
+			fmt.Sprintf("--rgw-ldap-uri=%s", ldap.URI),
+			fmt.Sprintf("--rgw-ldap-binddn=%s", ldap.BindDN),
+			fmt.Sprintf("--rgw-ldap-secret-path=%s", path.Join(ldapMountPath, ldapSecretKey)),
+			fmt.Sprintf("--rgw-ldap-searchdn=%s", ldap.SearchDN))
+		if ldap.DNAttribute != "" {
+			container.Args = append(container.Args, fmt.Sprintf("--rgw-ldap-dnattr=%s", ldap.DNAttribute))
+		}
+		if ldap.SearchFilter != "" {
+			container.Args = append(container.Args, fmt.Sprintf("--rgw-ldap-searchfilter=%s", ldap.SearchFilter))
+		}
+	}
+
+	if keystone := auth.Keystone; keystone != nil {
+		container.Args = append(container.Args, fmt.Sprintf("--rgw-keystone-url=%s", keystone.URL))
+		if keystone.APIVersion != 0 {
+			container.Args = append(container.Args, fmt.Sprintf("--rgw-keystone-api-version=%d", keystone.APIVersion))
+		}
+		if len(keystone.AcceptedRoles) > 0 {
+			container.Args = append(container.Args, fmt.Sprintf("--rgw-keystone-accepted-roles=%s", strings.Join(keystone.AcceptedRoles, ",")))
+		}
+		if keystone.ImplicitTenants {
+			container.Args = append(container.Args, "--rgw-keystone-implicit-tenants")
+		}
+		if keystone.S3 {
+			container.Args = append(container.Args, "--rgw-keystone-s3")
+		}
+		if keystone.InsecureSkipVerify {
+			container.Args = append(container.Args, "--rgw-keystone-insecure-skip-verify")
+		}
+		container.Env = append(container.Env,
+			keystoneEnvVar("ROOK_RGW_KEYSTONE_ADMIN_USER", keystone.AdminSecretRef, keystoneUserKey, false),
+			keystoneEnvVar("ROOK_RGW_KEYSTONE_ADMIN_SECRET", keystone.AdminSecretRef, keystonePasswordKey, false),
+			keystoneEnvVar("ROOK_RGW_KEYSTONE_ADMIN_PROJECT", keystone.AdminSecretRef, keystoneProjectKey, false),
+			keystoneEnvVar("ROOK_RGW_KEYSTONE_ADMIN_DOMAIN", keystone.AdminSecretRef, keystoneDomainKey, true))
+	}
+
+	if auth.CASecretRef != "" {
+		// the ca is trusted by the ldap client (openldap). the http client of keystone (libcurl) only trusts the ca
+		// certificates of the image, so the certificate of a keystone service with a private ca is not verified instead.
+		container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{Name: caVolumeName, MountPath: caMountPath, ReadOnly: true})
+		container.Env = append(container.Env, v1.EnvVar{Name: "LDAPTLS_CACERT", Value: path.Join(caMountPath, caKeyName)})
+	}
+}
+
+func keystoneEnvVar(name, secretName, key string, optional bool) v1.EnvVar {
+	return v1.EnvVar{Name: name, ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{
+		LocalObjectReference: v1.LocalObjectReference{Name: secretName},
+		Key:                  key,
+		Optional:             &optional,
+	}}}
+}
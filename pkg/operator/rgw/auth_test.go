@@ -0,0 +1,104 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+)
+
+func TestValidateAuth(t *testing.T) {
+	store := simpleStore()
+	assert.Nil(t, store.validateAuth())
+
+	// ldap requires the uri, search dn and bind credentials
+	store.Spec.Auth = &AuthSpec{LDAP: &LDAPSpec{URI: "ldaps://ldap.example.com"}}
+	assert.NotNil(t, store.validateAuth())
+	store.Spec.Auth.LDAP.SearchDN = "ou=users,dc=example,dc=com"
+	assert.NotNil(t, store.validateAuth())
+	store.Spec.Auth.LDAP.BindDN = "uid=rgw,dc=example,dc=com"
+	store.Spec.Auth.LDAP.BindSecretRef = "ldap-bind"
+	assert.Nil(t, store.validateAuth())
+
+	// keystone requires the url and the admin secret
+	store.Spec.Auth = &AuthSpec{Keystone: &KeystoneSpec{URL: "https://keystone.example.com:5000"}}
+	assert.NotNil(t, store.validateAuth())
+	store.Spec.Auth.Keystone.AdminSecretRef = "keystone-admin"
+	assert.Nil(t, store.validateAuth())
+	store.Spec.Auth.Keystone.APIVersion = 4
+	assert.NotNil(t, store.validateAuth())
+	store.Spec.Auth.Keystone.APIVersion = 3
+
+	// the ca certificate only verifies the ldap server
+	store.Spec.Auth.CASecretRef = "corp-ca"
+	assert.NotNil(t, store.validateAuth())
+	store.Spec.Auth.LDAP = &LDAPSpec{URI: "ldaps://ldap.example.com", SearchDN: "ou=users,dc=example,dc=com",
+		BindDN: "uid=rgw,dc=example,dc=com", BindSecretRef: "ldap-bind"}
+	assert.Nil(t, store.validateAuth())
+}
+
+func TestAuthPodSpec(t *testing.T) {
+	store := simpleStore()
+	store.Spec.Auth = &AuthSpec{
+		LDAP: &LDAPSpec{
+			URI:           "ldaps://ldap.example.com:636",
+			BindDN:        "uid=rgw,ou=services,dc=example,dc=com",
+			BindSecretRef: "ldap-bind",
+			SearchDN:      "ou=users,dc=example,dc=com",
+			SearchFilter:  "(memberOf=cn=s3,ou=groups,dc=example,dc=com)",
+		},
+		Keystone: &KeystoneSpec{
+			URL:                "https://keystone.example.com:5000",
+			AdminSecretRef:     "keystone-admin",
+			AcceptedRoles:      []string{"member", "admin"},
+			S3:                 true,
+			InsecureSkipVerify: true,
+		},
+		CASecretRef: "corp-ca",
+	}
+
+	s := store.makeRGWPodSpec("v1.0", false)
+	assert.Equal(t, 4, len(s.Spec.Volumes))
+	assert.Equal(t, "ldap-bind", s.Spec.Volumes[2].Secret.SecretName)
+	assert.Equal(t, "corp-ca", s.Spec.Volumes[3].Secret.SecretName)
+
+	cont := s.Spec.Containers[0]
+	assert.Equal(t, 4, len(cont.VolumeMounts))
+	assert.Contains(t, cont.Args, "--rgw-ldap-uri=ldaps://ldap.example.com:636")
+	assert.Contains(t, cont.Args, "--rgw-ldap-secret-path=/etc/rook/ldap/password")
+	assert.Contains(t, cont.Args, "--rgw-ldap-searchfilter=(memberOf=cn=s3,ou=groups,dc=example,dc=com)")
+	assert.Contains(t, cont.Args, "--rgw-keystone-url=https://keystone.example.com:5000")
+	assert.Contains(t, cont.Args, "--rgw-keystone-accepted-roles=member,admin")
+	assert.Contains(t, cont.Args, "--rgw-keystone-s3")
+	assert.Contains(t, cont.Args, "--rgw-keystone-insecure-skip-verify")
+	assert.NotContains(t, cont.Args, "--rgw-keystone-implicit-tenants")
+
+	// the keystone credentials are read from the secret
+	env := map[string]v1.EnvVar{}
+	for _, e := range cont.Env {
+		env[e.Name] = e
+	}
+	password := env["ROOK_RGW_KEYSTONE_ADMIN_SECRET"].ValueFrom.SecretKeyRef
+	assert.Equal(t, "keystone-admin", password.Name)
+	assert.Equal(t, "password", password.Key)
+	assert.False(t, *password.Optional)
+	assert.True(t, *env["ROOK_RGW_KEYSTONE_ADMIN_DOMAIN"].ValueFrom.SecretKeyRef.Optional)
+	assert.Equal(t, "/etc/rook/ca/ca.crt", env["LDAPTLS_CACERT"].Value)
+	_, ok := env["SSL_CERT_FILE"]
+	assert.False(t, ok)
+}
@@ -22,18 +22,29 @@ package rgw
 
 import (
 	"fmt"
+	"reflect"
+	"time"
 
+	cephrgw "github.com/rook/rook/pkg/ceph/rgw"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	"github.com/rook/rook/pkg/operator/kit"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 )
 
+// SyncStatusInterval is the interval between the updates of the sync status of the secondary zones
+var SyncStatusInterval = time.Minute
+
 // ObjectStoreController represents a controller object for object store custom resources
 type ObjectStoreController struct {
 	context     *clusterd.Context
 	scheme      *runtime.Scheme
+	client      rest.Interface
 	versionTag  string
 	hostNetwork bool
 }
@@ -54,6 +65,7 @@ func (c *ObjectStoreController) StartWatch(namespace string, stopCh chan struct{
 		return fmt.Errorf("failed to get a k8s client for watching object store resources: %v", err)
 	}
 	c.scheme = scheme
+	c.client = client
 
 	resourceHandlerFuncs := cache.ResourceEventHandlerFuncs{
 		AddFunc:    c.onAdd,
@@ -62,6 +74,8 @@ func (c *ObjectStoreController) StartWatch(namespace string, stopCh chan struct{
 	}
 	watcher := kit.NewWatcher(ObjectStoreResource, namespace, resourceHandlerFuncs, client)
 	go watcher.Watch(&ObjectStore{}, stopCh)
+	go c.reportSyncStatus(namespace, stopCh)
+	go c.watchTLSSecrets(namespace, stopCh)
 	return nil
 }
 
@@ -80,13 +94,24 @@ func (c *ObjectStoreController) onAdd(obj interface{}) {
 	err = objectStoreCopy.Create(c.context, c.versionTag, c.hostNetwork)
 	if err != nil {
 		logger.Errorf("failed to create object store %s. %+v", objectStore.Name, err)
+		return
+	}
+
+	if err := c.updateSyncStatus(objectStoreCopy); err != nil {
+		logger.Warningf("failed to update the sync status of object store %s. %+v", objectStore.Name, err)
 	}
 }
 
 func (c *ObjectStoreController) onUpdate(oldObj, newObj interface{}) {
-	//oldObjectStore := oldObj.(*ObjectStore)
+	oldObjectStore := oldObj.(*ObjectStore)
 	newObjectStore := newObj.(*ObjectStore)
 
+	// the status is updated by the operator and does not require an update of the object store
+	if reflect.DeepEqual(oldObjectStore.Spec, newObjectStore.Spec) {
+		logger.Debugf("object store %s did not change", newObjectStore.Name)
+		return
+	}
+
 	// if the object store is modified, allow the object store to be created if it wasn't already
 	err := newObjectStore.Update(c.context, c.versionTag, c.hostNetwork)
 	if err != nil {
@@ -101,3 +126,94 @@ func (c *ObjectStoreController) onDelete(obj interface{}) {
 		logger.Errorf("failed to delete object store %s. %+v", objectStore.Name, err)
 	}
 }
+
+// watchTLSSecrets restarts the rgw pods of the object stores when their tls secret is updated, for example when the
+// certificate is renewed
+func (c *ObjectStoreController) watchTLSSecrets(namespace string, stopCh chan struct{}) {
+	source := cache.NewListWatchFromClient(c.context.Clientset.CoreV1().RESTClient(), "secrets", namespace, fields.Everything())
+	_, controller := cache.NewInformer(source, &v1.Secret{}, 0, cache.ResourceEventHandlerFuncs{
+		UpdateFunc: c.onSecretUpdate,
+	})
+	controller.Run(stopCh)
+}
+
+func (c *ObjectStoreController) onSecretUpdate(oldObj, newObj interface{}) {
+	oldSecret := oldObj.(*v1.Secret)
+	newSecret := newObj.(*v1.Secret)
+	if oldSecret.Type != v1.SecretTypeTLS || reflect.DeepEqual(oldSecret.Data, newSecret.Data) {
+		return
+	}
+
+	var stores ObjectStoreList
+	err := c.client.Get().Namespace(newSecret.Namespace).Resource(ObjectStoreResource.Plural).Do().Into(&stores)
+	if err != nil {
+		logger.Errorf("failed to list object stores. %+v", err)
+		return
+	}
+	c.restartTLSSecretStores(stores.Items, newSecret)
+}
+
+// restartTLSSecretStores restarts the rgw pods of the object stores that use the tls secret
+func (c *ObjectStoreController) restartTLSSecretStores(stores []ObjectStore, secret *v1.Secret) {
+	certHash := tlsSecretHash(secret)
+	for i := range stores {
+		store := &stores[i]
+		if store.Spec.Gateway.TLSSecretRef != secret.Name {
+			continue
+		}
+		logger.Infof("tls secret %s of object store %s was updated. restarting the rgw pods", secret.Name, store.Name)
+		if err := store.restartRGWPods(c.context, certHash); err != nil {
+			logger.Errorf("failed to restart the rgw pods of object store %s. %+v", store.Name, err)
+		}
+	}
+}
+
+// reportSyncStatus periodically updates the sync status of the object stores that are secondary zones until the stop
+// channel is closed
+func (c *ObjectStoreController) reportSyncStatus(namespace string, stopCh chan struct{}) {
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(SyncStatusInterval):
+		}
+
+		var stores ObjectStoreList
+		err := c.client.Get().Namespace(namespace).Resource(ObjectStoreResource.Plural).Do().Into(&stores)
+		if err != nil {
+			logger.Errorf("failed to list object stores. %+v", err)
+			continue
+		}
+		for i := range stores.Items {
+			if err := c.updateSyncStatus(&stores.Items[i]); err != nil {
+				logger.Warningf("failed to update the sync status of object store %s. %+v", stores.Items[i].Name, err)
+			}
+		}
+	}
+}
+
+// updateSyncStatus sets the status of the replication from the other zones in the status of a secondary zone
+func (c *ObjectStoreController) updateSyncStatus(store *ObjectStore) error {
+	if store.Spec.Zone == nil {
+		return nil
+	}
+
+	status := &SyncStatus{LastChecked: metav1.Now()}
+	syncStatus, err := cephrgw.GetSyncStatus(store.objectContext(c.context))
+	if err != nil {
+		status.Error = err.Error()
+	} else {
+		status.MetadataSync = syncStatus.MetadataSync
+		for _, d := range syncStatus.DataSync {
+			status.DataSync = append(status.DataSync, DataSyncStatus{Source: d.Source, Status: d.Status})
+		}
+	}
+	store.Status.Sync = status
+
+	return c.client.Put().
+		Namespace(store.Namespace).
+		Resource(ObjectStoreResource.Plural).
+		Name(store.Name).
+		Body(store).
+		Do().Error()
+}
@@ -0,0 +1,303 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	"k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+const wildcardPrefix = "*."
+
+// ExternalServiceName gets the name of the service that exposes the object store outside of the cluster. The name does
+// not start with the instance name prefix, so it cannot collide with the service of another object store.
+func ExternalServiceName(name string) string {
+	return fmt.Sprintf("rook-ceph-object-external-%s", name)
+}
+
+// validateExternal validates the settings to expose the object store outside of the cluster
+func (s *ObjectStore) validateExternal() error {
+	external := s.Spec.Gateway.External
+	if external == nil {
+		return nil
+	}
+	switch external.ServiceType {
+	case "", v1.ServiceTypeNodePort, v1.ServiceTypeLoadBalancer:
+	default:
+		return fmt.Errorf("unsupported external service type %s", external.ServiceType)
+	}
+	if ingress := external.Ingress; ingress != nil {
+		if len(ingress.Hosts) == 0 {
+			return fmt.Errorf("missing ingress hosts")
+		}
+		wildcards := 0
+		for _, host := range ingress.Hosts {
+			if strings.HasPrefix(host, wildcardPrefix) {
+				wildcards++
+			}
+		}
+		if wildcards > 1 {
+			return fmt.Errorf("only one wildcard ingress host is supported")
+		}
+	}
+	return nil
+}
+
+// dnsName gets the domain of the wildcard ingress host, e.g. s3.example.com for *.s3.example.com. The buckets are
+// then accessed with virtual-host-style requests to <bucket>.s3.example.com.
+func (s *ObjectStore) dnsName() string {
+	external := s.Spec.Gateway.External
+	if external == nil || external.Ingress == nil {
+		return ""
+	}
+	for _, host := range external.Ingress.Hosts {
+		if strings.HasPrefix(host, wildcardPrefix) {
+			return strings.TrimPrefix(host, wildcardPrefix)
+		}
+	}
+	return ""
+}
+
+// startExternalService creates or updates the NodePort or LoadBalancer service of the object store. The service is
+// deleted when the object store is not exposed with a service.
+func (s *ObjectStore) startExternalService(context *clusterd.Context) error {
+	services := context.Clientset.CoreV1().Services(s.Namespace)
+	name := ExternalServiceName(s.Name)
+	external := s.Spec.Gateway.External
+	if external == nil || external.ServiceType == "" {
+		err := services.Delete(name, &metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete external rgw service. %+v", err)
+		}
+		return nil
+	}
+
+	labels := s.getLabels()
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   s.Namespace,
+			Labels:      labels,
+			Annotations: external.Annotations,
+		},
+		Spec: v1.ServiceSpec{
+			Selector: labels,
+			Type:     external.ServiceType,
+		},
+	}
+	addPort(svc, "http", s.Spec.Gateway.Port)
+	addPort(svc, "https", s.Spec.Gateway.SecurePort)
+
+	existing, err := services.Get(name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get external rgw service. %+v", err)
+		}
+		if _, err := services.Create(svc); err != nil {
+			return fmt.Errorf("failed to create external rgw service. %+v", err)
+		}
+		logger.Infof("external rgw service %s of type %s created", name, external.ServiceType)
+		return nil
+	}
+
+	// keep the node ports that were allocated to the existing service
+	for i, port := range svc.Spec.Ports {
+		for _, existingPort := range existing.Spec.Ports {
+			if port.Name == existingPort.Name {
+				svc.Spec.Ports[i].NodePort = existingPort.NodePort
+			}
+		}
+	}
+	existing.Annotations = external.Annotations
+	existing.Spec.Type = external.ServiceType
+	existing.Spec.Ports = svc.Spec.Ports
+	if _, err := services.Update(existing); err != nil {
+		return fmt.Errorf("failed to update external rgw service. %+v", err)
+	}
+	logger.Infof("external rgw service %s of type %s updated", name, external.ServiceType)
+	return nil
+}
+
+// startIngress creates or updates the ingress of the object store, which routes the requests to the hosts to the rgw
+// service. The ingress is deleted when the object store is not exposed with an ingress.
+func (s *ObjectStore) startIngress(context *clusterd.Context) error {
+	ingresses := context.Clientset.ExtensionsV1beta1().Ingresses(s.Namespace)
+	external := s.Spec.Gateway.External
+	if external == nil || external.Ingress == nil {
+		err := ingresses.Delete(s.instanceName(), &metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete rgw ingress. %+v", err)
+		}
+		return nil
+	}
+
+	port := s.Spec.Gateway.Port
+	if port == 0 {
+		port = s.Spec.Gateway.SecurePort
+	}
+	backend := extensions.IngressBackend{ServiceName: s.instanceName(), ServicePort: intstr.FromInt(int(port))}
+	ingress := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        s.instanceName(),
+			Namespace:   s.Namespace,
+			Labels:      s.getLabels(),
+			Annotations: external.Ingress.Annotations,
+		},
+	}
+	for _, host := range external.Ingress.Hosts {
+		ingress.Spec.Rules = append(ingress.Spec.Rules, extensions.IngressRule{
+			Host: host,
+			IngressRuleValue: extensions.IngressRuleValue{HTTP: &extensions.HTTPIngressRuleValue{
+				Paths: []extensions.HTTPIngressPath{{Path: "/", Backend: backend}},
+			}},
+		})
+	}
+	if external.Ingress.TLSSecretRef != "" {
+		ingress.Spec.TLS = []extensions.IngressTLS{{Hosts: external.Ingress.Hosts, SecretName: external.Ingress.TLSSecretRef}}
+	}
+
+	existing, err := ingresses.Get(s.instanceName(), metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get rgw ingress. %+v", err)
+		}
+		if _, err := ingresses.Create(ingress); err != nil {
+			return fmt.Errorf("failed to create rgw ingress. %+v", err)
+		}
+		logger.Infof("rgw ingress created for hosts %v", external.Ingress.Hosts)
+		return nil
+	}
+
+	existing.Annotations = ingress.Annotations
+	existing.Spec = ingress.Spec
+	if _, err := ingresses.Update(existing); err != nil {
+		return fmt.Errorf("failed to update rgw ingress. %+v", err)
+	}
+	logger.Infof("rgw ingress updated for hosts %v", external.Ingress.Hosts)
+	return nil
+}
+
+// GetConnectionInfo gets the endpoint of the object store. If the object store is exposed with an ingress or an
+// external service, the external endpoint is returned. Otherwise, the endpoint is the rgw service in the cluster.
+func GetConnectionInfo(clientset kubernetes.Interface, namespace, name string) (*model.ObjectStoreConnectInfo, error) {
+	service, err := clientset.CoreV1().Services(namespace).Get(InstanceName(name), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	ingress, err := clientset.ExtensionsV1beta1().Ingresses(namespace).Get(InstanceName(name), metav1.GetOptions{})
+	if err == nil {
+		return ingressConnectionInfo(ingress), nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get rgw ingress. %+v", err)
+	}
+
+	external, err := clientset.CoreV1().Services(namespace).Get(ExternalServiceName(name), metav1.GetOptions{})
+	if err == nil {
+		return externalServiceConnectionInfo(clientset, external)
+	}
+	if !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get external rgw service. %+v", err)
+	}
+
+	info := &model.ObjectStoreConnectInfo{
+		Host:      InstanceName(name),
+		IPAddress: service.Spec.ClusterIP,
+		Ports:     []int32{},
+	}
+	for _, port := range service.Spec.Ports {
+		info.Ports = append(info.Ports, port.Port)
+	}
+	return info, nil
+}
+
+func ingressConnectionInfo(ingress *extensions.Ingress) *model.ObjectStoreConnectInfo {
+	info := &model.ObjectStoreConnectInfo{Ports: []int32{80}}
+	if len(ingress.Spec.TLS) > 0 {
+		info.Ports = append(info.Ports, 443)
+	}
+
+	// the host of a wildcard rule is the domain of the buckets
+	for _, rule := range ingress.Spec.Rules {
+		if !strings.HasPrefix(rule.Host, wildcardPrefix) {
+			info.Host = rule.Host
+			break
+		}
+		if info.Host == "" {
+			info.Host = strings.TrimPrefix(rule.Host, wildcardPrefix)
+		}
+	}
+	if lb := ingress.Status.LoadBalancer.Ingress; len(lb) > 0 {
+		info.IPAddress = lb[0].IP
+	}
+	return info
+}
+
+func externalServiceConnectionInfo(clientset kubernetes.Interface, service *v1.Service) (*model.ObjectStoreConnectInfo, error) {
+	info := &model.ObjectStoreConnectInfo{Host: service.Name, Ports: []int32{}}
+	if service.Spec.Type == v1.ServiceTypeNodePort {
+		// the node ports are open on all nodes
+		nodes, err := clientset.CoreV1().Nodes().List(metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list nodes. %+v", err)
+		}
+		if len(nodes.Items) > 0 {
+			info.IPAddress = nodeAddress(nodes.Items[0])
+		}
+		for _, port := range service.Spec.Ports {
+			info.Ports = append(info.Ports, port.NodePort)
+		}
+		return info, nil
+	}
+
+	// the address of the load balancer is set when the load balancer is provisioned
+	if lb := service.Status.LoadBalancer.Ingress; len(lb) > 0 {
+		info.IPAddress = lb[0].IP
+		if lb[0].Hostname != "" {
+			info.Host = lb[0].Hostname
+		}
+	}
+	for _, port := range service.Spec.Ports {
+		info.Ports = append(info.Ports, port.Port)
+	}
+	return info, nil
+}
+
+// nodeAddress gets the external address of the node, or the internal address if it has no external address
+func nodeAddress(node v1.Node) string {
+	var internal string
+	for _, address := range node.Status.Addresses {
+		switch address.Type {
+		case v1.NodeExternalIP:
+			return address.Address
+		case v1.NodeInternalIP:
+			if internal == "" {
+				internal = address.Address
+			}
+		}
+	}
+	return internal
+}
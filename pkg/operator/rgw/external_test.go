@@ -0,0 +1,181 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateExternal(t *testing.T) {
+	store := simpleStore()
+	assert.Nil(t, store.validateExternal())
+
+	store.Spec.Gateway.External = &ExternalSpec{ServiceType: v1.ServiceTypeLoadBalancer}
+	assert.Nil(t, store.validateExternal())
+	store.Spec.Gateway.External.ServiceType = v1.ServiceTypeExternalName
+	assert.NotNil(t, store.validateExternal())
+
+	// the ingress requires the hosts and only one wildcard
+	store.Spec.Gateway.External = &ExternalSpec{Ingress: &IngressSpec{}}
+	assert.NotNil(t, store.validateExternal())
+	store.Spec.Gateway.External.Ingress.Hosts = []string{"*.s3.example.com", "s3.example.com"}
+	assert.Nil(t, store.validateExternal())
+	assert.Equal(t, "s3.example.com", store.dnsName())
+	store.Spec.Gateway.External.Ingress.Hosts = []string{"*.s3.example.com", "*.s3.example.org"}
+	assert.NotNil(t, store.validateExternal())
+}
+
+func TestDNSNameArg(t *testing.T) {
+	store := simpleStore()
+	cont := store.rgwContainer("v1.0")
+	for _, arg := range cont.Args {
+		assert.NotContains(t, arg, "--rgw-dns-name")
+	}
+
+	store.Spec.Gateway.External = &ExternalSpec{Ingress: &IngressSpec{Hosts: []string{"*.s3.example.com"}}}
+	cont = store.rgwContainer("v1.0")
+	assert.Equal(t, "--rgw-dns-name=s3.example.com", cont.Args[len(cont.Args)-1])
+}
+
+func TestExternalService(t *testing.T) {
+	clientset := testop.New(3)
+	context := &clusterd.Context{Clientset: clientset}
+	store := simpleStore()
+	store.Spec.Gateway.SecurePort = 443
+	_, err := store.startService(context, false)
+	assert.Nil(t, err)
+
+	// the cluster service is the endpoint by default
+	assert.Nil(t, store.startExternalService(context))
+	_, err = clientset.CoreV1().Services(store.Namespace).Get(ExternalServiceName(store.Name), metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	info, err := GetConnectionInfo(clientset, store.Namespace, store.Name)
+	assert.Nil(t, err)
+	assert.Equal(t, store.instanceName(), info.Host)
+	assert.Equal(t, []int32{123, 443}, info.Ports)
+
+	// create a load balancer
+	store.Spec.Gateway.External = &ExternalSpec{
+		ServiceType: v1.ServiceTypeLoadBalancer,
+		Annotations: map[string]string{"service.beta.kubernetes.io/aws-load-balancer-internal": "0.0.0.0/0"},
+	}
+	assert.Nil(t, store.startExternalService(context))
+	svc, err := clientset.CoreV1().Services(store.Namespace).Get(ExternalServiceName(store.Name), metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1.ServiceTypeLoadBalancer, svc.Spec.Type)
+	assert.Equal(t, "0.0.0.0/0", svc.Annotations["service.beta.kubernetes.io/aws-load-balancer-internal"])
+	assert.Equal(t, store.getLabels(), svc.Spec.Selector)
+
+	// the address of the load balancer is the endpoint
+	svc.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "1.2.3.4", Hostname: "rgw.elb.example.com"}}
+	_, err = clientset.CoreV1().Services(store.Namespace).Update(svc)
+	assert.Nil(t, err)
+	info, err = GetConnectionInfo(clientset, store.Namespace, store.Name)
+	assert.Nil(t, err)
+	assert.Equal(t, model.ObjectStoreConnectInfo{Host: "rgw.elb.example.com", IPAddress: "1.2.3.4", Ports: []int32{123, 443}}, *info)
+
+	// change to a node port, which keeps the allocated node ports
+	svc.Spec.Ports[0].NodePort = 30080
+	_, err = clientset.CoreV1().Services(store.Namespace).Update(svc)
+	assert.Nil(t, err)
+	store.Spec.Gateway.External.ServiceType = v1.ServiceTypeNodePort
+	assert.Nil(t, store.startExternalService(context))
+	svc, err = clientset.CoreV1().Services(store.Namespace).Get(ExternalServiceName(store.Name), metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1.ServiceTypeNodePort, svc.Spec.Type)
+	assert.Equal(t, int32(30080), svc.Spec.Ports[0].NodePort)
+	info, err = GetConnectionInfo(clientset, store.Namespace, store.Name)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(30080), info.Ports[0])
+	assert.NotEqual(t, "", info.IPAddress)
+
+	// the external service is removed when it is no longer declared
+	store.Spec.Gateway.External = nil
+	assert.Nil(t, store.startExternalService(context))
+	_, err = clientset.CoreV1().Services(store.Namespace).Get(ExternalServiceName(store.Name), metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+
+	// the service of a store named like the external service of another store is not removed
+	other := simpleStore()
+	other.Name = store.Name + "-external"
+	_, err = other.startService(context, false)
+	assert.Nil(t, err)
+	assert.Nil(t, store.startExternalService(context))
+	_, err = clientset.CoreV1().Services(store.Namespace).Get(other.instanceName(), metav1.GetOptions{})
+	assert.Nil(t, err)
+}
+
+func TestIngress(t *testing.T) {
+	clientset := testop.New(3)
+	context := &clusterd.Context{Clientset: clientset}
+	store := simpleStore()
+	_, err := store.startService(context, false)
+	assert.Nil(t, err)
+
+	store.Spec.Gateway.External = &ExternalSpec{Ingress: &IngressSpec{
+		Hosts:        []string{"*.s3.example.com", "s3.example.com"},
+		TLSSecretRef: "s3-tls",
+		Annotations:  map[string]string{"kubernetes.io/ingress.class": "nginx"},
+	}}
+	assert.Nil(t, store.startIngress(context))
+	ingress, err := clientset.ExtensionsV1beta1().Ingresses(store.Namespace).Get(store.instanceName(), metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "nginx", ingress.Annotations["kubernetes.io/ingress.class"])
+	assert.Equal(t, 2, len(ingress.Spec.Rules))
+	assert.Equal(t, "*.s3.example.com", ingress.Spec.Rules[0].Host)
+	backend := ingress.Spec.Rules[0].HTTP.Paths[0].Backend
+	assert.Equal(t, store.instanceName(), backend.ServiceName)
+	assert.Equal(t, 123, backend.ServicePort.IntValue())
+	assert.Equal(t, "s3-tls", ingress.Spec.TLS[0].SecretName)
+
+	// the ingress host is the endpoint
+	info, err := GetConnectionInfo(clientset, store.Namespace, store.Name)
+	assert.Nil(t, err)
+	assert.Equal(t, model.ObjectStoreConnectInfo{Host: "s3.example.com", Ports: []int32{80, 443}}, *info)
+
+	// update the hosts
+	store.Spec.Gateway.External.Ingress.Hosts = []string{"*.objects.example.com"}
+	store.Spec.Gateway.External.Ingress.TLSSecretRef = ""
+	assert.Nil(t, store.startIngress(context))
+	info, err = GetConnectionInfo(clientset, store.Namespace, store.Name)
+	assert.Nil(t, err)
+	assert.Equal(t, model.ObjectStoreConnectInfo{Host: "objects.example.com", Ports: []int32{80}}, *info)
+
+	// the ingress is removed when it is no longer declared
+	store.Spec.Gateway.External = nil
+	assert.Nil(t, store.startIngress(context))
+	_, err = clientset.ExtensionsV1beta1().Ingresses(store.Namespace).Get(store.instanceName(), metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestNodeAddress(t *testing.T) {
+	node := v1.Node{Status: v1.NodeStatus{Addresses: []v1.NodeAddress{
+		{Type: v1.NodeInternalIP, Address: "10.0.0.1"},
+		{Type: v1.NodeExternalIP, Address: "1.2.3.4"},
+	}}}
+	assert.Equal(t, "1.2.3.4", nodeAddress(node))
+
+	node.Status.Addresses = node.Status.Addresses[:1]
+	assert.Equal(t, "10.0.0.1", nodeAddress(node))
+}
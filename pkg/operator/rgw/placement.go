@@ -0,0 +1,123 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"fmt"
+	"strings"
+
+	cephrgw "github.com/rook/rook/pkg/ceph/rgw"
+	"github.com/rook/rook/pkg/clusterd"
+)
+
+// validatePlacement validates the placement targets, storage classes and their pools
+func (s *ObjectStore) validatePlacement(context *clusterd.Context) error {
+	placement := s.Spec.Placement
+	if placement == nil {
+		return nil
+	}
+	if placement.IndexPool != nil {
+		if err := placement.IndexPool.Validate(context, s.Namespace); err != nil {
+			return fmt.Errorf("invalid index pool spec. %+v", err)
+		}
+		if placement.IndexPool.ErasureCoded.CodingChunks > 0 || placement.IndexPool.ErasureCoded.DataChunks > 0 {
+			return fmt.Errorf("the index pool cannot be erasure coded")
+		}
+	}
+
+	targets := map[string]bool{}
+	storageClasses := false
+	for _, target := range placement.Targets {
+		if target.Name == "" {
+			return fmt.Errorf("missing name of placement target")
+		}
+		if targets[target.Name] {
+			return fmt.Errorf("duplicate placement target %s", target.Name)
+		}
+		targets[target.Name] = true
+
+		if target.Name == cephrgw.DefaultPlacementTarget {
+			if target.DataPool != nil {
+				return fmt.Errorf("the data pool of the %s target is the data pool of the object store", target.Name)
+			}
+		} else {
+			if target.DataPool == nil {
+				return fmt.Errorf("missing data pool of placement target %s", target.Name)
+			}
+			if err := target.DataPool.Validate(context, s.Namespace); err != nil {
+				return fmt.Errorf("invalid data pool spec of placement target %s. %+v", target.Name, err)
+			}
+		}
+
+		classes := map[string]bool{}
+		for _, class := range target.StorageClasses {
+			storageClasses = true
+			if class.Name == "" {
+				return fmt.Errorf("missing name of storage class in placement target %s", target.Name)
+			}
+			if strings.EqualFold(class.Name, cephrgw.StandardStorageClass) {
+				return fmt.Errorf("the %s storage class of placement target %s is its data pool", class.Name, target.Name)
+			}
+			if classes[strings.ToLower(class.Name)] {
+				return fmt.Errorf("duplicate storage class %s in placement target %s", class.Name, target.Name)
+			}
+			classes[strings.ToLower(class.Name)] = true
+			if err := class.DataPool.Validate(context, s.Namespace); err != nil {
+				return fmt.Errorf("invalid data pool spec of storage class %s. %+v", class.Name, err)
+			}
+		}
+	}
+
+	if placement.DefaultTarget != "" && placement.DefaultTarget != cephrgw.DefaultPlacementTarget && !targets[placement.DefaultTarget] {
+		return fmt.Errorf("unknown default placement target %s", placement.DefaultTarget)
+	}
+
+	// the pools of the storage classes must not be created when rgw cannot use them
+	if storageClasses {
+		supported, err := cephrgw.SupportsStorageClasses(context)
+		if err != nil {
+			return err
+		}
+		if !supported {
+			return fmt.Errorf("storage classes require ceph nautilus or newer")
+		}
+	}
+	return nil
+}
+
+// placement gets the placement of the buckets and objects in the pools of the object store
+func (s *ObjectStore) placement() cephrgw.Placement {
+	spec := s.Spec.Placement
+	if spec == nil {
+		return cephrgw.Placement{}
+	}
+
+	placement := cephrgw.Placement{DefaultTarget: spec.DefaultTarget}
+	if spec.IndexPool != nil {
+		placement.IndexPool = spec.IndexPool.ToModel("")
+	}
+	for _, t := range spec.Targets {
+		target := cephrgw.PlacementTarget{Name: t.Name}
+		if t.DataPool != nil {
+			target.DataPool = *t.DataPool.ToModel("")
+		}
+		for _, c := range t.StorageClasses {
+			target.StorageClasses = append(target.StorageClasses, cephrgw.StorageClass{Name: c.Name, DataPool: *c.DataPool.ToModel("")})
+		}
+		placement.Targets = append(placement.Targets, target)
+	}
+	return placement
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/operator/pool"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func placementStore() *ObjectStore {
+	store := simpleStore()
+	store.Spec.Placement = &PlacementSpec{
+		IndexPool: &pool.PoolSpec{Replicated: pool.ReplicatedSpec{Size: 3}},
+		Targets: []PlacementTargetSpec{
+			{
+				Name: "default-placement",
+				StorageClasses: []StorageClassSpec{
+					{Name: "COLD", DataPool: pool.PoolSpec{ErasureCoded: pool.ErasureCodedSpec{CodingChunks: 2, DataChunks: 4}}},
+				},
+			},
+			{Name: "fast", DataPool: &pool.PoolSpec{Replicated: pool.ReplicatedSpec{Size: 2}}},
+		},
+		DefaultTarget: "fast",
+	}
+	return store
+}
+
+func TestValidatePlacement(t *testing.T) {
+	version := "ceph version 14.2.1 (d555a9489eb35f84f2e1ef49b77e19da9d113972) nautilus (stable)"
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			return version, nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+	store := simpleStore()
+	assert.Nil(t, store.validatePlacement(context))
+
+	store = placementStore()
+	assert.Nil(t, store.validatePlacement(context))
+
+	// the index pool is replicated
+	store.Spec.Placement.IndexPool = &pool.PoolSpec{ErasureCoded: pool.ErasureCodedSpec{CodingChunks: 1, DataChunks: 2}}
+	assert.NotNil(t, store.validatePlacement(context))
+
+	// the default target must exist
+	store = placementStore()
+	store.Spec.Placement.DefaultTarget = "missing"
+	assert.NotNil(t, store.validatePlacement(context))
+	store.Spec.Placement.DefaultTarget = "default-placement"
+	assert.Nil(t, store.validatePlacement(context))
+
+	// the targets have unique names and a data pool except for the default target
+	store = placementStore()
+	store.Spec.Placement.Targets[1].Name = "default-placement"
+	assert.NotNil(t, store.validatePlacement(context))
+	store = placementStore()
+	store.Spec.Placement.Targets[1].DataPool = nil
+	assert.NotNil(t, store.validatePlacement(context))
+
+	// the STANDARD storage class is the data pool of the target
+	store = placementStore()
+	store.Spec.Placement.Targets[0].StorageClasses[0].Name = "standard"
+	assert.NotNil(t, store.validatePlacement(context))
+	store = placementStore()
+	store.Spec.Placement.Targets[0].StorageClasses = append(store.Spec.Placement.Targets[0].StorageClasses, StorageClassSpec{
+		Name: "cold", DataPool: pool.PoolSpec{Replicated: pool.ReplicatedSpec{Size: 1}},
+	})
+	assert.NotNil(t, store.validatePlacement(context))
+
+	// the storage classes require nautilus
+	version = "ceph version 12.2.5 (cad919881333ac92274171586c827e01f554a70a) luminous (stable)"
+	store = placementStore()
+	assert.NotNil(t, store.validatePlacement(context))
+	store.Spec.Placement.Targets[0].StorageClasses = nil
+	assert.Nil(t, store.validatePlacement(context))
+}
+
+func TestPlacementModel(t *testing.T) {
+	assert.Equal(t, 0, len(simpleStore().placement().Targets))
+
+	placement := placementStore().placement()
+	assert.Equal(t, "fast", placement.DefaultTarget)
+	assert.Equal(t, uint(3), placement.IndexPool.ReplicatedConfig.Size)
+	assert.Equal(t, 2, len(placement.Targets))
+	assert.Equal(t, "COLD", placement.Targets[0].StorageClasses[0].Name)
+	assert.Equal(t, model.ErasureCoded, placement.Targets[0].StorageClasses[0].DataPool.Type)
+	assert.Equal(t, uint(4), placement.Targets[0].StorageClasses[0].DataPool.ErasureCodedConfig.DataChunkCount)
+	assert.Equal(t, model.Replicated, placement.Targets[1].DataPool.Type)
+}
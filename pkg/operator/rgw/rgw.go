@@ -18,8 +18,12 @@ limitations under the License.
 package rgw
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"path"
+	"strconv"
+	"strings"
 
 	"github.com/coreos/pkg/capnslog"
 	"github.com/rook/rook/pkg/ceph/client"
@@ -34,6 +38,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
 )
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-rgw")
@@ -45,6 +50,22 @@ const (
 	certMountPath  = "/etc/rook/private"
 	certKeyName    = "cert"
 	certFilename   = "rgw-cert.pem"
+	// the annotation of the rgw pods with the hash of the tls secret, which restarts the pods when it changes
+	certHashAnnotation = "rook.io/rgw-cert-hash"
+	// the annotations of the rgw service with the realm and zone group of the object store, which are not named after
+	// the object store if it is a secondary zone
+	realmAnnotation     = "rook.io/rgw-realm"
+	zoneGroupAnnotation = "rook.io/rgw-zonegroup"
+	// the annotations of the rgw service with whether the http port redirects to https and the name of the secret with
+	// the certificate of the object store
+	redirectHTTPAnnotation = "rook.io/rgw-redirect-http"
+	certSecretAnnotation   = "rook.io/rgw-cert-secret"
+	objectStoreAttr        = "rook_object_store"
+	// the key of the ca certificate in a tls secret, e.g. issued by cert-manager
+	caCertKeyName = "ca.crt"
+	// the keys of the system user in the secret of a secondary zone
+	accessKeyName = "access-key"
+	secretKeyName = "secret-key"
 )
 
 // Start the rgw manager
@@ -67,7 +88,8 @@ func (s *ObjectStore) createOrUpdate(context *clusterd.Context, version string,
 	if err == nil && exists {
 		if !update {
 			logger.Infof("object store %s exists in namespace %s", s.Name, s.Namespace)
-			return nil
+			// object stores created before the keys of the admin user were saved in a secret get a new admin user
+			return s.createAdminUser(context)
 		}
 		logger.Infof("object store %s exists in namespace %s. checking for updates", s.Name, s.Namespace)
 	}
@@ -85,16 +107,38 @@ func (s *ObjectStore) createOrUpdate(context *clusterd.Context, version string,
 	}
 
 	// create the ceph artifacts for the object store
-	objContext := cephrgw.NewContext(context, s.Name, s.Namespace)
-	err = cephrgw.CreateObjectStore(objContext, *s.Spec.MetadataPool.ToModel(""), *s.Spec.DataPool.ToModel(""), serviceIP, s.Spec.Gateway.Port)
-	if err != nil {
-		return fmt.Errorf("failed to create pools. %+v", err)
+	objContext := s.objectContext(context)
+	if s.Spec.Zone != nil {
+		zoneConfig, err := s.zoneConfig(context)
+		if err != nil {
+			return fmt.Errorf("failed to get the zone config. %+v", err)
+		}
+		err = cephrgw.CreateSecondaryZone(objContext, *s.Spec.MetadataPool.ToModel(""), *s.Spec.DataPool.ToModel(""), s.placement(), *zoneConfig)
+		if err != nil {
+			return fmt.Errorf("failed to create secondary zone. %+v", err)
+		}
+	} else {
+		err = cephrgw.CreateObjectStore(objContext, *s.Spec.MetadataPool.ToModel(""), *s.Spec.DataPool.ToModel(""), s.placement(), serviceIP, s.Spec.Gateway.Port)
+		if err != nil {
+			return fmt.Errorf("failed to create pools. %+v", err)
+		}
+	}
+	if err := s.createAdminUser(context); err != nil {
+		return fmt.Errorf("failed to create admin user. %+v", err)
 	}
 
 	if err := s.startRGWPods(context, version, hostNetwork, update); err != nil {
 		return fmt.Errorf("failed to start pods. %+v", err)
 	}
 
+	// expose the object store outside of the cluster
+	if err := s.startExternalService(context); err != nil {
+		return err
+	}
+	if err := s.startIngress(context); err != nil {
+		return err
+	}
+
 	logger.Infof("created object store %s", s.Name)
 	return nil
 }
@@ -121,7 +165,7 @@ func (s *ObjectStore) startRGWPods(context *clusterd.Context, version string, ho
 		err = s.startDaemonset(context, version, hostNetwork)
 	} else {
 		rgwType = "deployment"
-		err = s.startDeployment(context, version, s.Spec.Gateway.Instances, hostNetwork)
+		err = s.startDeployment(context, version, s.replicas(), hostNetwork)
 	}
 
 	if err != nil {
@@ -133,7 +177,10 @@ func (s *ObjectStore) startRGWPods(context *clusterd.Context, version string, ho
 		logger.Infof("rgw %s started", rgwType)
 	}
 
-	return nil
+	if err := s.startPodDisruptionBudget(context); err != nil {
+		return err
+	}
+	return s.startAutoscaler(context)
 }
 
 // Delete the object store.
@@ -160,8 +207,23 @@ func (s *ObjectStore) Delete(context *clusterd.Context) error {
 	if err != nil && !errors.IsNotFound(err) {
 		logger.Warningf("failed to delete rgw service. %+v", err)
 	}
+	err = context.Clientset.CoreV1().Services(s.Namespace).Delete(ExternalServiceName(s.Name), options)
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Warningf("failed to delete external rgw service. %+v", err)
+	}
+	err = context.Clientset.ExtensionsV1beta1().Ingresses(s.Namespace).Delete(s.instanceName(), options)
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Warningf("failed to delete rgw ingress. %+v", err)
+	}
 
 	// Make a best effort to delete the rgw pods
+	if err := s.deleteAutoscaler(context); err != nil {
+		logger.Warningf(err.Error())
+	}
+	err = context.Clientset.PolicyV1beta1().PodDisruptionBudgets(s.Namespace).Delete(s.instanceName(), options)
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Warningf("failed to delete rgw pod disruption budget. %+v", err)
+	}
 	err = k8sutil.DeleteDeployment(context.Clientset, s.Namespace, s.instanceName())
 	if err != nil {
 		logger.Warningf(err.Error())
@@ -171,15 +233,23 @@ func (s *ObjectStore) Delete(context *clusterd.Context) error {
 		logger.Warningf(err.Error())
 	}
 
-	// Delete the rgw keyring
+	// Delete the rgw keyring and the keys of the admin user, which is deleted with the pools
 	err = context.Clientset.CoreV1().Secrets(s.Namespace).Delete(s.instanceName(), options)
 	if err != nil && !errors.IsNotFound(err) {
 		logger.Warningf("failed to delete rgw secret. %+v", err)
 	}
+	err = context.Clientset.CoreV1().Secrets(s.Namespace).Delete(AdminUserSecretName(s.Name), options)
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Warningf("failed to delete admin user secret. %+v", err)
+	}
 
-	// Delete the realm and pools
-	objContext := cephrgw.NewContext(context, s.Name, s.Namespace)
-	err = cephrgw.DeleteObjectStore(objContext)
+	// Delete the realm and pools. The realm of a secondary zone belongs to the master zone and only the zone is removed.
+	objContext := s.objectContext(context)
+	if s.Spec.Zone != nil {
+		err = cephrgw.DeleteSecondaryZone(objContext, s.placement())
+	} else {
+		err = cephrgw.DeleteObjectStore(objContext, s.placement())
+	}
 	if err != nil {
 		return fmt.Errorf("failed to delete the realm and pools. %+v", err)
 	}
@@ -227,6 +297,64 @@ func (s *ObjectStore) validate(context *clusterd.Context) error {
 	if err := s.Spec.DataPool.Validate(context, s.Namespace); err != nil {
 		return fmt.Errorf("invalid data pool spec. %+v", err)
 	}
+	if zone := s.Spec.Zone; zone != nil {
+		if zone.Realm == "" {
+			return fmt.Errorf("missing realm of the zone")
+		}
+		if zone.MasterEndpoint == "" {
+			return fmt.Errorf("missing master endpoint of the zone")
+		}
+		if zone.SystemUserSecretRef == "" {
+			return fmt.Errorf("missing system user secret of the zone")
+		}
+		// the rgw service is only reachable in this cluster, so the other zones could not sync from it
+		if len(zone.Endpoints) == 0 {
+			return fmt.Errorf("missing endpoints of the zone")
+		}
+		for _, endpoint := range zone.Endpoints {
+			if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+				return fmt.Errorf("endpoint %s of the zone must start with http:// or https://", endpoint)
+			}
+		}
+	}
+	gateway := s.Spec.Gateway
+	if gateway.SSLCertificateRef != "" && gateway.TLSSecretRef != "" {
+		return fmt.Errorf("only one of the ssl certificate and the tls secret can be set")
+	}
+	switch gateway.Frontend {
+	case "", cephrgw.FrontendCivetweb:
+	case cephrgw.FrontendBeast:
+		// the beast frontend of luminous only serves http on a port and has no other options
+		if gateway.RedirectHTTP {
+			return fmt.Errorf("the beast frontend does not redirect http to https")
+		}
+		if gateway.SecurePort != 0 || gateway.SSLCertificateRef != "" || gateway.TLSSecretRef != "" {
+			return fmt.Errorf("the beast frontend does not support ssl")
+		}
+		if gateway.RequestTimeoutMs != 0 {
+			return fmt.Errorf("the beast frontend does not support the request timeout")
+		}
+	default:
+		return fmt.Errorf("unknown frontend %s", gateway.Frontend)
+	}
+	if gateway.RedirectHTTP && (gateway.Port == 0 || gateway.SecurePort == 0) {
+		return fmt.Errorf("the http redirect requires the port and the secure port")
+	}
+	if gateway.ThreadPoolSize < 0 || gateway.RequestTimeoutMs < 0 {
+		return fmt.Errorf("invalid thread pool size or request timeout")
+	}
+	if err := s.validateScaling(); err != nil {
+		return err
+	}
+	if err := s.validateExternal(); err != nil {
+		return err
+	}
+	if err := s.validateAuth(); err != nil {
+		return err
+	}
+	if err := s.validatePlacement(context); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -265,6 +393,44 @@ func (s *ObjectStore) createKeyring(context *clusterd.Context) error {
 	return nil
 }
 
+// objectContext returns the context of the realm and zone group of the object store
+func (s *ObjectStore) objectContext(context *clusterd.Context) *cephrgw.Context {
+	objContext := cephrgw.NewContext(context, s.Name, s.Namespace)
+	if s.Spec.Zone != nil {
+		objContext.Realm = s.Spec.Zone.Realm
+		objContext.ZoneGroup = s.zoneGroup()
+	}
+	return objContext
+}
+
+func (s *ObjectStore) zoneGroup() string {
+	if s.Spec.Zone.ZoneGroup != "" {
+		return s.Spec.Zone.ZoneGroup
+	}
+	return s.Spec.Zone.Realm
+}
+
+// zoneConfig gets the master endpoint and system user keys of a secondary zone, and the endpoints of the zone in the spec
+func (s *ObjectStore) zoneConfig(context *clusterd.Context) (*cephrgw.ZoneConfig, error) {
+	zone := s.Spec.Zone
+	secret, err := context.Clientset.CoreV1().Secrets(s.Namespace).Get(zone.SystemUserSecretRef, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get system user secret %s. %+v", zone.SystemUserSecretRef, err)
+	}
+	accessKey := string(secret.Data[accessKeyName])
+	secretKey := string(secret.Data[secretKeyName])
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("system user secret %s must contain %s and %s", zone.SystemUserSecretRef, accessKeyName, secretKeyName)
+	}
+
+	return &cephrgw.ZoneConfig{
+		MasterEndpoint: zone.MasterEndpoint,
+		AccessKey:      accessKey,
+		SecretKey:      secretKey,
+		Endpoints:      zone.Endpoints,
+	}, nil
+}
+
 func (s *ObjectStore) instanceName() string {
 	return InstanceName(s.Name)
 }
@@ -273,6 +439,105 @@ func InstanceName(name string) string {
 	return fmt.Sprintf("%s-%s", appName, name)
 }
 
+// GetObjectContext gets the context of the admin commands of the object store in the cluster namespace. The realm and
+// zone group are read from the service of the object store, since those of a secondary zone are not named after the
+// object store.
+func GetObjectContext(context *clusterd.Context, namespace, name string) (*cephrgw.Context, error) {
+	svc, err := context.Clientset.CoreV1().Services(namespace).Get(InstanceName(name), metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service of object store %s in cluster %s. %+v", name, namespace, err)
+	}
+	return serviceObjectContext(context, name, svc), nil
+}
+
+// GetObjectContexts gets the contexts of the admin commands of all the object stores in the cluster namespace
+func GetObjectContexts(context *clusterd.Context, namespace string) ([]*cephrgw.Context, error) {
+	options := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", k8sutil.AppAttr, appName)}
+	services, err := context.Clientset.CoreV1().Services(namespace).List(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rgw services in cluster %s. %+v", namespace, err)
+	}
+
+	objContexts := []*cephrgw.Context{}
+	for i, svc := range services.Items {
+		// the external services of the object stores have the same labels
+		name := svc.Labels[objectStoreAttr]
+		if name == "" || svc.Name != InstanceName(name) {
+			continue
+		}
+		objContexts = append(objContexts, serviceObjectContext(context, name, &services.Items[i]))
+	}
+	return objContexts, nil
+}
+
+// serviceObjectContext gets the context of the object store from the annotations of its service. A service without the
+// annotations was created before secondary zones were supported, so the realm is named after the object store.
+func serviceObjectContext(context *clusterd.Context, name string, svc *v1.Service) *cephrgw.Context {
+	objContext := cephrgw.NewContext(context, name, svc.Namespace)
+	if realm := svc.Annotations[realmAnnotation]; realm != "" {
+		objContext.Realm = realm
+	}
+	if zoneGroup := svc.Annotations[zoneGroupAnnotation]; zoneGroup != "" {
+		objContext.ZoneGroup = zoneGroup
+	}
+	return objContext
+}
+
+// GetServiceEndpoint gets the host and port of the service of the object store in the cluster namespace, and whether
+// the port serves https. The http port is preferred, so a gateway is only reached with https if it has no http port or
+// if its http port only redirects to https.
+func GetServiceEndpoint(clientset kubernetes.Interface, namespace, name string) (string, int32, bool, error) {
+	svc, err := clientset.CoreV1().Services(namespace).Get(InstanceName(name), metav1.GetOptions{})
+	if err != nil {
+		return "", 0, false, fmt.Errorf("failed to get service of object store %s in cluster %s. %+v", name, namespace, err)
+	}
+
+	host := fmt.Sprintf("%s.%s", svc.Name, svc.Namespace)
+	redirect := svc.Annotations[redirectHTTPAnnotation] == "true"
+	var httpPort, securePort *v1.ServicePort
+	for i, port := range svc.Spec.Ports {
+		switch port.Name {
+		case "http":
+			httpPort = &svc.Spec.Ports[i]
+		case "https":
+			securePort = &svc.Spec.Ports[i]
+		}
+	}
+	if httpPort != nil && (securePort == nil || !redirect) {
+		return host, httpPort.Port, false, nil
+	}
+	if securePort != nil {
+		return host, securePort.Port, true, nil
+	}
+	return "", 0, false, fmt.Errorf("http or https port of object store %s not found", name)
+}
+
+// GetServiceCACert gets the certificates in PEM format that verify the certificate of the object store in the cluster
+// namespace. The ca certificate of a tls secret is preferred over its certificate, and the certificate file of the
+// object store may also contain its CA and intermediate certificates. Nil is returned if the object store has no
+// certificate.
+func GetServiceCACert(clientset kubernetes.Interface, namespace, name string) ([]byte, error) {
+	svc, err := clientset.CoreV1().Services(namespace).Get(InstanceName(name), metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service of object store %s in cluster %s. %+v", name, namespace, err)
+	}
+	secretName := svc.Annotations[certSecretAnnotation]
+	if secretName == "" {
+		return nil, nil
+	}
+
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(secretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get certificate secret %s of object store %s. %+v", secretName, name, err)
+	}
+	for _, key := range []string{caCertKeyName, v1.TLSCertKey, certKeyName} {
+		if cert := secret.Data[key]; len(cert) > 0 {
+			return cert, nil
+		}
+	}
+	return nil, fmt.Errorf("certificate secret %s of object store %s has no certificate", secretName, name)
+}
+
 func ModelToSpec(store model.ObjectStore, namespace string) *ObjectStore {
 	return &ObjectStore{
 		ObjectMeta: metav1.ObjectMeta{Name: store.Name, Namespace: namespace},
@@ -312,7 +577,17 @@ func (s *ObjectStore) makeRGWPodSpec(version string, hostNetwork bool) v1.PodTem
 			Items:      []v1.KeyToPath{{Key: certKeyName, Path: certFilename}},
 		}}}
 		podSpec.Volumes = append(podSpec.Volumes, certVol)
+	} else if s.Spec.Gateway.TLSSecretRef != "" {
+		certVol := v1.Volume{Name: certVolumeName, VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{
+			SecretName: s.Spec.Gateway.TLSSecretRef,
+			Items: []v1.KeyToPath{
+				{Key: v1.TLSCertKey, Path: v1.TLSCertKey},
+				{Key: v1.TLSPrivateKeyKey, Path: v1.TLSPrivateKeyKey},
+			},
+		}}}
+		podSpec.Volumes = append(podSpec.Volumes, certVol)
 	}
+	podSpec.Volumes = append(podSpec.Volumes, s.authVolumes()...)
 
 	s.Spec.Gateway.Placement.ApplyToPodSpec(&podSpec)
 
@@ -346,7 +621,11 @@ func (s *ObjectStore) startDaemonset(context *clusterd.Context, version string,
 			Name:      s.instanceName(),
 			Namespace: s.Namespace,
 		},
-		Spec: extensions.DaemonSetSpec{Template: s.makeRGWPodSpec(version, hostNetwork)},
+		Spec: extensions.DaemonSetSpec{
+			Template: s.makeRGWPodSpec(version, hostNetwork),
+			// the pods are restarted when the tls secret is updated
+			UpdateStrategy: extensions.DaemonSetUpdateStrategy{Type: extensions.RollingUpdateDaemonSetStrategyType},
+		},
 	}
 
 	_, err := context.Clientset.ExtensionsV1beta1().DaemonSets(s.Namespace).Create(daemonset)
@@ -379,6 +658,17 @@ func (s *ObjectStore) rgwContainer(version string) v1.Container {
 			opmon.SecretEnvVar(),
 			k8sutil.ConfigOverrideEnvVar(),
 		},
+		Resources: s.Spec.Gateway.Resources,
+		// the service only sends requests to the ready pods and the pods that stop answering are restarted
+		ReadinessProbe: s.healthProbe(readinessProbeDelay, readinessProbeFailure),
+		LivenessProbe:  s.healthProbe(livenessProbeDelay, livenessProbeFailure),
+	}
+
+	if s.Spec.Zone != nil {
+		// the zone of the store is in the realm and zone group of the master zone
+		container.Args = append(container.Args,
+			fmt.Sprintf("--rgw-realm=%s", s.Spec.Zone.Realm),
+			fmt.Sprintf("--rgw-zonegroup=%s", s.zoneGroup()))
 	}
 
 	if s.Spec.Gateway.SSLCertificateRef != "" {
@@ -389,18 +679,102 @@ func (s *ObjectStore) rgwContainer(version string) v1.Container {
 		// Pass the flag for using the ssl cert
 		path := path.Join(certMountPath, certFilename)
 		container.Args = append(container.Args, fmt.Sprintf("--rgw-cert=%s", path))
+	} else if s.Spec.Gateway.TLSSecretRef != "" {
+		mount := v1.VolumeMount{Name: certVolumeName, MountPath: certMountPath, ReadOnly: true}
+		container.VolumeMounts = append(container.VolumeMounts, mount)
+
+		// the certificate and the private key are in separate files
+		container.Args = append(container.Args,
+			fmt.Sprintf("--rgw-cert=%s", path.Join(certMountPath, v1.TLSCertKey)),
+			fmt.Sprintf("--rgw-key=%s", path.Join(certMountPath, v1.TLSPrivateKeyKey)))
 	}
 
+	gateway := s.Spec.Gateway
+	if gateway.Frontend != "" {
+		container.Args = append(container.Args, fmt.Sprintf("--rgw-frontend=%s", gateway.Frontend))
+	}
+	if gateway.ThreadPoolSize != 0 {
+		container.Args = append(container.Args, fmt.Sprintf("--rgw-thread-pool-size=%d", gateway.ThreadPoolSize))
+	}
+	if gateway.RequestTimeoutMs != 0 {
+		container.Args = append(container.Args, fmt.Sprintf("--rgw-request-timeout-ms=%d", gateway.RequestTimeoutMs))
+	}
+	if gateway.RedirectHTTP {
+		container.Args = append(container.Args, "--rgw-redirect-http")
+	}
+	if dnsName := s.dnsName(); dnsName != "" {
+		// the bucket is the subdomain of the virtual-host-style requests
+		container.Args = append(container.Args, fmt.Sprintf("--rgw-dns-name=%s", dnsName))
+	}
+	s.addAuth(&container)
+
 	return container
 }
 
+// restartRGWPods sets the hash of the tls secret in the pod template of the rgw deployment or daemonset. When the hash
+// changes, the pods are restarted with a rolling update to load the new certificate. The daemonsets created before the
+// tls secret was supported have the OnDelete update strategy, which is changed to a rolling update.
+func (s *ObjectStore) restartRGWPods(context *clusterd.Context, certHash string) error {
+	if s.Spec.Gateway.AllNodes {
+		daemonset, err := context.Clientset.ExtensionsV1beta1().DaemonSets(s.Namespace).Get(s.instanceName(), metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get rgw daemonset. %+v", err)
+		}
+		rollingUpdate := daemonset.Spec.UpdateStrategy.Type == extensions.RollingUpdateDaemonSetStrategyType
+		if !setCertHash(&daemonset.Spec.Template, certHash) && rollingUpdate {
+			return nil
+		}
+		if !rollingUpdate {
+			daemonset.Spec.UpdateStrategy = extensions.DaemonSetUpdateStrategy{Type: extensions.RollingUpdateDaemonSetStrategyType}
+		}
+		if _, err := context.Clientset.ExtensionsV1beta1().DaemonSets(s.Namespace).Update(daemonset); err != nil {
+			return fmt.Errorf("failed to update rgw daemonset. %+v", err)
+		}
+		return nil
+	}
+
+	deployment, err := context.Clientset.ExtensionsV1beta1().Deployments(s.Namespace).Get(s.instanceName(), metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get rgw deployment. %+v", err)
+	}
+	if !setCertHash(&deployment.Spec.Template, certHash) {
+		return nil
+	}
+	if _, err := context.Clientset.ExtensionsV1beta1().Deployments(s.Namespace).Update(deployment); err != nil {
+		return fmt.Errorf("failed to update rgw deployment. %+v", err)
+	}
+	return nil
+}
+
+// setCertHash sets the cert hash annotation of the pod template and returns whether it changed
+func setCertHash(template *v1.PodTemplateSpec, certHash string) bool {
+	if template.Annotations[certHashAnnotation] == certHash {
+		return false
+	}
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[certHashAnnotation] = certHash
+	return true
+}
+
+// tlsSecretHash gets the hash of the certificate and private key of a tls secret
+func tlsSecretHash(secret *v1.Secret) string {
+	hash := sha256.New()
+	hash.Write(secret.Data[v1.TLSCertKey])
+	hash.Write(secret.Data[v1.TLSPrivateKeyKey])
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
 func (s *ObjectStore) startService(context *clusterd.Context, hostNetwork bool) (string, error) {
 	labels := s.getLabels()
+	annotations := s.serviceAnnotations(context)
 	svc := &v1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      s.instanceName(),
-			Namespace: s.Namespace,
-			Labels:    labels,
+			Name:        s.instanceName(),
+			Namespace:   s.Namespace,
+			Labels:      labels,
+			Annotations: annotations,
 		},
 		Spec: v1.ServiceSpec{
 			Selector: labels,
@@ -419,13 +793,57 @@ func (s *ObjectStore) startService(context *clusterd.Context, hostNetwork bool)
 			return "", fmt.Errorf("failed to create rgw service. %+v", err)
 		}
 		logger.Infof("Gateway service already running")
-		return "", nil
+		return "", s.updateServiceAnnotations(context, annotations)
 	}
 
 	logger.Infof("Gateway service running at %s:%d", svc.Spec.ClusterIP, s.Spec.Gateway.Port)
 	return svc.Spec.ClusterIP, nil
 }
 
+// serviceAnnotations gets the annotations of the rgw service that describe the object store to the API server and the
+// controllers, which only read the service of the object store
+func (s *ObjectStore) serviceAnnotations(context *clusterd.Context) map[string]string {
+	objContext := s.objectContext(context)
+	certSecret := s.Spec.Gateway.SSLCertificateRef
+	if certSecret == "" {
+		certSecret = s.Spec.Gateway.TLSSecretRef
+	}
+	return map[string]string{
+		realmAnnotation:        objContext.Realm,
+		zoneGroupAnnotation:    objContext.ZoneGroup,
+		redirectHTTPAnnotation: strconv.FormatBool(s.Spec.Gateway.RedirectHTTP),
+		certSecretAnnotation:   certSecret,
+	}
+}
+
+// updateServiceAnnotations sets the annotations of the existing rgw service when the object store is updated. Other
+// annotations of the service are kept.
+func (s *ObjectStore) updateServiceAnnotations(context *clusterd.Context, annotations map[string]string) error {
+	services := context.Clientset.CoreV1().Services(s.Namespace)
+	svc, err := services.Get(s.instanceName(), metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get rgw service. %+v", err)
+	}
+
+	changed := false
+	if svc.Annotations == nil {
+		svc.Annotations = map[string]string{}
+	}
+	for key, value := range annotations {
+		if current, ok := svc.Annotations[key]; !ok || current != value {
+			svc.Annotations[key] = value
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if _, err := services.Update(svc); err != nil {
+		return fmt.Errorf("failed to update rgw service. %+v", err)
+	}
+	return nil
+}
+
 func addPort(service *v1.Service, name string, port int32) {
 	if port == 0 {
 		return
@@ -442,7 +860,7 @@ func (s *ObjectStore) getLabels() map[string]string {
 	return map[string]string{
 		k8sutil.AppAttr:     appName,
 		k8sutil.ClusterAttr: s.Namespace,
-		"rook_object_store": s.Name,
+		objectStoreAttr:     s.Name,
 	}
 }
 
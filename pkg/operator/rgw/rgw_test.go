@@ -28,6 +28,7 @@ import (
 	exectest "github.com/rook/rook/pkg/util/exec/test"
 	"github.com/stretchr/testify/assert"
 	"k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
@@ -40,6 +41,9 @@ func TestStartRGW(t *testing.T) {
 			return `{"key":"mysecurekey"}`, nil
 		},
 		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return `{"user_id":"` + args[3] + `","keys":[{"access_key":"adminkey","secret_key":"adminsecret"}]}`, nil
+			}
 			return `{"id":"test-id"}`, nil
 		},
 	}
@@ -89,6 +93,10 @@ func validateStart(t *testing.T, store *ObjectStore, clientset *fake.Clientset,
 	assert.Nil(t, err)
 	assert.Equal(t, store.instanceName(), secret.Name)
 	assert.Equal(t, 1, len(secret.StringData))
+
+	secret, err = clientset.CoreV1().Secrets(store.Namespace).Get(AdminUserSecretName(store.Name), metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "adminkey", secret.StringData[accessKeyName])
 }
 
 func TestPodSpecs(t *testing.T) {
@@ -144,9 +152,97 @@ func TestSSLPodSpec(t *testing.T) {
 	assert.Equal(t, fmt.Sprintf("--rgw-cert=%s/%s", certMountPath, certFilename), cont.Args[6])
 }
 
+func TestTLSPodSpec(t *testing.T) {
+	store := simpleStore()
+	store.Spec.Gateway.TLSSecretRef = "mytls"
+	store.Spec.Gateway.SecurePort = 443
+	store.Spec.Gateway.Frontend = "civetweb"
+	store.Spec.Gateway.ThreadPoolSize = 256
+	store.Spec.Gateway.RequestTimeoutMs = 30000
+
+	s := store.makeRGWPodSpec("v1.0", false)
+	assert.Equal(t, 3, len(s.Spec.Volumes))
+	assert.Equal(t, "mytls", s.Spec.Volumes[2].Secret.SecretName)
+	assert.Equal(t, 2, len(s.Spec.Volumes[2].Secret.Items))
+
+	cont := s.Spec.Containers[0]
+	assert.Equal(t, 3, len(cont.VolumeMounts))
+	assert.Equal(t, certMountPath, cont.VolumeMounts[2].MountPath)
+	assert.Equal(t, 11, len(cont.Args))
+	assert.Equal(t, fmt.Sprintf("--rgw-cert=%s/tls.crt", certMountPath), cont.Args[6])
+	assert.Equal(t, fmt.Sprintf("--rgw-key=%s/tls.key", certMountPath), cont.Args[7])
+	assert.Equal(t, "--rgw-frontend=civetweb", cont.Args[8])
+	assert.Equal(t, "--rgw-thread-pool-size=256", cont.Args[9])
+	assert.Equal(t, "--rgw-request-timeout-ms=30000", cont.Args[10])
+
+	// redirect http to https
+	store.Spec.Gateway.Frontend = ""
+	store.Spec.Gateway.RedirectHTTP = true
+	cont = store.makeRGWPodSpec("v1.0", false).Spec.Containers[0]
+	assert.Equal(t, "--rgw-redirect-http", cont.Args[len(cont.Args)-1])
+}
+
+func TestRestartRGWPods(t *testing.T) {
+	clientset := testop.New(3)
+	context := &clusterd.Context{Clientset: clientset}
+	store := simpleStore()
+	store.Spec.Gateway.TLSSecretRef = "mytls"
+	err := store.startDeployment(context, "v1.0", 1, false)
+	assert.Nil(t, err)
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "mytls", Namespace: store.Namespace},
+		Type:       v1.SecretTypeTLS,
+		Data:       map[string][]byte{v1.TLSCertKey: []byte("cert"), v1.TLSPrivateKeyKey: []byte("key")},
+	}
+	other := simpleStore()
+	other.Name = "other"
+	c := &ObjectStoreController{context: context}
+
+	// the pods of the store with the secret are restarted with the hash of the secret
+	c.restartTLSSecretStores([]ObjectStore{*store, *other}, secret)
+	d, err := clientset.ExtensionsV1beta1().Deployments(store.Namespace).Get(store.instanceName(), metav1.GetOptions{})
+	assert.Nil(t, err)
+	hash := d.Spec.Template.Annotations[certHashAnnotation]
+	assert.Equal(t, tlsSecretHash(secret), hash)
+
+	// a renewed certificate changes the hash
+	secret.Data[v1.TLSCertKey] = []byte("renewed")
+	err = store.restartRGWPods(context, tlsSecretHash(secret))
+	assert.Nil(t, err)
+	d, err = clientset.ExtensionsV1beta1().Deployments(store.Namespace).Get(store.instanceName(), metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.NotEqual(t, hash, d.Spec.Template.Annotations[certHashAnnotation])
+
+	// the daemonset is restarted when the pods run on all nodes
+	store.Spec.Gateway.AllNodes = true
+	err = store.restartRGWPods(context, "newhash")
+	assert.NotNil(t, err)
+	err = store.startDaemonset(context, "v1.0", false)
+	assert.Nil(t, err)
+	err = store.restartRGWPods(context, "newhash")
+	assert.Nil(t, err)
+	ds, err := clientset.ExtensionsV1beta1().DaemonSets(store.Namespace).Get(store.instanceName(), metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "newhash", ds.Spec.Template.Annotations[certHashAnnotation])
+
+	// the daemonsets created without a rolling update are changed to a rolling update
+	ds.Spec.UpdateStrategy = extensions.DaemonSetUpdateStrategy{Type: extensions.OnDeleteDaemonSetStrategyType}
+	_, err = clientset.ExtensionsV1beta1().DaemonSets(store.Namespace).Update(ds)
+	assert.Nil(t, err)
+	err = store.restartRGWPods(context, "newhash")
+	assert.Nil(t, err)
+	ds, err = clientset.ExtensionsV1beta1().DaemonSets(store.Namespace).Get(store.instanceName(), metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, extensions.RollingUpdateDaemonSetStrategyType, ds.Spec.UpdateStrategy.Type)
+}
+
 func TestCreateObjectStore(t *testing.T) {
 	executor := &exectest.MockExecutor{
 		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return `{"user_id":"` + args[3] + `","keys":[{"access_key":"adminkey","secret_key":"adminsecret"}]}`, nil
+			}
 			return `{"realms": []}`, nil
 		},
 		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outfile string, args ...string) (string, error) {
@@ -203,6 +299,233 @@ func TestValidateSpec(t *testing.T) {
 	s.Spec.MetadataPool.Replicated.Size = 1
 	err = s.validate(context)
 	assert.Nil(t, err)
+
+	// only one of the certificates
+	s.Spec.Gateway.SSLCertificateRef = "mycert"
+	s.Spec.Gateway.TLSSecretRef = "mytls"
+	err = s.validate(context)
+	assert.NotNil(t, err)
+	s.Spec.Gateway.SSLCertificateRef = ""
+	err = s.validate(context)
+	assert.Nil(t, err)
+
+	// unknown frontend
+	s.Spec.Gateway.Frontend = "apache"
+	err = s.validate(context)
+	assert.NotNil(t, err)
+	s.Spec.Gateway.Frontend = "beast"
+	s.Spec.Gateway.TLSSecretRef = ""
+	err = s.validate(context)
+	assert.Nil(t, err)
+
+	// beast does not support ssl or the request timeout
+	s.Spec.Gateway.TLSSecretRef = "mytls"
+	err = s.validate(context)
+	assert.NotNil(t, err)
+	s.Spec.Gateway.TLSSecretRef = ""
+	s.Spec.Gateway.RequestTimeoutMs = 30000
+	err = s.validate(context)
+	assert.NotNil(t, err)
+	s.Spec.Gateway.RequestTimeoutMs = 0
+
+	// beast does not redirect http
+	s.Spec.Gateway.SecurePort = 443
+	s.Spec.Gateway.RedirectHTTP = true
+	err = s.validate(context)
+	assert.NotNil(t, err)
+	s.Spec.Gateway.Frontend = "civetweb"
+	s.Spec.Gateway.TLSSecretRef = "mytls"
+	err = s.validate(context)
+	assert.Nil(t, err)
+
+	// the redirect requires the secure port
+	s.Spec.Gateway.SecurePort = 0
+	err = s.validate(context)
+	assert.NotNil(t, err)
+}
+
+func TestCreateSecondaryZone(t *testing.T) {
+	zoneCreated := false
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			logger.Infof("Command: %s %v", command, args)
+			if args[0] == "realm" && args[1] == "create" || args[0] == "zonegroup" && args[1] == "create" {
+				assert.Fail(t, "unexpected command %v", args)
+			}
+			if args[0] == "zone" && args[1] == "get" {
+				return "", fmt.Errorf("induce a create")
+			}
+			if args[0] == "zone" && args[1] == "create" {
+				zoneCreated = true
+				assert.Contains(t, args, "--rgw-realm=gold")
+				assert.Contains(t, args, "--rgw-zonegroup=gold")
+				assert.Contains(t, args, "--access-key=access")
+				assert.Contains(t, args, "--secret=secret")
+				assert.Contains(t, args, "--endpoints=http://rgw.site-b.example.com:80")
+			}
+			return `{"id":"test-id"}`, nil
+		},
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outfile string, args ...string) (string, error) {
+			if args[0] == "auth" && args[1] == "get-or-create-key" {
+				return `{"key":"mykey"}`, nil
+			}
+			return "", nil
+		},
+	}
+
+	store := simpleStore()
+	store.Spec.Zone = &ZoneSpec{
+		Realm:               "gold",
+		MasterEndpoint:      "http://rgw.site-a.example.com:80",
+		SystemUserSecretRef: "realm-keys",
+		Endpoints:           []string{"http://rgw.site-b.example.com:80"},
+	}
+	clientset := testop.New(3)
+	context := &clusterd.Context{Executor: executor, Clientset: clientset}
+
+	// the secret with the keys of the system user is required
+	err := store.Create(context, "1.2.3.4", false)
+	assert.NotNil(t, err)
+	assert.False(t, zoneCreated)
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "realm-keys", Namespace: store.Namespace},
+		Data:       map[string][]byte{"access-key": []byte("access"), "secret-key": []byte("secret")},
+	}
+	clientset.CoreV1().Secrets(store.Namespace).Create(secret)
+	err = store.Create(context, "1.2.3.4", false)
+	assert.Nil(t, err)
+	assert.True(t, zoneCreated)
+
+	// the rgw runs in the zone group of the realm
+	cont := store.rgwContainer("v1.0")
+	assert.Contains(t, cont.Args, "--rgw-realm=gold")
+	assert.Contains(t, cont.Args, "--rgw-zonegroup=gold")
+}
+
+func TestValidateZoneSpec(t *testing.T) {
+	context := &clusterd.Context{Executor: &exectest.MockExecutor{}}
+
+	s := simpleStore()
+	s.Spec.Zone = &ZoneSpec{Realm: "gold", MasterEndpoint: "http://10.0.0.1:80", SystemUserSecretRef: "realm-keys",
+		Endpoints: []string{"https://rgw.site-b.example.com:443"}}
+	err := s.validate(context)
+	assert.Nil(t, err)
+
+	// the endpoints of the zone must be reachable from the other zones, so the rgw service is not the default
+	s.Spec.Zone.Endpoints = nil
+	err = s.validate(context)
+	assert.NotNil(t, err)
+	s.Spec.Zone.Endpoints = []string{"rgw.site-b.example.com:80"}
+	err = s.validate(context)
+	assert.NotNil(t, err)
+	s.Spec.Zone.Endpoints = []string{"https://rgw.site-b.example.com:443"}
+
+	s.Spec.Zone.MasterEndpoint = ""
+	err = s.validate(context)
+	assert.NotNil(t, err)
+
+	s.Spec.Zone = &ZoneSpec{Realm: "gold", MasterEndpoint: "http://10.0.0.1:80"}
+	err = s.validate(context)
+	assert.NotNil(t, err)
+}
+
+func TestGetObjectContext(t *testing.T) {
+	clientset := testop.New(3)
+	context := &clusterd.Context{Clientset: clientset}
+
+	// the service of the object store is required
+	_, err := GetObjectContext(context, "mycluster", "default")
+	assert.NotNil(t, err)
+
+	// the realm is named after the object store
+	store := simpleStore()
+	_, err = store.startService(context, false)
+	assert.Nil(t, err)
+	objContext, err := GetObjectContext(context, "mycluster", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, "default", objContext.Name)
+	assert.Equal(t, "default", objContext.Realm)
+	assert.Equal(t, "default", objContext.ZoneGroup)
+
+	// the realm and zone group of a secondary zone are those of the master zone
+	zone := simpleStore()
+	zone.Name = "zone"
+	zone.Spec.Zone = &ZoneSpec{Realm: "remote", ZoneGroup: "remote-group"}
+	_, err = zone.startService(context, false)
+	assert.Nil(t, err)
+	objContext, err = GetObjectContext(context, "mycluster", "zone")
+	assert.Nil(t, err)
+	assert.Equal(t, "zone", objContext.Name)
+	assert.Equal(t, "remote", objContext.Realm)
+	assert.Equal(t, "remote-group", objContext.ZoneGroup)
+
+	// the stores are listed by their services, not by the realms or the external services
+	external := &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: ExternalServiceName("zone"), Namespace: "mycluster", Labels: zone.getLabels()}}
+	_, err = clientset.CoreV1().Services("mycluster").Create(external)
+	assert.Nil(t, err)
+	objContexts, err := GetObjectContexts(context, "mycluster")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(objContexts))
+	realms := map[string]string{}
+	for _, c := range objContexts {
+		realms[c.Name] = c.Realm
+	}
+	assert.Equal(t, map[string]string{"default": "default", "zone": "remote"}, realms)
+}
+
+func TestGetServiceEndpoint(t *testing.T) {
+	clientset := testop.New(3)
+	context := &clusterd.Context{Clientset: clientset}
+	store := simpleStore()
+	store.Spec.Gateway.SecurePort = 443
+	store.Spec.Gateway.TLSSecretRef = "rgw-tls"
+	_, err := store.startService(context, false)
+	assert.Nil(t, err)
+
+	// the http port is preferred
+	host, port, secure, err := GetServiceEndpoint(clientset, store.Namespace, store.Name)
+	assert.Nil(t, err)
+	assert.Equal(t, "rook-ceph-rgw-default.mycluster", host)
+	assert.Equal(t, int32(123), port)
+	assert.False(t, secure)
+
+	// the https port is used when the http port only redirects, which is updated in the existing service
+	store.Spec.Gateway.RedirectHTTP = true
+	_, err = store.startService(context, false)
+	assert.Nil(t, err)
+	_, port, secure, err = GetServiceEndpoint(clientset, store.Namespace, store.Name)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(443), port)
+	assert.True(t, secure)
+
+	// the ca certificate of the tls secret verifies the object store
+	_, err = GetServiceCACert(clientset, store.Namespace, store.Name)
+	assert.NotNil(t, err)
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "rgw-tls", Namespace: store.Namespace},
+		Data:       map[string][]byte{v1.TLSCertKey: []byte("cert"), v1.TLSPrivateKeyKey: []byte("key")},
+	}
+	_, err = clientset.CoreV1().Secrets(store.Namespace).Create(secret)
+	assert.Nil(t, err)
+	caCert, err := GetServiceCACert(clientset, store.Namespace, store.Name)
+	assert.Nil(t, err)
+	assert.Equal(t, "cert", string(caCert))
+	secret.Data[caCertKeyName] = []byte("ca")
+	_, err = clientset.CoreV1().Secrets(store.Namespace).Update(secret)
+	assert.Nil(t, err)
+	caCert, err = GetServiceCACert(clientset, store.Namespace, store.Name)
+	assert.Nil(t, err)
+	assert.Equal(t, "ca", string(caCert))
+
+	// an object store without a certificate has no ca certificate
+	other := simpleStore()
+	other.Name = "other"
+	_, err = other.startService(context, false)
+	assert.Nil(t, err)
+	caCert, err = GetServiceCACert(clientset, other.Namespace, other.Name)
+	assert.Nil(t, err)
+	assert.Nil(t, caCert)
 }
 
 func simpleStore() *ObjectStore {
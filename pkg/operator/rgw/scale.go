@@ -0,0 +1,181 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"fmt"
+
+	"github.com/rook/rook/pkg/clusterd"
+	autoscaling "k8s.io/api/autoscaling/v1"
+	"k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// the default cpu utilization of the rgw pods targeted by the autoscaler
+	defaultTargetCPUUtilization = 80
+
+	readinessProbeDelay   = 10
+	livenessProbeDelay    = 60
+	probePeriod           = 10
+	probeTimeout          = 5
+	readinessProbeFailure = 3
+	livenessProbeFailure  = 6
+)
+
+// replicas gets the number of rgw pods of the deployment. When autoscaled, the deployment starts with the min
+// instances and the autoscaler adjusts the replicas.
+func (s *ObjectStore) replicas() int32 {
+	if s.Spec.Gateway.Autoscale != nil {
+		return s.Spec.Gateway.Autoscale.MinInstances
+	}
+	return s.Spec.Gateway.Instances
+}
+
+// validateScaling validates the resources and autoscaling settings of the gateway
+func (s *ObjectStore) validateScaling() error {
+	autoscale := s.Spec.Gateway.Autoscale
+	if autoscale == nil {
+		return nil
+	}
+	if s.Spec.Gateway.AllNodes {
+		return fmt.Errorf("autoscaling is not supported when the rgw pods run on all nodes")
+	}
+	if autoscale.MinInstances < 1 || autoscale.MaxInstances < autoscale.MinInstances {
+		return fmt.Errorf("invalid autoscale instances. min=%d, max=%d", autoscale.MinInstances, autoscale.MaxInstances)
+	}
+	if _, ok := s.Spec.Gateway.Resources.Requests[v1.ResourceCPU]; !ok {
+		return fmt.Errorf("autoscaling requires the cpu request of the rgw pods")
+	}
+	return nil
+}
+
+// healthProbe checks that rgw answers the requests on its port. The secure port is only checked when there is no
+// plain http port.
+func (s *ObjectStore) healthProbe(initialDelay, failureThreshold int32) *v1.Probe {
+	port := s.Spec.Gateway.Port
+	scheme := v1.URISchemeHTTP
+	if port == 0 {
+		port = s.Spec.Gateway.SecurePort
+		scheme = v1.URISchemeHTTPS
+	}
+	return &v1.Probe{
+		Handler: v1.Handler{
+			HTTPGet: &v1.HTTPGetAction{Path: "/", Port: intstr.FromInt(int(port)), Scheme: scheme},
+		},
+		InitialDelaySeconds: initialDelay,
+		PeriodSeconds:       probePeriod,
+		TimeoutSeconds:      probeTimeout,
+		FailureThreshold:    failureThreshold,
+	}
+}
+
+// startPodDisruptionBudget allows only one rgw pod of the deployment at a time to be evicted, e.g. when the nodes are
+// drained. The max unavailable pods of a budget are only supported for the pods of a deployment, and the pods of a
+// daemonset are not evicted when the nodes are drained, so a daemonset has no budget.
+func (s *ObjectStore) startPodDisruptionBudget(context *clusterd.Context) error {
+	if s.Spec.Gateway.AllNodes {
+		err := context.Clientset.PolicyV1beta1().PodDisruptionBudgets(s.Namespace).Delete(s.instanceName(), &metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete rgw pod disruption budget. %+v", err)
+		}
+		return nil
+	}
+
+	maxUnavailable := intstr.FromInt(1)
+	pdb := &policy.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.instanceName(),
+			Namespace: s.Namespace,
+			Labels:    s.getLabels(),
+		},
+		Spec: policy.PodDisruptionBudgetSpec{
+			MaxUnavailable: &maxUnavailable,
+			Selector:       &metav1.LabelSelector{MatchLabels: s.getLabels()},
+		},
+	}
+	_, err := context.Clientset.PolicyV1beta1().PodDisruptionBudgets(s.Namespace).Create(pdb)
+	if err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create rgw pod disruption budget. %+v", err)
+		}
+		logger.Infof("rgw pod disruption budget already exists")
+	}
+	return nil
+}
+
+// startAutoscaler creates or updates the horizontal pod autoscaler of the rgw deployment. The autoscaler is deleted
+// when autoscaling is not enabled.
+func (s *ObjectStore) startAutoscaler(context *clusterd.Context) error {
+	autoscale := s.Spec.Gateway.Autoscale
+	if autoscale == nil {
+		return s.deleteAutoscaler(context)
+	}
+
+	target := autoscale.TargetCPUUtilizationPercentage
+	if target == 0 {
+		target = defaultTargetCPUUtilization
+	}
+	minReplicas := autoscale.MinInstances
+	hpa := &autoscaling.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.instanceName(),
+			Namespace: s.Namespace,
+			Labels:    s.getLabels(),
+		},
+		Spec: autoscaling.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscaling.CrossVersionObjectReference{
+				APIVersion: "extensions/v1beta1",
+				Kind:       "Deployment",
+				Name:       s.instanceName(),
+			},
+			MinReplicas:                    &minReplicas,
+			MaxReplicas:                    autoscale.MaxInstances,
+			TargetCPUUtilizationPercentage: &target,
+		},
+	}
+
+	autoscalers := context.Clientset.AutoscalingV1().HorizontalPodAutoscalers(s.Namespace)
+	existing, err := autoscalers.Get(s.instanceName(), metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get rgw autoscaler. %+v", err)
+		}
+		if _, err := autoscalers.Create(hpa); err != nil {
+			return fmt.Errorf("failed to create rgw autoscaler. %+v", err)
+		}
+		logger.Infof("rgw autoscaler started with %d to %d instances", autoscale.MinInstances, autoscale.MaxInstances)
+		return nil
+	}
+
+	existing.Spec = hpa.Spec
+	if _, err := autoscalers.Update(existing); err != nil {
+		return fmt.Errorf("failed to update rgw autoscaler. %+v", err)
+	}
+	logger.Infof("rgw autoscaler updated with %d to %d instances", autoscale.MinInstances, autoscale.MaxInstances)
+	return nil
+}
+
+func (s *ObjectStore) deleteAutoscaler(context *clusterd.Context) error {
+	err := context.Clientset.AutoscalingV1().HorizontalPodAutoscalers(s.Namespace).Delete(s.instanceName(), &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete rgw autoscaler. %+v", err)
+	}
+	return nil
+}
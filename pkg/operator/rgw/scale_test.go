@@ -0,0 +1,123 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rgw
+
+import (
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHealthProbes(t *testing.T) {
+	store := simpleStore()
+	store.Spec.Gateway.Resources = v1.ResourceRequirements{
+		Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+	}
+
+	cont := store.rgwContainer("v1.0")
+	assert.Equal(t, "1Gi", cont.Resources.Limits.Memory().String())
+	assert.Equal(t, v1.URISchemeHTTP, cont.ReadinessProbe.HTTPGet.Scheme)
+	assert.Equal(t, 123, cont.ReadinessProbe.HTTPGet.Port.IntValue())
+	assert.Equal(t, int32(readinessProbeDelay), cont.ReadinessProbe.InitialDelaySeconds)
+	assert.Equal(t, int32(livenessProbeDelay), cont.LivenessProbe.InitialDelaySeconds)
+
+	// the secure port is probed without the http port
+	store.Spec.Gateway.Port = 0
+	store.Spec.Gateway.SecurePort = 443
+	cont = store.rgwContainer("v1.0")
+	assert.Equal(t, v1.URISchemeHTTPS, cont.LivenessProbe.HTTPGet.Scheme)
+	assert.Equal(t, 443, cont.LivenessProbe.HTTPGet.Port.IntValue())
+}
+
+func TestValidateAutoscale(t *testing.T) {
+	store := simpleStore()
+	store.Spec.Gateway.Autoscale = &AutoscaleSpec{MinInstances: 2, MaxInstances: 5}
+
+	// the cpu request is required
+	assert.NotNil(t, store.validateScaling())
+	store.Spec.Gateway.Resources.Requests = v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")}
+	assert.Nil(t, store.validateScaling())
+	assert.Equal(t, int32(2), store.replicas())
+
+	// invalid instances
+	store.Spec.Gateway.Autoscale.MaxInstances = 1
+	assert.NotNil(t, store.validateScaling())
+	store.Spec.Gateway.Autoscale = &AutoscaleSpec{MaxInstances: 3}
+	assert.NotNil(t, store.validateScaling())
+
+	// not with a daemonset
+	store.Spec.Gateway.Autoscale = &AutoscaleSpec{MinInstances: 1, MaxInstances: 3}
+	store.Spec.Gateway.AllNodes = true
+	assert.NotNil(t, store.validateScaling())
+}
+
+func TestStartAutoscaler(t *testing.T) {
+	clientset := testop.New(3)
+	context := &clusterd.Context{Clientset: clientset}
+	store := simpleStore()
+
+	// the pod disruption budget is created once
+	assert.Nil(t, store.startPodDisruptionBudget(context))
+	assert.Nil(t, store.startPodDisruptionBudget(context))
+	pdb, err := clientset.PolicyV1beta1().PodDisruptionBudgets(store.Namespace).Get(store.instanceName(), metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, pdb.Spec.MaxUnavailable.IntValue())
+	assert.Equal(t, store.getLabels(), pdb.Spec.Selector.MatchLabels)
+
+	// the pods of a daemonset have no budget
+	store.Spec.Gateway.AllNodes = true
+	assert.Nil(t, store.startPodDisruptionBudget(context))
+	_, err = clientset.PolicyV1beta1().PodDisruptionBudgets(store.Namespace).Get(store.instanceName(), metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	assert.Nil(t, store.startPodDisruptionBudget(context))
+	store.Spec.Gateway.AllNodes = false
+
+	// no autoscaler by default
+	assert.Nil(t, store.startAutoscaler(context))
+	_, err = clientset.AutoscalingV1().HorizontalPodAutoscalers(store.Namespace).Get(store.instanceName(), metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+
+	// create the autoscaler with the default target
+	store.Spec.Gateway.Autoscale = &AutoscaleSpec{MinInstances: 2, MaxInstances: 5}
+	assert.Nil(t, store.startAutoscaler(context))
+	hpa, err := clientset.AutoscalingV1().HorizontalPodAutoscalers(store.Namespace).Get(store.instanceName(), metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "Deployment", hpa.Spec.ScaleTargetRef.Kind)
+	assert.Equal(t, store.instanceName(), hpa.Spec.ScaleTargetRef.Name)
+	assert.Equal(t, int32(2), *hpa.Spec.MinReplicas)
+	assert.Equal(t, int32(5), hpa.Spec.MaxReplicas)
+	assert.Equal(t, int32(defaultTargetCPUUtilization), *hpa.Spec.TargetCPUUtilizationPercentage)
+
+	// update the autoscaler
+	store.Spec.Gateway.Autoscale = &AutoscaleSpec{MinInstances: 3, MaxInstances: 10, TargetCPUUtilizationPercentage: 60}
+	assert.Nil(t, store.startAutoscaler(context))
+	hpa, err = clientset.AutoscalingV1().HorizontalPodAutoscalers(store.Namespace).Get(store.instanceName(), metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(10), hpa.Spec.MaxReplicas)
+	assert.Equal(t, int32(60), *hpa.Spec.TargetCPUUtilizationPercentage)
+
+	// the autoscaler is removed when disabled
+	store.Spec.Gateway.Autoscale = nil
+	assert.Nil(t, store.startAutoscaler(context))
+	_, err = clientset.AutoscalingV1().HorizontalPodAutoscalers(store.Namespace).Get(store.instanceName(), metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+}
@@ -23,6 +23,7 @@ package rgw
 import (
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	"github.com/rook/rook/pkg/operator/pool"
+	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
@@ -34,7 +35,8 @@ var schemeGroupVersion = schema.GroupVersion{Group: k8sutil.CustomResourceGroup,
 type ObjectStore struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
-	Spec              ObjectStoreSpec `json:"spec"`
+	Spec              ObjectStoreSpec   `json:"spec"`
+	Status            ObjectStoreStatus `json:"status,omitempty"`
 }
 
 // ObjectstoreList is the definition of a list of object stores for CRDs (1.7+)
@@ -61,6 +63,152 @@ type ObjectStoreSpec struct {
 
 	// The rgw pod info
 	Gateway GatewaySpec `json:"gateway"`
+
+	// The realm of another cluster to join as a secondary zone. If not set, the object store is the master zone of
+	// its own realm.
+	Zone *ZoneSpec `json:"zone,omitempty"`
+
+	// The authentication of the users with ldap or keystone in addition to the local rgw users
+	Auth *AuthSpec `json:"auth,omitempty"`
+
+	// The placement targets and storage classes of the buckets and objects in addition to the metadata and data pools
+	Placement *PlacementSpec `json:"placement,omitempty"`
+}
+
+// PlacementSpec represents the pools where the buckets and objects are placed
+type PlacementSpec struct {
+	// The bucket index pool settings. If not set, the indexes are in pools with the metadata pool settings.
+	IndexPool *pool.PoolSpec `json:"indexPool,omitempty"`
+
+	// The placement targets that the buckets select when they are created
+	Targets []PlacementTargetSpec `json:"targets"`
+
+	// The placement target of the buckets that don't select one. Default is default-placement.
+	DefaultTarget string `json:"defaultTarget"`
+}
+
+// PlacementTargetSpec represents a placement target with its own pools
+type PlacementTargetSpec struct {
+	// The name of the placement target. The default-placement target has the data pool of the object store.
+	Name string `json:"name"`
+
+	// The data pool settings of the STANDARD storage class. Required except for the default-placement target.
+	DataPool *pool.PoolSpec `json:"dataPool,omitempty"`
+
+	// The storage classes that the objects select when they are written
+	StorageClasses []StorageClassSpec `json:"storageClasses"`
+}
+
+// StorageClassSpec represents a storage class of a placement target with its own data pool
+type StorageClassSpec struct {
+	// The name of the storage class, e.g. COLD
+	Name string `json:"name"`
+
+	// The data pool settings of the storage class
+	DataPool pool.PoolSpec `json:"dataPool"`
+}
+
+// AuthSpec represents the external services that authenticate the users of the object store
+type AuthSpec struct {
+	// The ldap server that authenticates the s3 users
+	LDAP *LDAPSpec `json:"ldap,omitempty"`
+
+	// The openstack keystone service that authenticates the swift and s3 users
+	Keystone *KeystoneSpec `json:"keystone,omitempty"`
+
+	// The name of the secret with the ca certificate (ca.crt) that verifies the ldap server
+	CASecretRef string `json:"caSecretRef"`
+}
+
+// LDAPSpec represents the ldap server that authenticates the s3 users
+type LDAPSpec struct {
+	// The uri of the ldap server, e.g. ldaps://ldap.example.com:636
+	URI string `json:"uri"`
+
+	// The dn of the service account that searches the users
+	BindDN string `json:"bindDN"`
+
+	// The name of the secret with the password of the service account
+	BindSecretRef string `json:"bindSecretRef"`
+
+	// The base dn where the users are searched
+	SearchDN string `json:"searchDN"`
+
+	// The attribute of the user dn with the user name. Default is uid.
+	DNAttribute string `json:"dnAttribute"`
+
+	// An optional ldap filter of the users that can access the object store
+	SearchFilter string `json:"searchFilter"`
+}
+
+// KeystoneSpec represents the openstack keystone service that authenticates the users
+type KeystoneSpec struct {
+	// The url of the keystone service, e.g. https://keystone.example.com:5000
+	URL string `json:"url"`
+
+	// The keystone api version, 2 or 3. Default is 3.
+	APIVersion int `json:"apiVersion"`
+
+	// The name of the secret with the username, password, project and domain of the keystone admin user
+	AdminSecretRef string `json:"adminSecretRef"`
+
+	// The keystone roles of the users that are allowed to access the object store
+	AcceptedRoles []string `json:"acceptedRoles"`
+
+	// Whether each keystone project gets its own rgw tenant
+	ImplicitTenants bool `json:"implicitTenants"`
+
+	// Whether the s3 requests are also authenticated with the ec2 credentials of keystone
+	S3 bool `json:"s3"`
+
+	// Whether the certificate of the keystone service is not verified, e.g. when it is issued by a private ca that is
+	// not trusted by the rgw image
+	InsecureSkipVerify bool `json:"insecureSkipVerify"`
+}
+
+// ZoneSpec represents the realm and master zone that an object store joins as a secondary zone
+type ZoneSpec struct {
+	// The name of the realm to join
+	Realm string `json:"realm"`
+
+	// The name of the zone group of the realm where the zone is created. Default is the name of the realm.
+	ZoneGroup string `json:"zoneGroup"`
+
+	// The endpoint of the master zone, e.g. http://rgw.site-a.example.com:80
+	MasterEndpoint string `json:"masterEndpoint"`
+
+	// The name of the secret with the access-key and secret-key of the system user of the realm
+	SystemUserSecretRef string `json:"systemUserSecretRef"`
+
+	// The endpoints of the zone for the other zones of the realm, which cannot reach the rgw service of this cluster
+	Endpoints []string `json:"endpoints"`
+}
+
+// ObjectStoreStatus represents the status of an object store
+type ObjectStoreStatus struct {
+	// The replication status of a secondary zone
+	Sync *SyncStatus `json:"sync,omitempty"`
+}
+
+// SyncStatus is the replication status of a secondary zone from "radosgw-admin sync status"
+type SyncStatus struct {
+	// The status of the metadata sync from the master zone
+	MetadataSync string `json:"metadataSync"`
+
+	// The status of the data sync from each source zone
+	DataSync []DataSyncStatus `json:"dataSync"`
+
+	// The error when the status could not be retrieved
+	Error string `json:"error,omitempty"`
+
+	// When the status was last retrieved
+	LastChecked metav1.Time `json:"lastChecked"`
+}
+
+// DataSyncStatus is the status of the data sync from a source zone
+type DataSyncStatus struct {
+	Source string `json:"source"`
+	Status string `json:"status"`
 }
 
 type GatewaySpec struct {
@@ -79,6 +227,70 @@ type GatewaySpec struct {
 	// The name of the secret that stores the ssl certificate for secure rgw connections
 	SSLCertificateRef string `json:"sslCertificateRef"`
 
+	// The name of a secret of type kubernetes.io/tls with the certificate (tls.crt) and private key (tls.key) for
+	// secure rgw connections. The rgw pods are restarted when the secret is updated.
+	TLSSecretRef string `json:"tlsSecretRef"`
+
+	// Whether the http port redirects to the secure port. Only supported by the civetweb frontend.
+	RedirectHTTP bool `json:"redirectHttp"`
+
+	// The http frontend of rgw, either civetweb or beast. Default is civetweb.
+	Frontend string `json:"frontend"`
+
+	// The number of threads that serve the requests. Default is the rgw default.
+	ThreadPoolSize int `json:"threadPoolSize"`
+
+	// The timeout of the requests in milliseconds. Default is the frontend default.
+	RequestTimeoutMs int `json:"requestTimeoutMs"`
+
 	// The affinity to place the rgw pods (default is to place on any available node)
 	Placement k8sutil.Placement `json:"placement"`
+
+	// The resource requests and limits of the rgw pods
+	Resources v1.ResourceRequirements `json:"resources"`
+
+	// The horizontal autoscaling of the rgw pods. If set, the instances are ignored.
+	Autoscale *AutoscaleSpec `json:"autoscale,omitempty"`
+
+	// How the object store is exposed outside of the cluster. If not set, the object store is only exposed with the
+	// rgw service in the cluster.
+	External *ExternalSpec `json:"external,omitempty"`
+}
+
+// ExternalSpec represents how the object store is exposed outside of the cluster
+type ExternalSpec struct {
+	// The type of the external service, either NodePort or LoadBalancer. If not set, no external service is created.
+	ServiceType v1.ServiceType `json:"serviceType"`
+
+	// The annotations of the external service, e.g. to configure the load balancer of the cloud provider
+	Annotations map[string]string `json:"annotations"`
+
+	// The ingress that routes the requests to the hosts to the object store
+	Ingress *IngressSpec `json:"ingress,omitempty"`
+}
+
+// IngressSpec represents the ingress of the object store
+type IngressSpec struct {
+	// The host names of the object store. A wildcard host name, e.g. *.s3.example.com, enables the virtual-host-style
+	// access to the buckets, e.g. mybucket.s3.example.com.
+	Hosts []string `json:"hosts"`
+
+	// The name of the secret with the tls certificate of the hosts
+	TLSSecretRef string `json:"tlsSecretRef"`
+
+	// The annotations of the ingress, e.g. to select the ingress controller
+	Annotations map[string]string `json:"annotations"`
+}
+
+// AutoscaleSpec represents the horizontal autoscaling of the rgw pods by their cpu utilization
+type AutoscaleSpec struct {
+	// The min number of rgw pods
+	MinInstances int32 `json:"minInstances"`
+
+	// The max number of rgw pods
+	MaxInstances int32 `json:"maxInstances"`
+
+	// The average cpu utilization of the rgw pods targeted by the autoscaler, in percent of the cpu request.
+	// Default is 80.
+	TargetCPUUtilizationPercentage int32 `json:"targetCPUUtilizationPercentage"`
 }
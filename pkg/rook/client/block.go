@@ -18,13 +18,16 @@ package client
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/url"
+	"path"
 
 	"github.com/rook/rook/pkg/model"
 )
 
 const (
-	imageQueryName = "image"
+	imageQueryName     = "image"
+	snapshotsQueryName = "snapshots"
 )
 
 func (c *RookNetworkRestClient) GetBlockImages() ([]model.BlockImage, error) {
@@ -75,3 +78,123 @@ func (c *RookNetworkRestClient) DeleteBlockImage(image model.BlockImage) (string
 
 	return string(resp), nil
 }
+
+func (c *RookNetworkRestClient) GetBlockImageMirrorStatus(poolName string) ([]model.BlockImageMirrorStatus, error) {
+	body, err := c.DoGet(fmt.Sprintf("%s/%s/mirror", imageQueryName, poolName))
+	if err != nil {
+		return nil, err
+	}
+
+	var status []model.BlockImageMirrorStatus
+	err = json.Unmarshal(body, &status)
+	if err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+func (c *RookNetworkRestClient) GetBlockImageSnapshots(image model.BlockImage) ([]model.BlockSnapshot, error) {
+	body, err := c.DoGet(getSnapshotsQuery(image))
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []model.BlockSnapshot
+	err = json.Unmarshal(body, &snapshots)
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+func (c *RookNetworkRestClient) CreateBlockImageSnapshot(snapshot model.BlockSnapshot) (string, error) {
+	body, err := json.Marshal(snapshot)
+	if err != nil {
+		return "", err
+	}
+
+	image := model.BlockImage{Name: snapshot.ImageName, PoolName: snapshot.PoolName}
+	resp, err := c.DoPost(getSnapshotsQuery(image), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	return string(resp), nil
+}
+
+func (c *RookNetworkRestClient) DeleteBlockImageSnapshot(snapshot model.BlockSnapshot) (string, error) {
+	resp, err := c.DoDelete(getSnapshotQuery(snapshot))
+	if err != nil {
+		return "", err
+	}
+
+	return string(resp), nil
+}
+
+func (c *RookNetworkRestClient) RollbackBlockImageSnapshot(snapshot model.BlockSnapshot) (string, error) {
+	return c.postSnapshotAction(snapshot, "rollback")
+}
+
+func (c *RookNetworkRestClient) ProtectBlockImageSnapshot(snapshot model.BlockSnapshot) (string, error) {
+	return c.postSnapshotAction(snapshot, "protect")
+}
+
+func (c *RookNetworkRestClient) UnprotectBlockImageSnapshot(snapshot model.BlockSnapshot) (string, error) {
+	return c.postSnapshotAction(snapshot, "unprotect")
+}
+
+func (c *RookNetworkRestClient) CloneBlockImageSnapshot(snapshot model.BlockSnapshot, clone model.BlockImage) (string, error) {
+	body, err := json.Marshal(clone)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.DoPost(path.Join(getSnapshotQuery(snapshot), "clone"), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	return string(resp), nil
+}
+
+func (c *RookNetworkRestClient) FlattenBlockImage(image model.BlockImage) (string, error) {
+	resp, err := c.DoPost(path.Join(imageQueryName, image.PoolName, image.Name, "flatten"), nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(resp), nil
+}
+
+func (c *RookNetworkRestClient) ResizeBlockImage(image model.BlockImage) (string, error) {
+	body, err := json.Marshal(image)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.DoPut(path.Join(imageQueryName, image.PoolName, image.Name), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	return string(resp), nil
+}
+
+func (c *RookNetworkRestClient) postSnapshotAction(snapshot model.BlockSnapshot, action string) (string, error) {
+	resp, err := c.DoPost(path.Join(getSnapshotQuery(snapshot), action), nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(resp), nil
+}
+
+func getSnapshotsQuery(image model.BlockImage) string {
+	return path.Join(imageQueryName, image.PoolName, image.Name, snapshotsQueryName)
+}
+
+func getSnapshotQuery(snapshot model.BlockSnapshot) string {
+	return path.Join(imageQueryName, snapshot.PoolName, snapshot.ImageName, snapshotsQueryName, snapshot.Name)
+}
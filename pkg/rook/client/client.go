@@ -39,6 +39,16 @@ type RookRestClient interface {
 	GetBlockImages() ([]model.BlockImage, error)
 	CreateBlockImage(image model.BlockImage) (string, error)
 	DeleteBlockImage(image model.BlockImage) (string, error)
+	GetBlockImageMirrorStatus(poolName string) ([]model.BlockImageMirrorStatus, error)
+	GetBlockImageSnapshots(image model.BlockImage) ([]model.BlockSnapshot, error)
+	CreateBlockImageSnapshot(snapshot model.BlockSnapshot) (string, error)
+	DeleteBlockImageSnapshot(snapshot model.BlockSnapshot) (string, error)
+	RollbackBlockImageSnapshot(snapshot model.BlockSnapshot) (string, error)
+	ProtectBlockImageSnapshot(snapshot model.BlockSnapshot) (string, error)
+	UnprotectBlockImageSnapshot(snapshot model.BlockSnapshot) (string, error)
+	CloneBlockImageSnapshot(snapshot model.BlockSnapshot, clone model.BlockImage) (string, error)
+	FlattenBlockImage(image model.BlockImage) (string, error)
+	ResizeBlockImage(image model.BlockImage) (string, error)
 	GetClientAccessInfo() (model.ClientAccessInfo, error)
 	GetFilesystems() ([]model.Filesystem, error)
 	CreateFilesystem(model.FilesystemRequest) (string, error)
@@ -51,11 +61,27 @@ type RookRestClient interface {
 	ListBuckets(storeName string) ([]model.ObjectBucket, error)
 	GetBucket(storeName, bucketName string) (*model.ObjectBucket, error)
 	DeleteBucket(storeName, bucketName string, purge bool) error
+	SetBucketQuota(storeName, bucketName string, quota *model.ObjectBucketQuota) error
+	SetBucketOwner(storeName, bucketName, owner string) error
+	ReshardBucket(storeName, bucketName string, numShards int) error
+	CheckBucketIndex(storeName, bucketName string, fix bool) (*model.ObjectBucketIndexCheck, error)
+	GetBucketPolicy(storeName, bucketName string) (string, error)
+	SetBucketPolicy(storeName, bucketName, policy string) error
+	DeleteBucketPolicy(storeName, bucketName string) error
+	GetBucketLifecycle(storeName, bucketName string) ([]model.ObjectBucketLifecycleRule, error)
+	SetBucketLifecycle(storeName, bucketName string, rules []model.ObjectBucketLifecycleRule) error
+	DeleteBucketLifecycle(storeName, bucketName string) error
 	ListObjectUsers(storeName string) ([]model.ObjectUser, error)
 	GetObjectUser(storeName, id string) (*model.ObjectUser, error)
 	CreateObjectUser(storeName string, user model.ObjectUser) (*model.ObjectUser, error)
 	UpdateObjectUser(storeName string, user model.ObjectUser) (*model.ObjectUser, error)
 	DeleteObjectUser(storeName, id string) error
+	ListObjectUserKeys(storeName, id string) ([]model.ObjectUserKey, error)
+	CreateObjectUserKey(storeName, id string, key model.ObjectUserKey) (*model.ObjectUserKey, error)
+	DeleteObjectUserKey(storeName, id, accessKey string) error
+	RotateObjectUserKeys(storeName, id string, rotation model.ObjectUserKeyRotation) (*model.ObjectUserKeyRotationResult, error)
+	CreateObjectSubuser(storeName, id string, subuser model.ObjectSubuser) (*model.ObjectUserKey, error)
+	DeleteObjectSubuser(storeName, id, name string) error
 }
 
 type RookNetworkRestClient struct {
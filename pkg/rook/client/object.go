@@ -31,7 +31,16 @@ const (
 	connectionInfoQueryName = "connectioninfo"
 	bucketsQueryName        = "buckets"
 	bucketACLQueryName      = "acl"
+	quotaQueryName          = "quota"
+	ownerQueryName          = "owner"
+	reshardQueryName        = "reshard"
+	checkQueryName          = "check"
+	policyQueryName         = "policy"
+	lifecycleQueryName      = "lifecycle"
 	usersQueryName          = "users"
+	keysQueryName           = "keys"
+	rotateQueryName         = "rotate"
+	subusersQueryName       = "subusers"
 )
 
 func (c *RookNetworkRestClient) GetObjectStores() ([]model.ObjectStoreResponse, error) {
@@ -132,6 +141,125 @@ func (c *RookNetworkRestClient) DeleteBucket(storeName, bucketName string, purge
 	return nil
 }
 
+// SetBucketQuota sets the quota of the bucket, or disables the quota if nil
+func (c *RookNetworkRestClient) SetBucketQuota(storeName, bucketName string, quota *model.ObjectBucketQuota) error {
+	query := path.Join(objectStoreQueryName, storeName, bucketsQueryName, bucketName, quotaQueryName)
+	if quota == nil {
+		_, err := c.DoDelete(query)
+		if err != nil && !IsHttpStatusCode(err, http.StatusNoContent) {
+			return err
+		}
+		return nil
+	}
+
+	body, err := json.Marshal(quota)
+	if err != nil {
+		return err
+	}
+	return c.doPutNoContent(query, body)
+}
+
+func (c *RookNetworkRestClient) SetBucketOwner(storeName, bucketName, owner string) error {
+	body, err := json.Marshal(model.ObjectBucketOwner{Owner: owner})
+	if err != nil {
+		return err
+	}
+	return c.doPutNoContent(path.Join(objectStoreQueryName, storeName, bucketsQueryName, bucketName, ownerQueryName), body)
+}
+
+func (c *RookNetworkRestClient) ReshardBucket(storeName, bucketName string, numShards int) error {
+	body, err := json.Marshal(model.ObjectBucketReshard{NumShards: numShards})
+	if err != nil {
+		return err
+	}
+
+	_, err = c.DoPost(path.Join(objectStoreQueryName, storeName, bucketsQueryName, bucketName, reshardQueryName), bytes.NewReader(body))
+	if err != nil && !IsHttpStatusCode(err, http.StatusNoContent) {
+		return err
+	}
+	return nil
+}
+
+func (c *RookNetworkRestClient) CheckBucketIndex(storeName, bucketName string, fix bool) (*model.ObjectBucketIndexCheck, error) {
+	query := path.Join(objectStoreQueryName, storeName, bucketsQueryName, bucketName, checkQueryName)
+	if fix {
+		query += "?fix=true"
+	}
+
+	body, err := c.DoPost(query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var check model.ObjectBucketIndexCheck
+	err = json.Unmarshal(body, &check)
+	if err != nil {
+		return nil, err
+	}
+
+	return &check, nil
+}
+
+func (c *RookNetworkRestClient) GetBucketPolicy(storeName, bucketName string) (string, error) {
+	body, err := c.DoGet(path.Join(objectStoreQueryName, storeName, bucketsQueryName, bucketName, policyQueryName))
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+func (c *RookNetworkRestClient) SetBucketPolicy(storeName, bucketName, policy string) error {
+	return c.doPutNoContent(path.Join(objectStoreQueryName, storeName, bucketsQueryName, bucketName, policyQueryName), []byte(policy))
+}
+
+func (c *RookNetworkRestClient) DeleteBucketPolicy(storeName, bucketName string) error {
+	_, err := c.DoDelete(path.Join(objectStoreQueryName, storeName, bucketsQueryName, bucketName, policyQueryName))
+	if err != nil && !IsHttpStatusCode(err, http.StatusNoContent) {
+		return err
+	}
+	return nil
+}
+
+func (c *RookNetworkRestClient) GetBucketLifecycle(storeName, bucketName string) ([]model.ObjectBucketLifecycleRule, error) {
+	body, err := c.DoGet(path.Join(objectStoreQueryName, storeName, bucketsQueryName, bucketName, lifecycleQueryName))
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []model.ObjectBucketLifecycleRule
+	err = json.Unmarshal(body, &rules)
+	if err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (c *RookNetworkRestClient) SetBucketLifecycle(storeName, bucketName string, rules []model.ObjectBucketLifecycleRule) error {
+	body, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	return c.doPutNoContent(path.Join(objectStoreQueryName, storeName, bucketsQueryName, bucketName, lifecycleQueryName), body)
+}
+
+func (c *RookNetworkRestClient) DeleteBucketLifecycle(storeName, bucketName string) error {
+	_, err := c.DoDelete(path.Join(objectStoreQueryName, storeName, bucketsQueryName, bucketName, lifecycleQueryName))
+	if err != nil && !IsHttpStatusCode(err, http.StatusNoContent) {
+		return err
+	}
+	return nil
+}
+
+// doPutNoContent puts the body to an api that responds with no content
+func (c *RookNetworkRestClient) doPutNoContent(query string, body []byte) error {
+	_, err := c.DoPut(query, bytes.NewReader(body))
+	if err != nil && !IsHttpStatusCode(err, http.StatusNoContent) {
+		return err
+	}
+	return nil
+}
+
 func (c *RookNetworkRestClient) ListObjectUsers(storeName string) ([]model.ObjectUser, error) {
 	body, err := c.DoGet(path.Join(objectStoreQueryName, storeName, usersQueryName))
 	if err != nil {
@@ -214,3 +342,99 @@ func (c *RookNetworkRestClient) DeleteObjectUser(storeName, id string) error {
 
 	return nil
 }
+
+func (c *RookNetworkRestClient) ListObjectUserKeys(storeName, id string) ([]model.ObjectUserKey, error) {
+	body, err := c.DoGet(path.Join(objectStoreQueryName, storeName, usersQueryName, id, keysQueryName))
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []model.ObjectUserKey
+	err = json.Unmarshal(body, &keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (c *RookNetworkRestClient) CreateObjectUserKey(storeName, id string, key model.ObjectUserKey) (*model.ObjectUserKey, error) {
+	body, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := c.DoPost(path.Join(objectStoreQueryName, storeName, usersQueryName, id, keysQueryName), bytes.NewReader(body))
+	if err != nil && !IsHttpStatusCode(err, http.StatusCreated) {
+		return nil, err
+	}
+
+	var createdKey model.ObjectUserKey
+	err = json.Unmarshal(respBody, &createdKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &createdKey, nil
+}
+
+func (c *RookNetworkRestClient) DeleteObjectUserKey(storeName, id, accessKey string) error {
+	query := path.Join(objectStoreQueryName, storeName, usersQueryName, id, keysQueryName, accessKey)
+	_, err := c.DoDelete(query)
+	if err != nil && !IsHttpStatusCode(err, http.StatusNoContent) {
+		return err
+	}
+
+	return nil
+}
+
+func (c *RookNetworkRestClient) RotateObjectUserKeys(storeName, id string, rotation model.ObjectUserKeyRotation) (*model.ObjectUserKeyRotationResult, error) {
+	body, err := json.Marshal(rotation)
+	if err != nil {
+		return nil, err
+	}
+
+	query := path.Join(objectStoreQueryName, storeName, usersQueryName, id, keysQueryName, rotateQueryName)
+	respBody, err := c.DoPost(query, bytes.NewReader(body))
+	if err != nil && !IsHttpStatusCode(err, http.StatusCreated) {
+		return nil, err
+	}
+
+	var result model.ObjectUserKeyRotationResult
+	err = json.Unmarshal(respBody, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (c *RookNetworkRestClient) CreateObjectSubuser(storeName, id string, subuser model.ObjectSubuser) (*model.ObjectUserKey, error) {
+	body, err := json.Marshal(subuser)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := c.DoPost(path.Join(objectStoreQueryName, storeName, usersQueryName, id, subusersQueryName), bytes.NewReader(body))
+	if err != nil && !IsHttpStatusCode(err, http.StatusCreated) {
+		return nil, err
+	}
+
+	var key model.ObjectUserKey
+	err = json.Unmarshal(respBody, &key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (c *RookNetworkRestClient) DeleteObjectSubuser(storeName, id, name string) error {
+	query := path.Join(objectStoreQueryName, storeName, usersQueryName, id, subusersQueryName, name)
+	_, err := c.DoDelete(query)
+	if err != nil && !IsHttpStatusCode(err, http.StatusNoContent) {
+		return err
+	}
+
+	return nil
+}
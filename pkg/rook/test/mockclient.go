@@ -37,6 +37,16 @@ type MockRookRestClient struct {
 	MockGetBlockImages               func() ([]model.BlockImage, error)
 	MockCreateBlockImage             func(image model.BlockImage) (string, error)
 	MockDeleteBlockImage             func(image model.BlockImage) (string, error)
+	MockGetBlockImageMirrorStatus    func(poolName string) ([]model.BlockImageMirrorStatus, error)
+	MockGetBlockImageSnapshots       func(image model.BlockImage) ([]model.BlockSnapshot, error)
+	MockCreateBlockImageSnapshot     func(snapshot model.BlockSnapshot) (string, error)
+	MockDeleteBlockImageSnapshot     func(snapshot model.BlockSnapshot) (string, error)
+	MockRollbackBlockImageSnapshot   func(snapshot model.BlockSnapshot) (string, error)
+	MockProtectBlockImageSnapshot    func(snapshot model.BlockSnapshot) (string, error)
+	MockUnprotectBlockImageSnapshot  func(snapshot model.BlockSnapshot) (string, error)
+	MockCloneBlockImageSnapshot      func(snapshot model.BlockSnapshot, clone model.BlockImage) (string, error)
+	MockFlattenBlockImage            func(image model.BlockImage) (string, error)
+	MockResizeBlockImage             func(image model.BlockImage) (string, error)
 	MockGetClientAccessInfo          func() (model.ClientAccessInfo, error)
 	MockGetFilesystems               func() ([]model.Filesystem, error)
 	MockCreateFilesystem             func(model.FilesystemRequest) (string, error)
@@ -54,6 +64,22 @@ type MockRookRestClient struct {
 	MockGetObjectUser                func(storeName, id string) (*model.ObjectUser, error)
 	MockUpdateObjectUser             func(storeName string, user model.ObjectUser) (*model.ObjectUser, error)
 	MockDeleteObjectUser             func(storeName, id string) error
+	MockListObjectUserKeys           func(storeName, id string) ([]model.ObjectUserKey, error)
+	MockCreateObjectUserKey          func(storeName, id string, key model.ObjectUserKey) (*model.ObjectUserKey, error)
+	MockDeleteObjectUserKey          func(storeName, id, accessKey string) error
+	MockRotateObjectUserKeys         func(storeName, id string, rotation model.ObjectUserKeyRotation) (*model.ObjectUserKeyRotationResult, error)
+	MockCreateObjectSubuser          func(storeName, id string, subuser model.ObjectSubuser) (*model.ObjectUserKey, error)
+	MockDeleteObjectSubuser          func(storeName, id, name string) error
+	MockSetBucketQuota               func(storeName, bucketName string, quota *model.ObjectBucketQuota) error
+	MockSetBucketOwner               func(storeName, bucketName, owner string) error
+	MockReshardBucket                func(storeName, bucketName string, numShards int) error
+	MockCheckBucketIndex             func(storeName, bucketName string, fix bool) (*model.ObjectBucketIndexCheck, error)
+	MockGetBucketPolicy              func(storeName, bucketName string) (string, error)
+	MockSetBucketPolicy              func(storeName, bucketName, policy string) error
+	MockDeleteBucketPolicy           func(storeName, bucketName string) error
+	MockGetBucketLifecycle           func(storeName, bucketName string) ([]model.ObjectBucketLifecycleRule, error)
+	MockSetBucketLifecycle           func(storeName, bucketName string, rules []model.ObjectBucketLifecycleRule) error
+	MockDeleteBucketLifecycle        func(storeName, bucketName string) error
 }
 
 func (m *MockRookRestClient) GetNodes() ([]model.Node, error) {
@@ -112,6 +138,86 @@ func (m *MockRookRestClient) DeleteBlockImage(image model.BlockImage) (string, e
 	return "", nil
 }
 
+func (m *MockRookRestClient) GetBlockImageMirrorStatus(poolName string) ([]model.BlockImageMirrorStatus, error) {
+	if m.MockGetBlockImageMirrorStatus != nil {
+		return m.MockGetBlockImageMirrorStatus(poolName)
+	}
+
+	return nil, nil
+}
+
+func (m *MockRookRestClient) GetBlockImageSnapshots(image model.BlockImage) ([]model.BlockSnapshot, error) {
+	if m.MockGetBlockImageSnapshots != nil {
+		return m.MockGetBlockImageSnapshots(image)
+	}
+
+	return nil, nil
+}
+
+func (m *MockRookRestClient) CreateBlockImageSnapshot(snapshot model.BlockSnapshot) (string, error) {
+	if m.MockCreateBlockImageSnapshot != nil {
+		return m.MockCreateBlockImageSnapshot(snapshot)
+	}
+
+	return "", nil
+}
+
+func (m *MockRookRestClient) DeleteBlockImageSnapshot(snapshot model.BlockSnapshot) (string, error) {
+	if m.MockDeleteBlockImageSnapshot != nil {
+		return m.MockDeleteBlockImageSnapshot(snapshot)
+	}
+
+	return "", nil
+}
+
+func (m *MockRookRestClient) RollbackBlockImageSnapshot(snapshot model.BlockSnapshot) (string, error) {
+	if m.MockRollbackBlockImageSnapshot != nil {
+		return m.MockRollbackBlockImageSnapshot(snapshot)
+	}
+
+	return "", nil
+}
+
+func (m *MockRookRestClient) ProtectBlockImageSnapshot(snapshot model.BlockSnapshot) (string, error) {
+	if m.MockProtectBlockImageSnapshot != nil {
+		return m.MockProtectBlockImageSnapshot(snapshot)
+	}
+
+	return "", nil
+}
+
+func (m *MockRookRestClient) UnprotectBlockImageSnapshot(snapshot model.BlockSnapshot) (string, error) {
+	if m.MockUnprotectBlockImageSnapshot != nil {
+		return m.MockUnprotectBlockImageSnapshot(snapshot)
+	}
+
+	return "", nil
+}
+
+func (m *MockRookRestClient) CloneBlockImageSnapshot(snapshot model.BlockSnapshot, clone model.BlockImage) (string, error) {
+	if m.MockCloneBlockImageSnapshot != nil {
+		return m.MockCloneBlockImageSnapshot(snapshot, clone)
+	}
+
+	return "", nil
+}
+
+func (m *MockRookRestClient) FlattenBlockImage(image model.BlockImage) (string, error) {
+	if m.MockFlattenBlockImage != nil {
+		return m.MockFlattenBlockImage(image)
+	}
+
+	return "", nil
+}
+
+func (m *MockRookRestClient) ResizeBlockImage(image model.BlockImage) (string, error) {
+	if m.MockResizeBlockImage != nil {
+		return m.MockResizeBlockImage(image)
+	}
+
+	return "", nil
+}
+
 func (m *MockRookRestClient) GetClientAccessInfo() (model.ClientAccessInfo, error) {
 	if m.MockGetClientAccessInfo != nil {
 		return m.MockGetClientAccessInfo()
@@ -251,3 +357,131 @@ func (m *MockRookRestClient) DeleteObjectUser(storeName, id string) error {
 
 	return nil
 }
+
+func (m *MockRookRestClient) ListObjectUserKeys(storeName, id string) ([]model.ObjectUserKey, error) {
+	if m.MockListObjectUserKeys != nil {
+		return m.MockListObjectUserKeys(storeName, id)
+	}
+
+	return nil, nil
+}
+
+func (m *MockRookRestClient) CreateObjectUserKey(storeName, id string, key model.ObjectUserKey) (*model.ObjectUserKey, error) {
+	if m.MockCreateObjectUserKey != nil {
+		return m.MockCreateObjectUserKey(storeName, id, key)
+	}
+
+	return nil, nil
+}
+
+func (m *MockRookRestClient) DeleteObjectUserKey(storeName, id, accessKey string) error {
+	if m.MockDeleteObjectUserKey != nil {
+		return m.MockDeleteObjectUserKey(storeName, id, accessKey)
+	}
+
+	return nil
+}
+
+func (m *MockRookRestClient) RotateObjectUserKeys(storeName, id string, rotation model.ObjectUserKeyRotation) (*model.ObjectUserKeyRotationResult, error) {
+	if m.MockRotateObjectUserKeys != nil {
+		return m.MockRotateObjectUserKeys(storeName, id, rotation)
+	}
+
+	return nil, nil
+}
+
+func (m *MockRookRestClient) CreateObjectSubuser(storeName, id string, subuser model.ObjectSubuser) (*model.ObjectUserKey, error) {
+	if m.MockCreateObjectSubuser != nil {
+		return m.MockCreateObjectSubuser(storeName, id, subuser)
+	}
+
+	return nil, nil
+}
+
+func (m *MockRookRestClient) DeleteObjectSubuser(storeName, id, name string) error {
+	if m.MockDeleteObjectSubuser != nil {
+		return m.MockDeleteObjectSubuser(storeName, id, name)
+	}
+
+	return nil
+}
+
+func (m *MockRookRestClient) SetBucketQuota(storeName, bucketName string, quota *model.ObjectBucketQuota) error {
+	if m.MockSetBucketQuota != nil {
+		return m.MockSetBucketQuota(storeName, bucketName, quota)
+	}
+
+	return nil
+}
+
+func (m *MockRookRestClient) SetBucketOwner(storeName, bucketName, owner string) error {
+	if m.MockSetBucketOwner != nil {
+		return m.MockSetBucketOwner(storeName, bucketName, owner)
+	}
+
+	return nil
+}
+
+func (m *MockRookRestClient) ReshardBucket(storeName, bucketName string, numShards int) error {
+	if m.MockReshardBucket != nil {
+		return m.MockReshardBucket(storeName, bucketName, numShards)
+	}
+
+	return nil
+}
+
+func (m *MockRookRestClient) CheckBucketIndex(storeName, bucketName string, fix bool) (*model.ObjectBucketIndexCheck, error) {
+	if m.MockCheckBucketIndex != nil {
+		return m.MockCheckBucketIndex(storeName, bucketName, fix)
+	}
+
+	return nil, nil
+}
+
+func (m *MockRookRestClient) GetBucketPolicy(storeName, bucketName string) (string, error) {
+	if m.MockGetBucketPolicy != nil {
+		return m.MockGetBucketPolicy(storeName, bucketName)
+	}
+
+	return "", nil
+}
+
+func (m *MockRookRestClient) SetBucketPolicy(storeName, bucketName, policy string) error {
+	if m.MockSetBucketPolicy != nil {
+		return m.MockSetBucketPolicy(storeName, bucketName, policy)
+	}
+
+	return nil
+}
+
+func (m *MockRookRestClient) DeleteBucketPolicy(storeName, bucketName string) error {
+	if m.MockDeleteBucketPolicy != nil {
+		return m.MockDeleteBucketPolicy(storeName, bucketName)
+	}
+
+	return nil
+}
+
+func (m *MockRookRestClient) GetBucketLifecycle(storeName, bucketName string) ([]model.ObjectBucketLifecycleRule, error) {
+	if m.MockGetBucketLifecycle != nil {
+		return m.MockGetBucketLifecycle(storeName, bucketName)
+	}
+
+	return nil, nil
+}
+
+func (m *MockRookRestClient) SetBucketLifecycle(storeName, bucketName string, rules []model.ObjectBucketLifecycleRule) error {
+	if m.MockSetBucketLifecycle != nil {
+		return m.MockSetBucketLifecycle(storeName, bucketName, rules)
+	}
+
+	return nil
+}
+
+func (m *MockRookRestClient) DeleteBucketLifecycle(storeName, bucketName string) error {
+	if m.MockDeleteBucketLifecycle != nil {
+		return m.MockDeleteBucketLifecycle(storeName, bucketName)
+	}
+
+	return nil
+}
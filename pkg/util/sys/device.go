@@ -253,6 +253,62 @@ func UnmountDevice(devicePath string, executor exec.Executor) error {
 	return nil
 }
 
+// grow the file system on the device to the size of the device. The file system must be mounted at the mount path.
+func GrowFilesystem(devicePath, mountPath, fstype string, executor exec.Executor) error {
+	var tool string
+	var args []string
+	switch fstype {
+	case "ext2", "ext3", "ext4":
+		tool = "resize2fs"
+		args = []string{devicePath}
+	case "xfs":
+		// xfs can only be grown through the mount path
+		tool = "xfs_growfs"
+		args = []string{mountPath}
+	default:
+		return fmt.Errorf("growing file system %s on %s is not supported", fstype, devicePath)
+	}
+
+	cmd := fmt.Sprintf("%s %s", tool, devicePath)
+	if err := executor.ExecuteCommand(false, cmd, tool, args...); err != nil {
+		return fmt.Errorf("command %s failed: %+v", cmd, err)
+	}
+
+	return nil
+}
+
+// get the size of the file system on the device in bytes. The file system must be mounted at the mount path.
+func GetFilesystemSize(devicePath, mountPath, fstype string, executor exec.Executor) (uint64, error) {
+	var output string
+	var err error
+	switch fstype {
+	case "ext2", "ext3", "ext4":
+		output, err = executor.ExecuteCommandWithOutput(false, fmt.Sprintf("dumpe2fs %s", devicePath), "dumpe2fs", "-h", devicePath)
+	case "xfs":
+		output, err = executor.ExecuteCommandWithOutput(false, fmt.Sprintf("xfs_info %s", mountPath), "xfs_info", mountPath)
+	default:
+		return 0, fmt.Errorf("getting the size of file system %s on %s is not supported", fstype, devicePath)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get size of file system on %s: %+v", devicePath, err)
+	}
+
+	return parseFilesystemSize(fstype, output)
+}
+
+// get the size of the device in bytes
+func GetDeviceSize(devicePath string, executor exec.Executor) (uint64, error) {
+	props, err := GetDevicePropertiesFromPath(devicePath, executor)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get properties of %s: %+v", devicePath, err)
+	}
+	size, err := strconv.ParseUint(props["SIZE"], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get size of %s: %+v", devicePath, err)
+	}
+	return size, nil
+}
+
 func DoesDeviceHaveChildren(device string, executor exec.Executor) (bool, error) {
 	cmd := fmt.Sprintf("check children for device %s", device)
 	output, err := executor.ExecuteCommandWithOutput(false, cmd, "lsblk --all -n -l --output PKNAME")
@@ -280,6 +336,49 @@ func parseDFOutput(device, output string) string {
 	return strings.Join(fs, ",")
 }
 
+// finds the block count and block size in the output of 'dumpe2fs -h' or in the data section of 'xfs_info'
+func parseFilesystemSize(fstype, output string) (uint64, error) {
+	var blocks, blockSize uint64
+	for _, line := range strings.Split(output, "\n") {
+		if fstype == "xfs" {
+			// data     =                       bsize=4096   blocks=262144, imaxpct=25
+			if !strings.HasPrefix(strings.TrimSpace(line), "data") {
+				continue
+			}
+			for _, field := range strings.Fields(line) {
+				kv := strings.SplitN(strings.TrimSuffix(field, ","), "=", 2)
+				if len(kv) != 2 {
+					continue
+				}
+				switch kv[0] {
+				case "bsize":
+					blockSize, _ = strconv.ParseUint(kv[1], 10, 64)
+				case "blocks":
+					blocks, _ = strconv.ParseUint(kv[1], 10, 64)
+				}
+			}
+			break
+		}
+
+		// Block count:              262144
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch strings.TrimSpace(kv[0]) {
+		case "Block count":
+			blocks, _ = strconv.ParseUint(strings.TrimSpace(kv[1]), 10, 64)
+		case "Block size":
+			blockSize, _ = strconv.ParseUint(strings.TrimSpace(kv[1]), 10, 64)
+		}
+	}
+
+	if blocks == 0 || blockSize == 0 {
+		return 0, fmt.Errorf("file system size not found. output=%s", output)
+	}
+	return blocks * blockSize, nil
+}
+
 // finds the disk uuid in the output of sgdisk
 func parseUUID(device, output string) (string, error) {
 
@@ -122,6 +122,57 @@ func TestMountDeviceWithOptions(t *testing.T) {
 	MountDeviceWithOptions("/dev/abc1", "/tmp/mount1", "myfstype", "foo=bar,baz=biz", e)
 }
 
+func TestGrowFilesystem(t *testing.T) {
+	var tool string
+	var toolArgs []string
+	e := &exectest.MockExecutor{
+		MockExecuteCommand: func(debug bool, actionName string, command string, arg ...string) error {
+			tool = command
+			toolArgs = arg
+			return nil
+		},
+	}
+
+	// ext file systems are grown through the device
+	err := GrowFilesystem("/dev/rbd0", "/tmp/mount1", "ext4", e)
+	assert.Nil(t, err)
+	assert.Equal(t, "resize2fs", tool)
+	assert.Equal(t, []string{"/dev/rbd0"}, toolArgs)
+
+	// xfs is grown through the mount path
+	err = GrowFilesystem("/dev/rbd0", "/tmp/mount1", "xfs", e)
+	assert.Nil(t, err)
+	assert.Equal(t, "xfs_growfs", tool)
+	assert.Equal(t, []string{"/tmp/mount1"}, toolArgs)
+
+	err = GrowFilesystem("/dev/rbd0", "/tmp/mount1", "btrfs", e)
+	assert.NotNil(t, err)
+}
+
+func TestParseFilesystemSize(t *testing.T) {
+	dumpe2fs := `Filesystem volume name:   <none>
+Block count:              262144
+Reserved block count:     13107
+Block size:               4096
+`
+	size, err := parseFilesystemSize("ext4", dumpe2fs)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1073741824), size)
+
+	xfsInfo := `meta-data=/dev/rbd0              isize=512    agcount=8, agsize=32768 blks
+         =                       sectsz=512   attr=2, projid32bit=1
+data     =                       bsize=4096   blocks=524288, imaxpct=25
+         =                       sunit=1024   swidth=1024 blks
+log      =internal               bsize=4096   blocks=2560, version=2
+`
+	size, err = parseFilesystemSize("xfs", xfsInfo)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2147483648), size)
+
+	_, err = parseFilesystemSize("ext4", "")
+	assert.NotNil(t, err)
+}
+
 func TestGetPartitions(t *testing.T) {
 	run := 0
 	executor := &exectest.MockExecutor{